@@ -0,0 +1,97 @@
+// Backup script: go run ./cmd/backup [-keep N] [-migrate] [create | list | restore FILE]
+// create writes a consistent copy of db/data.sqlite3 (safe while the server runs) to
+// db/backups/data-<time>.sqlite3 and keeps the newest -keep backups. restore checks a backup's
+// integrity and schema version, saves the current database aside and swaps the backup in;
+// stop the server first. SQLite only; back up PostgreSQL with pg_dump.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	dbfiles "triangle_travel/db"
+	"triangle_travel/internal/backup"
+	"triangle_travel/internal/db"
+	"triangle_travel/internal/migrate"
+)
+
+func main() {
+	dataDir := flag.String("data", ".", "Project root (contains db/data.sqlite3)")
+	dir := flag.String("dir", "", "Backup directory (default <data>/db/backups)")
+	keep := flag.Int("keep", 7, "Newest backups to keep after create (0 keeps all)")
+	upgrade := flag.Bool("migrate", false, "restore: apply pending migrations to an older backup instead of refusing it")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: backup [-data dir] [-dir dir] [-keep N] [-migrate] create | list | restore FILE")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *dir == "" {
+		*dir = filepath.Join(*dataDir, "db", "backups")
+	}
+	set := &backup.Set{Dir: *dir, Keep: *keep}
+	ctx := context.Background()
+
+	cmd := flag.Arg(0)
+	if cmd == "" {
+		cmd = "create"
+	}
+	switch cmd {
+	case "create":
+		if _, err := os.Stat(db.Path(*dataDir)); err != nil {
+			log.Fatalf("Database: %v", err)
+		}
+		database, err := db.New(*dataDir, db.DefaultOptions())
+		if err != nil {
+			log.Fatalf("Database: %v", err)
+		}
+		defer database.Close()
+		set.Source = database
+		f, err := set.Create(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s (%d bytes)\n", filepath.Join(set.Dir, f.Name), f.Size)
+	case "list":
+		files, err := set.List()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, f := range files {
+			fmt.Printf("%s\t%d\t%s\n", f.Name, f.Size, f.Created.Format("2006-01-02 15:04:05Z"))
+		}
+	case "restore":
+		src := flag.Arg(1)
+		if src == "" {
+			flag.Usage()
+			os.Exit(2)
+		}
+		// A bare backup name refers to the backup directory
+		if path, err := set.Path(src); err == nil {
+			src = path
+		}
+		migrations, err := migrate.Load(dbfiles.Migrations(*dataDir, string(db.SQLite)))
+		if err != nil {
+			log.Fatal(err)
+		}
+		previous, err := backup.Restore(ctx, *dataDir, src, migrations, *upgrade)
+		if errors.Is(err, migrate.ErrPending) {
+			log.Fatalf("Restore: %v (pass -migrate to upgrade the backup)", err)
+		}
+		if err != nil {
+			log.Fatalf("Restore: %v", err)
+		}
+		if previous != "" {
+			fmt.Printf("previous database saved as %s\n", previous)
+		}
+		fmt.Printf("restored %s\n", src)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,68 @@
+// Import script: go run ./cmd/import -ourairports airports.csv -routes routes.dat ...
+// Loads OpenFlights (airports.dat, airlines.dat, routes.dat, countries.dat) and OurAirports
+// (airports.csv) files into iata_cities, city_routes, airports and airlines, then snapshots the
+// route data and prints what changed since the previous snapshot. Run from project root.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"triangle_travel/internal/dataset"
+	"triangle_travel/internal/db"
+	"triangle_travel/internal/importer"
+)
+
+func main() {
+	dataDir := flag.String("data", ".", "Project root (contains db/data.sqlite3)")
+	dsn := flag.String("database", os.Getenv("DATABASE_URL"), "PostgreSQL URL; empty uses SQLite under -data")
+	ourAirports := flag.String("ourairports", "", "OurAirports airports.csv")
+	airports := flag.String("airports", "", "OpenFlights airports.dat")
+	airlines := flag.String("airlines", "", "OpenFlights airlines.dat")
+	routes := flag.String("routes", "", "OpenFlights routes.dat")
+	countries := flag.String("countries", "", "OpenFlights countries.dat (needed for airports.dat and airlines.dat countries)")
+	replace := flag.Bool("replace", false, "Empty the imported tables before loading instead of merging")
+	flag.Parse()
+
+	src := importer.Sources{
+		OurAirports: *ourAirports,
+		Airports:    *airports,
+		Airlines:    *airlines,
+		Routes:      *routes,
+		Countries:   *countries,
+	}
+	if src.OurAirports == "" && src.Airports == "" && src.Airlines == "" && src.Routes == "" {
+		flag.Usage()
+		log.Fatal("no input files given")
+	}
+
+	database, err := db.Open(*dataDir, *dsn, db.DefaultOptions())
+	if err != nil {
+		log.Fatalf("Database: %v", err)
+	}
+	defer database.Close()
+
+	stats, err := importer.Run(database, src, *replace)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(stats)
+
+	var files []string
+	for _, f := range []string{src.OurAirports, src.Airports, src.Airlines, src.Routes} {
+		if f != "" {
+			files = append(files, filepath.Base(f))
+		}
+	}
+	recorded, err := dataset.Record(context.Background(), database, "import "+strings.Join(files, " "))
+	if err != nil {
+		log.Fatalf("Snapshot: %v", err)
+	}
+	fmt.Println(recorded)
+}
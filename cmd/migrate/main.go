@@ -0,0 +1,86 @@
+// Migrate script: go run ./cmd/migrate [up [N] | down [N] | status]
+// Applies or reverts the numbered migrations (db/migrations on disk, else the embedded copy;
+// db/migrations/postgres with -database or DATABASE_URL).
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	dbfiles "triangle_travel/db"
+	"triangle_travel/internal/db"
+	"triangle_travel/internal/migrate"
+)
+
+func main() {
+	dataDir := flag.String("data", ".", "Project root (contains db/data.sqlite3 and db/migrations)")
+	dsn := flag.String("database", os.Getenv("DATABASE_URL"), "PostgreSQL URL; empty uses SQLite under -data")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: migrate [-data dir] [-database url] up [N] | down [N] | status")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	cmd := flag.Arg(0)
+	if cmd == "" {
+		cmd = "up"
+	}
+	steps := 0
+	if n := flag.Arg(1); n != "" {
+		var err error
+		if steps, err = strconv.Atoi(n); err != nil {
+			log.Fatalf("invalid step count %q", n)
+		}
+	}
+
+	database, err := db.Open(*dataDir, *dsn, db.DefaultOptions())
+	if err != nil {
+		log.Fatalf("Database: %v", err)
+	}
+	defer database.Close()
+	migrations, err := migrate.Load(dbfiles.Migrations(*dataDir, string(database.Dialect())))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch cmd {
+	case "up":
+		ran, err := migrate.Up(database, migrations, steps)
+		for _, v := range ran {
+			fmt.Printf("applied %04d\n", v)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(ran) == 0 {
+			fmt.Println("database is up to date")
+		}
+	case "down":
+		reverted, err := migrate.Down(database, migrations, steps)
+		for _, v := range reverted {
+			fmt.Printf("reverted %04d\n", v)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "status":
+		status, err := migrate.GetStatus(database, migrations)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, s := range status {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, state)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,40 @@
+// OpenID Connect stand-in: go run ./cmd/oidcmock [-addr localhost:9090] [-client-id id -client-secret s]
+// A local identity provider whose sign-in page accepts any email, so the server's OIDC sign-in
+// runs end to end offline:
+//
+//	go run . -oidc-issuer http://localhost:9090 -oidc-client-id triangle-travel
+//
+// Signing in as the same email again is the same identity; unverified@example.com gets an ID
+// token with email_verified false. Codes and the signing key last until it stops.
+
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"triangle_travel/internal/oidc"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:9090", "Listen address")
+	issuer := flag.String("issuer", "", "Issuer URL (default http://<addr>)")
+	clientID := flag.String("client-id", "", "Client ID to require (empty accepts any client)")
+	clientSecret := flag.String("client-secret", "", "Client secret to require with -client-id (empty for a public client)")
+	flag.Parse()
+
+	if *issuer == "" {
+		*issuer = "http://" + *addr
+	}
+	mock, err := oidc.NewMock(*issuer)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mock.ClientID, mock.ClientSecret = *clientID, *clientSecret
+	mock.OnLogin = func(sub, email string) {
+		log.Printf("Signed in %s as %s", email, sub)
+	}
+	log.Printf("OIDC stand-in at %s (discovery: %s/.well-known/openid-configuration)", *issuer, *issuer)
+	log.Fatal(http.ListenAndServe(*addr, mock.Handler()))
+}
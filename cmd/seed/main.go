@@ -1,63 +1,76 @@
 // Seed script: go run ./cmd/seed
-// Populates SQLite from db/schema.sql and db/seed_data.sql. Run from project root.
+// Applies pending migrations, then replaces the reference data with db/seed_data.sql (on-disk
+// files win over the embedded copies) and snapshots it if it changed. Users, sessions and booked
+// flights are kept.
 
 package main
 
 import (
-	"database/sql"
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 
-	_ "modernc.org/sqlite"
+	dbfiles "triangle_travel/db"
+	"triangle_travel/internal/dataset"
+	"triangle_travel/internal/db"
+	"triangle_travel/internal/migrate"
 )
 
 func main() {
-	wd, _ := os.Getwd()
-	dbPath := filepath.Join(wd, "db", "data.sqlite3")
-	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
-		log.Fatal(err)
+	dataDir := flag.String("data", ".", "Project root (contains db/)")
+	dsn := flag.String("database", os.Getenv("DATABASE_URL"), "PostgreSQL URL; empty uses SQLite under -data")
+	fresh := flag.Bool("fresh", false, "Delete the database first (wipes users, sessions and flights; SQLite only)")
+	flag.Parse()
+
+	dbPath := filepath.Join(*dataDir, "db", "data.sqlite3")
+	if *dsn != "" {
+		if *fresh {
+			log.Fatal("-fresh only applies to SQLite; revert with go run ./cmd/migrate down instead")
+		}
+		dbPath = "PostgreSQL"
+	} else {
+		if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+			log.Fatal(err)
+		}
+		if *fresh {
+			for _, suffix := range []string{"", "-wal", "-shm"} {
+				os.Remove(dbPath + suffix)
+			}
+		}
 	}
-	os.Remove(dbPath) // Start fresh
 
-	db, err := sql.Open("sqlite", dbPath)
+	database, err := db.Open(*dataDir, *dsn, db.DefaultOptions())
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer db.Close()
+	defer database.Close()
 
-	// Schema
-	schemaPath := filepath.Join(wd, "db", "schema.sql")
-	schema, err := os.ReadFile(schemaPath)
+	migrations, err := migrate.Load(dbfiles.Migrations(*dataDir, string(database.Dialect())))
 	if err != nil {
 		log.Fatal(err)
 	}
-	if _, err := db.Exec(string(schema)); err != nil {
+	ran, err := migrate.Up(database, migrations, 0)
+	if err != nil {
 		log.Fatal(err)
 	}
-	log.Println("Schema created")
+	log.Printf("Migrations applied: %d", len(ran))
 
-	// Auth schema
-	authSchemaPath := filepath.Join(wd, "db", "schema_auth.sql")
-	authSchema, err := os.ReadFile(authSchemaPath)
-	if err == nil {
-		if _, err := db.Exec(string(authSchema)); err != nil {
-			log.Fatal(err)
-		}
-		log.Println("Auth schema created")
-	}
-
-	// Seed data (embedded in SQL)
-	seedPath := filepath.Join(wd, "db", "seed_data.sql")
-	seedData, err := os.ReadFile(seedPath)
+	seedData, err := dbfiles.SeedData(*dataDir)
 	if err != nil {
 		log.Fatal(err)
 	}
-	if _, err := db.Exec(string(seedData)); err != nil {
+	if err := migrate.RefreshReference(database, string(seedData)); err != nil {
 		log.Fatal(err)
 	}
-	log.Println("Seed data loaded")
+	log.Println("Reference data loaded")
+	recorded, err := dataset.Record(context.Background(), database, "seed")
+	if err != nil {
+		log.Fatalf("Snapshot: %v", err)
+	}
+	log.Println(recorded)
 
 	fmt.Println("Database seeded successfully at", dbPath)
 }
@@ -0,0 +1,30 @@
+// SMS gateway stand-in: go run ./cmd/smsgateway [-addr localhost:8099] [-sid AC...] [-token ...]
+// Serves the Twilio Messages API locally and prints each message instead of delivering it, so
+// the server's twilio sender can be exercised end to end:
+//
+//	go run . -sms twilio -twilio-url http://localhost:8099 -twilio-sid ACtest -twilio-token t -sms-from +15005550006
+//
+// GET /messages?to=+1... lists received messages as JSON; DELETE /messages clears them.
+
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"triangle_travel/internal/sms"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8099", "Listen address")
+	sid := flag.String("sid", "", "Account SID to require (empty accepts any credentials)")
+	token := flag.String("token", "", "Auth token to require with -sid")
+	flag.Parse()
+
+	gateway := &sms.Gateway{AccountSID: *sid, AuthToken: *token, OnMessage: func(m sms.Message) {
+		log.Printf("%s -> %s: %s", m.From, m.To, m.Body)
+	}}
+	log.Printf("SMS gateway stand-in listening on http://%s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, gateway.Handler()))
+}
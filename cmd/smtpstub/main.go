@@ -0,0 +1,36 @@
+// SMTP stand-in: go run ./cmd/smtpstub [-addr localhost:2525] [-http localhost:8025] [-user u -password p]
+// Accepts mail over SMTP and prints each message instead of delivering it, so the server's smtp
+// sender can be exercised end to end:
+//
+//	go run . -mail smtp -smtp-addr localhost:2525 -mail-from login@triangle.travel
+//
+// GET /messages?to=someone@example.com on the -http address lists received messages as JSON;
+// DELETE /messages clears them.
+
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"triangle_travel/internal/mail"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:2525", "SMTP listen address")
+	httpAddr := flag.String("http", "localhost:8025", "Listen address for the message list")
+	user := flag.String("user", "", "AUTH PLAIN username to require (empty accepts mail without AUTH)")
+	password := flag.String("password", "", "Password to require with -user")
+	flag.Parse()
+
+	server := &mail.Server{Username: *user, Password: *password, OnMessage: func(m mail.Message) {
+		log.Printf("%s -> %s: %s\n%s", m.From, m.To, m.Subject, m.Body)
+	}}
+	go func() {
+		log.Printf("Message list on http://%s/messages", *httpAddr)
+		log.Fatal(http.ListenAndServe(*httpAddr, server.Handler()))
+	}()
+	log.Printf("SMTP stand-in listening on %s", *addr)
+	log.Fatal(server.ListenAndServe(*addr))
+}
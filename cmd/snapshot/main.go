@@ -0,0 +1,119 @@
+// Snapshot script: go run ./cmd/snapshot [-source S] [-json] [create | list | diff FROM [TO] | delete ID]
+// Snapshots freeze the route data (cities, routes, distances, airports); seed and import take one
+// automatically when the data changed. diff compares two snapshots, or a snapshot and "current"
+// (the live tables, the default TO). Searches can be pinned to a snapshot by ID.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"triangle_travel/internal/dataset"
+	"triangle_travel/internal/db"
+)
+
+func main() {
+	dataDir := flag.String("data", ".", "Project root (contains db/data.sqlite3)")
+	dsn := flag.String("database", os.Getenv("DATABASE_URL"), "PostgreSQL URL; empty uses SQLite under -data")
+	source := flag.String("source", "manual", "create: what produced the data")
+	asJSON := flag.Bool("json", false, "list, diff: print JSON")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: snapshot [-data dir] [-database url] [-source S] [-json] create | list | diff FROM [TO] | delete ID")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	database, err := db.Open(*dataDir, *dsn, db.DefaultOptions())
+	if err != nil {
+		log.Fatalf("Database: %v", err)
+	}
+	defer database.Close()
+	ctx := context.Background()
+
+	switch flag.Arg(0) {
+	case "create":
+		s, err := database.CreateSnapshot(ctx, *source)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("snapshot %d: %d city airports, %d routes, %d distances, %d airports\n",
+			s.ID, s.CityAirports, s.Routes, s.Distances, s.Airports)
+	case "list":
+		snapshots, err := database.ListSnapshots(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *asJSON {
+			printJSON(snapshots)
+			return
+		}
+		for _, s := range snapshots {
+			fmt.Printf("%d\t%s\t%s\troutes=%d distances=%d airports=%d\n",
+				s.ID, s.CreatedAt.Format("2006-01-02 15:04:05Z"), s.Source, s.Routes, s.Distances, s.Airports)
+		}
+	case "diff":
+		if flag.Arg(1) == "" {
+			flag.Usage()
+			os.Exit(2)
+		}
+		from, to := routeData(database, flag.Arg(1)), routeData(database, flag.Arg(2))
+		diff, err := dataset.Compare(ctx, from, to)
+		if errors.Is(err, db.ErrSnapshotNotFound) {
+			log.Fatal("Snapshot not found (see snapshot list)")
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *asJSON {
+			printJSON(diff)
+			return
+		}
+		if err := diff.WriteText(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+	case "delete":
+		id, err := strconv.ParseInt(flag.Arg(1), 10, 64)
+		if err != nil {
+			flag.Usage()
+			os.Exit(2)
+		}
+		ok, err := database.DeleteSnapshot(ctx, id)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !ok {
+			log.Fatalf("snapshot %d not found", id)
+		}
+		fmt.Printf("deleted snapshot %d\n", id)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// routeData resolves a snapshot ID, or "current" (or empty) for the live tables
+func routeData(database *db.DB, ref string) db.RouteData {
+	if ref == "" || ref == "current" {
+		return database
+	}
+	id, err := strconv.ParseInt(ref, 10, 64)
+	if err != nil || id <= 0 {
+		log.Fatalf("%q is not a snapshot ID or current", ref)
+	}
+	return database.SnapshotData(id)
+}
+
+func printJSON(v interface{}) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,95 @@
+// Validate script: go run ./cmd/validate [-seed] [-strict] [-json]
+// Checks the reference data for asymmetric or impossible distances, unknown codes, duplicate or
+// self routes and orphan city codes. Exits 1 on errors (or on warnings with -strict) so data
+// changes can be gated in CI.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	dbfiles "triangle_travel/db"
+	"triangle_travel/internal/db"
+	"triangle_travel/internal/migrate"
+	"triangle_travel/internal/refcheck"
+)
+
+func main() {
+	dataDir := flag.String("data", ".", "Project root (contains db/)")
+	dsn := flag.String("database", os.Getenv("DATABASE_URL"), "PostgreSQL URL; empty uses SQLite under -data")
+	seed := flag.Bool("seed", false, "Validate db/seed_data.sql in a scratch database instead of db/data.sqlite3")
+	strict := flag.Bool("strict", false, "Fail on warnings too")
+	asJSON := flag.Bool("json", false, "Print the report as JSON")
+	flag.Parse()
+
+	var database *db.DB
+	var err error
+	if *seed {
+		database, err = scratch(*dataDir)
+	} else {
+		if _, statErr := os.Stat(filepath.Join(*dataDir, "db", "data.sqlite3")); *dsn == "" && statErr != nil {
+			log.Fatalf("No database in %s (run: go run ./cmd/seed, or pass -seed)", *dataDir)
+		}
+		database, err = db.Open(*dataDir, *dsn, db.DefaultOptions())
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer database.Close()
+
+	report, err := refcheck.Run(context.Background(), database)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		report.Write(os.Stdout)
+	}
+
+	if report.Errors() > 0 || *strict && report.Warnings() > 0 {
+		os.Exit(1)
+	}
+}
+
+// scratch builds a throwaway database from the migrations and seed data
+func scratch(dataDir string) (*db.DB, error) {
+	dir, err := os.MkdirTemp("", "triangle-validate-")
+	if err != nil {
+		return nil, err
+	}
+	// The open handle keeps the file usable on Unix; the directory is gone once we exit
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "db"), 0755); err != nil {
+		return nil, err
+	}
+	// Driver defaults: WAL would need side files in a directory that is already gone
+	database, err := db.New(dir, db.Options{})
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := migrate.Load(dbfiles.Migrations(dataDir, string(database.Dialect())))
+	if err == nil {
+		_, err = migrate.Up(database, migrations, 0)
+	}
+	var seedData []byte
+	if err == nil {
+		seedData, err = dbfiles.SeedData(dataDir)
+	}
+	if err == nil {
+		err = migrate.RefreshReference(database, string(seedData))
+	}
+	if err != nil {
+		database.Close()
+		return nil, fmt.Errorf("scratch database: %w", err)
+	}
+	return database, nil
+}
@@ -0,0 +1,40 @@
+// Package dbfiles embeds the SQL migrations and reference seed data so the server binary can
+// initialize a database on its own. Files on disk under <data>/db take precedence (development).
+package dbfiles
+
+import (
+	"embed"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+//go:embed migrations/*.sql migrations/postgres/*.sql
+var migrations embed.FS
+
+//go:embed seed_data.sql
+var seedData []byte
+
+// Migrations returns the migrations for a dialect ("sqlite" or "postgres"): dataDir/db/migrations
+// (or its postgres/ subdirectory) if it exists, else the embedded copy
+func Migrations(dataDir, dialect string) fs.FS {
+	rel := "migrations"
+	if dialect == "postgres" {
+		rel = "migrations/postgres"
+	}
+	dir := filepath.Join(dataDir, "db", filepath.FromSlash(rel))
+	if info, err := os.Stat(dir); err == nil && info.IsDir() {
+		return os.DirFS(dir)
+	}
+	sub, _ := fs.Sub(migrations, rel)
+	return sub
+}
+
+// SeedData returns dataDir/db/seed_data.sql if it exists, else the embedded copy
+func SeedData(dataDir string) ([]byte, error) {
+	b, err := os.ReadFile(filepath.Join(dataDir, "db", "seed_data.sql"))
+	if os.IsNotExist(err) {
+		return seedData, nil
+	}
+	return b, err
+}
@@ -0,0 +1,23 @@
+-- Drop reference schema
+
+DROP TRIGGER IF EXISTS trg_iata_cities_ins;
+DROP TRIGGER IF EXISTS trg_iata_cities_upd;
+DROP TRIGGER IF EXISTS trg_iata_cities_del;
+DROP TRIGGER IF EXISTS trg_city_routes_ins;
+DROP TRIGGER IF EXISTS trg_city_routes_upd;
+DROP TRIGGER IF EXISTS trg_city_routes_del;
+DROP TRIGGER IF EXISTS trg_distances_ins;
+DROP TRIGGER IF EXISTS trg_distances_upd;
+DROP TRIGGER IF EXISTS trg_distances_del;
+DROP TABLE IF EXISTS reference_version;
+DROP TABLE IF EXISTS airlines;
+DROP TABLE IF EXISTS rtw_rule_sets;
+DROP TABLE IF EXISTS award_zone_prices;
+DROP TABLE IF EXISTS award_zones;
+DROP TABLE IF EXISTS award_distance_bands;
+DROP TABLE IF EXISTS award_programs;
+DROP TABLE IF EXISTS airports;
+DROP TABLE IF EXISTS stopover_programs;
+DROP TABLE IF EXISTS city_routes;
+DROP TABLE IF EXISTS distances;
+DROP TABLE IF EXISTS iata_cities;
@@ -0,0 +1,146 @@
+-- Triangle Travel reference schema (airports, cities, routes, distances, programs)
+
+-- City codes to airport IATA codes
+CREATE TABLE IF NOT EXISTS iata_cities (
+    city_code TEXT NOT NULL,
+    airport_code TEXT NOT NULL,
+    PRIMARY KEY (city_code, airport_code)
+);
+
+CREATE INDEX IF NOT EXISTS idx_iata_cities_city ON iata_cities(city_code);
+
+-- Distances between airports in miles
+CREATE TABLE IF NOT EXISTS distances (
+    from_iata TEXT NOT NULL,
+    to_iata TEXT NOT NULL,
+    distance_miles REAL NOT NULL,
+    PRIMARY KEY (from_iata, to_iata)
+);
+
+CREATE INDEX IF NOT EXISTS idx_distances_from ON distances(from_iata);
+CREATE INDEX IF NOT EXISTS idx_distances_to ON distances(to_iata);
+
+-- City routes by alliance
+CREATE TABLE IF NOT EXISTS city_routes (
+    city_iata TEXT NOT NULL,
+    alliance TEXT NOT NULL,
+    route_to TEXT NOT NULL,
+    PRIMARY KEY (city_iata, alliance, route_to)
+);
+
+CREATE INDEX IF NOT EXISTS idx_city_routes_city ON city_routes(city_iata);
+CREATE INDEX IF NOT EXISTS idx_city_routes_alliance ON city_routes(city_iata, alliance);
+
+-- Free stopover programs by airline (hub city, max nights, fare conditions)
+CREATE TABLE IF NOT EXISTS stopover_programs (
+    airline_code TEXT NOT NULL,
+    airline TEXT NOT NULL,
+    program TEXT NOT NULL,
+    alliance TEXT NOT NULL,
+    hub_city TEXT NOT NULL,
+    max_nights INTEGER NOT NULL,
+    fare_restrictions TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (airline_code, hub_city)
+);
+
+CREATE INDEX IF NOT EXISTS idx_stopover_programs_alliance ON stopover_programs(alliance);
+
+-- Airport master data (coordinates in decimal degrees, country ISO 3166-1 alpha-2)
+CREATE TABLE IF NOT EXISTS airports (
+    iata_code TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    city_code TEXT NOT NULL,
+    country TEXT NOT NULL,
+    continent TEXT NOT NULL,
+    latitude REAL NOT NULL,
+    longitude REAL NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_airports_city ON airports(city_code);
+
+-- Award (points) programs; chart_type is distance, distance_total or zone
+CREATE TABLE IF NOT EXISTS award_programs (
+    code TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    alliance TEXT NOT NULL,
+    chart_type TEXT NOT NULL,
+    one_way_allowed INTEGER NOT NULL DEFAULT 1,
+    open_jaw_allowed INTEGER NOT NULL DEFAULT 1,
+    max_stopovers INTEGER NOT NULL DEFAULT 0,
+    stopover_points INTEGER NOT NULL DEFAULT 0
+);
+
+-- Distance-based award charts: one-way points per cabin and mileage band
+CREATE TABLE IF NOT EXISTS award_distance_bands (
+    program TEXT NOT NULL,
+    cabin TEXT NOT NULL,
+    min_miles INTEGER NOT NULL,
+    max_miles INTEGER NOT NULL,
+    points INTEGER NOT NULL,
+    PRIMARY KEY (program, cabin, min_miles)
+);
+
+-- Zone-based award charts: area is a country (ISO alpha-2) or continent code;
+-- country rows take precedence over their continent
+CREATE TABLE IF NOT EXISTS award_zones (
+    program TEXT NOT NULL,
+    area_type TEXT NOT NULL CHECK (area_type IN ('country', 'continent')),
+    area TEXT NOT NULL,
+    zone TEXT NOT NULL,
+    PRIMARY KEY (program, area_type, area)
+);
+
+-- One-way points between two zones (symmetric)
+CREATE TABLE IF NOT EXISTS award_zone_prices (
+    program TEXT NOT NULL,
+    cabin TEXT NOT NULL,
+    from_zone TEXT NOT NULL,
+    to_zone TEXT NOT NULL,
+    points INTEGER NOT NULL,
+    PRIMARY KEY (program, cabin, from_zone, to_zone)
+);
+
+-- Round-the-world award/fare rule sets (0 disables a numeric limit)
+CREATE TABLE IF NOT EXISTS rtw_rule_sets (
+    code TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    alliance TEXT NOT NULL,
+    min_continents INTEGER NOT NULL DEFAULT 0,
+    max_continents INTEGER NOT NULL DEFAULT 0,
+    max_miles INTEGER NOT NULL DEFAULT 0,
+    min_segments INTEGER NOT NULL DEFAULT 0,
+    max_segments INTEGER NOT NULL DEFAULT 16,
+    one_direction INTEGER NOT NULL DEFAULT 1,
+    max_atlantic_crossings INTEGER NOT NULL DEFAULT 1,
+    max_pacific_crossings INTEGER NOT NULL DEFAULT 1
+);
+
+-- Airlines (alliance is None, ONE_WORLD, SKY_TEAM or STAR_ALLIANCE)
+CREATE TABLE IF NOT EXISTS airlines (
+    iata_code TEXT PRIMARY KEY,
+    icao_code TEXT NOT NULL DEFAULT '',
+    name TEXT NOT NULL,
+    country TEXT NOT NULL DEFAULT '',
+    alliance TEXT NOT NULL DEFAULT 'None',
+    active INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS idx_airlines_alliance ON airlines(alliance);
+
+-- Reference data version, bumped by triggers so in-memory route graphs know when to reload
+CREATE TABLE IF NOT EXISTS reference_version (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    version INTEGER NOT NULL DEFAULT 0
+);
+
+INSERT OR IGNORE INTO reference_version (id, version) VALUES (1, 0);
+
+CREATE TRIGGER IF NOT EXISTS trg_iata_cities_ins AFTER INSERT ON iata_cities BEGIN UPDATE reference_version SET version = version + 1; END;
+CREATE TRIGGER IF NOT EXISTS trg_iata_cities_upd AFTER UPDATE ON iata_cities BEGIN UPDATE reference_version SET version = version + 1; END;
+CREATE TRIGGER IF NOT EXISTS trg_iata_cities_del AFTER DELETE ON iata_cities BEGIN UPDATE reference_version SET version = version + 1; END;
+CREATE TRIGGER IF NOT EXISTS trg_city_routes_ins AFTER INSERT ON city_routes BEGIN UPDATE reference_version SET version = version + 1; END;
+CREATE TRIGGER IF NOT EXISTS trg_city_routes_upd AFTER UPDATE ON city_routes BEGIN UPDATE reference_version SET version = version + 1; END;
+CREATE TRIGGER IF NOT EXISTS trg_city_routes_del AFTER DELETE ON city_routes BEGIN UPDATE reference_version SET version = version + 1; END;
+CREATE TRIGGER IF NOT EXISTS trg_distances_ins AFTER INSERT ON distances BEGIN UPDATE reference_version SET version = version + 1; END;
+CREATE TRIGGER IF NOT EXISTS trg_distances_upd AFTER UPDATE ON distances BEGIN UPDATE reference_version SET version = version + 1; END;
+CREATE TRIGGER IF NOT EXISTS trg_distances_del AFTER DELETE ON distances BEGIN UPDATE reference_version SET version = version + 1; END;
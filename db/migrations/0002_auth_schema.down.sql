@@ -0,0 +1,6 @@
+-- Drop auth and user flights
+
+DROP TABLE IF EXISTS booked_flights;
+DROP TABLE IF EXISTS otp_codes;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS users;
@@ -1,4 +1,4 @@
--- Auth and user flights (run after schema.sql)
+-- Auth and user flights
 
 -- Users (phone is E.164, e.g. +15551234567)
 CREATE TABLE IF NOT EXISTS users (
@@ -0,0 +1,5 @@
+-- Stop airport edits from bumping reference_version
+
+DROP TRIGGER IF EXISTS trg_airports_ins;
+DROP TRIGGER IF EXISTS trg_airports_upd;
+DROP TRIGGER IF EXISTS trg_airports_del;
@@ -0,0 +1,6 @@
+-- The route graph also holds airport coordinates (spatial index), so airport edits must bump
+-- reference_version like cities, routes and distances do
+
+CREATE TRIGGER IF NOT EXISTS trg_airports_ins AFTER INSERT ON airports BEGIN UPDATE reference_version SET version = version + 1; END;
+CREATE TRIGGER IF NOT EXISTS trg_airports_upd AFTER UPDATE ON airports BEGIN UPDATE reference_version SET version = version + 1; END;
+CREATE TRIGGER IF NOT EXISTS trg_airports_del AFTER DELETE ON airports BEGIN UPDATE reference_version SET version = version + 1; END;
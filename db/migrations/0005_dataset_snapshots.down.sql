@@ -0,0 +1,7 @@
+-- Drop dataset snapshots
+
+DROP TABLE IF EXISTS snapshot_airports;
+DROP TABLE IF EXISTS snapshot_distances;
+DROP TABLE IF EXISTS snapshot_city_routes;
+DROP TABLE IF EXISTS snapshot_iata_cities;
+DROP TABLE IF EXISTS dataset_snapshots;
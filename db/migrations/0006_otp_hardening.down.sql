@@ -0,0 +1,11 @@
+-- Back to plaintext login codes without attempt limits
+
+DROP TABLE IF EXISTS otp_lockouts;
+DROP TABLE IF EXISTS otp_codes;
+
+CREATE TABLE otp_codes (
+    phone TEXT NOT NULL,
+    code TEXT NOT NULL,
+    expires_at DATETIME NOT NULL,
+    PRIMARY KEY (phone)
+);
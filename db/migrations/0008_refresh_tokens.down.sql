@@ -0,0 +1,4 @@
+-- Drop refresh tokens; access tokens then last as long as their session
+
+DROP TABLE IF EXISTS refresh_tokens;
+ALTER TABLE sessions DROP COLUMN access_expires_at;
@@ -0,0 +1,18 @@
+-- Sessions hand out short-lived access tokens, renewed with single-use refresh tokens. A
+-- session's refresh tokens are one family: presenting one that was already used signs the
+-- session out. Sessions from before this have no refresh token; their token keeps working as an
+-- access token for one access-token lifetime (15 minutes), then they sign in again.
+
+ALTER TABLE sessions ADD COLUMN access_expires_at DATETIME;
+UPDATE sessions SET access_expires_at = MIN(expires_at, datetime('now', '+15 minutes'));
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    token_hash TEXT NOT NULL,
+    session_id INTEGER NOT NULL,
+    created_at DATETIME NOT NULL,
+    used_at DATETIME,
+    PRIMARY KEY (token_hash),
+    FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session ON refresh_tokens(session_id);
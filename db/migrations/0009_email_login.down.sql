@@ -0,0 +1,21 @@
+-- migrate:foreign-keys off
+-- Back to phone-only users: accounts without a phone are deleted with their sessions and flights
+
+DROP TABLE IF EXISTS email_links;
+
+DELETE FROM refresh_tokens WHERE session_id IN
+    (SELECT id FROM sessions WHERE user_id IN (SELECT id FROM users WHERE phone IS NULL));
+DELETE FROM sessions WHERE user_id IN (SELECT id FROM users WHERE phone IS NULL);
+DELETE FROM booked_flights WHERE user_id IN (SELECT id FROM users WHERE phone IS NULL);
+
+CREATE TABLE users_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    phone TEXT NOT NULL UNIQUE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO users_old (id, phone, created_at) SELECT id, phone, created_at FROM users WHERE phone IS NOT NULL;
+DROP TABLE users;
+ALTER TABLE users_old RENAME TO users;
+
+CREATE INDEX IF NOT EXISTS idx_users_phone ON users(phone);
@@ -0,0 +1,28 @@
+-- migrate:foreign-keys off
+-- Users sign in by phone, email or both: users.phone becomes optional and users.email is added.
+-- SQLite can't drop NOT NULL in place, so users is rebuilt (keeping ids). email_links holds
+-- magic-link tokens, hashed; user_id is set on links that add an email to an existing account.
+
+CREATE TABLE users_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    phone TEXT UNIQUE,
+    email TEXT UNIQUE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO users_new (id, phone, created_at) SELECT id, phone, created_at FROM users;
+DROP TABLE users;
+ALTER TABLE users_new RENAME TO users;
+
+CREATE TABLE IF NOT EXISTS email_links (
+    token_hash TEXT NOT NULL,
+    email TEXT NOT NULL,
+    user_id INTEGER,
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME,
+    PRIMARY KEY (token_hash),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_links_email ON email_links(email, created_at);
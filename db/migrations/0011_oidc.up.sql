@@ -0,0 +1,29 @@
+-- OpenID Connect sign-in: the external identities (issuer + subject) linked to users, and the
+-- logins in flight between redirecting to the identity provider and its callback. A login's
+-- state is stored hashed and taken once; user_id is set when a signed-in user links an identity.
+
+CREATE TABLE IF NOT EXISTS oidc_identities (
+    issuer TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    user_id INTEGER NOT NULL,
+    email TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    last_login_at DATETIME,
+    PRIMARY KEY (issuer, subject),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_oidc_identities_user ON oidc_identities(user_id);
+
+CREATE TABLE IF NOT EXISTS oidc_logins (
+    state_hash TEXT NOT NULL,
+    nonce TEXT NOT NULL,
+    code_verifier TEXT NOT NULL,
+    user_id INTEGER,
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    PRIMARY KEY (state_hash),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_oidc_logins_expiry ON oidc_logins(expires_at);
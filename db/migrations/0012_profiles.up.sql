@@ -0,0 +1,17 @@
+-- Travel profiles: one row per user who saved one. Lists (home airports, loyalty programs,
+-- nationalities) are comma-separated codes, in the user's order. A zero ground radius means the
+-- search default.
+
+CREATE TABLE IF NOT EXISTS user_profiles (
+    user_id INTEGER NOT NULL,
+    display_name TEXT NOT NULL DEFAULT '',
+    home_airports TEXT NOT NULL DEFAULT '',
+    preferred_alliance TEXT NOT NULL DEFAULT '',
+    preferred_cabin TEXT NOT NULL DEFAULT '',
+    loyalty_programs TEXT NOT NULL DEFAULT '',
+    nationalities TEXT NOT NULL DEFAULT '',
+    ground_radius_miles REAL NOT NULL DEFAULT 0,
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
@@ -0,0 +1,18 @@
+-- Drop reference schema
+
+DROP TRIGGER IF EXISTS trg_iata_cities_version ON iata_cities;
+DROP TRIGGER IF EXISTS trg_city_routes_version ON city_routes;
+DROP TRIGGER IF EXISTS trg_distances_version ON distances;
+DROP FUNCTION IF EXISTS bump_reference_version();
+DROP TABLE IF EXISTS reference_version;
+DROP TABLE IF EXISTS airlines;
+DROP TABLE IF EXISTS rtw_rule_sets;
+DROP TABLE IF EXISTS award_zone_prices;
+DROP TABLE IF EXISTS award_zones;
+DROP TABLE IF EXISTS award_distance_bands;
+DROP TABLE IF EXISTS award_programs;
+DROP TABLE IF EXISTS airports;
+DROP TABLE IF EXISTS stopover_programs;
+DROP TABLE IF EXISTS city_routes;
+DROP TABLE IF EXISTS distances;
+DROP TABLE IF EXISTS iata_cities;
@@ -0,0 +1,48 @@
+-- Auth and user flights (PostgreSQL)
+
+-- Users (phone is E.164, e.g. +15551234567)
+CREATE TABLE IF NOT EXISTS users (
+    id BIGSERIAL PRIMARY KEY,
+    phone TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_users_phone ON users(phone);
+
+-- Sessions (JWT or opaque token)
+CREATE TABLE IF NOT EXISTS sessions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    token TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_token ON sessions(token);
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id);
+
+-- OTP codes (short-lived)
+CREATE TABLE IF NOT EXISTS otp_codes (
+    phone TEXT NOT NULL,
+    code TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (phone)
+);
+
+-- Booked flights (user-entered)
+CREATE TABLE IF NOT EXISTS booked_flights (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    airline TEXT NOT NULL,
+    flight_number TEXT NOT NULL,
+    from_iata TEXT NOT NULL,
+    to_iata TEXT NOT NULL,
+    departure_date TEXT NOT NULL,
+    departure_time TEXT,
+    confirmation TEXT,
+    created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_booked_flights_user ON booked_flights(user_id);
@@ -0,0 +1,3 @@
+-- Stop airport edits from bumping reference_version
+
+DROP TRIGGER IF EXISTS trg_airports_version ON airports;
@@ -0,0 +1,5 @@
+-- The route graph also holds airport coordinates (spatial index), so airport edits must bump
+-- reference_version like cities, routes and distances do
+
+CREATE TRIGGER trg_airports_version AFTER INSERT OR UPDATE OR DELETE ON airports
+    FOR EACH STATEMENT EXECUTE FUNCTION bump_reference_version();
@@ -0,0 +1,15 @@
+-- Audit log of admin changes to reference data (before/after are JSON rows; null on create/delete)
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor TEXT NOT NULL,
+    action TEXT NOT NULL CHECK (action IN ('create', 'update', 'delete')),
+    entity TEXT NOT NULL,
+    entity_key TEXT NOT NULL,
+    before_json TEXT,
+    after_json TEXT,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity, entity_key);
+
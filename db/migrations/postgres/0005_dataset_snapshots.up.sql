@@ -0,0 +1,52 @@
+-- Dataset snapshots: frozen copies of the route data (cities, routes, distances, airports) taken
+-- after each import or seed, so changes can be diffed and searches pinned to a version
+
+CREATE TABLE IF NOT EXISTS dataset_snapshots (
+    id BIGSERIAL PRIMARY KEY,
+    source TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    reference_version BIGINT NOT NULL,
+    city_airports INTEGER NOT NULL,
+    routes INTEGER NOT NULL,
+    distances INTEGER NOT NULL,
+    airports INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS snapshot_iata_cities (
+    snapshot_id BIGINT NOT NULL,
+    city_code TEXT NOT NULL,
+    airport_code TEXT NOT NULL,
+    PRIMARY KEY (snapshot_id, city_code, airport_code),
+    FOREIGN KEY (snapshot_id) REFERENCES dataset_snapshots(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS snapshot_city_routes (
+    snapshot_id BIGINT NOT NULL,
+    city_iata TEXT NOT NULL,
+    alliance TEXT NOT NULL,
+    route_to TEXT NOT NULL,
+    PRIMARY KEY (snapshot_id, city_iata, alliance, route_to),
+    FOREIGN KEY (snapshot_id) REFERENCES dataset_snapshots(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS snapshot_distances (
+    snapshot_id BIGINT NOT NULL,
+    from_iata TEXT NOT NULL,
+    to_iata TEXT NOT NULL,
+    distance_miles DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (snapshot_id, from_iata, to_iata),
+    FOREIGN KEY (snapshot_id) REFERENCES dataset_snapshots(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS snapshot_airports (
+    snapshot_id BIGINT NOT NULL,
+    iata_code TEXT NOT NULL,
+    name TEXT NOT NULL,
+    city_code TEXT NOT NULL,
+    country TEXT NOT NULL,
+    continent TEXT NOT NULL,
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (snapshot_id, iata_code),
+    FOREIGN KEY (snapshot_id) REFERENCES dataset_snapshots(id) ON DELETE CASCADE
+);
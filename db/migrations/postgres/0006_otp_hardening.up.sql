@@ -0,0 +1,21 @@
+-- Login codes are kept as salted hashes with a count of wrong guesses; otp_lockouts outlives
+-- individual codes so requesting a new one doesn't reset a lockout. Pending plaintext codes are
+-- dropped (they expire within minutes anyway).
+
+DROP TABLE IF EXISTS otp_codes;
+
+CREATE TABLE otp_codes (
+    phone TEXT NOT NULL,
+    code_hash TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    sent_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (phone)
+);
+
+CREATE TABLE IF NOT EXISTS otp_lockouts (
+    phone TEXT NOT NULL,
+    lockouts INTEGER NOT NULL,
+    locked_until TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (phone)
+);
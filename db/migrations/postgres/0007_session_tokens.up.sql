@@ -0,0 +1,19 @@
+-- Sessions keep a SHA-256 hash of the bearer token instead of the token, plus the device and IP
+-- they were used from. Existing sessions held raw tokens and can't be converted, so they are
+-- dropped: everyone signs in again once.
+
+DROP TABLE IF EXISTS sessions;
+
+CREATE TABLE sessions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL,
+    last_used_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id);
@@ -0,0 +1,12 @@
+-- Back to phone-only users: accounts without a phone are deleted with their sessions and flights
+
+DROP TABLE IF EXISTS email_links;
+
+DELETE FROM refresh_tokens WHERE session_id IN
+    (SELECT id FROM sessions WHERE user_id IN (SELECT id FROM users WHERE phone IS NULL));
+DELETE FROM sessions WHERE user_id IN (SELECT id FROM users WHERE phone IS NULL);
+DELETE FROM booked_flights WHERE user_id IN (SELECT id FROM users WHERE phone IS NULL);
+DELETE FROM users WHERE phone IS NULL;
+
+ALTER TABLE users DROP COLUMN email;
+ALTER TABLE users ALTER COLUMN phone SET NOT NULL;
@@ -0,0 +1,19 @@
+-- Users sign in by phone, email or both: users.phone becomes optional and users.email is added.
+-- email_links holds magic-link tokens, hashed; user_id is set on links that add an email to an
+-- existing account.
+
+ALTER TABLE users ALTER COLUMN phone DROP NOT NULL;
+ALTER TABLE users ADD COLUMN email TEXT UNIQUE;
+
+CREATE TABLE IF NOT EXISTS email_links (
+    token_hash TEXT NOT NULL,
+    email TEXT NOT NULL,
+    user_id BIGINT,
+    created_at TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    PRIMARY KEY (token_hash),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_links_email ON email_links(email, created_at);
@@ -1,4 +1,4 @@
--- Seed data (from JSON, now embedded in SQL)
+-- Reference data (airports, cities, routes, distances, programs). Replaced wholesale by cmd/seed; never touches user tables.
 
 INSERT INTO iata_cities (city_code, airport_code) VALUES
 ('NYC','JFK'),
@@ -61,7 +61,9 @@ INSERT INTO iata_cities (city_code, airport_code) VALUES
 ('MIL','BGY'),
 ('YMQ','YUL'),
 ('YMQ','YMX'),
-('YMQ','YHU');
+('YMQ','YHU'),
+('REK','KEF'),
+('REK','RKV');
 
 INSERT INTO distances (from_iata, to_iata, distance_miles) VALUES
 ('MAD','ZAZ',190.760897),
@@ -199,6 +201,15 @@ INSERT INTO city_routes (city_iata, alliance, route_to) VALUES
 ('NYC','None','ORH'),
 ('NYC','None','JAX'),
 ('NYC','None','ATL'),
+('NYC','None','LHR'),
+('NYC','None','KEF'),
+('NYC','None','DUB'),
+('NYC','None','LIS'),
+('NYC','None','HEL'),
+('NYC','None','DOH'),
+('NYC','None','IST'),
+('NYC','None','AUH'),
+('NYC','None','PTY'),
 ('WAS','None','ACK'),
 ('WAS','None','ILM'),
 ('WAS','None','LGA'),
@@ -627,4 +638,844 @@ INSERT INTO city_routes (city_iata, alliance, route_to) VALUES
 ('BOS','ONE_WORLD','TYS'),
 ('BOS','ONE_WORLD','ORD'),
 ('BOS','ONE_WORLD','SYR'),
-('BOS','ONE_WORLD','JFK');
\ No newline at end of file
+('BOS','ONE_WORLD','JFK'),
+('NYC','ONE_WORLD','LHR'),
+('NYC','ONE_WORLD','DOH'),
+('NYC','ONE_WORLD','LAX'),
+('LON','ONE_WORLD','JFK'),
+('LON','ONE_WORLD','DOH'),
+('LON','ONE_WORLD','HKG'),
+('DOH','ONE_WORLD','LHR'),
+('DOH','ONE_WORLD','JFK'),
+('DOH','ONE_WORLD','HKG'),
+('DOH','ONE_WORLD','SYD'),
+('HKG','ONE_WORLD','DOH'),
+('HKG','ONE_WORLD','LHR'),
+('HKG','ONE_WORLD','SYD'),
+('HKG','ONE_WORLD','NRT'),
+('HKG','ONE_WORLD','LAX'),
+('SYD','ONE_WORLD','DOH'),
+('SYD','ONE_WORLD','HKG'),
+('SYD','ONE_WORLD','LAX'),
+('TYO','ONE_WORLD','HKG'),
+('TYO','ONE_WORLD','LAX'),
+('LAX','ONE_WORLD','JFK'),
+('LAX','ONE_WORLD','SYD'),
+('LAX','ONE_WORLD','HKG'),
+('LAX','ONE_WORLD','NRT');
+
+INSERT INTO stopover_programs (airline_code, airline, program, alliance, hub_city, max_nights, fare_restrictions) VALUES
+('FI','Icelandair','Icelandair Stopover','None','REK',7,'Transatlantic itineraries between North America and Europe; all fare classes; no added airfare'),
+('TP','TAP Air Portugal','TAP Portugal Stopover','STAR_ALLIANCE','LIS',10,'Long-haul itineraries connecting in Lisbon; book via multi-city or stopover tool'),
+('TP','TAP Air Portugal','TAP Portugal Stopover','STAR_ALLIANCE','OPO',10,'Long-haul itineraries connecting in Porto; book via multi-city or stopover tool'),
+('TK','Turkish Airlines','Stopover in Istanbul','STAR_ALLIANCE','IST',2,'International itineraries with a 20+ hour connection in Istanbul; free hotel nights (1 economy, 2 business)'),
+('QR','Qatar Airways','Qatar Airways Stopover','ONE_WORLD','DOH',4,'Round-trip or multi-city tickets via Doha; stopover must be added at booking'),
+('AY','Finnair','Finnair Stopover','ONE_WORLD','HEL',5,'Itineraries between Europe and Asia connecting in Helsinki'),
+('EY','Etihad Airways','Abu Dhabi Stopover','None','AUH',2,'Itineraries connecting in Abu Dhabi; free hotel nights on eligible fares'),
+('CM','Copa Airlines','Panama Stopover','STAR_ALLIANCE','PTY',7,'Itineraries connecting in Panama City; no added airfare'),
+('EI','Aer Lingus','Aer Lingus Stopover','None','DUB',7,'Transatlantic itineraries connecting in Dublin; economy and business fares');
+
+INSERT INTO airports (iata_code, name, city_code, country, continent, latitude, longitude) VALUES
+('JFK','John F. Kennedy International','NYC','US','NA',40.6413,-73.7781),
+('LGA','LaGuardia','NYC','US','NA',40.7769,-73.8740),
+('EWR','Newark Liberty International','NYC','US','NA',40.6895,-74.1745),
+('ORD','Chicago O''Hare International','CHI','US','NA',41.9742,-87.9073),
+('MDW','Chicago Midway International','CHI','US','NA',41.7868,-87.7522),
+('LAX','Los Angeles International','LAX','US','NA',33.9416,-118.4085),
+('LGB','Long Beach','LAX','US','NA',33.8177,-118.1516),
+('ONT','Ontario International','LAX','US','NA',34.0560,-117.6012),
+('SNA','John Wayne','LAX','US','NA',33.6762,-117.8675),
+('BUR','Hollywood Burbank','LAX','US','NA',34.2007,-118.3587),
+('SFO','San Francisco International','SFO','US','NA',37.6213,-122.3790),
+('OAK','Oakland International','SFO','US','NA',37.7126,-122.2197),
+('SJC','San Jose International','SFO','US','NA',37.3639,-121.9289),
+('SEA','Seattle-Tacoma International','SEA','US','NA',47.4502,-122.3088),
+('PAE','Paine Field','SEA','US','NA',47.9063,-122.2816),
+('MIA','Miami International','MIA','US','NA',25.7959,-80.2870),
+('FLL','Fort Lauderdale-Hollywood International','MIA','US','NA',26.0742,-80.1506),
+('PBI','Palm Beach International','MIA','US','NA',26.6832,-80.0956),
+('IAD','Washington Dulles International','WAS','US','NA',38.9531,-77.4565),
+('DCA','Ronald Reagan Washington National','WAS','US','NA',38.8512,-77.0402),
+('BWI','Baltimore/Washington International','WAS','US','NA',39.1754,-76.6683),
+('DFW','Dallas/Fort Worth International','DFW','US','NA',32.8998,-97.0403),
+('DAL','Dallas Love Field','DFW','US','NA',32.8471,-96.8518),
+('IAH','George Bush Intercontinental','HOU','US','NA',29.9902,-95.3368),
+('HOU','William P. Hobby','HOU','US','NA',29.6454,-95.2789),
+('BOS','Boston Logan International','BOS','US','NA',42.3656,-71.0096),
+('ATL','Hartsfield-Jackson Atlanta International','ATL','US','NA',33.6407,-84.4277),
+('DEN','Denver International','DEN','US','NA',39.8561,-104.6737),
+('LAS','Harry Reid International','LAS','US','NA',36.0840,-115.1537),
+('PHX','Phoenix Sky Harbor International','PHX','US','NA',33.4352,-112.0101),
+('MCO','Orlando International','ORL','US','NA',28.4312,-81.3081),
+('TPA','Tampa International','TPA','US','NA',27.9755,-82.5332),
+('MSY','Louis Armstrong New Orleans International','MSY','US','NA',29.9934,-90.2580),
+('PHL','Philadelphia International','PHL','US','NA',39.8744,-75.2424),
+('DTW','Detroit Metropolitan Wayne County','DTT','US','NA',42.2162,-83.3554),
+('MSP','Minneapolis-Saint Paul International','MSP','US','NA',44.8848,-93.2223),
+('SLC','Salt Lake City International','SLC','US','NA',40.7899,-111.9791),
+('SAN','San Diego International','SAN','US','NA',32.7338,-117.1933),
+('PDX','Portland International','PDX','US','NA',45.5898,-122.5951),
+('AUS','Austin-Bergstrom International','AUS','US','NA',30.1975,-97.6664),
+('PIT','Pittsburgh International','PIT','US','NA',40.4915,-80.2329),
+('BNA','Nashville International','BNA','US','NA',36.1263,-86.6774),
+('SAV','Savannah/Hilton Head International','SAV','US','NA',32.1276,-81.2021),
+('CVG','Cincinnati/Northern Kentucky International','CVG','US','NA',39.0488,-84.6678),
+('SJU','Luis Munoz Marin International','SJU','PR','NA',18.4394,-66.0018),
+('YYZ','Toronto Pearson International','YTO','CA','NA',43.6777,-79.6248),
+('YTZ','Billy Bishop Toronto City','YTO','CA','NA',43.6275,-79.3962),
+('YHM','John C. Munro Hamilton International','YTO','CA','NA',43.1736,-79.9350),
+('YUL','Montreal-Trudeau International','YMQ','CA','NA',45.4706,-73.7408),
+('YVR','Vancouver International','YVR','CA','NA',49.1947,-123.1792),
+('YOW','Ottawa Macdonald-Cartier International','YOW','CA','NA',45.3225,-75.6692),
+('YHZ','Halifax Stanfield International','YHZ','CA','NA',44.8808,-63.5086),
+('PTY','Tocumen International','PTY','PA','NA',9.0714,-79.3835),
+('MEX','Mexico City International','MEX','MX','NA',19.4361,-99.0719),
+('LHR','London Heathrow','LON','GB','EU',51.4700,-0.4543),
+('LGW','London Gatwick','LON','GB','EU',51.1537,-0.1821),
+('LCY','London City','LON','GB','EU',51.5048,0.0495),
+('STN','London Stansted','LON','GB','EU',51.8860,0.2389),
+('LTN','London Luton','LON','GB','EU',51.8747,-0.3683),
+('SEN','London Southend','LON','GB','EU',51.5714,0.6956),
+('SOU','Southampton','SOU','GB','EU',50.9503,-1.3568),
+('MAN','Manchester','MAN','GB','EU',53.3537,-2.2750),
+('EDI','Edinburgh','EDI','GB','EU',55.9508,-3.3615),
+('DUB','Dublin','DUB','IE','EU',53.4264,-6.2499),
+('CDG','Paris Charles de Gaulle','PAR','FR','EU',49.0097,2.5479),
+('ORY','Paris Orly','PAR','FR','EU',48.7262,2.3652),
+('BVA','Paris Beauvais','PAR','FR','EU',49.4544,2.1128),
+('NCE','Nice Cote d''Azur','NCE','FR','EU',43.6584,7.2159),
+('LYS','Lyon-Saint Exupery','LYS','FR','EU',45.7256,5.0811),
+('AMS','Amsterdam Schiphol','AMS','NL','EU',52.3105,4.7683),
+('BRU','Brussels','BRU','BE','EU',50.9014,4.4844),
+('FRA','Frankfurt','FRA','DE','EU',50.0379,8.5622),
+('MUC','Munich','MUC','DE','EU',48.3537,11.7750),
+('BER','Berlin Brandenburg','BER','DE','EU',52.3667,13.5033),
+('ZRH','Zurich','ZRH','CH','EU',47.4582,8.5555),
+('GVA','Geneva','GVA','CH','EU',46.2381,6.1090),
+('VIE','Vienna International','VIE','AT','EU',48.1103,16.5697),
+('CPH','Copenhagen','CPH','DK','EU',55.6180,12.6508),
+('ARN','Stockholm Arlanda','STO','SE','EU',59.6498,17.9238),
+('OSL','Oslo Gardermoen','OSL','NO','EU',60.1976,11.1004),
+('HEL','Helsinki-Vantaa','HEL','FI','EU',60.3172,24.9633),
+('KEF','Keflavik International','REK','IS','EU',63.9850,-22.6056),
+('RKV','Reykjavik','REK','IS','EU',64.1300,-21.9406),
+('MAD','Adolfo Suarez Madrid-Barajas','MAD','ES','EU',40.4983,-3.5676),
+('BCN','Barcelona-El Prat','BCN','ES','EU',41.2974,2.0833),
+('VLC','Valencia','VLC','ES','EU',39.4893,-0.4816),
+('AGP','Malaga','AGP','ES','EU',36.6749,-4.4991),
+('PMI','Palma de Mallorca','PMI','ES','EU',39.5517,2.7388),
+('LIS','Lisbon Humberto Delgado','LIS','PT','EU',38.7742,-9.1342),
+('OPO','Porto Francisco Sa Carneiro','OPO','PT','EU',41.2481,-8.6814),
+('FAO','Faro','FAO','PT','EU',37.0144,-7.9659),
+('FCO','Rome Fiumicino','ROM','IT','EU',41.8003,12.2389),
+('MXP','Milan Malpensa','MIL','IT','EU',45.6306,8.7281),
+('LIN','Milan Linate','MIL','IT','EU',45.4451,9.2767),
+('BGY','Milan Bergamo','MIL','IT','EU',45.6739,9.7042),
+('VCE','Venice Marco Polo','VCE','IT','EU',45.5053,12.3519),
+('NAP','Naples International','NAP','IT','EU',40.8860,14.2908),
+('ATH','Athens International','ATH','GR','EU',37.9364,23.9445),
+('PRG','Vaclav Havel Prague','PRG','CZ','EU',50.1008,14.2600),
+('BUD','Budapest Ferenc Liszt International','BUD','HU','EU',47.4394,19.2618),
+('WAW','Warsaw Chopin','WAW','PL','EU',52.1657,20.9671),
+('KRK','Krakow John Paul II International','KRK','PL','EU',50.0777,19.7848),
+('IST','Istanbul','IST','TR','EU',41.2753,28.7519),
+('SAW','Istanbul Sabiha Gokcen','IST','TR','EU',40.8986,29.3092),
+('DOH','Hamad International','DOH','QA','AS',25.2731,51.6081),
+('DXB','Dubai International','DXB','AE','AS',25.2532,55.3657),
+('DWC','Al Maktoum International','DXB','AE','AS',24.8964,55.1614),
+('AUH','Abu Dhabi International','AUH','AE','AS',24.4330,54.6511),
+('HKG','Hong Kong International','HKG','HK','AS',22.3080,113.9185),
+('HND','Tokyo Haneda','TYO','JP','AS',35.5494,139.7798),
+('NRT','Tokyo Narita','TYO','JP','AS',35.7720,140.3929),
+('ICN','Incheon International','SEL','KR','AS',37.4602,126.4407),
+('PEK','Beijing Capital International','BJS','CN','AS',40.0799,116.6031),
+('PVG','Shanghai Pudong International','SHA','CN','AS',31.1443,121.8083),
+('CAN','Guangzhou Baiyun International','CAN','CN','AS',23.3924,113.2988),
+('SIN','Singapore Changi','SIN','SG','AS',1.3644,103.9915),
+('BKK','Suvarnabhumi','BKK','TH','AS',13.6900,100.7501),
+('DMK','Don Mueang International','BKK','TH','AS',13.9126,100.6067),
+('MNL','Ninoy Aquino International','MNL','PH','AS',14.5086,121.0194),
+('SGN','Tan Son Nhat International','SGN','VN','AS',10.8188,106.6520),
+('HAN','Noi Bai International','HAN','VN','AS',21.2212,105.8072),
+('DEL','Indira Gandhi International','DEL','IN','AS',28.5562,77.1000),
+('BOM','Chhatrapati Shivaji Maharaj International','BOM','IN','AS',19.0896,72.8656),
+('SYD','Sydney Kingsford Smith','SYD','AU','OC',-33.9399,151.1753),
+('MEL','Melbourne','MEL','AU','OC',-37.6690,144.8410),
+('AKL','Auckland','AKL','NZ','OC',-37.0082,174.7850),
+('JNB','O.R. Tambo International','JNB','ZA','AF',-26.1392,28.2460),
+('CPT','Cape Town International','CPT','ZA','AF',-33.9715,18.6021),
+('CAI','Cairo International','CAI','EG','AF',30.1219,31.4056),
+('RAK','Marrakesh Menara','RAK','MA','AF',31.6069,-8.0363),
+('GRU','Sao Paulo/Guarulhos International','SAO','BR','SA',-23.4356,-46.4731),
+('GIG','Rio de Janeiro/Galeao International','RIO','BR','SA',-22.8090,-43.2506),
+('EZE','Ministro Pistarini International','BUE','AR','SA',-34.8222,-58.5358),
+('SCL','Arturo Merino Benitez International','SCL','CL','SA',-33.3930,-70.7858),
+('BOG','El Dorado International','BOG','CO','SA',4.7016,-74.1469),
+('LIM','Jorge Chavez International','LIM','PE','SA',-12.0219,-77.1143);
+
+INSERT INTO award_programs (code, name, alliance, chart_type, one_way_allowed, open_jaw_allowed, max_stopovers, stopover_points) VALUES
+('BA_AVIOS','British Airways Executive Club','ONE_WORLD','distance',1,1,0,0),
+('CX_ASIA_MILES','Cathay Pacific Asia Miles','ONE_WORLD','distance_total',1,1,1,0),
+('AC_AEROPLAN','Air Canada Aeroplan','STAR_ALLIANCE','distance_total',1,1,1,5000),
+('UA_MILEAGEPLUS','United MileagePlus','STAR_ALLIANCE','zone',1,1,1,0),
+('AA_AADVANTAGE','American AAdvantage','ONE_WORLD','zone',1,1,0,0),
+('AF_FLYING_BLUE','Air France-KLM Flying Blue','SKY_TEAM','zone',1,1,1,0),
+('NH_MILEAGE_CLUB','ANA Mileage Club','STAR_ALLIANCE','zone',0,1,1,0);
+
+INSERT INTO award_distance_bands (program, cabin, min_miles, max_miles, points) VALUES
+('BA_AVIOS','economy',0,650,7500),
+('BA_AVIOS','economy',651,1150,9000),
+('BA_AVIOS','economy',1151,2000,11000),
+('BA_AVIOS','economy',2001,3000,13000),
+('BA_AVIOS','economy',3001,4000,20750),
+('BA_AVIOS','economy',4001,5500,25750),
+('BA_AVIOS','economy',5501,6500,31000),
+('BA_AVIOS','economy',6501,7000,37250),
+('BA_AVIOS','economy',7001,99999,50000),
+('BA_AVIOS','premium',0,650,11250),
+('BA_AVIOS','premium',651,1150,13500),
+('BA_AVIOS','premium',1151,2000,16500),
+('BA_AVIOS','premium',2001,3000,19500),
+('BA_AVIOS','premium',3001,4000,31000),
+('BA_AVIOS','premium',4001,5500,38500),
+('BA_AVIOS','premium',5501,6500,46500),
+('BA_AVIOS','premium',6501,7000,56000),
+('BA_AVIOS','premium',7001,99999,75000),
+('BA_AVIOS','business',0,650,18750),
+('BA_AVIOS','business',651,1150,22500),
+('BA_AVIOS','business',1151,2000,27500),
+('BA_AVIOS','business',2001,3000,32500),
+('BA_AVIOS','business',3001,4000,52000),
+('BA_AVIOS','business',4001,5500,64500),
+('BA_AVIOS','business',5501,6500,77500),
+('BA_AVIOS','business',6501,7000,93000),
+('BA_AVIOS','business',7001,99999,125000),
+('BA_AVIOS','first',0,650,26250),
+('BA_AVIOS','first',651,1150,31500),
+('BA_AVIOS','first',1151,2000,38500),
+('BA_AVIOS','first',2001,3000,45500),
+('BA_AVIOS','first',3001,4000,72500),
+('BA_AVIOS','first',4001,5500,90000),
+('BA_AVIOS','first',5501,6500,108500),
+('BA_AVIOS','first',6501,7000,130500),
+('BA_AVIOS','first',7001,99999,175000),
+('CX_ASIA_MILES','economy',0,750,7000),
+('CX_ASIA_MILES','economy',751,2750,9000),
+('CX_ASIA_MILES','economy',2751,5000,20000),
+('CX_ASIA_MILES','economy',5001,7500,27000),
+('CX_ASIA_MILES','economy',7501,99999,38000),
+('CX_ASIA_MILES','premium',0,750,10500),
+('CX_ASIA_MILES','premium',751,2750,13500),
+('CX_ASIA_MILES','premium',2751,5000,30000),
+('CX_ASIA_MILES','premium',5001,7500,40500),
+('CX_ASIA_MILES','premium',7501,99999,57000),
+('CX_ASIA_MILES','business',0,750,17500),
+('CX_ASIA_MILES','business',751,2750,22500),
+('CX_ASIA_MILES','business',2751,5000,50000),
+('CX_ASIA_MILES','business',5001,7500,67500),
+('CX_ASIA_MILES','business',7501,99999,95000),
+('CX_ASIA_MILES','first',0,750,24500),
+('CX_ASIA_MILES','first',751,2750,31500),
+('CX_ASIA_MILES','first',2751,5000,70000),
+('CX_ASIA_MILES','first',5001,7500,94500),
+('CX_ASIA_MILES','first',7501,99999,133000),
+('AC_AEROPLAN','economy',0,500,6000),
+('AC_AEROPLAN','economy',501,1500,10000),
+('AC_AEROPLAN','economy',1501,2750,12500),
+('AC_AEROPLAN','economy',2751,4000,35000),
+('AC_AEROPLAN','economy',4001,6000,45000),
+('AC_AEROPLAN','economy',6001,99999,55000),
+('AC_AEROPLAN','premium',0,500,9000),
+('AC_AEROPLAN','premium',501,1500,15000),
+('AC_AEROPLAN','premium',1501,2750,18750),
+('AC_AEROPLAN','premium',2751,4000,52500),
+('AC_AEROPLAN','premium',4001,6000,67500),
+('AC_AEROPLAN','premium',6001,99999,82500),
+('AC_AEROPLAN','business',0,500,15000),
+('AC_AEROPLAN','business',501,1500,25000),
+('AC_AEROPLAN','business',1501,2750,31250),
+('AC_AEROPLAN','business',2751,4000,87500),
+('AC_AEROPLAN','business',4001,6000,112500),
+('AC_AEROPLAN','business',6001,99999,137500),
+('AC_AEROPLAN','first',0,500,21000),
+('AC_AEROPLAN','first',501,1500,35000),
+('AC_AEROPLAN','first',1501,2750,43750),
+('AC_AEROPLAN','first',2751,4000,122500),
+('AC_AEROPLAN','first',4001,6000,157500),
+('AC_AEROPLAN','first',6001,99999,192500);
+
+INSERT INTO award_zones (program, area_type, area, zone) VALUES
+('UA_MILEAGEPLUS','continent','NA','NA'),
+('UA_MILEAGEPLUS','continent','SA','SA'),
+('UA_MILEAGEPLUS','continent','EU','EU'),
+('UA_MILEAGEPLUS','continent','AF','AF'),
+('UA_MILEAGEPLUS','continent','AS','AS'),
+('UA_MILEAGEPLUS','continent','OC','OC'),
+('UA_MILEAGEPLUS','country','AE','ME'),
+('UA_MILEAGEPLUS','country','QA','ME'),
+('UA_MILEAGEPLUS','country','SA','ME'),
+('UA_MILEAGEPLUS','country','BH','ME'),
+('UA_MILEAGEPLUS','country','KW','ME'),
+('UA_MILEAGEPLUS','country','OM','ME'),
+('UA_MILEAGEPLUS','country','JO','ME'),
+('UA_MILEAGEPLUS','country','IL','ME'),
+('UA_MILEAGEPLUS','country','LB','ME'),
+('AA_AADVANTAGE','continent','NA','NA'),
+('AA_AADVANTAGE','continent','SA','SA'),
+('AA_AADVANTAGE','continent','EU','EU'),
+('AA_AADVANTAGE','continent','AF','AF'),
+('AA_AADVANTAGE','continent','AS','AS'),
+('AA_AADVANTAGE','continent','OC','OC'),
+('AA_AADVANTAGE','country','AE','ME'),
+('AA_AADVANTAGE','country','QA','ME'),
+('AA_AADVANTAGE','country','SA','ME'),
+('AA_AADVANTAGE','country','BH','ME'),
+('AA_AADVANTAGE','country','KW','ME'),
+('AA_AADVANTAGE','country','OM','ME'),
+('AA_AADVANTAGE','country','JO','ME'),
+('AA_AADVANTAGE','country','IL','ME'),
+('AA_AADVANTAGE','country','LB','ME'),
+('AA_AADVANTAGE','country','JP','ASIA1'),
+('AA_AADVANTAGE','country','KR','ASIA1'),
+('AF_FLYING_BLUE','continent','NA','NA'),
+('AF_FLYING_BLUE','continent','SA','SA'),
+('AF_FLYING_BLUE','continent','EU','EU'),
+('AF_FLYING_BLUE','continent','AF','AF'),
+('AF_FLYING_BLUE','continent','AS','AS'),
+('AF_FLYING_BLUE','continent','OC','OC'),
+('AF_FLYING_BLUE','country','AE','ME'),
+('AF_FLYING_BLUE','country','QA','ME'),
+('AF_FLYING_BLUE','country','SA','ME'),
+('AF_FLYING_BLUE','country','BH','ME'),
+('AF_FLYING_BLUE','country','KW','ME'),
+('AF_FLYING_BLUE','country','OM','ME'),
+('AF_FLYING_BLUE','country','JO','ME'),
+('AF_FLYING_BLUE','country','IL','ME'),
+('AF_FLYING_BLUE','country','LB','ME'),
+('NH_MILEAGE_CLUB','continent','NA','NA'),
+('NH_MILEAGE_CLUB','continent','SA','SA'),
+('NH_MILEAGE_CLUB','continent','EU','EU'),
+('NH_MILEAGE_CLUB','continent','AF','AF'),
+('NH_MILEAGE_CLUB','continent','AS','AS'),
+('NH_MILEAGE_CLUB','continent','OC','OC'),
+('NH_MILEAGE_CLUB','country','AE','ME'),
+('NH_MILEAGE_CLUB','country','QA','ME'),
+('NH_MILEAGE_CLUB','country','SA','ME'),
+('NH_MILEAGE_CLUB','country','BH','ME'),
+('NH_MILEAGE_CLUB','country','KW','ME'),
+('NH_MILEAGE_CLUB','country','OM','ME'),
+('NH_MILEAGE_CLUB','country','JO','ME'),
+('NH_MILEAGE_CLUB','country','IL','ME'),
+('NH_MILEAGE_CLUB','country','LB','ME');
+
+INSERT INTO award_zone_prices (program, cabin, from_zone, to_zone, points) VALUES
+('UA_MILEAGEPLUS','economy','AF','AF',11000),
+('UA_MILEAGEPLUS','economy','AF','AS',38500),
+('UA_MILEAGEPLUS','economy','AF','ME',22000),
+('UA_MILEAGEPLUS','economy','AF','OC',49500),
+('UA_MILEAGEPLUS','economy','AS','AS',16500),
+('UA_MILEAGEPLUS','economy','AS','OC',27500),
+('UA_MILEAGEPLUS','economy','EU','AF',27500),
+('UA_MILEAGEPLUS','economy','EU','AS',38500),
+('UA_MILEAGEPLUS','economy','EU','EU',11000),
+('UA_MILEAGEPLUS','economy','EU','ME',22000),
+('UA_MILEAGEPLUS','economy','EU','OC',60500),
+('UA_MILEAGEPLUS','economy','EU','SA',44000),
+('UA_MILEAGEPLUS','economy','ME','AS',27500),
+('UA_MILEAGEPLUS','economy','ME','ME',11000),
+('UA_MILEAGEPLUS','economy','ME','OC',44000),
+('UA_MILEAGEPLUS','economy','NA','AF',44000),
+('UA_MILEAGEPLUS','economy','NA','AS',38500),
+('UA_MILEAGEPLUS','economy','NA','EU',33000),
+('UA_MILEAGEPLUS','economy','NA','ME',44000),
+('UA_MILEAGEPLUS','economy','NA','NA',13750),
+('UA_MILEAGEPLUS','economy','NA','OC',44000),
+('UA_MILEAGEPLUS','economy','NA','SA',33000),
+('UA_MILEAGEPLUS','economy','OC','OC',13750),
+('UA_MILEAGEPLUS','economy','SA','AF',49500),
+('UA_MILEAGEPLUS','economy','SA','AS',60500),
+('UA_MILEAGEPLUS','economy','SA','ME',55000),
+('UA_MILEAGEPLUS','economy','SA','OC',60500),
+('UA_MILEAGEPLUS','economy','SA','SA',11000),
+('UA_MILEAGEPLUS','premium','AF','AF',16500),
+('UA_MILEAGEPLUS','premium','AF','AS',57750),
+('UA_MILEAGEPLUS','premium','AF','ME',33000),
+('UA_MILEAGEPLUS','premium','AF','OC',74250),
+('UA_MILEAGEPLUS','premium','AS','AS',24750),
+('UA_MILEAGEPLUS','premium','AS','OC',41250),
+('UA_MILEAGEPLUS','premium','EU','AF',41250),
+('UA_MILEAGEPLUS','premium','EU','AS',57750),
+('UA_MILEAGEPLUS','premium','EU','EU',16500),
+('UA_MILEAGEPLUS','premium','EU','ME',33000),
+('UA_MILEAGEPLUS','premium','EU','OC',90750),
+('UA_MILEAGEPLUS','premium','EU','SA',66000),
+('UA_MILEAGEPLUS','premium','ME','AS',41250),
+('UA_MILEAGEPLUS','premium','ME','ME',16500),
+('UA_MILEAGEPLUS','premium','ME','OC',66000),
+('UA_MILEAGEPLUS','premium','NA','AF',66000),
+('UA_MILEAGEPLUS','premium','NA','AS',57750),
+('UA_MILEAGEPLUS','premium','NA','EU',49500),
+('UA_MILEAGEPLUS','premium','NA','ME',66000),
+('UA_MILEAGEPLUS','premium','NA','NA',20750),
+('UA_MILEAGEPLUS','premium','NA','OC',66000),
+('UA_MILEAGEPLUS','premium','NA','SA',49500),
+('UA_MILEAGEPLUS','premium','OC','OC',20750),
+('UA_MILEAGEPLUS','premium','SA','AF',74250),
+('UA_MILEAGEPLUS','premium','SA','AS',90750),
+('UA_MILEAGEPLUS','premium','SA','ME',82500),
+('UA_MILEAGEPLUS','premium','SA','OC',90750),
+('UA_MILEAGEPLUS','premium','SA','SA',16500),
+('UA_MILEAGEPLUS','business','AF','AF',27500),
+('UA_MILEAGEPLUS','business','AF','AS',96250),
+('UA_MILEAGEPLUS','business','AF','ME',55000),
+('UA_MILEAGEPLUS','business','AF','OC',123750),
+('UA_MILEAGEPLUS','business','AS','AS',41250),
+('UA_MILEAGEPLUS','business','AS','OC',68750),
+('UA_MILEAGEPLUS','business','EU','AF',68750),
+('UA_MILEAGEPLUS','business','EU','AS',96250),
+('UA_MILEAGEPLUS','business','EU','EU',27500),
+('UA_MILEAGEPLUS','business','EU','ME',55000),
+('UA_MILEAGEPLUS','business','EU','OC',151250),
+('UA_MILEAGEPLUS','business','EU','SA',110000),
+('UA_MILEAGEPLUS','business','ME','AS',68750),
+('UA_MILEAGEPLUS','business','ME','ME',27500),
+('UA_MILEAGEPLUS','business','ME','OC',110000),
+('UA_MILEAGEPLUS','business','NA','AF',110000),
+('UA_MILEAGEPLUS','business','NA','AS',96250),
+('UA_MILEAGEPLUS','business','NA','EU',82500),
+('UA_MILEAGEPLUS','business','NA','ME',110000),
+('UA_MILEAGEPLUS','business','NA','NA',34500),
+('UA_MILEAGEPLUS','business','NA','OC',110000),
+('UA_MILEAGEPLUS','business','NA','SA',82500),
+('UA_MILEAGEPLUS','business','OC','OC',34500),
+('UA_MILEAGEPLUS','business','SA','AF',123750),
+('UA_MILEAGEPLUS','business','SA','AS',151250),
+('UA_MILEAGEPLUS','business','SA','ME',137500),
+('UA_MILEAGEPLUS','business','SA','OC',151250),
+('UA_MILEAGEPLUS','business','SA','SA',27500),
+('UA_MILEAGEPLUS','first','AF','AF',38500),
+('UA_MILEAGEPLUS','first','AF','AS',134750),
+('UA_MILEAGEPLUS','first','AF','ME',77000),
+('UA_MILEAGEPLUS','first','AF','OC',173250),
+('UA_MILEAGEPLUS','first','AS','AS',57750),
+('UA_MILEAGEPLUS','first','AS','OC',96250),
+('UA_MILEAGEPLUS','first','EU','AF',96250),
+('UA_MILEAGEPLUS','first','EU','AS',134750),
+('UA_MILEAGEPLUS','first','EU','EU',38500),
+('UA_MILEAGEPLUS','first','EU','ME',77000),
+('UA_MILEAGEPLUS','first','EU','OC',211750),
+('UA_MILEAGEPLUS','first','EU','SA',154000),
+('UA_MILEAGEPLUS','first','ME','AS',96250),
+('UA_MILEAGEPLUS','first','ME','ME',38500),
+('UA_MILEAGEPLUS','first','ME','OC',154000),
+('UA_MILEAGEPLUS','first','NA','AF',154000),
+('UA_MILEAGEPLUS','first','NA','AS',134750),
+('UA_MILEAGEPLUS','first','NA','EU',115500),
+('UA_MILEAGEPLUS','first','NA','ME',154000),
+('UA_MILEAGEPLUS','first','NA','NA',48250),
+('UA_MILEAGEPLUS','first','NA','OC',154000),
+('UA_MILEAGEPLUS','first','NA','SA',115500),
+('UA_MILEAGEPLUS','first','OC','OC',48250),
+('UA_MILEAGEPLUS','first','SA','AF',173250),
+('UA_MILEAGEPLUS','first','SA','AS',211750),
+('UA_MILEAGEPLUS','first','SA','ME',192500),
+('UA_MILEAGEPLUS','first','SA','OC',211750),
+('UA_MILEAGEPLUS','first','SA','SA',38500),
+('AA_AADVANTAGE','economy','AF','AF',10000),
+('AA_AADVANTAGE','economy','AF','AS',35000),
+('AA_AADVANTAGE','economy','AF','ASIA1',29750),
+('AA_AADVANTAGE','economy','AF','ME',20000),
+('AA_AADVANTAGE','economy','AF','OC',45000),
+('AA_AADVANTAGE','economy','AS','AS',15000),
+('AA_AADVANTAGE','economy','AS','ASIA1',15000),
+('AA_AADVANTAGE','economy','AS','OC',25000),
+('AA_AADVANTAGE','economy','ASIA1','ASIA1',12750),
+('AA_AADVANTAGE','economy','ASIA1','OC',21250),
+('AA_AADVANTAGE','economy','EU','AF',25000),
+('AA_AADVANTAGE','economy','EU','AS',35000),
+('AA_AADVANTAGE','economy','EU','ASIA1',29750),
+('AA_AADVANTAGE','economy','EU','EU',10000),
+('AA_AADVANTAGE','economy','EU','ME',20000),
+('AA_AADVANTAGE','economy','EU','OC',55000),
+('AA_AADVANTAGE','economy','EU','SA',40000),
+('AA_AADVANTAGE','economy','ME','AS',25000),
+('AA_AADVANTAGE','economy','ME','ASIA1',21250),
+('AA_AADVANTAGE','economy','ME','ME',10000),
+('AA_AADVANTAGE','economy','ME','OC',40000),
+('AA_AADVANTAGE','economy','NA','AF',40000),
+('AA_AADVANTAGE','economy','NA','AS',35000),
+('AA_AADVANTAGE','economy','NA','ASIA1',29750),
+('AA_AADVANTAGE','economy','NA','EU',30000),
+('AA_AADVANTAGE','economy','NA','ME',40000),
+('AA_AADVANTAGE','economy','NA','NA',12500),
+('AA_AADVANTAGE','economy','NA','OC',40000),
+('AA_AADVANTAGE','economy','NA','SA',30000),
+('AA_AADVANTAGE','economy','OC','OC',12500),
+('AA_AADVANTAGE','economy','SA','AF',45000),
+('AA_AADVANTAGE','economy','SA','AS',55000),
+('AA_AADVANTAGE','economy','SA','ASIA1',46750),
+('AA_AADVANTAGE','economy','SA','ME',50000),
+('AA_AADVANTAGE','economy','SA','OC',55000),
+('AA_AADVANTAGE','economy','SA','SA',10000),
+('AA_AADVANTAGE','premium','AF','AF',15000),
+('AA_AADVANTAGE','premium','AF','AS',52500),
+('AA_AADVANTAGE','premium','AF','ASIA1',44500),
+('AA_AADVANTAGE','premium','AF','ME',30000),
+('AA_AADVANTAGE','premium','AF','OC',67500),
+('AA_AADVANTAGE','premium','AS','AS',22500),
+('AA_AADVANTAGE','premium','AS','ASIA1',22500),
+('AA_AADVANTAGE','premium','AS','OC',37500),
+('AA_AADVANTAGE','premium','ASIA1','ASIA1',19000),
+('AA_AADVANTAGE','premium','ASIA1','OC',32000),
+('AA_AADVANTAGE','premium','EU','AF',37500),
+('AA_AADVANTAGE','premium','EU','AS',52500),
+('AA_AADVANTAGE','premium','EU','ASIA1',44500),
+('AA_AADVANTAGE','premium','EU','EU',15000),
+('AA_AADVANTAGE','premium','EU','ME',30000),
+('AA_AADVANTAGE','premium','EU','OC',82500),
+('AA_AADVANTAGE','premium','EU','SA',60000),
+('AA_AADVANTAGE','premium','ME','AS',37500),
+('AA_AADVANTAGE','premium','ME','ASIA1',32000),
+('AA_AADVANTAGE','premium','ME','ME',15000),
+('AA_AADVANTAGE','premium','ME','OC',60000),
+('AA_AADVANTAGE','premium','NA','AF',60000),
+('AA_AADVANTAGE','premium','NA','AS',52500),
+('AA_AADVANTAGE','premium','NA','ASIA1',44500),
+('AA_AADVANTAGE','premium','NA','EU',45000),
+('AA_AADVANTAGE','premium','NA','ME',60000),
+('AA_AADVANTAGE','premium','NA','NA',18750),
+('AA_AADVANTAGE','premium','NA','OC',60000),
+('AA_AADVANTAGE','premium','NA','SA',45000),
+('AA_AADVANTAGE','premium','OC','OC',18750),
+('AA_AADVANTAGE','premium','SA','AF',67500),
+('AA_AADVANTAGE','premium','SA','AS',82500),
+('AA_AADVANTAGE','premium','SA','ASIA1',70000),
+('AA_AADVANTAGE','premium','SA','ME',75000),
+('AA_AADVANTAGE','premium','SA','OC',82500),
+('AA_AADVANTAGE','premium','SA','SA',15000),
+('AA_AADVANTAGE','business','AF','AF',25000),
+('AA_AADVANTAGE','business','AF','AS',87500),
+('AA_AADVANTAGE','business','AF','ASIA1',74500),
+('AA_AADVANTAGE','business','AF','ME',50000),
+('AA_AADVANTAGE','business','AF','OC',112500),
+('AA_AADVANTAGE','business','AS','AS',37500),
+('AA_AADVANTAGE','business','AS','ASIA1',37500),
+('AA_AADVANTAGE','business','AS','OC',62500),
+('AA_AADVANTAGE','business','ASIA1','ASIA1',32000),
+('AA_AADVANTAGE','business','ASIA1','OC',53000),
+('AA_AADVANTAGE','business','EU','AF',62500),
+('AA_AADVANTAGE','business','EU','AS',87500),
+('AA_AADVANTAGE','business','EU','ASIA1',74500),
+('AA_AADVANTAGE','business','EU','EU',25000),
+('AA_AADVANTAGE','business','EU','ME',50000),
+('AA_AADVANTAGE','business','EU','OC',137500),
+('AA_AADVANTAGE','business','EU','SA',100000),
+('AA_AADVANTAGE','business','ME','AS',62500),
+('AA_AADVANTAGE','business','ME','ASIA1',53000),
+('AA_AADVANTAGE','business','ME','ME',25000),
+('AA_AADVANTAGE','business','ME','OC',100000),
+('AA_AADVANTAGE','business','NA','AF',100000),
+('AA_AADVANTAGE','business','NA','AS',87500),
+('AA_AADVANTAGE','business','NA','ASIA1',74500),
+('AA_AADVANTAGE','business','NA','EU',75000),
+('AA_AADVANTAGE','business','NA','ME',100000),
+('AA_AADVANTAGE','business','NA','NA',31250),
+('AA_AADVANTAGE','business','NA','OC',100000),
+('AA_AADVANTAGE','business','NA','SA',75000),
+('AA_AADVANTAGE','business','OC','OC',31250),
+('AA_AADVANTAGE','business','SA','AF',112500),
+('AA_AADVANTAGE','business','SA','AS',137500),
+('AA_AADVANTAGE','business','SA','ASIA1',117000),
+('AA_AADVANTAGE','business','SA','ME',125000),
+('AA_AADVANTAGE','business','SA','OC',137500),
+('AA_AADVANTAGE','business','SA','SA',25000),
+('AA_AADVANTAGE','first','AF','AF',35000),
+('AA_AADVANTAGE','first','AF','AS',122500),
+('AA_AADVANTAGE','first','AF','ASIA1',104000),
+('AA_AADVANTAGE','first','AF','ME',70000),
+('AA_AADVANTAGE','first','AF','OC',157500),
+('AA_AADVANTAGE','first','AS','AS',52500),
+('AA_AADVANTAGE','first','AS','ASIA1',52500),
+('AA_AADVANTAGE','first','AS','OC',87500),
+('AA_AADVANTAGE','first','ASIA1','ASIA1',44500),
+('AA_AADVANTAGE','first','ASIA1','OC',74500),
+('AA_AADVANTAGE','first','EU','AF',87500),
+('AA_AADVANTAGE','first','EU','AS',122500),
+('AA_AADVANTAGE','first','EU','ASIA1',104000),
+('AA_AADVANTAGE','first','EU','EU',35000),
+('AA_AADVANTAGE','first','EU','ME',70000),
+('AA_AADVANTAGE','first','EU','OC',192500),
+('AA_AADVANTAGE','first','EU','SA',140000),
+('AA_AADVANTAGE','first','ME','AS',87500),
+('AA_AADVANTAGE','first','ME','ASIA1',74500),
+('AA_AADVANTAGE','first','ME','ME',35000),
+('AA_AADVANTAGE','first','ME','OC',140000),
+('AA_AADVANTAGE','first','NA','AF',140000),
+('AA_AADVANTAGE','first','NA','AS',122500),
+('AA_AADVANTAGE','first','NA','ASIA1',104000),
+('AA_AADVANTAGE','first','NA','EU',105000),
+('AA_AADVANTAGE','first','NA','ME',140000),
+('AA_AADVANTAGE','first','NA','NA',43750),
+('AA_AADVANTAGE','first','NA','OC',140000),
+('AA_AADVANTAGE','first','NA','SA',105000),
+('AA_AADVANTAGE','first','OC','OC',43750),
+('AA_AADVANTAGE','first','SA','AF',157500),
+('AA_AADVANTAGE','first','SA','AS',192500),
+('AA_AADVANTAGE','first','SA','ASIA1',163500),
+('AA_AADVANTAGE','first','SA','ME',175000),
+('AA_AADVANTAGE','first','SA','OC',192500),
+('AA_AADVANTAGE','first','SA','SA',35000),
+('AF_FLYING_BLUE','economy','AF','AF',9000),
+('AF_FLYING_BLUE','economy','AF','AS',31500),
+('AF_FLYING_BLUE','economy','AF','ME',18000),
+('AF_FLYING_BLUE','economy','AF','OC',40500),
+('AF_FLYING_BLUE','economy','AS','AS',13500),
+('AF_FLYING_BLUE','economy','AS','OC',22500),
+('AF_FLYING_BLUE','economy','EU','AF',22500),
+('AF_FLYING_BLUE','economy','EU','AS',31500),
+('AF_FLYING_BLUE','economy','EU','EU',9000),
+('AF_FLYING_BLUE','economy','EU','ME',18000),
+('AF_FLYING_BLUE','economy','EU','OC',49500),
+('AF_FLYING_BLUE','economy','EU','SA',36000),
+('AF_FLYING_BLUE','economy','ME','AS',22500),
+('AF_FLYING_BLUE','economy','ME','ME',9000),
+('AF_FLYING_BLUE','economy','ME','OC',36000),
+('AF_FLYING_BLUE','economy','NA','AF',36000),
+('AF_FLYING_BLUE','economy','NA','AS',31500),
+('AF_FLYING_BLUE','economy','NA','EU',27000),
+('AF_FLYING_BLUE','economy','NA','ME',36000),
+('AF_FLYING_BLUE','economy','NA','NA',11250),
+('AF_FLYING_BLUE','economy','NA','OC',36000),
+('AF_FLYING_BLUE','economy','NA','SA',27000),
+('AF_FLYING_BLUE','economy','OC','OC',11250),
+('AF_FLYING_BLUE','economy','SA','AF',40500),
+('AF_FLYING_BLUE','economy','SA','AS',49500),
+('AF_FLYING_BLUE','economy','SA','ME',45000),
+('AF_FLYING_BLUE','economy','SA','OC',49500),
+('AF_FLYING_BLUE','economy','SA','SA',9000),
+('AF_FLYING_BLUE','premium','AF','AF',13500),
+('AF_FLYING_BLUE','premium','AF','AS',47250),
+('AF_FLYING_BLUE','premium','AF','ME',27000),
+('AF_FLYING_BLUE','premium','AF','OC',60750),
+('AF_FLYING_BLUE','premium','AS','AS',20250),
+('AF_FLYING_BLUE','premium','AS','OC',33750),
+('AF_FLYING_BLUE','premium','EU','AF',33750),
+('AF_FLYING_BLUE','premium','EU','AS',47250),
+('AF_FLYING_BLUE','premium','EU','EU',13500),
+('AF_FLYING_BLUE','premium','EU','ME',27000),
+('AF_FLYING_BLUE','premium','EU','OC',74250),
+('AF_FLYING_BLUE','premium','EU','SA',54000),
+('AF_FLYING_BLUE','premium','ME','AS',33750),
+('AF_FLYING_BLUE','premium','ME','ME',13500),
+('AF_FLYING_BLUE','premium','ME','OC',54000),
+('AF_FLYING_BLUE','premium','NA','AF',54000),
+('AF_FLYING_BLUE','premium','NA','AS',47250),
+('AF_FLYING_BLUE','premium','NA','EU',40500),
+('AF_FLYING_BLUE','premium','NA','ME',54000),
+('AF_FLYING_BLUE','premium','NA','NA',17000),
+('AF_FLYING_BLUE','premium','NA','OC',54000),
+('AF_FLYING_BLUE','premium','NA','SA',40500),
+('AF_FLYING_BLUE','premium','OC','OC',17000),
+('AF_FLYING_BLUE','premium','SA','AF',60750),
+('AF_FLYING_BLUE','premium','SA','AS',74250),
+('AF_FLYING_BLUE','premium','SA','ME',67500),
+('AF_FLYING_BLUE','premium','SA','OC',74250),
+('AF_FLYING_BLUE','premium','SA','SA',13500),
+('AF_FLYING_BLUE','business','AF','AF',22500),
+('AF_FLYING_BLUE','business','AF','AS',78750),
+('AF_FLYING_BLUE','business','AF','ME',45000),
+('AF_FLYING_BLUE','business','AF','OC',101250),
+('AF_FLYING_BLUE','business','AS','AS',33750),
+('AF_FLYING_BLUE','business','AS','OC',56250),
+('AF_FLYING_BLUE','business','EU','AF',56250),
+('AF_FLYING_BLUE','business','EU','AS',78750),
+('AF_FLYING_BLUE','business','EU','EU',22500),
+('AF_FLYING_BLUE','business','EU','ME',45000),
+('AF_FLYING_BLUE','business','EU','OC',123750),
+('AF_FLYING_BLUE','business','EU','SA',90000),
+('AF_FLYING_BLUE','business','ME','AS',56250),
+('AF_FLYING_BLUE','business','ME','ME',22500),
+('AF_FLYING_BLUE','business','ME','OC',90000),
+('AF_FLYING_BLUE','business','NA','AF',90000),
+('AF_FLYING_BLUE','business','NA','AS',78750),
+('AF_FLYING_BLUE','business','NA','EU',67500),
+('AF_FLYING_BLUE','business','NA','ME',90000),
+('AF_FLYING_BLUE','business','NA','NA',28000),
+('AF_FLYING_BLUE','business','NA','OC',90000),
+('AF_FLYING_BLUE','business','NA','SA',67500),
+('AF_FLYING_BLUE','business','OC','OC',28000),
+('AF_FLYING_BLUE','business','SA','AF',101250),
+('AF_FLYING_BLUE','business','SA','AS',123750),
+('AF_FLYING_BLUE','business','SA','ME',112500),
+('AF_FLYING_BLUE','business','SA','OC',123750),
+('AF_FLYING_BLUE','business','SA','SA',22500),
+('AF_FLYING_BLUE','first','AF','AF',31500),
+('AF_FLYING_BLUE','first','AF','AS',110250),
+('AF_FLYING_BLUE','first','AF','ME',63000),
+('AF_FLYING_BLUE','first','AF','OC',141750),
+('AF_FLYING_BLUE','first','AS','AS',47250),
+('AF_FLYING_BLUE','first','AS','OC',78750),
+('AF_FLYING_BLUE','first','EU','AF',78750),
+('AF_FLYING_BLUE','first','EU','AS',110250),
+('AF_FLYING_BLUE','first','EU','EU',31500),
+('AF_FLYING_BLUE','first','EU','ME',63000),
+('AF_FLYING_BLUE','first','EU','OC',173250),
+('AF_FLYING_BLUE','first','EU','SA',126000),
+('AF_FLYING_BLUE','first','ME','AS',78750),
+('AF_FLYING_BLUE','first','ME','ME',31500),
+('AF_FLYING_BLUE','first','ME','OC',126000),
+('AF_FLYING_BLUE','first','NA','AF',126000),
+('AF_FLYING_BLUE','first','NA','AS',110250),
+('AF_FLYING_BLUE','first','NA','EU',94500),
+('AF_FLYING_BLUE','first','NA','ME',126000),
+('AF_FLYING_BLUE','first','NA','NA',39500),
+('AF_FLYING_BLUE','first','NA','OC',126000),
+('AF_FLYING_BLUE','first','NA','SA',94500),
+('AF_FLYING_BLUE','first','OC','OC',39500),
+('AF_FLYING_BLUE','first','SA','AF',141750),
+('AF_FLYING_BLUE','first','SA','AS',173250),
+('AF_FLYING_BLUE','first','SA','ME',157500),
+('AF_FLYING_BLUE','first','SA','OC',173250),
+('AF_FLYING_BLUE','first','SA','SA',31500),
+('NH_MILEAGE_CLUB','economy','AF','AF',8500),
+('NH_MILEAGE_CLUB','economy','AF','AS',29750),
+('NH_MILEAGE_CLUB','economy','AF','ME',17000),
+('NH_MILEAGE_CLUB','economy','AF','OC',38250),
+('NH_MILEAGE_CLUB','economy','AS','AS',12750),
+('NH_MILEAGE_CLUB','economy','AS','OC',21250),
+('NH_MILEAGE_CLUB','economy','EU','AF',21250),
+('NH_MILEAGE_CLUB','economy','EU','AS',29750),
+('NH_MILEAGE_CLUB','economy','EU','EU',8500),
+('NH_MILEAGE_CLUB','economy','EU','ME',17000),
+('NH_MILEAGE_CLUB','economy','EU','OC',46750),
+('NH_MILEAGE_CLUB','economy','EU','SA',34000),
+('NH_MILEAGE_CLUB','economy','ME','AS',21250),
+('NH_MILEAGE_CLUB','economy','ME','ME',8500),
+('NH_MILEAGE_CLUB','economy','ME','OC',34000),
+('NH_MILEAGE_CLUB','economy','NA','AF',34000),
+('NH_MILEAGE_CLUB','economy','NA','AS',29750),
+('NH_MILEAGE_CLUB','economy','NA','EU',25500),
+('NH_MILEAGE_CLUB','economy','NA','ME',34000),
+('NH_MILEAGE_CLUB','economy','NA','NA',10500),
+('NH_MILEAGE_CLUB','economy','NA','OC',34000),
+('NH_MILEAGE_CLUB','economy','NA','SA',25500),
+('NH_MILEAGE_CLUB','economy','OC','OC',10500),
+('NH_MILEAGE_CLUB','economy','SA','AF',38250),
+('NH_MILEAGE_CLUB','economy','SA','AS',46750),
+('NH_MILEAGE_CLUB','economy','SA','ME',42500),
+('NH_MILEAGE_CLUB','economy','SA','OC',46750),
+('NH_MILEAGE_CLUB','economy','SA','SA',8500),
+('NH_MILEAGE_CLUB','premium','AF','AF',12750),
+('NH_MILEAGE_CLUB','premium','AF','AS',44500),
+('NH_MILEAGE_CLUB','premium','AF','ME',25500),
+('NH_MILEAGE_CLUB','premium','AF','OC',57500),
+('NH_MILEAGE_CLUB','premium','AS','AS',19000),
+('NH_MILEAGE_CLUB','premium','AS','OC',32000),
+('NH_MILEAGE_CLUB','premium','EU','AF',32000),
+('NH_MILEAGE_CLUB','premium','EU','AS',44500),
+('NH_MILEAGE_CLUB','premium','EU','EU',12750),
+('NH_MILEAGE_CLUB','premium','EU','ME',25500),
+('NH_MILEAGE_CLUB','premium','EU','OC',70000),
+('NH_MILEAGE_CLUB','premium','EU','SA',51000),
+('NH_MILEAGE_CLUB','premium','ME','AS',32000),
+('NH_MILEAGE_CLUB','premium','ME','ME',12750),
+('NH_MILEAGE_CLUB','premium','ME','OC',51000),
+('NH_MILEAGE_CLUB','premium','NA','AF',51000),
+('NH_MILEAGE_CLUB','premium','NA','AS',44500),
+('NH_MILEAGE_CLUB','premium','NA','EU',38250),
+('NH_MILEAGE_CLUB','premium','NA','ME',51000),
+('NH_MILEAGE_CLUB','premium','NA','NA',16000),
+('NH_MILEAGE_CLUB','premium','NA','OC',51000),
+('NH_MILEAGE_CLUB','premium','NA','SA',38250),
+('NH_MILEAGE_CLUB','premium','OC','OC',16000),
+('NH_MILEAGE_CLUB','premium','SA','AF',57500),
+('NH_MILEAGE_CLUB','premium','SA','AS',70000),
+('NH_MILEAGE_CLUB','premium','SA','ME',63750),
+('NH_MILEAGE_CLUB','premium','SA','OC',70000),
+('NH_MILEAGE_CLUB','premium','SA','SA',12750),
+('NH_MILEAGE_CLUB','business','AF','AF',21250),
+('NH_MILEAGE_CLUB','business','AF','AS',74500),
+('NH_MILEAGE_CLUB','business','AF','ME',42500),
+('NH_MILEAGE_CLUB','business','AF','OC',95500),
+('NH_MILEAGE_CLUB','business','AS','AS',32000),
+('NH_MILEAGE_CLUB','business','AS','OC',53000),
+('NH_MILEAGE_CLUB','business','EU','AF',53000),
+('NH_MILEAGE_CLUB','business','EU','AS',74500),
+('NH_MILEAGE_CLUB','business','EU','EU',21250),
+('NH_MILEAGE_CLUB','business','EU','ME',42500),
+('NH_MILEAGE_CLUB','business','EU','OC',117000),
+('NH_MILEAGE_CLUB','business','EU','SA',85000),
+('NH_MILEAGE_CLUB','business','ME','AS',53000),
+('NH_MILEAGE_CLUB','business','ME','ME',21250),
+('NH_MILEAGE_CLUB','business','ME','OC',85000),
+('NH_MILEAGE_CLUB','business','NA','AF',85000),
+('NH_MILEAGE_CLUB','business','NA','AS',74500),
+('NH_MILEAGE_CLUB','business','NA','EU',63750),
+('NH_MILEAGE_CLUB','business','NA','ME',85000),
+('NH_MILEAGE_CLUB','business','NA','NA',26500),
+('NH_MILEAGE_CLUB','business','NA','OC',85000),
+('NH_MILEAGE_CLUB','business','NA','SA',63750),
+('NH_MILEAGE_CLUB','business','OC','OC',26500),
+('NH_MILEAGE_CLUB','business','SA','AF',95500),
+('NH_MILEAGE_CLUB','business','SA','AS',117000),
+('NH_MILEAGE_CLUB','business','SA','ME',106250),
+('NH_MILEAGE_CLUB','business','SA','OC',117000),
+('NH_MILEAGE_CLUB','business','SA','SA',21250),
+('NH_MILEAGE_CLUB','first','AF','AF',29750),
+('NH_MILEAGE_CLUB','first','AF','AS',104000),
+('NH_MILEAGE_CLUB','first','AF','ME',59500),
+('NH_MILEAGE_CLUB','first','AF','OC',134000),
+('NH_MILEAGE_CLUB','first','AS','AS',44500),
+('NH_MILEAGE_CLUB','first','AS','OC',74500),
+('NH_MILEAGE_CLUB','first','EU','AF',74500),
+('NH_MILEAGE_CLUB','first','EU','AS',104000),
+('NH_MILEAGE_CLUB','first','EU','EU',29750),
+('NH_MILEAGE_CLUB','first','EU','ME',59500),
+('NH_MILEAGE_CLUB','first','EU','OC',163500),
+('NH_MILEAGE_CLUB','first','EU','SA',119000),
+('NH_MILEAGE_CLUB','first','ME','AS',74500),
+('NH_MILEAGE_CLUB','first','ME','ME',29750),
+('NH_MILEAGE_CLUB','first','ME','OC',119000),
+('NH_MILEAGE_CLUB','first','NA','AF',119000),
+('NH_MILEAGE_CLUB','first','NA','AS',104000),
+('NH_MILEAGE_CLUB','first','NA','EU',89250),
+('NH_MILEAGE_CLUB','first','NA','ME',119000),
+('NH_MILEAGE_CLUB','first','NA','NA',37250),
+('NH_MILEAGE_CLUB','first','NA','OC',119000),
+('NH_MILEAGE_CLUB','first','NA','SA',89250),
+('NH_MILEAGE_CLUB','first','OC','OC',37250),
+('NH_MILEAGE_CLUB','first','SA','AF',134000),
+('NH_MILEAGE_CLUB','first','SA','AS',163500),
+('NH_MILEAGE_CLUB','first','SA','ME',148750),
+('NH_MILEAGE_CLUB','first','SA','OC',163500),
+('NH_MILEAGE_CLUB','first','SA','SA',29750);
+
+INSERT INTO rtw_rule_sets (code, name, alliance, min_continents, max_continents, max_miles, min_segments, max_segments, one_direction, max_atlantic_crossings, max_pacific_crossings) VALUES
+('OW_EXPLORER','oneworld Explorer','ONE_WORLD',3,6,0,3,16,1,1,1),
+('STAR_RTW_29K','Star Alliance Round the World (29,000 mi)','STAR_ALLIANCE',0,0,29000,3,16,1,1,1),
+('STAR_RTW_34K','Star Alliance Round the World (34,000 mi)','STAR_ALLIANCE',0,0,34000,3,16,1,1,1),
+('STAR_RTW_39K','Star Alliance Round the World (39,000 mi)','STAR_ALLIANCE',0,0,39000,3,16,1,1,1),
+('SKYTEAM_GO_RTW','SkyTeam Go Round the World','SKY_TEAM',0,0,38000,3,16,1,1,1);
+
+INSERT INTO airlines (iata_code, icao_code, name, country, alliance, active) VALUES
+('AA','AAL','American Airlines','US','ONE_WORLD',1),
+('AC','ACA','Air Canada','CA','STAR_ALLIANCE',1),
+('AF','AFR','Air France','FR','SKY_TEAM',1),
+('AS','ASA','Alaska Airlines','US','ONE_WORLD',1),
+('AY','FIN','Finnair','FI','ONE_WORLD',1),
+('B6','JBU','JetBlue Airways','US','None',1),
+('BA','BAW','British Airways','GB','ONE_WORLD',1),
+('CM','CMP','Copa Airlines','PA','STAR_ALLIANCE',1),
+('CX','CPA','Cathay Pacific','HK','ONE_WORLD',1),
+('DL','DAL','Delta Air Lines','US','SKY_TEAM',1),
+('EI','EIN','Aer Lingus','IE','None',1),
+('EY','ETD','Etihad Airways','AE','None',1),
+('FI','ICE','Icelandair','IS','None',1),
+('KL','KLM','KLM Royal Dutch Airlines','NL','SKY_TEAM',1),
+('LH','DLH','Lufthansa','DE','STAR_ALLIANCE',1),
+('NH','ANA','All Nippon Airways','JP','STAR_ALLIANCE',1),
+('QR','QTR','Qatar Airways','QA','ONE_WORLD',1),
+('TK','THY','Turkish Airlines','TR','STAR_ALLIANCE',1),
+('TP','TAP','TAP Air Portugal','PT','STAR_ALLIANCE',1),
+('UA','UAL','United Airlines','US','STAR_ALLIANCE',1),
+('WN','SWA','Southwest Airlines','US','None',1);
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"triangle_travel/internal/db"
+)
+
+// ParseAdminTokens parses a comma-separated list of name:token pairs. A bare token is named
+// "admin". The names identify admins in the audit log.
+func ParseAdminTokens(spec string) (map[string]string, error) {
+	tokens := make(map[string]string)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, token, found := strings.Cut(item, ":")
+		if !found {
+			name, token = "admin", item
+		}
+		if name == "" || token == "" {
+			return nil, fmt.Errorf("admin token %q: want name:token", item)
+		}
+		if _, dup := tokens[token]; dup {
+			return nil, fmt.Errorf("admin token for %s is used twice", name)
+		}
+		tokens[token] = name
+	}
+	return tokens, nil
+}
+
+// AdminMiddleware requires "Authorization: Bearer <token>" with one of AdminTokens and sets
+// "admin" to its name; without configured tokens the admin API is disabled
+func (h *Handlers) AdminMiddleware(c *gin.Context) {
+	if len(h.AdminTokens) == 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin API disabled (start the server with -admin-token)"})
+		c.Abort()
+		return
+	}
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	admin := ""
+	// Compare against every token so the response time doesn't reveal which one came close
+	for t, name := range h.AdminTokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+			admin = name
+		}
+	}
+	if admin == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Admin token required"})
+		c.Abort()
+		return
+	}
+	c.Set("admin", admin)
+	c.Next()
+}
+
+// CreateBackup handles POST /api/admin/backups: a consistent copy of the live database.
+// It runs without the per-request DB deadline or the server's write timeout since copying a
+// large database takes a while.
+func (h *Handlers) CreateBackup(c *gin.Context) {
+	if h.Backups == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": db.ErrBackupUnsupported.Error()})
+		return
+	}
+	noWriteDeadline(c)
+	f, err := h.Backups.Create(c.Request.Context())
+	if errors.Is(err, db.ErrBackupUnsupported) {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		dbError(c, db.ContextError(c.Request.Context(), err), "")
+		return
+	}
+	c.JSON(http.StatusCreated, f)
+}
+
+// ListBackups handles GET /api/admin/backups, newest first
+func (h *Handlers) ListBackups(c *gin.Context) {
+	if h.Backups == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": db.ErrBackupUnsupported.Error()})
+		return
+	}
+	files, err := h.Backups.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, files)
+}
+
+// DownloadBackup handles GET /api/admin/backups/:name, exporting a backup file. Like
+// CreateBackup it isn't bound by the server's write timeout.
+func (h *Handlers) DownloadBackup(c *gin.Context) {
+	if h.Backups == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": db.ErrBackupUnsupported.Error()})
+		return
+	}
+	path, err := h.Backups.Path(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Backup not found"})
+		return
+	}
+	noWriteDeadline(c)
+	c.FileAttachment(path, c.Param("name"))
+}
+
+// noWriteDeadline lifts the server's WriteTimeout for a response that may take longer
+func noWriteDeadline(c *gin.Context) {
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Admin %s: clear write deadline: %v", c.FullPath(), err)
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Backup routes outlive the server's WriteTimeout; other routes are still cut off
+func TestNoWriteDeadline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	slow := func(c *gin.Context) {
+		time.Sleep(200 * time.Millisecond)
+		c.String(http.StatusOK, "done")
+	}
+	router.GET("/slow", slow)
+	router.GET("/backup", func(c *gin.Context) {
+		noWriteDeadline(c)
+		slow(c)
+	})
+	server := httptest.NewUnstartedServer(router)
+	server.Config.WriteTimeout = 50 * time.Millisecond
+	server.Start()
+	defer server.Close()
+
+	tests := []struct {
+		path string
+		ok   bool
+	}{
+		{"/backup", true},
+		{"/slow", false},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(server.URL + tt.path)
+		var body []byte
+		if err == nil {
+			body, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		if ok := err == nil && string(body) == "done"; ok != tt.ok {
+			t.Errorf("%s: completed = %v (err %v), want %v", tt.path, ok, err, tt.ok)
+		}
+	}
+}
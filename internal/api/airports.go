@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultNearestLimit = 5
+	maxNearestLimit     = 50
+	maxNearbyMiles      = 1000
+)
+
+// NearestAirportsRequest for GET /api/airports/nearest
+type NearestAirportsRequest struct {
+	Lat      *float64 `form:"lat" binding:"required,min=-90,max=90"`
+	Lon      *float64 `form:"lon" binding:"required,min=-180,max=180"`
+	Limit    int      `form:"limit"`
+	MaxMiles float64  `form:"maxMiles"`
+}
+
+// AirportsNearRequest for GET /api/airports/near/:code
+type AirportsNearRequest struct {
+	Miles float64 `form:"miles" binding:"required,gt=0"`
+}
+
+// NearestAirports returns the airports closest to a lat/lon
+func (h *Handlers) NearestAirports(c *gin.Context) {
+	var req NearestAirportsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultNearestLimit
+	}
+	if req.Limit > maxNearestLimit {
+		req.Limit = maxNearestLimit
+	}
+	c.JSON(http.StatusOK, h.Routes.Graph().NearestAirports(*req.Lat, *req.Lon, req.Limit, req.MaxMiles))
+}
+
+// AirportsNear returns every airport within miles of a city or airport code
+func (h *Handlers) AirportsNear(c *gin.Context) {
+	var req AirportsNearRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Miles > maxNearbyMiles {
+		req.Miles = maxNearbyMiles
+	}
+	code := strings.ToUpper(strings.TrimSpace(c.Param("code")))
+	airports, ok := h.Routes.Graph().AirportsWithin(code, req.Miles)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown city or airport, or no coordinates for it"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": code, "miles": req.Miles, "airports": airports})
+}
@@ -1,52 +1,105 @@
 package api
 
 import (
-	"database/sql"
+	"context"
+	"errors"
+	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"triangle_travel/internal/auth"
+	"triangle_travel/internal/db"
+	"triangle_travel/internal/sms"
 )
 
+// smsTimeout bounds handing a code to the SMS sender
+const smsTimeout = 10 * time.Second
+
+// otpCleanupTimeout bounds dropping a code that couldn't be sent
+const otpCleanupTimeout = 5 * time.Second
+
 // SendOTPRequest for POST /api/auth/send-otp
 type SendOTPRequest struct {
 	Phone string `json:"phone" binding:"required"`
 }
 
-// SendOTP creates/stores OTP for phone
+// SendOTP creates/stores OTP for phone and texts it, answering with the number normalized to
+// E.164 (verify-otp takes either form). Delivery failures are reported: 400 when the gateway
+// refuses the number, 502 when it can't be reached or fails. A locked-out number, or one sent
+// a code within the resend cooldown, gets 429 with Retry-After.
 func (h *Handlers) SendOTP(c *gin.Context) {
 	var req SendOTPRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Phone required"})
 		return
 	}
-	phone := strings.TrimSpace(req.Phone)
-	if !strings.HasPrefix(phone, "+1") || len(phone) != 12 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "US phone number required (+1XXXXXXXXXX)"})
+	number, err := h.Phones.Parse(req.Phone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	phone := number.E164
 
 	// Dev: accept dev phone without storing OTP
 	if auth.IsDev() && phone == auth.DevPhone {
-		c.JSON(http.StatusOK, gin.H{"ok": true})
+		c.JSON(http.StatusOK, gin.H{"ok": true, "phone": phone})
 		return
 	}
 
-	// Store OTP (mock in dev: 123456 for any; prod: use Twilio)
-	code := auth.GenerateOTP()
-	expires := auth.OTPExpiry()
-	_, err := h.DB.Exec(`
-		INSERT INTO otp_codes (phone, code, expires_at) VALUES (?, ?, ?)
-		ON CONFLICT(phone) DO UPDATE SET code = ?, expires_at = ?
-	`, phone, code, expires, code, expires)
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	now := time.Now()
+	if h.lockedOut(ctx, c, phone, now) {
+		return
+	}
+	code, err := auth.GenerateOTP()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send OTP"})
 		return
 	}
+	hash, err := auth.HashOTP(phone, code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send OTP"})
+		return
+	}
+	otp := db.OTPCode{Phone: phone, CodeHash: hash, SentAt: now, ExpiresAt: now.Add(auth.OTPLifetime)}
+	saved, err := h.DB.SaveOTP(ctx, otp, auth.OTPResendCooldown)
+	if err != nil {
+		dbError(c, err, "Failed to send OTP")
+		return
+	}
+	if !saved {
+		wait := auth.OTPResendCooldown
+		if pending, err := h.DB.GetOTP(ctx, phone); err == nil && pending != nil {
+			wait = pending.SentAt.Add(auth.OTPResendCooldown).Sub(now)
+		}
+		tooManyRequests(c, wait, "A code was just sent")
+		return
+	}
 
-	// TODO: In production, send via Twilio: h.twilio.Send(phone, "Your code is: "+code)
-	c.JSON(http.StatusOK, gin.H{"ok": true})
+	sendCtx, cancelSend := context.WithTimeout(c.Request.Context(), smsTimeout)
+	defer cancelSend()
+	if err := h.SMS.Send(sendCtx, phone, auth.OTPMessage(code)); err != nil {
+		log.Printf("SMS to %s: %v", phone, err)
+		// The code never arrived, so don't leave it usable; dropping it also lifts the cooldown.
+		// The send may have used up the request's deadline, so the delete gets its own.
+		deleteCtx, cancelDelete := context.WithTimeout(context.Background(), otpCleanupTimeout)
+		defer cancelDelete()
+		if err := h.DB.DeleteOTP(deleteCtx, phone); err != nil {
+			log.Printf("SMS to %s: drop unsent code: %v", phone, err)
+		}
+		var gwErr *sms.Error
+		if errors.As(err, &gwErr) && gwErr.Rejected() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Can't send a text to this number: " + gwErr.Message})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Couldn't send the code by SMS, try again shortly"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true, "phone": phone})
 }
 
 // VerifyOTPRequest for POST /api/auth/verify-otp
@@ -55,89 +108,227 @@ type VerifyOTPRequest struct {
 	Code  string `json:"code" binding:"required"`
 }
 
-// VerifyOTP verifies code and returns JWT token
+// VerifyOTP verifies code and returns a session token, creating the user on its first login
 func (h *Handlers) VerifyOTP(c *gin.Context) {
 	var req VerifyOTPRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Phone and code required"})
 		return
 	}
-	phone := strings.TrimSpace(req.Phone)
-	code := strings.TrimSpace(req.Code)
+	number, err := h.Phones.Parse(req.Phone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	phone := number.E164
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	if !h.checkOTP(ctx, c, phone, req.Code) {
+		return
+	}
+	user, err := h.DB.EnsureUser(ctx, phone)
+	if err != nil {
+		dbError(c, err, "Failed to create user")
+		return
+	}
+	h.startSession(ctx, c, user.ID)
+}
 
-	// Dev: accept dev phone + dev OTP (user is seeded on server start)
-	if auth.IsDev() && phone == auth.DevPhone && code == auth.DevOTP {
-		var userID int64
-		err := h.DB.QueryRow("SELECT id FROM users WHERE phone = ?", phone).Scan(&userID)
-		if err == sql.ErrNoRows {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Dev user not seeded. Restart the server."})
-			return
-		}
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
-			return
-		}
-		token, err := auth.GenerateToken()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
-			return
-		}
-		expiresAt := auth.TokenExpiry()
-		_, err = h.DB.Exec("INSERT INTO sessions (user_id, token, expires_at) VALUES (?, ?, ?)", userID, token, expiresAt)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
-			return
-		}
-		c.JSON(http.StatusOK, gin.H{"token": token})
+// LinkPhoneRequest for POST /api/auth/phone/link
+type LinkPhoneRequest struct {
+	Phone string `json:"phone" binding:"required"`
+	Code  string `json:"code" binding:"required"`
+}
+
+// LinkPhone adds a phone number to the signed-in user (replacing any it had) once it proves
+// the number with a code from send-otp, so the account can sign in with either. 409 if the
+// number already has its own account.
+func (h *Handlers) LinkPhone(c *gin.Context) {
+	var req LinkPhoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Phone and code required"})
 		return
 	}
+	number, err := h.Phones.Parse(req.Phone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	phone := number.E164
+	userID := c.GetInt64("user_id")
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	if !h.checkOTP(ctx, c, phone, req.Code) {
+		return
+	}
+	h.setIdentity(ctx, c, userID, h.DB.SetUserPhone(ctx, userID, phone), "That phone number already has its own account")
+}
 
-	var storedCode string
-	var expires interface{}
-	err := h.DB.QueryRow("SELECT code, expires_at FROM otp_codes WHERE phone = ?", phone).Scan(&storedCode, &expires)
-	if err == sql.ErrNoRows || code != storedCode {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+// setIdentity answers a phone or email change with the updated user, or 409 if the identity
+// belongs to someone else. Accounts aren't merged: the other account keeps its number or address.
+func (h *Handlers) setIdentity(ctx context.Context, c *gin.Context, userID int64, err error, inUse string) {
+	if errors.Is(err, db.ErrIdentityInUse) {
+		c.JSON(http.StatusConflict, gin.H{"error": inUse})
 		return
 	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Verification failed"})
+		dbError(c, err, "Failed to update account")
 		return
 	}
+	user, err := h.DB.GetUser(ctx, userID)
+	if err != nil {
+		dbError(c, err, "Failed to update account")
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
 
-	// Get or create user
-	var userID int64
-	err = h.DB.QueryRow("SELECT id FROM users WHERE phone = ?", phone).Scan(&userID)
-	if err == sql.ErrNoRows {
-		res, err := h.DB.Exec("INSERT INTO users (phone) VALUES (?)", phone)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
-			return
+// stepUp asks for a fresh proof of the account before a sensitive change (action, e.g. "add a
+// passkey"). An account with a phone number confirms with a code sent to it: without one it
+// answers 403 with code "step_up_required" and the number, so the client can send one with
+// send-otp and retry. An account without needs a session signed in within
+// auth.PasskeyFreshSession (403 "reauth_required"). It returns true if the change may go ahead.
+func (h *Handlers) stepUp(ctx context.Context, c *gin.Context, user *db.User, code, action string) bool {
+	switch {
+	case user.Phone != "" && strings.TrimSpace(code) == "":
+		c.JSON(http.StatusForbidden, gin.H{"error": "Confirm with a code sent to your phone to " + action,
+			"code": "step_up_required", "phone": user.Phone})
+		return false
+	case user.Phone != "":
+		return h.checkOTP(ctx, c, user.Phone, code)
+	case time.Since(c.GetTime("session_created_at")) > auth.PasskeyFreshSession:
+		c.JSON(http.StatusForbidden, gin.H{"error": "Sign in again to " + action, "code": "reauth_required"})
+		return false
+	}
+	return true
+}
+
+// checkOTP checks a code sent to phone and returns true if it matches, consuming the code (of
+// concurrent requests with it only one succeeds) and discarding any lockout; otherwise it answers
+// 401 (429 when locked out) and returns false. Each code allows auth.OTPMaxAttempts guesses; the
+// last wrong one discards it and locks the number out.
+func (h *Handlers) checkOTP(ctx context.Context, c *gin.Context, phone, code string) bool {
+	code = strings.TrimSpace(code)
+	// Dev: accept dev phone + dev OTP without a stored code
+	if auth.IsDev() && phone == auth.DevPhone && code == auth.DevOTP {
+		return true
+	}
+
+	now := time.Now()
+	if h.lockedOut(ctx, c, phone, now) {
+		return false
+	}
+	otp, err := h.DB.CountOTPAttempt(ctx, phone)
+	if err != nil {
+		dbError(c, err, "Verification failed")
+		return false
+	}
+	if otp == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code"})
+		return false
+	}
+	if !now.Before(otp.ExpiresAt) {
+		if err := h.DB.DeleteOTP(ctx, phone); err != nil {
+			log.Printf("OTP for %s: drop expired code: %v", phone, err)
 		}
-		userID, _ = res.LastInsertId()
-	} else if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
-		return
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Code expired, request a new one"})
+		return false
+	}
+	if !auth.CheckOTP(otp.CodeHash, phone, code) || otp.Attempts > auth.OTPMaxAttempts {
+		left := auth.OTPMaxAttempts - otp.Attempts
+		if left > 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid code (" + plural(left, "attempt") + " left)", "attemptsLeft": left})
+			return false
+		}
+		h.lockOut(ctx, c, phone, now)
+		return false
 	}
 
-	token, err := auth.GenerateToken()
+	// Consume the code; a concurrent request that got there first with it wins
+	used, err := h.DB.UseOTP(ctx, phone, otp.CodeHash)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
-		return
+		dbError(c, err, "Verification failed")
+		return false
+	}
+	if !used {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Code already used, request a new one"})
+		return false
 	}
-	expiresAt := auth.TokenExpiry()
-	_, err = h.DB.Exec("INSERT INTO sessions (user_id, token, expires_at) VALUES (?, ?, ?)", userID, token, expiresAt)
+	// Forget earlier lockouts; a stale one only lengthens the next lockout's backoff
+	if err := h.DB.DeleteOTPLockout(ctx, phone); err != nil {
+		log.Printf("OTP for %s: clear lockouts: %v", phone, err)
+	}
+	return true
+}
+
+// lockedOut answers 429 and returns true while phone is locked out of logging in
+func (h *Handlers) lockedOut(ctx context.Context, c *gin.Context, phone string, now time.Time) bool {
+	lockout, err := h.DB.GetOTPLockout(ctx, phone)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		dbError(c, err, "Verification failed")
+		return true
+	}
+	if lockout == nil || !now.Before(lockout.LockedUntil) {
+		return false
+	}
+	tooManyRequests(c, lockout.LockedUntil.Sub(now), "Too many wrong codes")
+	return true
+}
+
+// lockOut discards phone's code after its last wrong guess and locks the number out, for
+// longer after each recent lockout, then answers 429
+func (h *Handlers) lockOut(ctx context.Context, c *gin.Context, phone string, now time.Time) {
+	// A code left behind is out of attempts anyway, so the lockout still goes ahead
+	if err := h.DB.DeleteOTP(ctx, phone); err != nil {
+		log.Printf("OTP for %s: drop locked-out code: %v", phone, err)
+	}
+	lockout := db.OTPLockout{Phone: phone, Lockouts: 1}
+	prev, err := h.DB.GetOTPLockout(ctx, phone)
+	if err != nil {
+		dbError(c, err, "Verification failed")
 		return
 	}
+	if prev != nil && now.Sub(prev.LockedUntil) < auth.OTPLockoutMax {
+		lockout.Lockouts = prev.Lockouts + 1
+	}
+	lockout.LockedUntil = now.Add(auth.OTPLockoutDuration(lockout.Lockouts))
+	if err := h.DB.SaveOTPLockout(ctx, lockout); err != nil {
+		dbError(c, err, "Verification failed")
+		return
+	}
+	log.Printf("OTP lockout %d for %s until %s", lockout.Lockouts, phone, lockout.LockedUntil.UTC().Format(time.RFC3339))
+	tooManyRequests(c, lockout.LockedUntil.Sub(now), "Too many wrong codes")
+}
 
-	// Delete used OTP
-	h.DB.Exec("DELETE FROM otp_codes WHERE phone = ?", phone)
+// tooManyRequests answers 429 with Retry-After (whole seconds, rounded up) and the wait in the message
+func tooManyRequests(c *gin.Context, wait time.Duration, reason string) {
+	secs := int((wait + time.Second - 1) / time.Second)
+	if secs < 1 {
+		secs = 1
+	}
+	after := plural(secs, "second")
+	if secs > 90 {
+		mins := (secs + 59) / 60
+		after = plural(mins, "minute")
+		if mins > 90 {
+			after = plural((mins+59)/60, "hour")
+		}
+	}
+	c.Header("Retry-After", strconv.Itoa(secs))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": reason + ", try again in " + after, "retryAfter": secs})
+}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+// plural formats a count with its noun, e.g. "1 attempt", "3 attempts"
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return strconv.Itoa(n) + " " + noun + "s"
 }
 
-// AuthMiddleware extracts Bearer token and sets user_id in context
+// AuthMiddleware checks the Bearer access token and sets user_id, session_id and
+// session_created_at (when the session signed in) in context. An expired access token gets 401
+// with code "token_expired": refresh and retry.
 func (h *Handlers) AuthMiddleware(c *gin.Context) {
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
@@ -146,14 +337,44 @@ func (h *Handlers) AuthMiddleware(c *gin.Context) {
 		return
 	}
 	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if strings.HasPrefix(token, auth.RefreshTokenPrefix) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Send the access token; refresh tokens only work with /api/auth/refresh"})
+		c.Abort()
+		return
+	}
 
-	var userID int64
-	err := h.DB.QueryRow("SELECT user_id FROM sessions WHERE token = ? AND expires_at > datetime('now')", token).Scan(&userID)
-	if err == sql.ErrNoRows || err != nil {
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	session, err := h.DB.GetSession(ctx, auth.HashToken(token))
+	if err != nil && (errors.Is(err, db.ErrTimeout) || errors.Is(err, db.ErrCanceled)) {
+		dbError(c, err, "")
+		c.Abort()
+		return
+	}
+	if err != nil || session == nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 		c.Abort()
 		return
 	}
-	c.Set("user_id", userID)
+	if !time.Now().Before(session.AccessExpiresAt) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Access token expired", "code": "token_expired"})
+		c.Abort()
+		return
+	}
+	h.touchSession(ctx, session, c.ClientIP())
+	c.Set("user_id", session.UserID)
+	c.Set("session_id", session.ID)
+	c.Set("session_created_at", session.CreatedAt)
 	c.Next()
 }
+
+// OptionalAuthMiddleware is AuthMiddleware for routes that also serve anonymous requests: a
+// request without an Authorization header goes through without user_id, one with a token must
+// pass AuthMiddleware
+func (h *Handlers) OptionalAuthMiddleware(c *gin.Context) {
+	if c.GetHeader("Authorization") == "" {
+		c.Next()
+		return
+	}
+	h.AuthMiddleware(c)
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"triangle_travel/internal/auth"
+	"triangle_travel/internal/db"
+	"triangle_travel/internal/mail"
+)
+
+// mailTimeout bounds handing a sign-in link to the mail sender
+const mailTimeout = 15 * time.Second
+
+// linkCleanupTimeout bounds dropping a link that couldn't be sent
+const linkCleanupTimeout = 5 * time.Second
+
+// EmailRequest for POST /api/auth/email/send and /api/auth/email/link
+type EmailRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// SendEmailLink emails a sign-in link, answering with the address as it was normalized. An
+// address gets one link per auth.EmailLinkCooldown (429 with Retry-After); delivery failures are
+// 400 when the mail server refuses the address, 502 otherwise.
+func (h *Handlers) SendEmailLink(c *gin.Context) {
+	var req EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email required"})
+		return
+	}
+	email, err := mail.NormalizeAddress(req.Email)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	if h.sendEmailLink(ctx, c, email, 0) {
+		c.JSON(http.StatusOK, gin.H{"ok": true, "email": email})
+	}
+}
+
+// LinkEmail emails the signed-in user a link that adds the address to their account, so it can
+// sign in with either. 409 if the address already has its own account.
+func (h *Handlers) LinkEmail(c *gin.Context) {
+	var req EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email required"})
+		return
+	}
+	email, err := mail.NormalizeAddress(req.Email)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID := c.GetInt64("user_id")
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	owner, err := h.DB.GetUserByEmail(ctx, email)
+	if err != nil {
+		dbError(c, err, "Failed to send link")
+		return
+	}
+	switch {
+	case owner != nil && owner.ID == userID:
+		c.JSON(http.StatusOK, gin.H{"ok": true, "email": email, "linked": true})
+		return
+	case owner != nil:
+		c.JSON(http.StatusConflict, gin.H{"error": "That email already has its own account"})
+		return
+	}
+	if h.sendEmailLink(ctx, c, email, userID) {
+		c.JSON(http.StatusOK, gin.H{"ok": true, "email": email})
+	}
+}
+
+// sendEmailLink stores and mails a link to email, for userID's account if not 0, and returns
+// true; on failure it answers the request itself
+func (h *Handlers) sendEmailLink(ctx context.Context, c *gin.Context, email string, userID int64) bool {
+	now := time.Now()
+	token, err := h.Links.Issue(now.Add(auth.EmailLinkLifetime))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send link"})
+		return false
+	}
+	link := db.EmailLink{TokenHash: auth.HashToken(token), Email: email, UserID: userID,
+		CreatedAt: now, ExpiresAt: now.Add(auth.EmailLinkLifetime)}
+	saved, err := h.DB.SaveEmailLink(ctx, link, auth.EmailLinkCooldown)
+	if err != nil {
+		dbError(c, err, "Failed to send link")
+		return false
+	}
+	if !saved {
+		wait := auth.EmailLinkCooldown
+		if last, err := h.DB.LastEmailLink(ctx, email); err == nil && !last.IsZero() {
+			wait = last.Add(auth.EmailLinkCooldown).Sub(now)
+		}
+		tooManyRequests(c, wait, "A link was just sent")
+		return false
+	}
+
+	subject, body := auth.EmailLinkMessage(h.PublicURL+"/my-flights?email_token="+url.QueryEscape(token), userID != 0)
+	sendCtx, cancelSend := context.WithTimeout(c.Request.Context(), mailTimeout)
+	defer cancelSend()
+	if err := h.Mail.Send(sendCtx, mail.Message{To: email, Subject: subject, Body: body}); err != nil {
+		log.Printf("Email to %s: %v", email, err)
+		// The link never arrived; dropping it also lifts the cooldown so a retry can go out now.
+		// The send may have used up the request's deadline, so the delete gets its own.
+		deleteCtx, cancelDelete := context.WithTimeout(context.Background(), linkCleanupTimeout)
+		defer cancelDelete()
+		if err := h.DB.DeleteEmailLink(deleteCtx, link.TokenHash); err != nil {
+			log.Printf("Email to %s: drop unsent link: %v", email, err)
+		}
+		var smtpErr *mail.Error
+		if errors.As(err, &smtpErr) && smtpErr.Rejected() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Can't send email to this address: " + smtpErr.Message})
+			return false
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Couldn't send the email, try again shortly"})
+		return false
+	}
+	return true
+}
+
+// VerifyEmailRequest for POST /api/auth/email/verify
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// VerifyEmailLink uses the token from an emailed link. A sign-in link returns a session token,
+// creating the user on its first login; a link from LinkEmail adds the address to its account
+// and answers {linked: true} without signing this device in. Each link works once.
+func (h *Handlers) VerifyEmailLink(c *gin.Context) {
+	var req VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token required"})
+		return
+	}
+	token := strings.TrimSpace(req.Token)
+	now := time.Now()
+	if err := h.Links.Check(token, now); err != nil {
+		if errors.Is(err, auth.ErrLinkExpired) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "This link has expired, request a new one"})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid sign-in link"})
+		return
+	}
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	link, err := h.DB.UseEmailLink(ctx, auth.HashToken(token), now)
+	if err != nil {
+		dbError(c, err, "Verification failed")
+		return
+	}
+	if link == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "This link was already used or has expired"})
+		return
+	}
+	if link.UserID != 0 {
+		err := h.DB.SetUserEmail(ctx, link.UserID, link.Email)
+		if errors.Is(err, db.ErrIdentityInUse) {
+			c.JSON(http.StatusConflict, gin.H{"error": "That email already has its own account"})
+			return
+		}
+		if err != nil {
+			dbError(c, err, "Failed to update account")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true, "email": link.Email, "linked": true})
+		return
+	}
+	user, err := h.DB.EnsureUserByEmail(ctx, link.Email)
+	if err != nil {
+		dbError(c, err, "Failed to create user")
+		return
+	}
+	h.startSession(ctx, c, user.ID)
+}
@@ -1,56 +1,171 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strings"
+	"time"
+	"triangle_travel/internal/auth"
+	"triangle_travel/internal/awards"
+	"triangle_travel/internal/backup"
 	"triangle_travel/internal/db"
 	"triangle_travel/internal/flights"
+	"triangle_travel/internal/mail"
+	"triangle_travel/internal/oidc"
+	"triangle_travel/internal/phone"
+	"triangle_travel/internal/routegraph"
+	"triangle_travel/internal/sms"
 
 	"github.com/gin-gonic/gin"
 )
 
 // Handlers holds dependencies
 type Handlers struct {
-	DB *db.DB
+	DB     db.Store
+	Routes *routegraph.Store
+	// DBTimeout bounds the database work of one request; 0 means no deadline beyond the request's own
+	DBTimeout time.Duration
+	// AdminTokens maps bearer tokens for /api/admin to admin names (see ParseAdminTokens); empty disables it
+	AdminTokens map[string]string
+	// Backups is where admin backups go; nil on PostgreSQL
+	Backups *backup.Set
+	// SnapshotGraphs serves searches pinned to a dataset snapshot
+	SnapshotGraphs *routegraph.SnapshotCache
+	// SMS delivers login codes
+	SMS sms.Sender
+	// Phones parses login numbers and says which countries may sign in; nil requires the
+	// country code and allows all
+	Phones *phone.Policy
+	// Mail delivers sign-in links
+	Mail mail.Sender
+	// Links signs the tokens in sign-in links
+	Links *auth.LinkSigner
+	// PublicURL is the site's address as users reach it, e.g. https://triangle.travel; links in
+	// emails point there
+	PublicURL string
+	// Passkeys is the WebAuthn relying party passkeys are registered with and checked against
+	Passkeys *auth.RelyingParty
+	// OIDC is the OpenID Connect provider users may sign in with; nil when none is configured
+	OIDC *oidc.Provider
 }
 
-// SearchRequest for triangle travel
+// dbContext derives the context for a request's database work from gin's request context,
+// so queries stop when the client goes away or the deadline passes
+func (h *Handlers) dbContext(c *gin.Context) (context.Context, context.CancelFunc) {
+	if h.DBTimeout <= 0 {
+		return context.WithCancel(c.Request.Context())
+	}
+	return context.WithTimeout(c.Request.Context(), h.DBTimeout)
+}
+
+// dbError responds to a failed database call: 504 when the deadline passed, 503 when the
+// request was canceled, otherwise 500 with message (the error text if message is empty)
+func dbError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, db.ErrTimeout):
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "Database timed out, try again"})
+	case errors.Is(err, db.ErrCanceled):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Request canceled"})
+	default:
+		if message == "" {
+			message = err.Error()
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// SearchRequest for triangle travel. A signed-in user may leave out start, cabin, alliance,
+// awardPrograms and groundRadius to use their profile's preferences.
 type SearchRequest struct {
-	Start     string `json:"start" form:"start" binding:"required"`
-	End       string `json:"end" form:"end" binding:"required"`
-	StartDate string `json:"startDate" form:"startDate" binding:"required"`
-	EndDate   string `json:"endDate" form:"endDate" binding:"required"`
-	Cabin     string `json:"cabin" form:"cabin"`
-	Alliance  string `json:"alliance" form:"alliance"`
+	Start         string   `json:"start" form:"start"`
+	End           string   `json:"end" form:"end" binding:"required"`
+	StartDate     string   `json:"startDate" form:"startDate" binding:"required"`
+	EndDate       string   `json:"endDate" form:"endDate" binding:"required"`
+	Cabin         string   `json:"cabin" form:"cabin"`
+	Alliance      string   `json:"alliance" form:"alliance"`
+	FreeStopover  bool     `json:"freeStopover" form:"freeStopover"`
+	AwardPrograms []string `json:"awardPrograms" form:"awardPrograms"`
+	// GroundRadius is how far, in miles, to look for drive-then-fly airports (0 = default)
+	GroundRadius float64 `json:"groundRadius" form:"groundRadius"`
+	// Snapshot pins the route data to a dataset snapshot so results can be reproduced (0 = current).
+	// Snapshots don't cover stopover programs or award charts, so a pinned search can't ask for
+	// freeStopover or awardPrograms (400) and doesn't take award programs from the profile.
+	Snapshot int64 `json:"snapshot" form:"snapshot"`
 }
 
-// Search handles POST /api/search
+// Search handles POST /api/search; with OptionalAuthMiddleware a signed-in user's profile
+// fills in what the request leaves out
 func (h *Handlers) Search(c *gin.Context) {
 	var req SearchRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	// Prevent same-city searches (e.g. LGA to EWR)
-	same, err := h.DB.SameCity(req.Start, req.End)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if req.Snapshot != 0 && (req.FreeStopover || len(req.AwardPrograms) > 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Stopovers and award pricing use current data and can't be pinned to a snapshot"})
+		return
+	}
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	// One graph snapshot for the whole request, even if a reload swaps it meanwhile
+	graph := h.Routes.Graph()
+	if req.Snapshot != 0 {
+		var err error
+		if graph, err = h.SnapshotGraphs.Graph(ctx, req.Snapshot); err != nil {
+			if errors.Is(err, db.ErrSnapshotNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Snapshot not found"})
+				return
+			}
+			dbError(c, err, "")
+			return
+		}
+	}
+	args := flights.FlightSearch{
+		Start:         req.Start,
+		End:           req.End,
+		StartDate:     req.StartDate,
+		EndDate:       req.EndDate,
+		Cabin:         req.Cabin,
+		Alliance:      req.Alliance,
+		FreeStopover:  req.FreeStopover,
+		AwardPrograms: req.AwardPrograms,
+		GroundRadius:  req.GroundRadius,
+	}
+	if req.Snapshot != 0 {
+		// An empty list keeps the profile's programs out: their charts aren't in the snapshot
+		args.AwardPrograms = []string{}
+	}
+	var profile *db.Profile
+	if userID := c.GetInt64("user_id"); userID != 0 {
+		var err error
+		if profile, err = h.DB.GetProfile(ctx, userID); err != nil {
+			dbError(c, err, "Failed to load profile")
+			return
+		}
+	}
+	args.Normalize(profile)
+	if args.Start == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Start required (or set a home airport in your profile)"})
+		return
+	}
+	if args.GroundRadius > maxGroundRadius {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ground radius must be at most 1000 miles"})
 		return
 	}
-	if same {
+	// Prevent same-city searches (e.g. LGA to EWR)
+	if graph.SameCity(args.Start, args.End) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Start and end must be different cities (e.g. LGA and EWR are both NYC)"})
 		return
 	}
-	args := flights.FlightSearch{
-		Start:     req.Start,
-		End:       req.End,
-		StartDate: req.StartDate,
-		EndDate:   req.EndDate,
-		Cabin:     req.Cabin,
-		Alliance:  req.Alliance,
+	result, err := flights.Explore(ctx, h.DB, graph, args)
+	var unknown *awards.UnknownProgramsError
+	if errors.As(err, &unknown) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown award program " + strings.Join(unknown.Codes, ", "), "unknownPrograms": unknown.Codes})
+		return
 	}
-	result, err := flights.Explore(h.DB, args)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		dbError(c, err, "")
 		return
 	}
 	c.JSON(http.StatusOK, result)
@@ -58,19 +173,24 @@ func (h *Handlers) Search(c *gin.Context) {
 
 // Cities returns list of known city codes
 func (h *Handlers) Cities(c *gin.Context) {
-	rows, err := h.DB.Query("SELECT DISTINCT city_code FROM iata_cities ORDER BY city_code")
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	cities, err := h.DB.ListCities(ctx)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		dbError(c, err, "")
 		return
 	}
-	defer rows.Close()
-	var cities []string
-	for rows.Next() {
-		var code string
-		if err := rows.Scan(&code); err != nil {
-			continue
-		}
-		cities = append(cities, code)
-	}
 	c.JSON(http.StatusOK, cities)
 }
+
+// AwardPrograms lists loyalty programs usable in awardPrograms on /api/search
+func (h *Handlers) AwardPrograms(c *gin.Context) {
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	programs, err := h.DB.GetAwardPrograms(ctx, nil)
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	c.JSON(http.StatusOK, programs)
+}
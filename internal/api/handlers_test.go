@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Stopover programs and award charts aren't in snapshots, so a pinned search can't use them
+func TestSearchSnapshotRejectsLiveData(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/search", (&Handlers{}).Search)
+
+	tests := []string{
+		`{"end":"LON","startDate":"2026-06-01","endDate":"2026-06-10","snapshot":3,"freeStopover":true}`,
+		`{"end":"LON","startDate":"2026-06-01","endDate":"2026-06-10","snapshot":3,"awardPrograms":["BA_AVIOS"]}`,
+	}
+	for _, body := range tests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/search", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "snapshot") {
+			t.Errorf("%s: %d %s, want 400", body, w.Code, w.Body)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"triangle_travel/internal/auth"
+	"triangle_travel/internal/db"
+	"triangle_travel/internal/mail"
+	"triangle_travel/internal/oidc"
+)
+
+// oidcTimeout bounds each call to the identity provider (discovery, keys, token exchange)
+const oidcTimeout = 10 * time.Second
+
+// OIDCConfig tells the frontend whether single sign-on is offered and what to call it
+func (h *Handlers) OIDCConfig(c *gin.Context) {
+	if h.OIDC == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"enabled": true, "name": h.OIDC.Name()})
+}
+
+// StartOIDCLogin begins signing in with the identity provider: it answers with the provider URL
+// to send the browser to and the state the callback will carry. The frontend keeps the state and
+// only completes a callback that returns it, so a sign-in started elsewhere can't be slipped in.
+func (h *Handlers) StartOIDCLogin(c *gin.Context) {
+	h.startOIDC(c, 0)
+}
+
+// LinkOIDC begins linking an identity at the provider to the signed-in user, so they can sign
+// in with either; the callback then answers {linked: true} instead of signing in
+func (h *Handlers) LinkOIDC(c *gin.Context) {
+	h.startOIDC(c, c.GetInt64("user_id"))
+}
+
+func (h *Handlers) startOIDC(c *gin.Context, userID int64) {
+	if h.OIDC == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Single sign-on isn't set up"})
+		return
+	}
+	state, err1 := oidc.RandomString()
+	nonce, err2 := oidc.RandomString()
+	verifier, err3 := oidc.RandomString()
+	if err := errors.Join(err1, err2, err3); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start sign-in"})
+		return
+	}
+	providerCtx, cancelProvider := context.WithTimeout(c.Request.Context(), oidcTimeout)
+	defer cancelProvider()
+	authURL, err := h.OIDC.AuthCodeURL(providerCtx, state, nonce, verifier)
+	if err != nil {
+		log.Printf("OIDC: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Couldn't reach " + h.OIDC.Name() + ", try again shortly"})
+		return
+	}
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	now := time.Now()
+	login := db.OIDCLogin{StateHash: auth.HashToken(state), Nonce: nonce, CodeVerifier: verifier, UserID: userID,
+		CreatedAt: now, ExpiresAt: now.Add(oidc.LoginLifetime)}
+	if err := h.DB.SaveOIDCLogin(ctx, login); err != nil {
+		dbError(c, err, "Failed to start sign-in")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"url": authURL, "state": state})
+}
+
+// OIDCCallbackRequest for POST /api/auth/oidc/callback: the code and state the provider
+// redirected the browser back with
+type OIDCCallbackRequest struct {
+	Code  string `json:"code" binding:"required"`
+	State string `json:"state" binding:"required"`
+}
+
+// OIDCCallback completes a sign-in: it trades the code for an ID token, verifies it and signs
+// in as the user linked to its subject, creating one on the first sign-in. A callback for
+// LinkOIDC links the identity instead (409 if it belongs to another account). Each state works
+// once.
+func (h *Handlers) OIDCCallback(c *gin.Context) {
+	if h.OIDC == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Single sign-on isn't set up"})
+		return
+	}
+	var req OIDCCallbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Code and state required"})
+		return
+	}
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	now := time.Now()
+	login, err := h.DB.TakeOIDCLogin(ctx, auth.HashToken(strings.TrimSpace(req.State)), now)
+	if err != nil {
+		dbError(c, err, "Sign-in failed")
+		return
+	}
+	if login == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign-in request expired or already used; start again"})
+		return
+	}
+
+	providerCtx, cancelProvider := context.WithTimeout(c.Request.Context(), oidcTimeout)
+	defer cancelProvider()
+	claims, err := h.OIDC.Exchange(providerCtx, strings.TrimSpace(req.Code), login.CodeVerifier, login.Nonce)
+	if err != nil {
+		log.Printf("OIDC: %v", err)
+		var tokenErr *oidc.TokenError
+		switch {
+		case errors.Is(err, oidc.ErrInvalidToken):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": h.OIDC.Name() + " sent an identity that doesn't check out; start again"})
+		case errors.As(err, &tokenErr) && tokenErr.Rejected():
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign-in code expired or already used; start again"})
+		default:
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Couldn't complete sign-in with " + h.OIDC.Name() + ", try again shortly"})
+		}
+		return
+	}
+
+	// The exchange may have used up the first deadline; the rest of the database work gets its own
+	ctx, cancel = h.dbContext(c)
+	defer cancel()
+	// Only an address the provider has verified is trusted to name the account
+	var email string
+	if claims.EmailVerified {
+		email, _ = mail.NormalizeAddress(claims.Email)
+	}
+	ident := db.OIDCIdentity{Issuer: claims.Issuer, Subject: claims.Subject, UserID: login.UserID, Email: email, CreatedAt: now}
+	if login.UserID != 0 {
+		err := h.DB.LinkOIDCIdentity(ctx, ident)
+		if errors.Is(err, db.ErrIdentityInUse) {
+			c.JSON(http.StatusConflict, gin.H{"error": "That " + h.OIDC.Name() + " account already has its own account here"})
+			return
+		}
+		if err != nil {
+			dbError(c, err, "Failed to update account")
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"ok": true, "linked": true, "email": email})
+		return
+	}
+	user, err := h.DB.EnsureOIDCUser(ctx, ident, email)
+	if err != nil {
+		dbError(c, err, "Failed to create user")
+		return
+	}
+	h.startSession(ctx, c, user.ID)
+}
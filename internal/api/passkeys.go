@@ -0,0 +1,333 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"triangle_travel/internal/auth"
+	"triangle_travel/internal/db"
+)
+
+// maxPasskeyName bounds the label a user gives a passkey
+const maxPasskeyName = 64
+
+// b64 is binary data as WebAuthn's JSON carries it: base64url, padding optional
+type b64 []byte
+
+func (b *b64) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return err
+	}
+	*b = raw
+	return nil
+}
+
+// PasskeyRegisterRequest for POST /api/auth/passkeys/register/begin. Code is a login code
+// sent to the account's phone number with send-otp.
+type PasskeyRegisterRequest struct {
+	Code string `json:"code"`
+}
+
+// BeginPasskeyRegistration returns the options for navigator.credentials.create(). Adding a
+// passkey is a step-up: an account with a phone number confirms with a fresh code sent to it
+// (403 with code "step_up_required" and the number without one); an account without needs a
+// session signed in within auth.PasskeyFreshSession (403 "reauth_required").
+func (h *Handlers) BeginPasskeyRegistration(c *gin.Context) {
+	var req PasskeyRegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	userID := c.GetInt64("user_id")
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	user, err := h.DB.GetUser(ctx, userID)
+	if err != nil {
+		dbError(c, err, "Failed to start passkey registration")
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+	if !h.stepUp(ctx, c, user, req.Code, "add a passkey") {
+		return
+	}
+
+	existing, err := h.DB.ListPasskeys(ctx, userID)
+	if err != nil {
+		dbError(c, err, "Failed to start passkey registration")
+		return
+	}
+	exclude := make([]gin.H, 0, len(existing))
+	for _, p := range existing {
+		exclude = append(exclude, gin.H{"type": "public-key", "id": p.CredentialID, "transports": transportList(p.Transports)})
+	}
+	params := make([]gin.H, 0, len(auth.PasskeyAlgorithms))
+	for _, alg := range auth.PasskeyAlgorithms {
+		params = append(params, gin.H{"type": "public-key", "alg": alg})
+	}
+	challenge, ok := h.newPasskeyChallenge(ctx, c, db.PasskeyRegister, userID)
+	if !ok {
+		return
+	}
+	name := user.Email
+	if name == "" {
+		name = user.Phone
+	}
+	if name == "" {
+		// A single sign-on account may have neither; authenticators still need a label
+		name = h.Passkeys.Name + " account " + strconv.FormatInt(userID, 10)
+	}
+	c.JSON(http.StatusOK, gin.H{"publicKey": gin.H{
+		"challenge": challenge,
+		"rp":        gin.H{"id": h.Passkeys.ID, "name": h.Passkeys.Name},
+		"user": gin.H{"id": base64.RawURLEncoding.EncodeToString(auth.PasskeyUserHandle(userID)),
+			"name": name, "displayName": name},
+		"pubKeyCredParams":   params,
+		"timeout":            auth.PasskeyChallengeLifetime.Milliseconds(),
+		"attestation":        "none",
+		"excludeCredentials": exclude,
+		"authenticatorSelection": gin.H{"residentKey": "required", "requireResidentKey": true,
+			"userVerification": "required"},
+	}})
+}
+
+// PasskeyCredential is a PublicKeyCredential as its toJSON() encodes it: binary fields base64url
+type PasskeyCredential struct {
+	ID       string `json:"id" binding:"required"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    b64      `json:"clientDataJSON"`
+		AttestationObject b64      `json:"attestationObject"`
+		Transports        []string `json:"transports"`
+		AuthenticatorData b64      `json:"authenticatorData"`
+		Signature         b64      `json:"signature"`
+		UserHandle        b64      `json:"userHandle"`
+	} `json:"response"`
+}
+
+// FinishPasskeyRegistrationRequest for POST /api/auth/passkeys/register/finish
+type FinishPasskeyRegistrationRequest struct {
+	Name       string            `json:"name"`
+	Credential PasskeyCredential `json:"credential" binding:"required"`
+}
+
+// FinishPasskeyRegistration verifies the authenticator's response to a registration challenge
+// and stores the new passkey. 409 if that credential is already registered.
+func (h *Handlers) FinishPasskeyRegistration(c *gin.Context) {
+	var req FinishPasskeyRegistrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Credential required"})
+		return
+	}
+	userID := c.GetInt64("user_id")
+	resp := req.Credential.Response
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	now := time.Now()
+	if !h.takePasskeyChallenge(ctx, c, resp.ClientDataJSON, db.PasskeyRegister, userID, now) {
+		return
+	}
+	challenge, _ := auth.ClientDataChallenge(resp.ClientDataJSON)
+	key, err := h.Passkeys.VerifyRegistration(challenge, resp.ClientDataJSON, resp.AttestationObject)
+	if err != nil {
+		passkeyError(c, err)
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = auth.DeviceName(c.Request.UserAgent())
+	}
+	if len(name) > maxPasskeyName {
+		name = name[:maxPasskeyName]
+	}
+	passkey := &db.Passkey{UserID: userID, CredentialID: base64.RawURLEncoding.EncodeToString(key.CredentialID),
+		PublicKey: key.PublicKey, SignCount: key.SignCount, BackupEligible: key.BackupEligible, BackedUp: key.BackedUp,
+		Transports: strings.Join(resp.Transports, ","), Name: name, CreatedAt: now}
+	if err := h.DB.AddPasskey(ctx, passkey); err != nil {
+		if errors.Is(err, db.ErrPasskeyExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "This passkey is already registered"})
+			return
+		}
+		dbError(c, err, "Failed to save passkey")
+		return
+	}
+	c.JSON(http.StatusOK, passkey)
+}
+
+// BeginPasskeyLogin returns the options for navigator.credentials.get(). No account is named:
+// the browser offers the passkeys it holds for this site.
+func (h *Handlers) BeginPasskeyLogin(c *gin.Context) {
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	challenge, ok := h.newPasskeyChallenge(ctx, c, db.PasskeyLogin, 0)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"publicKey": gin.H{
+		"challenge":        challenge,
+		"rpId":             h.Passkeys.ID,
+		"timeout":          auth.PasskeyChallengeLifetime.Milliseconds(),
+		"userVerification": "required",
+		"allowCredentials": []gin.H{},
+	}})
+}
+
+// FinishPasskeyLoginRequest for POST /api/auth/passkeys/login/finish
+type FinishPasskeyLoginRequest struct {
+	Credential PasskeyCredential `json:"credential" binding:"required"`
+}
+
+// FinishPasskeyLogin verifies a signed sign-in challenge and returns a session token. A sign
+// count that didn't increase is refused (401) and logged: the passkey may have been cloned.
+func (h *Handlers) FinishPasskeyLogin(c *gin.Context) {
+	var req FinishPasskeyLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Credential required"})
+		return
+	}
+	resp := req.Credential.Response
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	now := time.Now()
+	if !h.takePasskeyChallenge(ctx, c, resp.ClientDataJSON, db.PasskeyLogin, 0, now) {
+		return
+	}
+	passkey, err := h.DB.GetPasskey(ctx, req.Credential.ID)
+	if err != nil {
+		dbError(c, err, "Verification failed")
+		return
+	}
+	if passkey == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "This passkey isn't registered; sign in another way"})
+		return
+	}
+	if len(resp.UserHandle) > 0 && !bytes.Equal(resp.UserHandle, auth.PasskeyUserHandle(passkey.UserID)) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Passkey doesn't match its account"})
+		return
+	}
+	challenge, _ := auth.ClientDataChallenge(resp.ClientDataJSON)
+	assertion, err := h.Passkeys.VerifyAssertion(challenge, resp.ClientDataJSON, resp.AuthenticatorData, resp.Signature,
+		passkey.PublicKey, passkey.SignCount)
+	if errors.Is(err, auth.ErrSignCount) {
+		log.Printf("Passkey %d of user %d: sign count went from %d backwards; possible clone", passkey.ID, passkey.UserID, passkey.SignCount)
+	}
+	if err != nil {
+		passkeyError(c, err)
+		return
+	}
+	ok, err := h.DB.UsePasskey(ctx, passkey.ID, passkey.SignCount, assertion.SignCount, assertion.BackedUp, now)
+	if err != nil {
+		dbError(c, err, "Verification failed")
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "This passkey was just used elsewhere; try again"})
+		return
+	}
+	h.startSession(ctx, c, passkey.UserID)
+}
+
+// ListPasskeys returns the signed-in user's passkeys
+func (h *Handlers) ListPasskeys(c *gin.Context) {
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	keys, err := h.DB.ListPasskeys(ctx, c.GetInt64("user_id"))
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// DeletePasskey removes one of the signed-in user's passkeys; it can't sign in afterwards
+func (h *Handlers) DeletePasskey(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	ok, err := h.DB.DeletePasskey(ctx, c.GetInt64("user_id"), id)
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Passkey not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// newPasskeyChallenge issues and stores a challenge; on failure it answers the request itself
+func (h *Handlers) newPasskeyChallenge(ctx context.Context, c *gin.Context, purpose string, userID int64) (string, bool) {
+	challenge, err := auth.GenerateChallenge()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start passkey ceremony"})
+		return "", false
+	}
+	now := time.Now()
+	ch := db.PasskeyChallenge{Hash: auth.HashToken(challenge), Purpose: purpose, UserID: userID,
+		CreatedAt: now, ExpiresAt: now.Add(auth.PasskeyChallengeLifetime)}
+	if err := h.DB.SavePasskeyChallenge(ctx, ch); err != nil {
+		dbError(c, err, "Failed to start passkey ceremony")
+		return "", false
+	}
+	return challenge, true
+}
+
+// takePasskeyChallenge consumes the challenge a response answers, which must have been issued
+// for purpose (and, for registration, to userID) and not have expired; otherwise it answers 401
+func (h *Handlers) takePasskeyChallenge(ctx context.Context, c *gin.Context, clientDataJSON []byte, purpose string, userID int64, now time.Time) bool {
+	challenge, err := auth.ClientDataChallenge(clientDataJSON)
+	if err != nil {
+		passkeyError(c, err)
+		return false
+	}
+	ch, err := h.DB.TakePasskeyChallenge(ctx, auth.HashToken(challenge), purpose, now)
+	if err != nil {
+		dbError(c, err, "Verification failed")
+		return false
+	}
+	if ch == nil || ch.UserID != userID {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Passkey request expired or already used; start again"})
+		return false
+	}
+	return true
+}
+
+// passkeyError answers a failed verification with its reason (401), anything else with 500
+func passkeyError(c *gin.Context, err error) {
+	var pe *auth.PasskeyError
+	if errors.As(err, &pe) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": pe.Msg})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Passkey verification failed"})
+}
+
+// transportList splits stored transport hints for credential descriptors
+func transportList(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
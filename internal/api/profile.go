@@ -0,0 +1,251 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"triangle_travel/internal/db"
+	"triangle_travel/internal/flights"
+	"triangle_travel/internal/phone"
+	"triangle_travel/internal/refcheck"
+)
+
+// Profile limits
+const (
+	maxDisplayName     = 80
+	maxHomeAirports    = 5
+	minGroundRadius    = 55 // closer airports are the same trip, see flights.Explore
+	maxGroundRadius    = 1000
+	maxProfileListSize = 20
+)
+
+// Me answers GET /api/me: the signed-in user and their profile
+func (h *Handlers) Me(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	user, err := h.DB.GetUser(ctx, userID)
+	if err != nil {
+		dbError(c, err, "Failed to load account")
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	profile, err := h.DB.GetProfile(ctx, userID)
+	if err != nil {
+		dbError(c, err, "Failed to load profile")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": user.ID, "phone": user.Phone, "email": user.Email, "profile": profile})
+}
+
+// ProfileRequest for PUT /api/me: the whole profile, replacing the saved one
+type ProfileRequest struct {
+	DisplayName       string   `json:"displayName"`
+	HomeAirports      []string `json:"homeAirports"`
+	PreferredAlliance string   `json:"preferredAlliance"`
+	PreferredCabin    string   `json:"preferredCabin"`
+	LoyaltyPrograms   []string `json:"loyaltyPrograms"`
+	Nationalities     []string `json:"nationalities"`
+	GroundRadius      float64  `json:"groundRadius"`
+}
+
+// UpdateMe handles PUT /api/me, saving the signed-in user's profile. Codes are checked against
+// the reference data: home airports and loyalty programs must exist, nationalities must be
+// known countries.
+func (h *Handlers) UpdateMe(c *gin.Context) {
+	var req ProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid profile"})
+		return
+	}
+	p := db.Profile{
+		DisplayName:       strings.TrimSpace(req.DisplayName),
+		HomeAirports:      codeList(req.HomeAirports, strings.ToUpper),
+		PreferredAlliance: strings.TrimSpace(req.PreferredAlliance),
+		PreferredCabin:    strings.ToLower(strings.TrimSpace(req.PreferredCabin)),
+		LoyaltyPrograms:   codeList(req.LoyaltyPrograms, strings.ToUpper),
+		Nationalities:     codeList(req.Nationalities, strings.ToUpper),
+		GroundRadius:      req.GroundRadius,
+	}
+	if p.PreferredAlliance == "None" {
+		p.PreferredAlliance = ""
+	}
+	switch {
+	case utf8.RuneCountInString(p.DisplayName) > maxDisplayName:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Display name is too long"})
+		return
+	case len(p.HomeAirports) > maxHomeAirports:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At most " + plural(maxHomeAirports, "home airport")})
+		return
+	case len(p.LoyaltyPrograms) > maxProfileListSize || len(p.Nationalities) > maxProfileListSize:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many loyalty programs or nationalities"})
+		return
+	case p.PreferredAlliance != "" && !slices.Contains(refcheck.RouteAlliances, p.PreferredAlliance):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown alliance " + p.PreferredAlliance})
+		return
+	case p.PreferredCabin != "" && !slices.Contains(flights.Cabins, p.PreferredCabin):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cabin must be one of " + strings.Join(flights.Cabins, ", ")})
+		return
+	case p.GroundRadius != 0 && (p.GroundRadius < minGroundRadius || p.GroundRadius > maxGroundRadius):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ground radius must be between 55 and 1000 miles"})
+		return
+	}
+	for _, iso := range p.Nationalities {
+		if !phone.KnownCountry(iso) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown country " + iso})
+			return
+		}
+	}
+
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	for _, code := range p.HomeAirports {
+		airport, err := h.DB.GetAirport(ctx, code)
+		if err != nil {
+			dbError(c, err, "Failed to check airports")
+			return
+		}
+		if len(code) != 3 || airport == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown airport " + code})
+			return
+		}
+	}
+	if len(p.LoyaltyPrograms) > 0 {
+		programs, err := h.DB.GetAwardPrograms(ctx, p.LoyaltyPrograms)
+		if err != nil {
+			dbError(c, err, "Failed to check loyalty programs")
+			return
+		}
+		for _, code := range p.LoyaltyPrograms {
+			if !slices.ContainsFunc(programs, func(ap db.AwardProgram) bool { return ap.Code == code }) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown loyalty program " + code})
+				return
+			}
+		}
+	}
+	if err := h.DB.SaveProfile(ctx, c.GetInt64("user_id"), &p, time.Now()); err != nil {
+		dbError(c, err, "Failed to save profile")
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// codeList trims and normalizes codes, dropping blanks and repeats but keeping the order
+func codeList(codes []string, normalize func(string) string) []string {
+	out := []string{}
+	for _, code := range codes {
+		code = normalize(strings.TrimSpace(code))
+		if code != "" && !slices.Contains(out, code) {
+			out = append(out, code)
+		}
+	}
+	return out
+}
+
+// DeleteMeRequest for DELETE /api/me. Code is a login code sent to the account's phone number
+// with send-otp.
+type DeleteMeRequest struct {
+	Code string `json:"code"`
+}
+
+// DeleteMe handles DELETE /api/me: it deletes the signed-in user's account and everything tied
+// to it, signing out every device. Like adding a passkey it needs a fresh proof of the account
+// (see stepUp).
+func (h *Handlers) DeleteMe(c *gin.Context) {
+	var req DeleteMeRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	userID := c.GetInt64("user_id")
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	user, err := h.DB.GetUser(ctx, userID)
+	if err != nil {
+		dbError(c, err, "Failed to delete account")
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+	if !h.stepUp(ctx, c, user, req.Code, "delete your account") {
+		return
+	}
+	ok, err := h.DB.DeleteUser(ctx, userID)
+	if err != nil {
+		dbError(c, err, "Failed to delete account")
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// ExportMe handles GET /api/me/export: everything stored about the signed-in user as one JSON
+// document, sent as a download. Secrets (token hashes, passkey keys) are left out.
+func (h *Handlers) ExportMe(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	user, err := h.DB.GetUser(ctx, userID)
+	if err != nil {
+		dbError(c, err, "Failed to export account")
+		return
+	}
+	if user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+	profile, err := h.DB.GetProfile(ctx, userID)
+	if err != nil {
+		dbError(c, err, "Failed to export account")
+		return
+	}
+	booked, err := h.DB.ListBookedFlights(ctx, userID)
+	if err != nil {
+		dbError(c, err, "Failed to export account")
+		return
+	}
+	sessions, err := h.DB.ListSessions(ctx, userID)
+	if err != nil {
+		dbError(c, err, "Failed to export account")
+		return
+	}
+	passkeys, err := h.DB.ListPasskeys(ctx, userID)
+	if err != nil {
+		dbError(c, err, "Failed to export account")
+		return
+	}
+	identities, err := h.DB.ListOIDCIdentities(ctx, userID)
+	if err != nil {
+		dbError(c, err, "Failed to export account")
+		return
+	}
+	if booked == nil {
+		booked = []db.BookedFlight{}
+	}
+	c.Header("Content-Disposition", `attachment; filename="triangle-travel-account-`+strconv.FormatInt(userID, 10)+`.json"`)
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"exportedAt": time.Now().UTC(),
+		"user":       user,
+		"profile":    profile,
+		"flights":    booked,
+		"sessions":   sessions,
+		"passkeys":   passkeys,
+		"identities": identities,
+	})
+}
@@ -0,0 +1,416 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"triangle_travel/internal/db"
+	"triangle_travel/internal/refcheck"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// DistanceRequest for PUT /api/admin/distances/:from/:to
+type DistanceRequest struct {
+	Miles float64 `json:"miles" binding:"required,gt=0"`
+}
+
+// AirlineRequest for PUT /api/admin/airlines/:code; alliance defaults to None and active to true
+type AirlineRequest struct {
+	ICAO     string `json:"icao"`
+	Name     string `json:"name" binding:"required"`
+	Country  string `json:"country"`
+	Alliance string `json:"alliance"`
+	Active   *bool  `json:"active"`
+}
+
+func normCode(s string) string {
+	return strings.ToUpper(strings.TrimSpace(s))
+}
+
+// refError responds to a rejected or failed reference data change
+func refError(c *gin.Context, err error) {
+	var invalid *refcheck.Invalid
+	if errors.As(err, &invalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reference data", "problems": invalid.Problems})
+		return
+	}
+	dbError(c, err, "")
+}
+
+// refreshRoutes reloads the route graph after a change so searches see it right away.
+// The reload outlives the request: the change is committed even if the client went away.
+func (h *Handlers) refreshRoutes(ctx context.Context) {
+	if err := h.Routes.Reload(context.WithoutCancel(ctx)); err != nil {
+		log.Printf("Route graph: reload after admin change: %v", err)
+	}
+}
+
+// AdminAirports handles GET /api/admin/airports
+func (h *Handlers) AdminAirports(c *gin.Context) {
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	airports, err := h.DB.ListAirports(ctx)
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	c.JSON(http.StatusOK, airports)
+}
+
+// AdminCodeUsage handles GET /api/admin/airports/:code: the airport and everything referring to the code
+func (h *Handlers) AdminCodeUsage(c *gin.Context) {
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	code := normCode(c.Param("code"))
+	u, err := h.DB.CodeUsage(ctx, code)
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	if !u.Known() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown city or airport"})
+		return
+	}
+	c.JSON(http.StatusOK, u)
+}
+
+// PutAirport handles PUT /api/admin/airports/:code, creating or replacing the airport
+func (h *Handlers) PutAirport(c *gin.Context) {
+	var a db.Airport
+	if err := c.ShouldBindJSON(&a); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	a.IATA = normCode(c.Param("code"))
+	a.CityCode = normCode(a.CityCode)
+	a.Country = normCode(a.Country)
+	a.Continent = normCode(a.Continent)
+	a.Name = strings.TrimSpace(a.Name)
+	if a.CityCode == "" {
+		a.CityCode = a.IATA
+	}
+	if err := refcheck.CheckAirport(a); err != nil {
+		refError(c, err)
+		return
+	}
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	if err := h.DB.PutAirport(ctx, c.GetString("admin"), a); err != nil {
+		dbError(c, err, "")
+		return
+	}
+	h.refreshRoutes(ctx)
+	c.JSON(http.StatusOK, a)
+}
+
+// DeleteAirport handles DELETE /api/admin/airports/:code. An airport still mapped to a city
+// or used by routes or distances is kept (409) so the reference data stays consistent.
+func (h *Handlers) DeleteAirport(c *gin.Context) {
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	code := normCode(c.Param("code"))
+	u, err := h.DB.CodeUsage(ctx, code)
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	if len(u.Cities) > 0 || len(u.Airports) > 0 || u.Routes > 0 || u.Distances > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Airport is still in use; remove its city mappings, routes and distances first", "usage": u})
+		return
+	}
+	ok, err := h.DB.DeleteAirport(ctx, c.GetString("admin"), code)
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Airport not found"})
+		return
+	}
+	h.refreshRoutes(ctx)
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// AdminCityAirports handles GET /api/admin/cities: every city to airport mapping
+func (h *Handlers) AdminCityAirports(c *gin.Context) {
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	mappings, err := h.DB.ListCityAirports(ctx)
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	c.JSON(http.StatusOK, mappings)
+}
+
+// AddCityAirport handles POST /api/admin/cities, mapping an airport to a city
+func (h *Handlers) AddCityAirport(c *gin.Context) {
+	var m db.CityAirport
+	if err := c.ShouldBindJSON(&m); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	m.CityCode, m.AirportCode = normCode(m.CityCode), normCode(m.AirportCode)
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	if err := refcheck.CheckCityAirport(ctx, h.DB, m); err != nil {
+		refError(c, err)
+		return
+	}
+	created, err := h.DB.AddCityAirport(ctx, c.GetString("admin"), m)
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	if !created {
+		c.JSON(http.StatusOK, m)
+		return
+	}
+	h.refreshRoutes(ctx)
+	c.JSON(http.StatusCreated, m)
+}
+
+// DeleteCityAirport handles DELETE /api/admin/cities/:city/:airport. A city's last airport
+// can't be removed while routes or distances still use the city.
+func (h *Handlers) DeleteCityAirport(c *gin.Context) {
+	m := db.CityAirport{CityCode: normCode(c.Param("city")), AirportCode: normCode(c.Param("airport"))}
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	u, err := h.DB.CodeUsage(ctx, m.CityCode)
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	if len(u.Airports) == 1 && u.Airports[0] == m.AirportCode && (u.Routes > 0 || u.Distances > 0) {
+		c.JSON(http.StatusConflict, gin.H{"error": "This is the city's last airport and the city is still in use", "usage": u})
+		return
+	}
+	ok, err := h.DB.DeleteCityAirport(ctx, c.GetString("admin"), m)
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Mapping not found"})
+		return
+	}
+	h.refreshRoutes(ctx)
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// AdminRoutes handles GET /api/admin/routes, optionally ?from=CITY
+func (h *Handlers) AdminRoutes(c *gin.Context) {
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	routes, err := h.DB.ListRoutes(ctx)
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	if from := normCode(c.Query("from")); from != "" {
+		filtered := []db.Route{}
+		for _, r := range routes {
+			if r.From == from {
+				filtered = append(filtered, r)
+			}
+		}
+		routes = filtered
+	}
+	c.JSON(http.StatusOK, routes)
+}
+
+// AddRoute handles POST /api/admin/routes
+func (h *Handlers) AddRoute(c *gin.Context) {
+	var r db.Route
+	if err := c.ShouldBindJSON(&r); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	r.From, r.To = normCode(r.From), normCode(r.To)
+	r.Alliance = strings.TrimSpace(r.Alliance)
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	if err := refcheck.CheckRoute(ctx, h.DB, r); err != nil {
+		refError(c, err)
+		return
+	}
+	created, err := h.DB.AddRoute(ctx, c.GetString("admin"), r)
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	if !created {
+		c.JSON(http.StatusOK, r)
+		return
+	}
+	h.refreshRoutes(ctx)
+	c.JSON(http.StatusCreated, r)
+}
+
+// DeleteRoute handles DELETE /api/admin/routes/:from/:alliance/:to
+func (h *Handlers) DeleteRoute(c *gin.Context) {
+	r := db.Route{From: normCode(c.Param("from")), Alliance: c.Param("alliance"), To: normCode(c.Param("to"))}
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	ok, err := h.DB.DeleteRoute(ctx, c.GetString("admin"), r)
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+		return
+	}
+	h.refreshRoutes(ctx)
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// AdminDistances handles GET /api/admin/distances, optionally ?from=CODE (either direction)
+func (h *Handlers) AdminDistances(c *gin.Context) {
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	distances, err := h.DB.ListDistances(ctx)
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	if from := normCode(c.Query("from")); from != "" {
+		filtered := []db.Distance{}
+		for _, x := range distances {
+			if x.From == from || x.To == from {
+				filtered = append(filtered, x)
+			}
+		}
+		distances = filtered
+	}
+	c.JSON(http.StatusOK, distances)
+}
+
+// PutDistance handles PUT /api/admin/distances/:from/:to
+func (h *Handlers) PutDistance(c *gin.Context) {
+	var req DistanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	x := db.Distance{From: normCode(c.Param("from")), To: normCode(c.Param("to")), Miles: req.Miles}
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	if err := refcheck.CheckDistance(ctx, h.DB, x); err != nil {
+		refError(c, err)
+		return
+	}
+	if err := h.DB.PutDistance(ctx, c.GetString("admin"), x); err != nil {
+		dbError(c, err, "")
+		return
+	}
+	h.refreshRoutes(ctx)
+	c.JSON(http.StatusOK, x)
+}
+
+// DeleteDistance handles DELETE /api/admin/distances/:from/:to (both directions)
+func (h *Handlers) DeleteDistance(c *gin.Context) {
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	ok, err := h.DB.DeleteDistance(ctx, c.GetString("admin"), normCode(c.Param("from")), normCode(c.Param("to")))
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Distance not found"})
+		return
+	}
+	h.refreshRoutes(ctx)
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// AdminAirlines handles GET /api/admin/airlines
+func (h *Handlers) AdminAirlines(c *gin.Context) {
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	airlines, err := h.DB.ListAirlines(ctx)
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	c.JSON(http.StatusOK, airlines)
+}
+
+// PutAirline handles PUT /api/admin/airlines/:code, creating or replacing the airline.
+// Airlines aren't part of the route graph, so no reload is needed.
+func (h *Handlers) PutAirline(c *gin.Context) {
+	var req AirlineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	a := db.Airline{
+		IATA:     normCode(c.Param("code")),
+		ICAO:     normCode(req.ICAO),
+		Name:     strings.TrimSpace(req.Name),
+		Country:  normCode(req.Country),
+		Alliance: strings.TrimSpace(req.Alliance),
+		Active:   req.Active == nil || *req.Active,
+	}
+	if a.Alliance == "" {
+		a.Alliance = "None"
+	}
+	if err := refcheck.CheckAirline(a); err != nil {
+		refError(c, err)
+		return
+	}
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	if err := h.DB.PutAirline(ctx, c.GetString("admin"), a); err != nil {
+		dbError(c, err, "")
+		return
+	}
+	c.JSON(http.StatusOK, a)
+}
+
+// DeleteAirline handles DELETE /api/admin/airlines/:code
+func (h *Handlers) DeleteAirline(c *gin.Context) {
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	ok, err := h.DB.DeleteAirline(ctx, c.GetString("admin"), normCode(c.Param("code")))
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Airline not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// AuditLog handles GET /api/admin/audit?entity=&limit=, newest first
+func (h *Handlers) AuditLog(c *gin.Context) {
+	limit := defaultAuditLimit
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = min(n, maxAuditLimit)
+	}
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	entries, err := h.DB.ListAudit(ctx, c.Query("entity"), limit)
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"triangle_travel/internal/flights"
+)
+
+// RTWValidateRequest for POST /api/rtw/validate
+type RTWValidateRequest struct {
+	RuleSet     string     `json:"ruleSet" binding:"required"`
+	Itineraries [][]string `json:"itineraries" binding:"required"`
+}
+
+// RTWSuggestRequest for POST /api/rtw/suggest
+type RTWSuggestRequest struct {
+	RuleSet string `json:"ruleSet" binding:"required"`
+	Origin  string `json:"origin" binding:"required"`
+	Limit   int    `json:"limit"`
+}
+
+// RTWRuleSets lists the configured round-the-world rule sets
+func (h *Handlers) RTWRuleSets(c *gin.Context) {
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	sets, err := h.DB.GetRTWRuleSets(ctx)
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	c.JSON(http.StatusOK, sets)
+}
+
+// ValidateRTW returns rule violations for each proposed itinerary
+func (h *Handlers) ValidateRTW(c *gin.Context) {
+	var req RTWValidateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	rules, err := h.DB.GetRTWRuleSet(ctx, req.RuleSet)
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	if rules == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown rule set"})
+		return
+	}
+	results := make([]*flights.RTWCheck, 0, len(req.Itineraries))
+	for _, itinerary := range req.Itineraries {
+		check, err := flights.ValidateRTW(ctx, h.DB, h.Routes.Graph(), *rules, itinerary)
+		if err != nil {
+			dbError(c, err, "")
+			return
+		}
+		results = append(results, check)
+	}
+	c.JSON(http.StatusOK, gin.H{"ruleSet": rules, "results": results})
+}
+
+// SuggestRTW proposes valid itineraries from an origin over known routes
+func (h *Handlers) SuggestRTW(c *gin.Context) {
+	var req RTWSuggestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Limit <= 0 || req.Limit > 20 {
+		req.Limit = 5
+	}
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	rules, err := h.DB.GetRTWRuleSet(ctx, req.RuleSet)
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	if rules == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown rule set"})
+		return
+	}
+	suggestions, err := flights.SuggestRTW(ctx, h.DB, h.Routes.Graph(), *rules, req.Origin, req.Limit)
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	if suggestions == nil {
+		suggestions = []*flights.RTWCheck{}
+	}
+	c.JSON(http.StatusOK, gin.H{"ruleSet": rules, "suggestions": suggestions})
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"triangle_travel/internal/auth"
+	"triangle_travel/internal/db"
+)
+
+// maxUserAgent bounds the User-Agent kept with a session
+const maxUserAgent = 512
+
+// SessionInfo is a signed-in device as GET /api/auth/sessions lists it
+type SessionInfo struct {
+	db.Session
+	Device  string `json:"device"`  // e.g. "Safari on iPhone", from the User-Agent
+	Current bool   `json:"current"` // the session making the request
+}
+
+// TokenResponse is what signing in and refreshing answer with
+type TokenResponse struct {
+	Token        string `json:"token"`        // access token, sent as Authorization: Bearer
+	RefreshToken string `json:"refreshToken"` // single use, for POST /api/auth/refresh
+	ExpiresIn    int    `json:"expiresIn"`    // seconds the access token lasts
+}
+
+// newTokens generates an access and refresh token pair
+func newTokens() (*TokenResponse, error) {
+	access, err := auth.GenerateToken(auth.AccessTokenPrefix)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := auth.GenerateToken(auth.RefreshTokenPrefix)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenResponse{Token: access, RefreshToken: refresh, ExpiresIn: int(auth.AccessTokenLifetime.Seconds())}, nil
+}
+
+// startSession signs userID in on the requesting device and answers with its first tokens
+func (h *Handlers) startSession(ctx context.Context, c *gin.Context, userID int64) {
+	tokens, err := newTokens()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
+		return
+	}
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > maxUserAgent {
+		userAgent = userAgent[:maxUserAgent]
+	}
+	now := time.Now()
+	session := &db.Session{UserID: userID, UserAgent: userAgent, IP: c.ClientIP(), CreatedAt: now, LastUsedAt: now,
+		AccessExpiresAt: now.Add(auth.AccessTokenLifetime), ExpiresAt: now.Add(auth.SessionLifetime)}
+	if err := h.DB.CreateSession(ctx, session, auth.HashToken(tokens.Token), auth.HashToken(tokens.RefreshToken)); err != nil {
+		dbError(c, err, "Failed to create session")
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// RefreshRequest for POST /api/auth/refresh
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+// Refresh trades a refresh token for a new access and refresh token and extends the session.
+// Each refresh token works once; presenting a spent one signs its session out, since either
+// it or the token it was exchanged for has been copied.
+func (h *Handlers) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Refresh token required"})
+		return
+	}
+	tokens, err := newTokens()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		return
+	}
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	now := time.Now()
+	session, err := h.DB.RotateRefreshToken(ctx, auth.HashToken(req.RefreshToken), db.TokenRotation{
+		AccessHash:       auth.HashToken(tokens.Token),
+		RefreshHash:      auth.HashToken(tokens.RefreshToken),
+		AccessExpiresAt:  now.Add(auth.AccessTokenLifetime),
+		ExpiresAt:        now.Add(auth.SessionLifetime),
+		IP:               c.ClientIP(),
+		At:               now,
+		ForgetUsedBefore: now.Add(-auth.RefreshReuseWindow),
+	})
+	if errors.Is(err, db.ErrRefreshTokenReused) {
+		log.Printf("Refresh token reused from %s; its session was signed out", c.ClientIP())
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session ended because its refresh token was used twice; sign in again"})
+		return
+	}
+	if err != nil {
+		dbError(c, err, "Failed to refresh session")
+		return
+	}
+	if session == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout ends the session making the request
+func (h *Handlers) Logout(c *gin.Context) {
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	if _, err := h.DB.DeleteSession(ctx, c.GetInt64("user_id"), c.GetInt64("session_id")); err != nil {
+		dbError(c, err, "")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// ListSessions lists the user's signed-in devices, most recently used first
+func (h *Handlers) ListSessions(c *gin.Context) {
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	sessions, err := h.DB.ListSessions(ctx, c.GetInt64("user_id"))
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	current := c.GetInt64("session_id")
+	out := make([]SessionInfo, len(sessions))
+	for i, s := range sessions {
+		out[i] = SessionInfo{Session: s, Device: auth.DeviceName(s.UserAgent), Current: s.ID == current}
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// DeleteSession signs one of the user's devices out (the current one too, like Logout)
+func (h *Handlers) DeleteSession(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	ok, err := h.DB.DeleteSession(ctx, c.GetInt64("user_id"), id)
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// touchSession refreshes a session's last-used time and IP when they are stale, so most
+// authenticated requests only read. A failure is logged, not charged to the request.
+func (h *Handlers) touchSession(ctx context.Context, s *db.Session, ip string) {
+	now := time.Now()
+	if now.Sub(s.LastUsedAt) < auth.SessionTouchInterval && s.IP == ip {
+		return
+	}
+	if err := h.DB.TouchSession(ctx, s.ID, ip, now); err != nil {
+		log.Printf("touch session %d: %v", s.ID, err)
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"triangle_travel/internal/dataset"
+	"triangle_travel/internal/db"
+)
+
+// CreateSnapshotRequest for POST /api/admin/snapshots
+type CreateSnapshotRequest struct {
+	Source string `json:"source"`
+}
+
+// ListSnapshots handles GET /api/snapshots: dataset versions searches can be pinned to, newest first
+func (h *Handlers) ListSnapshots(c *gin.Context) {
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	snapshots, err := h.DB.ListSnapshots(ctx)
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	c.JSON(http.StatusOK, snapshots)
+}
+
+// CreateSnapshot handles POST /api/admin/snapshots, e.g. after curating data through the admin API
+func (h *Handlers) CreateSnapshot(c *gin.Context) {
+	var req CreateSnapshotRequest
+	// The body is optional
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	source := strings.TrimSpace(req.Source)
+	if source == "" {
+		source = "admin " + c.GetString("admin")
+	}
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	snapshot, err := h.DB.CreateSnapshot(ctx, source)
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	c.JSON(http.StatusCreated, snapshot)
+}
+
+// DeleteSnapshot handles DELETE /api/admin/snapshots/:id
+func (h *Handlers) DeleteSnapshot(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	ok, err := h.DB.DeleteSnapshot(ctx, id)
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Snapshot not found"})
+		return
+	}
+	h.SnapshotGraphs.Forget(id)
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// DiffSnapshots handles GET /api/admin/snapshots/diff?from=ID&to=ID: routes gained and lost
+// per city, distance changes and city/airport changes. Either side may be "current" (the
+// live tables); to defaults to current.
+func (h *Handlers) DiffSnapshots(c *gin.Context) {
+	from, ok := h.routeData(c.Query("from"))
+	if !ok || c.Query("from") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a snapshot ID or current"})
+		return
+	}
+	to, ok := h.routeData(c.Query("to"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a snapshot ID or current"})
+		return
+	}
+	ctx, cancel := h.dbContext(c)
+	defer cancel()
+	diff, err := dataset.Compare(ctx, from, to)
+	if errors.Is(err, db.ErrSnapshotNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Snapshot not found"})
+		return
+	}
+	if err != nil {
+		dbError(c, err, "")
+		return
+	}
+	c.JSON(http.StatusOK, diff)
+}
+
+// routeData resolves a snapshot ID, or "current" (or empty) for the live tables
+func (h *Handlers) routeData(ref string) (db.RouteData, bool) {
+	if ref == "" || ref == "current" {
+		return h.DB, true
+	}
+	id, err := strconv.ParseInt(ref, 10, 64)
+	if err != nil || id <= 0 {
+		return nil, false
+	}
+	return h.DB.SnapshotData(id), true
+}
@@ -1,9 +1,15 @@
 package auth
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
+	"fmt"
+	"math/big"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -11,30 +17,113 @@ import (
 const DevPhone = "+15550000000"
 const DevOTP = "123456"
 
-// IsDev returns true when ENV is not production
+// IsDev returns true when ENV is development. Anything else, including an unset ENV, is
+// production, so a deployment that forgets ENV doesn't open the dev login.
 func IsDev() bool {
-	return os.Getenv("ENV") != "production" && os.Getenv("ENV") != "prod"
+	return os.Getenv("ENV") == "development" || os.Getenv("ENV") == "dev"
 }
 
-// Mock OTP for dev: "123456" works for any +1 number (legacy)
-const MockOTP = "123456"
+// Token prefixes tell access and refresh tokens apart. Tokens issued before refresh tokens
+// existed have no prefix; migration 0008 gave them one access-token lifetime to keep working.
+const (
+	AccessTokenPrefix  = "at_"
+	RefreshTokenPrefix = "rt_"
+)
+
+// Session lifetimes. An access token is good for AccessTokenLifetime; the refresh token issued
+// with it trades for a new pair once, and each trade moves the session's expiry to
+// SessionLifetime from then, so only a session unused that long signs out. Spent refresh
+// tokens are remembered for RefreshReuseWindow to catch replays.
+const (
+	AccessTokenLifetime = 15 * time.Minute
+	SessionLifetime     = 30 * 24 * time.Hour
+	RefreshReuseWindow  = 7 * 24 * time.Hour
+)
 
-func GenerateToken() (string, error) {
+// GenerateToken returns prefix followed by 256 random bits in hex
+func GenerateToken(prefix string) (string, error) {
 	b := make([]byte, 32)
 	if _, err := rand.Read(b); err != nil {
 		return "", err
 	}
-	return hex.EncodeToString(b), nil
+	return prefix + hex.EncodeToString(b), nil
 }
 
-func GenerateOTP() string {
-	return MockOTP // In production, use random 6 digits and send via Twilio
+// HashToken returns the SHA-256 of a session token as stored in the database. Tokens are 256
+// random bits, so unlike codes they need no salt: the hash is only there so a copy of the
+// database doesn't hold live tokens.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// SessionTouchInterval is how stale a session's last-used time may get before a request
+// refreshes it, so authenticated requests don't each cost a write
+const SessionTouchInterval = 5 * time.Minute
+
+// GenerateOTP returns a uniformly random 6-digit code from crypto/rand
+func GenerateOTP() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// Login code limits. A code allows OTPMaxAttempts guesses; using them up burns the code and
+// locks the number out for OTPLockoutBase, doubling with each further lockout up to
+// OTPLockoutMax. A number's lockouts are forgotten after a successful login or once
+// OTPLockoutMax has passed since the last one ended.
+const (
+	OTPLifetime       = 10 * time.Minute
+	OTPResendCooldown = time.Minute
+	OTPMaxAttempts    = 5
+	OTPLockoutBase    = 5 * time.Minute
+	OTPLockoutMax     = 24 * time.Hour
+)
+
+// OTPLockoutDuration is how long the nth consecutive lockout (from 1) lasts
+func OTPLockoutDuration(n int) time.Duration {
+	d := OTPLockoutBase
+	for i := 1; i < n && d < OTPLockoutMax; i++ {
+		d *= 2
+	}
+	return min(d, OTPLockoutMax)
+}
+
+// HashOTP returns a salted hash of a code for storage, bound to the phone number it was sent to.
+// Six digits are cheap to search offline, so this keeps codes out of backups and exports rather
+// than protecting them from someone holding the database; the attempt limits do the rest.
+func HashOTP(phone, code string) (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(salt) + "$" + hex.EncodeToString(otpMAC(salt, phone, code)), nil
+}
+
+// CheckOTP reports whether code matches a hash from HashOTP, in constant time
+func CheckOTP(hash, phone, code string) bool {
+	saltHex, sumHex, ok := strings.Cut(hash, "$")
+	if !ok {
+		return false
+	}
+	salt, err1 := hex.DecodeString(saltHex)
+	sum, err2 := hex.DecodeString(sumHex)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(sum, otpMAC(salt, phone, code)) == 1
 }
 
-func TokenExpiry() time.Time {
-	return time.Now().Add(30 * 24 * time.Hour) // 30 days
+func otpMAC(salt []byte, phone, code string) []byte {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(phone + "\x00" + code))
+	return mac.Sum(nil)
 }
 
-func OTPExpiry() time.Time {
-	return time.Now().Add(10 * time.Minute)
+// OTPMessage is the text message carrying a login code
+func OTPMessage(code string) string {
+	return fmt.Sprintf("Your Triangle Travel code is %s. It expires in %d minutes; don't share it.",
+		code, int(OTPLifetime.Minutes()))
 }
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// errCBOR is malformed or unsupported CBOR in a WebAuthn structure
+var errCBOR = errors.New("malformed CBOR")
+
+// cborMaxDepth bounds nesting so hostile input can't exhaust the stack
+const cborMaxDepth = 16
+
+// decodeCBOR decodes the first CBOR item in b (RFC 8949), returning it and the bytes after it.
+// It covers what WebAuthn uses: unsigned and negative integers (uint64, int64), byte and text
+// strings ([]byte, string), arrays ([]interface{}), maps (map[interface{}]interface{} keyed by
+// int64 or string), booleans, null and floats. Indefinite lengths and tags are rejected;
+// authenticators encode canonically.
+func decodeCBOR(b []byte) (interface{}, []byte, error) {
+	return decodeCBORItem(b, 0)
+}
+
+func decodeCBORItem(b []byte, depth int) (interface{}, []byte, error) {
+	if depth > cborMaxDepth {
+		return nil, nil, fmt.Errorf("%w: nested too deeply", errCBOR)
+	}
+	if len(b) == 0 {
+		return nil, nil, fmt.Errorf("%w: unexpected end", errCBOR)
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	b = b[1:]
+	if major == 7 {
+		return decodeCBORSimple(info, b)
+	}
+	n, b, err := cborArg(info, b)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch major {
+	case 0:
+		return n, b, nil
+	case 1:
+		if n > math.MaxInt64 {
+			return nil, nil, fmt.Errorf("%w: integer out of range", errCBOR)
+		}
+		return -1 - int64(n), b, nil
+	case 2, 3:
+		if n > uint64(len(b)) {
+			return nil, nil, fmt.Errorf("%w: string longer than input", errCBOR)
+		}
+		if major == 2 {
+			return append([]byte(nil), b[:n]...), b[n:], nil
+		}
+		return string(b[:n]), b[n:], nil
+	case 4:
+		if n > uint64(len(b)) {
+			return nil, nil, fmt.Errorf("%w: array longer than input", errCBOR)
+		}
+		items := make([]interface{}, 0, n)
+		for i := uint64(0); i < n; i++ {
+			var item interface{}
+			if item, b, err = decodeCBORItem(b, depth+1); err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+		}
+		return items, b, nil
+	case 5:
+		if n > uint64(len(b)) {
+			return nil, nil, fmt.Errorf("%w: map longer than input", errCBOR)
+		}
+		m := make(map[interface{}]interface{}, n)
+		for i := uint64(0); i < n; i++ {
+			var k, v interface{}
+			if k, b, err = decodeCBORItem(b, depth+1); err != nil {
+				return nil, nil, err
+			}
+			switch key := k.(type) {
+			case uint64:
+				if key > math.MaxInt64 {
+					return nil, nil, fmt.Errorf("%w: map key out of range", errCBOR)
+				}
+				k = int64(key)
+			case int64, string:
+			default:
+				return nil, nil, fmt.Errorf("%w: unsupported map key %T", errCBOR, k)
+			}
+			if v, b, err = decodeCBORItem(b, depth+1); err != nil {
+				return nil, nil, err
+			}
+			if _, dup := m[k]; dup {
+				return nil, nil, fmt.Errorf("%w: duplicate map key %v", errCBOR, k)
+			}
+			m[k] = v
+		}
+		return m, b, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: unsupported major type %d", errCBOR, major)
+	}
+}
+
+// cborArg reads the argument of an item head: the count, length or value info encodes
+func cborArg(info byte, b []byte) (uint64, []byte, error) {
+	size := 0
+	switch {
+	case info < 24:
+		return uint64(info), b, nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, nil, fmt.Errorf("%w: indefinite or reserved length", errCBOR)
+	}
+	if len(b) < size {
+		return 0, nil, fmt.Errorf("%w: unexpected end", errCBOR)
+	}
+	var n uint64
+	for _, c := range b[:size] {
+		n = n<<8 | uint64(c)
+	}
+	return n, b[size:], nil
+}
+
+func decodeCBORSimple(info byte, b []byte) (interface{}, []byte, error) {
+	switch info {
+	case 20:
+		return false, b, nil
+	case 21:
+		return true, b, nil
+	case 22, 23:
+		return nil, b, nil
+	case 26:
+		if len(b) < 4 {
+			return nil, nil, fmt.Errorf("%w: unexpected end", errCBOR)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), b[4:], nil
+	case 27:
+		if len(b) < 8 {
+			return nil, nil, fmt.Errorf("%w: unexpected end", errCBOR)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), b[8:], nil
+	default:
+		return nil, nil, fmt.Errorf("%w: unsupported simple value %d", errCBOR, info)
+	}
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"encoding/hex"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	tests := []struct {
+		in   string
+		want interface{}
+		rest string
+	}{
+		{"00", uint64(0), ""},
+		{"17", uint64(23), ""},
+		{"18 18", uint64(24), ""},
+		{"1b ffffffffffffffff", uint64(math.MaxUint64), ""},
+		{"20", int64(-1), ""},
+		{"38 63", int64(-100), ""},
+		{"3b 7fffffffffffffff", int64(math.MinInt64), ""},
+		{"43 010203", []byte{1, 2, 3}, ""},
+		{"65 6e6f6e6521", "none!", ""},
+		{"82 01 20", []interface{}{uint64(1), int64(-1)}, ""},
+		{"a2 01 02 61 61 20", map[interface{}]interface{}{int64(1): uint64(2), "a": int64(-1)}, ""},
+		{"f4", false, ""},
+		{"f5", true, ""},
+		{"f6", nil, ""},
+		{"fa 3fc00000", 1.5, ""},
+		{"fb 3ff0000000000000", 1.0, ""},
+		{"00 01", uint64(0), "01"}, // the next item is left for the caller
+	}
+	for _, tt := range tests {
+		got, rest, err := decodeCBOR(unhex(t, tt.in))
+		if err != nil {
+			t.Errorf("decodeCBOR(%s): %v", tt.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) || hex.EncodeToString(rest) != tt.rest {
+			t.Errorf("decodeCBOR(%s) = %#v, rest %x; want %#v, rest %s", tt.in, got, rest, tt.want, tt.rest)
+		}
+	}
+}
+
+func TestDecodeCBORErrors(t *testing.T) {
+	tests := []struct {
+		name, in string
+	}{
+		{"empty", ""},
+		{"truncated argument", "19 01"},
+		{"truncated string", "43 0102"},
+		{"truncated array", "82 01"},
+		{"truncated map value", "a1 01"},
+		{"truncated float", "fa 0000"},
+		{"huge byte string", "5b ffffffffffffffff"},
+		{"huge text string", "7b ffffffffffffffff"},
+		{"huge array", "9b ffffffffffffffff"},
+		{"huge map", "bb ffffffffffffffff"},
+		{"array longer than input", "9a 00010000 00"},
+		{"negative out of range", "3b ffffffffffffffff"},
+		{"indefinite byte string", "5f 41 00 ff"},
+		{"indefinite array", "9f 01 ff"},
+		{"reserved length", "1c"},
+		{"tag", "c1 00"},
+		{"simple value", "f8 20"},
+		{"byte string key", "a1 40 00"},
+		{"map key out of range", "a1 1b ffffffffffffffff 00"},
+		{"duplicate key", "a2 01 00 01 01"},
+		{"nested too deeply", strings.Repeat("81", cborMaxDepth+1) + "00"},
+	}
+	for _, tt := range tests {
+		if v, _, err := decodeCBOR(unhex(t, tt.in)); !errors.Is(err, errCBOR) {
+			t.Errorf("%s: decodeCBOR(%s) = %#v, %v; want a CBOR error", tt.name, tt.in, v, err)
+		}
+	}
+	if _, _, err := decodeCBOR(unhex(t, strings.Repeat("81", cborMaxDepth)+"00")); err != nil {
+		t.Errorf("nesting at the limit: %v", err)
+	}
+}
+
+// No cut-off attestation object decodes, or panics
+func TestDecodeCBORTruncated(t *testing.T) {
+	att := unhex(t, fixtureAttestationObject)
+	if _, rest, err := decodeCBOR(att); err != nil || len(rest) != 0 {
+		t.Fatalf("fixture: rest %x, %v", rest, err)
+	}
+	for i := range att {
+		if _, _, err := decodeCBOR(att[:i]); !errors.Is(err, errCBOR) {
+			t.Errorf("first %d bytes: %v, want a CBOR error", i, err)
+		}
+	}
+}
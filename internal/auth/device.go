@@ -0,0 +1,44 @@
+package auth
+
+import "strings"
+
+// Browser and OS markers in User-Agent strings, checked in order (Edge and Opera also claim to be
+// Chrome, and Chrome claims to be Safari)
+var (
+	uaBrowsers = []struct{ marker, name string }{
+		{"Edg/", "Edge"}, {"EdgiOS/", "Edge"}, {"OPR/", "Opera"}, {"Firefox/", "Firefox"}, {"FxiOS/", "Firefox"},
+		{"CriOS/", "Chrome"}, {"Chrome/", "Chrome"}, {"Safari/", "Safari"}, {"curl/", "curl"},
+	}
+	uaSystems = []struct{ marker, name string }{
+		{"iPhone", "iPhone"}, {"iPad", "iPad"}, {"Android", "Android"}, {"CrOS", "ChromeOS"},
+		{"Windows", "Windows"}, {"Mac OS X", "macOS"}, {"Macintosh", "macOS"}, {"Linux", "Linux"},
+	}
+)
+
+// DeviceName describes a User-Agent for a sessions list, e.g. "Firefox on Windows"
+func DeviceName(userAgent string) string {
+	browser, system := "", ""
+	for _, b := range uaBrowsers {
+		if strings.Contains(userAgent, b.marker) {
+			browser = b.name
+			break
+		}
+	}
+	for _, s := range uaSystems {
+		if strings.Contains(userAgent, s.marker) {
+			system = s.name
+			break
+		}
+	}
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	case userAgent != "":
+		return "Unknown browser"
+	}
+	return "Unknown device"
+}
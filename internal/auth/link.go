@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EmailLinkPrefix marks sign-in link tokens
+const EmailLinkPrefix = "ml_"
+
+// Sign-in links last EmailLinkLifetime and one address gets at most one per EmailLinkCooldown
+const (
+	EmailLinkLifetime = 15 * time.Minute
+	EmailLinkCooldown = time.Minute
+)
+
+// Link token errors
+var (
+	ErrLinkInvalid = errors.New("invalid sign-in link")
+	ErrLinkExpired = errors.New("sign-in link expired")
+)
+
+// LinkSigner issues and checks sign-in link tokens: ml_<nonce>.<expiry>.<signature>. The
+// signature lets a forged or expired link be turned away without a database lookup; the
+// database still decides whether a link is unused.
+type LinkSigner struct {
+	key []byte
+}
+
+// NewLinkSigner returns a signer keyed by key, which must be at least 32 bytes; an empty key
+// gets a random one (links then stop working when the server restarts)
+func NewLinkSigner(key string) (*LinkSigner, error) {
+	if key == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		return &LinkSigner{key: b}, nil
+	}
+	if len(key) < 32 {
+		return nil, fmt.Errorf("auth: email link key must be at least 32 bytes")
+	}
+	return &LinkSigner{key: []byte(key)}, nil
+}
+
+// Issue returns a new token that expires at expires
+func (s *LinkSigner) Issue(expires time.Time) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(nonce) + "." + strconv.FormatInt(expires.Unix(), 10)
+	return EmailLinkPrefix + payload + "." + s.sign(payload), nil
+}
+
+// Check returns ErrLinkInvalid for a token this signer didn't issue and ErrLinkExpired for one
+// past its expiry at now
+func (s *LinkSigner) Check(token string, now time.Time) error {
+	parts := strings.Split(strings.TrimPrefix(token, EmailLinkPrefix), ".")
+	if !strings.HasPrefix(token, EmailLinkPrefix) || len(parts) != 3 {
+		return ErrLinkInvalid
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(s.sign(parts[0]+"."+parts[1]))) {
+		return ErrLinkInvalid
+	}
+	exp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return ErrLinkInvalid
+	}
+	if !now.Before(time.Unix(exp, 0)) {
+		return ErrLinkExpired
+	}
+	return nil
+}
+
+func (s *LinkSigner) sign(payload string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// EmailLinkMessage is the subject and body of the email carrying a sign-in link; linking says
+// whether it adds the address to an account rather than signing in
+func EmailLinkMessage(link string, linking bool) (subject, body string) {
+	mins := int(EmailLinkLifetime.Minutes())
+	if linking {
+		return "Confirm your email for Triangle Travel",
+			fmt.Sprintf("Open this link to add this address to your Triangle Travel account:\n\n%s\n\n"+
+				"It expires in %d minutes and works once. If you didn't ask for this, ignore this email.\n", link, mins)
+	}
+	return "Sign in to Triangle Travel",
+		fmt.Sprintf("Open this link to sign in to Triangle Travel:\n\n%s\n\n"+
+			"It expires in %d minutes and works once. If you didn't ask for this, ignore this email; "+
+			"don't forward it, anyone with the link can sign in.\n", link, mins)
+}
@@ -0,0 +1,357 @@
+package auth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Passkey ceremony limits: a challenge must be answered within PasskeyChallengeLifetime, and
+// accounts without a phone number can add a passkey only from a session this fresh
+const (
+	PasskeyChallengeLifetime = 5 * time.Minute
+	PasskeyFreshSession      = 10 * time.Minute
+)
+
+// COSE algorithms passkeys may use, most preferred first (ES256 is what nearly all create)
+const (
+	coseES256 = -7
+	coseEdDSA = -8
+	coseRS256 = -257
+)
+
+// PasskeyAlgorithms are the COSE algorithm IDs offered in registration options
+var PasskeyAlgorithms = []int{coseES256, coseEdDSA, coseRS256}
+
+// Authenticator data flags
+const (
+	flagUserPresent    = 0x01
+	flagUserVerified   = 0x04
+	flagBackupEligible = 0x08
+	flagBackedUp       = 0x10
+	flagAttestedData   = 0x40
+	flagExtensions     = 0x80
+)
+
+// PasskeyError is a credential or response that fails verification; its message is safe to
+// show the person signing in
+type PasskeyError struct {
+	Msg string
+}
+
+func (e *PasskeyError) Error() string { return e.Msg }
+
+func passkeyErr(format string, args ...interface{}) error {
+	return &PasskeyError{Msg: fmt.Sprintf(format, args...)}
+}
+
+// ErrSignCount is a sign count that didn't increase, which suggests the authenticator was
+// cloned
+var ErrSignCount = &PasskeyError{Msg: "This passkey's signature counter went backwards; it may have been copied"}
+
+// RelyingParty is this site as WebAuthn sees it: credentials are scoped to ID (a registrable
+// domain) and responses must come from one of Origins
+type RelyingParty struct {
+	ID      string
+	Name    string
+	Origins []string
+}
+
+// NewRelyingParty derives the relying party from the site's public URL (its host is the RP
+// ID); extra origins, e.g. a dev server proxying the API, must be on the same host or a
+// subdomain of it
+func NewRelyingParty(name, publicURL string, extraOrigins []string) (*RelyingParty, error) {
+	u, err := url.Parse(publicURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("auth: public URL %q isn't an absolute URL", publicURL)
+	}
+	rp := &RelyingParty{ID: u.Hostname(), Name: name, Origins: []string{u.Scheme + "://" + u.Host}}
+	for _, o := range extraOrigins {
+		o = strings.TrimRight(strings.TrimSpace(o), "/")
+		if o == "" {
+			continue
+		}
+		ou, err := url.Parse(o)
+		if err != nil || ou.Host == "" || ou.Path != "" {
+			return nil, fmt.Errorf("auth: passkey origin %q isn't scheme://host[:port]", o)
+		}
+		if h := ou.Hostname(); h != rp.ID && !strings.HasSuffix(h, "."+rp.ID) {
+			return nil, fmt.Errorf("auth: passkey origin %q isn't on %s", o, rp.ID)
+		}
+		rp.Origins = append(rp.Origins, o)
+	}
+	return rp, nil
+}
+
+// GenerateChallenge returns 256 random bits, base64url-encoded as WebAuthn carries them
+func GenerateChallenge() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// PasskeyUserHandle is the WebAuthn user handle for a user: its ID, big-endian
+func PasskeyUserHandle(userID int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(userID))
+	return b
+}
+
+// NewPasskey is a credential that passed registration
+type NewPasskey struct {
+	CredentialID   []byte
+	PublicKey      []byte // COSE_Key as the authenticator sent it
+	SignCount      uint32
+	BackupEligible bool
+	BackedUp       bool
+}
+
+// Assertion is what a verified sign-in reports about the authenticator
+type Assertion struct {
+	SignCount uint32
+	BackedUp  bool
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+	publicKey    []byte
+}
+
+// VerifyRegistration checks a navigator.credentials.create() response against the challenge
+// issued for it and returns the new credential. Attestation isn't verified (options ask for
+// none): a passkey proves only that whoever registered it holds it, which the step-up before
+// registration already established.
+func (rp *RelyingParty) VerifyRegistration(challenge string, clientDataJSON, attestationObject []byte) (*NewPasskey, error) {
+	if err := rp.checkClientData(clientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+	att, rest, err := decodeCBOR(attestationObject)
+	if err != nil || len(rest) != 0 {
+		return nil, passkeyErr("Malformed attestation object")
+	}
+	m, _ := att.(map[interface{}]interface{})
+	raw, _ := m["authData"].([]byte)
+	if raw == nil {
+		return nil, passkeyErr("Attestation object has no authenticator data")
+	}
+	ad, err := parseAuthenticatorData(raw)
+	if err != nil {
+		return nil, err
+	}
+	if err := rp.checkAuthenticatorData(ad); err != nil {
+		return nil, err
+	}
+	if ad.credentialID == nil {
+		return nil, passkeyErr("Registration has no credential")
+	}
+	if _, err := parseCOSEKey(ad.publicKey); err != nil {
+		return nil, err
+	}
+	return &NewPasskey{CredentialID: ad.credentialID, PublicKey: ad.publicKey, SignCount: ad.signCount,
+		BackupEligible: ad.flags&flagBackupEligible != 0, BackedUp: ad.flags&flagBackedUp != 0}, nil
+}
+
+// VerifyAssertion checks a navigator.credentials.get() response against the challenge issued
+// for it and the stored credential's public key and sign count
+func (rp *RelyingParty) VerifyAssertion(challenge string, clientDataJSON, authData, signature, publicKey []byte, storedCount uint32) (*Assertion, error) {
+	if err := rp.checkClientData(clientDataJSON, "webauthn.get", challenge); err != nil {
+		return nil, err
+	}
+	ad, err := parseAuthenticatorData(authData)
+	if err != nil {
+		return nil, err
+	}
+	if err := rp.checkAuthenticatorData(ad); err != nil {
+		return nil, err
+	}
+	key, err := parseCOSEKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	clientHash := sha256.Sum256(clientDataJSON)
+	if !key.verify(append(append([]byte(nil), authData...), clientHash[:]...), signature) {
+		return nil, passkeyErr("Passkey signature doesn't verify")
+	}
+	// Authenticators that don't count report 0 every time; one that counts must move forward
+	if (ad.signCount != 0 || storedCount != 0) && ad.signCount <= storedCount {
+		return nil, ErrSignCount
+	}
+	return &Assertion{SignCount: ad.signCount, BackedUp: ad.flags&flagBackedUp != 0}, nil
+}
+
+// ClientDataChallenge returns the challenge a response claims to answer, for looking up the
+// challenge issued; the verify functions check it again along with the rest
+func ClientDataChallenge(clientDataJSON []byte) (string, error) {
+	var cd clientData
+	if err := json.Unmarshal(clientDataJSON, &cd); err != nil || cd.Challenge == "" {
+		return "", passkeyErr("Malformed client data")
+	}
+	return cd.Challenge, nil
+}
+
+func (rp *RelyingParty) checkClientData(raw []byte, typ, challenge string) error {
+	var cd clientData
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return passkeyErr("Malformed client data")
+	}
+	switch {
+	case cd.Type != typ:
+		return passkeyErr("Wrong ceremony: got %q, want %q", cd.Type, typ)
+	case cd.Challenge != challenge:
+		return passkeyErr("Response doesn't answer this challenge")
+	case cd.CrossOrigin:
+		return passkeyErr("Passkeys can't be used from an embedded frame")
+	}
+	for _, o := range rp.Origins {
+		if cd.Origin == o {
+			return nil
+		}
+	}
+	return passkeyErr("Passkey used from an unexpected origin %q", cd.Origin)
+}
+
+func (rp *RelyingParty) checkAuthenticatorData(ad *authenticatorData) error {
+	want := sha256.Sum256([]byte(rp.ID))
+	switch {
+	case !bytes.Equal(ad.rpIDHash, want[:]):
+		return passkeyErr("Passkey belongs to another site")
+	case ad.flags&flagUserPresent == 0:
+		return passkeyErr("Authenticator didn't confirm user presence")
+	case ad.flags&flagUserVerified == 0:
+		return passkeyErr("Authenticator didn't verify the user (PIN or biometric required)")
+	case ad.flags&flagBackedUp != 0 && ad.flags&flagBackupEligible == 0:
+		return passkeyErr("Malformed authenticator data")
+	}
+	return nil
+}
+
+// parseAuthenticatorData splits authenticator data: RP ID hash, flags, sign count, then the
+// attested credential (AAGUID, ID, COSE key) when flagged, then extensions
+func parseAuthenticatorData(b []byte) (*authenticatorData, error) {
+	if len(b) < 37 {
+		return nil, passkeyErr("Authenticator data too short")
+	}
+	ad := &authenticatorData{rpIDHash: b[:32], flags: b[32], signCount: binary.BigEndian.Uint32(b[33:37])}
+	rest := b[37:]
+	if ad.flags&flagAttestedData != 0 {
+		if len(rest) < 18 {
+			return nil, passkeyErr("Attested credential data too short")
+		}
+		n := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if n == 0 || n > 1023 || len(rest) < n {
+			return nil, passkeyErr("Bad credential ID length")
+		}
+		ad.credentialID, rest = rest[:n], rest[n:]
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, passkeyErr("Malformed credential public key")
+		}
+		ad.publicKey, rest = rest[:len(rest)-len(after)], after
+	}
+	if ad.flags&flagExtensions != 0 {
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, passkeyErr("Malformed authenticator extensions")
+		}
+		rest = after
+	}
+	if len(rest) != 0 {
+		return nil, passkeyErr("Trailing bytes in authenticator data")
+	}
+	return ad, nil
+}
+
+// coseKey is a credential public key with the algorithm it signs with
+type coseKey struct {
+	alg int64
+	pub crypto.PublicKey
+}
+
+// parseCOSEKey reads an EC2 P-256 (ES256), OKP Ed25519 (EdDSA) or RSA (RS256) COSE_Key
+func parseCOSEKey(b []byte) (*coseKey, error) {
+	v, rest, err := decodeCBOR(b)
+	m, ok := v.(map[interface{}]interface{})
+	if err != nil || len(rest) != 0 || !ok {
+		return nil, passkeyErr("Malformed credential public key")
+	}
+	num := func(k int64) int64 {
+		switch n := m[k].(type) {
+		case int64:
+			return n
+		case uint64:
+			return int64(n)
+		}
+		return 0
+	}
+	bytesAt := func(k int64) []byte { b, _ := m[k].([]byte); return b }
+	kty, alg := num(1), num(3)
+	switch {
+	case kty == 2 && alg == coseES256 && num(-1) == 1:
+		x, y := bytesAt(-2), bytesAt(-3)
+		if len(x) != 32 || len(y) != 32 {
+			return nil, passkeyErr("Bad P-256 key")
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, passkeyErr("Bad P-256 key")
+		}
+		return &coseKey{alg: alg, pub: pub}, nil
+	case kty == 1 && alg == coseEdDSA && num(-1) == 6:
+		x := bytesAt(-2)
+		if len(x) != ed25519.PublicKeySize {
+			return nil, passkeyErr("Bad Ed25519 key")
+		}
+		return &coseKey{alg: alg, pub: ed25519.PublicKey(x)}, nil
+	case kty == 3 && alg == coseRS256:
+		n, e := bytesAt(-1), bytesAt(-2)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, passkeyErr("Bad RSA key (2048 bits or more required)")
+		}
+		exp := 0
+		for _, c := range e {
+			exp = exp<<8 | int(c)
+		}
+		return &coseKey{alg: alg, pub: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exp}}, nil
+	}
+	return nil, passkeyErr("Unsupported passkey algorithm (kty %d, alg %d)", kty, alg)
+}
+
+func (k *coseKey) verify(data, sig []byte) bool {
+	switch pub := k.pub.(type) {
+	case *ecdsa.PublicKey:
+		sum := sha256.Sum256(data)
+		return ecdsa.VerifyASN1(pub, sum[:], sig)
+	case ed25519.PublicKey:
+		return ed25519.Verify(pub, data, sig)
+	case *rsa.PublicKey:
+		sum := sha256.Sum256(data)
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum[:], sig) == nil
+	}
+	return false
+}
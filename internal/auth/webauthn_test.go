@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// A registration and a sign-in with an ES256 passkey on example.com, recorded once: "none"
+// attestation, backup eligible, credential ID "fixture-cred-id!", and a sign count of 7 at
+// sign-in
+const (
+	fixtureRegisterChallenge = "cmVnaXN0ZXItY2hhbGxlbmdl"
+	fixtureRegisterClient    = `{"type":"webauthn.create","challenge":"cmVnaXN0ZXItY2hhbGxlbmdl","origin":"https://example.com","crossOrigin":false}`
+	fixtureAttestationObject = "a363666d74646e6f6e656761747453746d74a0686175746844617461590094a379a6f6eeafb9a55e378c118034e2751e682fab9f2d30ab13d2125586ce19474d00000000000000000000000000000000000000000010666978747572652d637265642d696421a5010203262001215820dfa2c0f1512dc950ba20181d43953a3ec18606e54218d15bf2f842ea40cc6a46225820aae46b87732d8a3c58098ac6fdfd19a737efb008c90afc70989e8162070cced9"
+	fixturePublicKey         = "a5010203262001215820dfa2c0f1512dc950ba20181d43953a3ec18606e54218d15bf2f842ea40cc6a46225820aae46b87732d8a3c58098ac6fdfd19a737efb008c90afc70989e8162070cced9"
+
+	fixtureGetChallenge = "Z2V0LWNoYWxsZW5nZQ"
+	fixtureGetClient    = `{"type":"webauthn.get","challenge":"Z2V0LWNoYWxsZW5nZQ","origin":"https://example.com","crossOrigin":false}`
+	fixtureAuthData     = "a379a6f6eeafb9a55e378c118034e2751e682fab9f2d30ab13d2125586ce19470d00000007"
+	fixtureSignature    = "30440220029b8430f2bc072750ba7358669bbc98889f65bea96208717a2e854ef4d8de99022004fd15d3956318bd12e36d96a45f851abb952ef4690f6ad1ec19aac520cfb0eb"
+)
+
+func testRP(t *testing.T) *RelyingParty {
+	t.Helper()
+	rp, err := NewRelyingParty("Triangle Travel", "https://example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rp
+}
+
+// authData builds authenticator data for rpID, with an attested credential when coseKey is set
+func authData(rpID string, flags byte, count uint32, coseKey []byte) []byte {
+	hash := sha256.Sum256([]byte(rpID))
+	b := append(hash[:], flags)
+	b = binary.BigEndian.AppendUint32(b, count)
+	if coseKey != nil {
+		b = append(b, make([]byte, 16)...) // AAGUID
+		b = binary.BigEndian.AppendUint16(b, 16)
+		b = append(b, "fixture-cred-id!"...)
+		b = append(b, coseKey...)
+	}
+	return b
+}
+
+// attestationObject wraps authenticator data as {"fmt": "none", "attStmt": {}, "authData": ...}
+func attestationObject(authData []byte) []byte {
+	b := []byte("\xa3\x63fmt\x64none\x67attStmt\xa0\x68authData\x59")
+	b = binary.BigEndian.AppendUint16(b, uint16(len(authData)))
+	return append(b, authData...)
+}
+
+// es256Key is the COSE encoding of a P-256 public key, with alg as its CBOR-encoded algorithm
+func es256Key(pub *ecdsa.PublicKey, alg string) []byte {
+	b := []byte("\xa5\x01\x02\x03" + alg + "\x20\x01\x21\x58\x20")
+	b = append(b, pub.X.FillBytes(make([]byte, 32))...)
+	b = append(b, "\x22\x58\x20"...)
+	return append(b, pub.Y.FillBytes(make([]byte, 32))...)
+}
+
+func sign(t *testing.T, key *ecdsa.PrivateKey, authData []byte, clientDataJSON string) []byte {
+	t.Helper()
+	clientHash := sha256.Sum256([]byte(clientDataJSON))
+	sum := sha256.Sum256(append(append([]byte(nil), authData...), clientHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sig
+}
+
+// checkPasskeyErr reports whether err is a *PasskeyError containing want, or nil when want
+// is empty
+func checkPasskeyErr(t *testing.T, err error, want string) {
+	t.Helper()
+	var perr *PasskeyError
+	switch {
+	case want == "" && err != nil:
+		t.Errorf("unexpected error: %v", err)
+	case want == "":
+	case !errors.As(err, &perr):
+		t.Errorf("error %v is not a *PasskeyError, want %q", err, want)
+	case !strings.Contains(perr.Msg, want):
+		t.Errorf("error %q, want %q", perr.Msg, want)
+	}
+}
+
+func TestVerifyRegistration(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	smallRSA := append([]byte("\xa4\x01\x03\x03\x39\x01\x00\x20\x58\x80"), rsaKey.N.Bytes()...)
+	smallRSA = append(smallRSA, "\x21\x43\x01\x00\x01"...)
+	good := es256Key(&key.PublicKey, "\x26")
+	att := unhex(t, fixtureAttestationObject)
+
+	tests := []struct {
+		name      string
+		rp        *RelyingParty
+		challenge string
+		client    string
+		att       []byte
+		err       string
+	}{
+		{"fixture", nil, fixtureRegisterChallenge, fixtureRegisterClient, att, ""},
+		{"built", nil, fixtureRegisterChallenge, fixtureRegisterClient, attestationObject(authData("example.com", 0x45, 0, good)), ""},
+		{"other challenge", nil, fixtureGetChallenge, fixtureRegisterClient, att, "doesn't answer this challenge"},
+		{"sign-in response", nil, fixtureGetChallenge, fixtureGetClient, att, "Wrong ceremony"},
+		{"other origin", nil, fixtureRegisterChallenge, strings.Replace(fixtureRegisterClient, "example.com", "example.org", 1), att, "unexpected origin"},
+		{"cross-origin", nil, fixtureRegisterChallenge, strings.Replace(fixtureRegisterClient, `"crossOrigin":false`, `"crossOrigin":true`, 1), att, "embedded frame"},
+		{"client data not JSON", nil, fixtureRegisterChallenge, "{", att, "Malformed client data"},
+		{"RP ID hash mismatch", &RelyingParty{ID: "login.example.com", Origins: []string{"https://example.com"}}, fixtureRegisterChallenge, fixtureRegisterClient, att, "another site"},
+		{"built for another RP", nil, fixtureRegisterChallenge, fixtureRegisterClient, attestationObject(authData("example.org", 0x45, 0, good)), "another site"},
+		{"user not present", nil, fixtureRegisterChallenge, fixtureRegisterClient, attestationObject(authData("example.com", 0x44, 0, good)), "user presence"},
+		{"user not verified", nil, fixtureRegisterChallenge, fixtureRegisterClient, attestationObject(authData("example.com", 0x41, 0, good)), "verify the user"},
+		{"backed up without eligibility", nil, fixtureRegisterChallenge, fixtureRegisterClient, attestationObject(authData("example.com", 0x55, 0, good)), "Malformed authenticator data"},
+		{"no credential", nil, fixtureRegisterChallenge, fixtureRegisterClient, attestationObject(authData("example.com", 0x05, 0, nil)), "no credential"},
+		{"ES384", nil, fixtureRegisterChallenge, fixtureRegisterClient, attestationObject(authData("example.com", 0x45, 0, es256Key(&key.PublicKey, "\x38\x22"))), "Unsupported passkey algorithm"},
+		{"RS256 under 2048 bits", nil, fixtureRegisterChallenge, fixtureRegisterClient, attestationObject(authData("example.com", 0x45, 0, smallRSA)), "Bad RSA key"},
+		{"truncated key", nil, fixtureRegisterChallenge, fixtureRegisterClient, attestationObject(authData("example.com", 0x45, 0, good[:len(good)-1])), "Malformed credential public key"},
+		{"trailing bytes", nil, fixtureRegisterChallenge, fixtureRegisterClient, attestationObject(append(authData("example.com", 0x45, 0, good), 0)), "Trailing bytes"},
+		{"truncated attestation", nil, fixtureRegisterChallenge, fixtureRegisterClient, att[:len(att)-1], "Malformed attestation object"},
+		{"no authData", nil, fixtureRegisterChallenge, fixtureRegisterClient, []byte("\xa1\x63fmt\x64none"), "no authenticator data"},
+		{"short authData", nil, fixtureRegisterChallenge, fixtureRegisterClient, attestationObject(make([]byte, 36)), "too short"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp := tt.rp
+			if rp == nil {
+				rp = testRP(t)
+			}
+			pk, err := rp.VerifyRegistration(tt.challenge, []byte(tt.client), tt.att)
+			checkPasskeyErr(t, err, tt.err)
+			if tt.name == "fixture" && err == nil {
+				if string(pk.CredentialID) != "fixture-cred-id!" || !bytes.Equal(pk.PublicKey, unhex(t, fixturePublicKey)) ||
+					pk.SignCount != 0 || !pk.BackupEligible || pk.BackedUp {
+					t.Errorf("got %+v", pk)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyAssertion(t *testing.T) {
+	ad, sig, pub := unhex(t, fixtureAuthData), unhex(t, fixtureSignature), unhex(t, fixturePublicKey)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := es256Key(&key.PublicKey, "\x26")
+	flipped := append([]byte(nil), sig...)
+	flipped[len(flipped)-1] ^= 1
+	counted := append([]byte(nil), ad...)
+	counted[len(counted)-1]++
+	noCount := authData("example.com", 0x05, 0, nil)
+
+	tests := []struct {
+		name      string
+		rp        *RelyingParty
+		challenge string
+		client    string
+		authData  []byte
+		sig       []byte
+		key       []byte
+		stored    uint32
+		err       string
+	}{
+		{"fixture", nil, fixtureGetChallenge, fixtureGetClient, ad, sig, pub, 6, ""},
+		{"first use", nil, fixtureGetChallenge, fixtureGetClient, ad, sig, pub, 0, ""},
+		{"counter not increased", nil, fixtureGetChallenge, fixtureGetClient, ad, sig, pub, 7, ErrSignCount.Msg},
+		{"counter went backwards", nil, fixtureGetChallenge, fixtureGetClient, ad, sig, pub, 100, ErrSignCount.Msg},
+		{"authenticator without a counter", nil, fixtureGetChallenge, fixtureGetClient, noCount, sign(t, key, noCount, fixtureGetClient), other, 0, ""},
+		{"counter stopped", nil, fixtureGetChallenge, fixtureGetClient, noCount, sign(t, key, noCount, fixtureGetClient), other, 3, ErrSignCount.Msg},
+		{"bad signature", nil, fixtureGetChallenge, fixtureGetClient, ad, flipped, pub, 0, "doesn't verify"},
+		{"malformed signature", nil, fixtureGetChallenge, fixtureGetClient, ad, []byte{0x30, 0x00}, pub, 0, "doesn't verify"},
+		{"no signature", nil, fixtureGetChallenge, fixtureGetClient, ad, nil, pub, 0, "doesn't verify"},
+		{"another key", nil, fixtureGetChallenge, fixtureGetClient, ad, sig, other, 0, "doesn't verify"},
+		{"authData changed after signing", nil, fixtureGetChallenge, fixtureGetClient, counted, sig, pub, 0, "doesn't verify"},
+		{"client data changed after signing", nil, fixtureGetChallenge, strings.Replace(fixtureGetClient, "}", ` }`, 1), ad, sig, pub, 0, "doesn't verify"},
+		{"other challenge", nil, fixtureRegisterChallenge, fixtureGetClient, ad, sig, pub, 0, "doesn't answer this challenge"},
+		{"registration response", nil, fixtureRegisterChallenge, fixtureRegisterClient, ad, sig, pub, 0, "Wrong ceremony"},
+		{"RP ID hash mismatch", &RelyingParty{ID: "login.example.com", Origins: []string{"https://example.com"}}, fixtureGetChallenge, fixtureGetClient, ad, sig, pub, 0, "another site"},
+		{"unsupported stored key", nil, fixtureGetChallenge, fixtureGetClient, ad, sig, es256Key(&key.PublicKey, "\x38\x22"), 0, "Unsupported passkey algorithm"},
+		{"EdDSA key on the wrong curve", nil, fixtureGetChallenge, fixtureGetClient, ad, sig, append([]byte("\xa4\x01\x01\x03\x27\x20\x04\x21\x58\x20"), make([]byte, 32)...), 0, "Unsupported passkey algorithm"},
+		{"stored key not a map", nil, fixtureGetChallenge, fixtureGetClient, ad, sig, []byte{0x01}, 0, "Malformed credential public key"},
+		{"point not on the curve", nil, fixtureGetChallenge, fixtureGetClient, ad, sig, append(pub[:len(pub)-1:len(pub)-1], pub[len(pub)-1]^1), 0, "Bad P-256 key"},
+		{"short authData", nil, fixtureGetChallenge, fixtureGetClient, ad[:36], sig, pub, 0, "too short"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp := tt.rp
+			if rp == nil {
+				rp = testRP(t)
+			}
+			a, err := rp.VerifyAssertion(tt.challenge, []byte(tt.client), tt.authData, tt.sig, tt.key, tt.stored)
+			checkPasskeyErr(t, err, tt.err)
+			if err == nil && a.SignCount != binary.BigEndian.Uint32(tt.authData[33:37]) {
+				t.Errorf("sign count %d, want the authenticator's", a.SignCount)
+			}
+		})
+	}
+}
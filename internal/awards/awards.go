@@ -0,0 +1,248 @@
+package awards
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"triangle_travel/internal/db"
+	"triangle_travel/internal/geo"
+)
+
+// Chart is a loyalty program's award chart plus its routing rules
+type Chart struct {
+	db.AwardProgram
+	bands      map[string][]db.AwardBand // cabin -> bands ordered by mileage
+	zones      map[string]string         // "country:XX" or "continent:XX" -> zone
+	zonePrices map[string]int            // cabin|from|to -> points
+}
+
+// Award is one ticket within a quote
+type Award struct {
+	Route  []string `json:"route"`  // airports in order; inner points are stopovers
+	Miles  float64  `json:"miles"`  // flown great-circle miles
+	Points int      `json:"points"` // including stopover charges
+}
+
+// Quote is the points cost of a triangle under one program (Points -1 if not bookable)
+type Quote struct {
+	Program  string   `json:"program"`
+	Name     string   `json:"name"`
+	Points   int      `json:"points"`
+	Bookable bool     `json:"bookable"`
+	Awards   []Award  `json:"awards,omitempty"`
+	Notes    []string `json:"notes,omitempty"`
+}
+
+// Triangle is the flown shape priced by the engine: Start -> End, optional End -> Via flight, Via -> Start.
+// Hop is false when End -> Via is covered by ground transport (an open jaw).
+type Triangle struct {
+	Start, End, Via *db.Airport
+	Hop             bool
+}
+
+// UnknownProgramsError is returned by LoadCharts for program codes that have no award chart
+type UnknownProgramsError struct {
+	Codes []string
+}
+
+func (e *UnknownProgramsError) Error() string {
+	return "unknown award program " + strings.Join(e.Codes, ", ")
+}
+
+// LoadCharts reads award charts for the given program codes (all programs when empty). Codes
+// are matched case-insensitively; any without a program give an *UnknownProgramsError.
+func LoadCharts(ctx context.Context, database db.ReferenceStore, codes []string) ([]*Chart, error) {
+	upper := make([]string, len(codes))
+	for i, code := range codes {
+		upper[i] = strings.ToUpper(strings.TrimSpace(code))
+	}
+	programs, err := database.GetAwardPrograms(ctx, upper)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]bool, len(programs))
+	for _, p := range programs {
+		found[p.Code] = true
+	}
+	var unknown []string
+	for _, code := range upper {
+		if !found[code] {
+			unknown = append(unknown, code)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, &UnknownProgramsError{Codes: unknown}
+	}
+	charts := make([]*Chart, 0, len(programs))
+	for _, p := range programs {
+		c := &Chart{
+			AwardProgram: p,
+			bands:        make(map[string][]db.AwardBand),
+			zonePrices:   make(map[string]int),
+		}
+		switch p.ChartType {
+		case "distance", "distance_total":
+			bands, err := database.GetAwardBands(ctx, p.Code)
+			if err != nil {
+				return nil, err
+			}
+			for _, b := range bands {
+				c.bands[b.Cabin] = append(c.bands[b.Cabin], b)
+			}
+		case "zone":
+			if c.zones, err = database.GetAwardZones(ctx, p.Code); err != nil {
+				return nil, err
+			}
+			prices, err := database.GetAwardZonePrices(ctx, p.Code)
+			if err != nil {
+				return nil, err
+			}
+			for _, zp := range prices {
+				c.zonePrices[zoneKey(zp.Cabin, zp.FromZone, zp.ToZone)] = zp.Points
+			}
+		default:
+			return nil, fmt.Errorf("award program %s: unknown chart type %q", p.Code, p.ChartType)
+		}
+		charts = append(charts, c)
+	}
+	return charts, nil
+}
+
+func zoneKey(cabin, from, to string) string {
+	return cabin + "|" + from + "|" + to
+}
+
+// Price quotes the cheapest legal way to book the triangle under the chart's rules
+func (c *Chart) Price(t Triangle, cabin string) Quote {
+	q := Quote{Program: c.Code, Name: c.Name, Points: -1}
+	if t.Start == nil || t.End == nil || t.Via == nil {
+		q.Notes = append(q.Notes, "missing airport data for this triangle")
+		return q
+	}
+
+	outbound := []*db.Airport{t.Start, t.End}
+	inbound := []*db.Airport{t.Via, t.Start}
+
+	var best []Award
+	bestPoints := -1
+	consider := func(awards []Award, note string) {
+		total := 0
+		for _, a := range awards {
+			if a.Points < 0 {
+				return
+			}
+			total += a.Points
+		}
+		if bestPoints < 0 || total < bestPoints {
+			best, bestPoints = awards, total
+			q.Notes = []string{note}
+		}
+	}
+
+	if c.OneWayAllowed {
+		awards := []Award{c.oneWay(outbound, cabin)}
+		if t.Hop {
+			awards = append(awards, c.oneWay([]*db.Airport{t.End, t.Via}, cabin))
+		}
+		awards = append(awards, c.oneWay(inbound, cabin))
+		consider(awards, "separate one-way awards")
+	}
+	if t.Hop && c.MaxStopovers > 0 && (c.OneWayAllowed || c.OpenJawAllowed) {
+		consider([]Award{
+			c.oneWay([]*db.Airport{t.Start, t.End, t.Via}, cabin),
+			c.oneWay(inbound, cabin),
+		}, "stopover in "+t.End.IATA+" on the outbound award")
+	}
+	if !c.OneWayAllowed && !t.Hop && c.OpenJawAllowed {
+		consider([]Award{c.oneWay(outbound, cabin), c.oneWay(inbound, cabin)}, "open-jaw round-trip award")
+	}
+
+	if bestPoints < 0 {
+		switch {
+		case !c.OneWayAllowed && !c.OpenJawAllowed:
+			q.Notes = append(q.Notes, "program requires round trips and does not allow open jaws")
+		case !c.OneWayAllowed && t.Hop && c.MaxStopovers == 0:
+			q.Notes = append(q.Notes, "program requires round trips and does not allow stopovers")
+		default:
+			q.Notes = append(q.Notes, "no "+cabin+" award price on this chart for this routing")
+		}
+		return q
+	}
+	q.Points = bestPoints
+	q.Bookable = true
+	q.Awards = best
+	return q
+}
+
+// oneWay prices a single award over route; inner airports are stopovers (Points -1 if unpriced)
+func (c *Chart) oneWay(route []*db.Airport, cabin string) Award {
+	a := Award{Points: -1}
+	var segments []float64
+	for i, apt := range route {
+		a.Route = append(a.Route, apt.IATA)
+		if i > 0 {
+			prev := route[i-1]
+			m := geo.DistanceMiles(prev.Latitude, prev.Longitude, apt.Latitude, apt.Longitude)
+			segments = append(segments, m)
+			a.Miles += m
+		}
+	}
+
+	points := 0
+	switch c.ChartType {
+	case "distance":
+		for _, m := range segments {
+			p := c.band(cabin, m)
+			if p < 0 {
+				return a
+			}
+			points += p
+		}
+	case "distance_total":
+		if points = c.band(cabin, a.Miles); points < 0 {
+			return a
+		}
+	case "zone":
+		if points = c.zonePrice(cabin, route[0], route[len(route)-1]); points < 0 {
+			return a
+		}
+	}
+	if stopovers := len(route) - 2; stopovers > 0 {
+		points += stopovers * c.StopoverPoints
+	}
+	a.Points = points
+	a.Miles = math.Round(a.Miles*10) / 10
+	return a
+}
+
+func (c *Chart) band(cabin string, miles float64) int {
+	for _, b := range c.bands[cabin] {
+		if miles >= float64(b.MinMiles) && miles < float64(b.MaxMiles)+1 {
+			return b.Points
+		}
+	}
+	return -1
+}
+
+func (c *Chart) zone(a *db.Airport) string {
+	if z, ok := c.zones["country:"+strings.ToUpper(a.Country)]; ok {
+		return z
+	}
+	return c.zones["continent:"+strings.ToUpper(a.Continent)]
+}
+
+func (c *Chart) zonePrice(cabin string, from, to *db.Airport) int {
+	zf, zt := c.zone(from), c.zone(to)
+	if zf == "" || zt == "" {
+		return -1
+	}
+	if p, ok := c.zonePrices[zoneKey(cabin, zf, zt)]; ok {
+		return p
+	}
+	if p, ok := c.zonePrices[zoneKey(cabin, zt, zf)]; ok {
+		return p
+	}
+	return -1
+}
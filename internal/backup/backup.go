@@ -0,0 +1,211 @@
+// Package backup takes consistent online backups of the SQLite database into a directory of
+// timestamped files with retention, and restores one after checking its schema version.
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"triangle_travel/internal/db"
+	"triangle_travel/internal/migrate"
+)
+
+// timeFormat names backups so they sort chronologically
+const timeFormat = "20060102T150405.000Z"
+
+var fileName = regexp.MustCompile(`^data-\d{8}T\d{6}\.\d{3}Z\.sqlite3$`)
+
+// Source is a database that can write a consistent copy of itself to a new file
+type Source interface {
+	BackupTo(ctx context.Context, path string) error
+}
+
+// File is one backup in a Set
+type File struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	Created time.Time `json:"created"`
+}
+
+// Set is a directory of timestamped backups of one database
+type Set struct {
+	Source Source
+	Dir    string
+	Keep   int // newest backups kept after Create; <= 0 keeps all
+}
+
+// Create backs up the source to a new timestamped file and prunes old backups.
+// The copy is written under a temporary name and renamed, so a listed backup is always complete.
+func (s *Set) Create(ctx context.Context) (*File, error) {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return nil, err
+	}
+	created := time.Now().UTC()
+	name := "data-" + created.Format(timeFormat) + ".sqlite3"
+	path := filepath.Join(s.Dir, name)
+	partial := path + ".partial"
+	os.Remove(partial)
+	if err := s.Source.BackupTo(ctx, partial); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(partial, path); err != nil {
+		os.Remove(partial)
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.prune(); err != nil {
+		return nil, fmt.Errorf("backup %s written, but pruning failed: %w", name, err)
+	}
+	return &File{Name: name, Size: info.Size(), Created: created}, nil
+}
+
+// List returns the backups, newest first
+func (s *Set) List() ([]File, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []File{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	files := []File{}
+	for _, e := range entries {
+		if e.IsDir() || !fileName.MatchString(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		created, err := time.Parse(timeFormat, e.Name()[len("data-"):len(e.Name())-len(".sqlite3")])
+		if err != nil {
+			continue
+		}
+		files = append(files, File{Name: e.Name(), Size: info.Size(), Created: created})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name > files[j].Name })
+	return files, nil
+}
+
+// Path returns the path of a backup by name; names that aren't backups are rejected,
+// so it is safe to call with user input
+func (s *Set) Path(name string) (string, error) {
+	if !fileName.MatchString(name) {
+		return "", fmt.Errorf("%q is not a backup name", name)
+	}
+	path := filepath.Join(s.Dir, name)
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func (s *Set) prune() error {
+	if s.Keep <= 0 {
+		return nil
+	}
+	files, err := s.List()
+	if err != nil {
+		return err
+	}
+	for i := s.Keep; i < len(files); i++ {
+		if err := os.Remove(filepath.Join(s.Dir, files[i].Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Verify checks a backup file's integrity and that its schema matches migrations.
+// With upgrade, an older schema is migrated in place instead of rejected.
+func Verify(ctx context.Context, path string, migrations []migrate.Migration, upgrade bool) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	database, err := db.NewFile(path, db.Options{})
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+	if err := database.IntegrityCheck(ctx); err != nil {
+		return err
+	}
+	err = migrate.Check(database, migrations)
+	if errors.Is(err, migrate.ErrPending) && upgrade {
+		if _, err := migrate.Up(database, migrations, 0); err != nil {
+			return err
+		}
+		return migrate.Check(database, migrations)
+	}
+	return err
+}
+
+// Restore replaces the SQLite database under dataDir with the backup at src. The backup is
+// copied and verified first (see Verify), and the current database, if any, is saved next to
+// it as data.sqlite3.pre-restore-<time>, whose path is returned. The server must be stopped.
+func Restore(ctx context.Context, dataDir, src string, migrations []migrate.Migration, upgrade bool) (previous string, err error) {
+	target := db.Path(dataDir)
+	staged := target + ".restore"
+	os.Remove(staged)
+	if err := copyFile(src, staged); err != nil {
+		return "", err
+	}
+	defer os.Remove(staged)
+	if err := Verify(ctx, staged, migrations, upgrade); err != nil {
+		return "", fmt.Errorf("%s: %w", src, err)
+	}
+
+	if _, err := os.Stat(target); err == nil {
+		// VACUUM INTO also captures changes still in the WAL, which a plain file copy would miss
+		previous = target + ".pre-restore-" + time.Now().UTC().Format(timeFormat)
+		current, err := db.NewFile(target, db.Options{})
+		if err != nil {
+			return "", err
+		}
+		err = current.BackupTo(ctx, previous)
+		current.Close()
+		if err != nil {
+			return "", fmt.Errorf("saving current database: %w", err)
+		}
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(target + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return previous, err
+		}
+	}
+	if err := os.Rename(staged, target); err != nil {
+		return previous, err
+	}
+	return previous, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,372 @@
+// Package dataset compares versions of the route data: two snapshots, or a snapshot and the
+// live tables. Record takes a snapshot after an import and reports what the import changed.
+package dataset
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"triangle_travel/internal/db"
+)
+
+// distanceEpsilon is how far two distances may differ (in miles) and still count as unchanged
+const distanceEpsilon = 0.01
+
+// CityRoutes is the change in one origin city's routes for one alliance
+type CityRoutes struct {
+	City     string   `json:"city"`
+	Alliance string   `json:"alliance"`
+	Gained   []string `json:"gained"`
+	Lost     []string `json:"lost"`
+}
+
+// DistanceChange is a distance that was added (Before nil), removed (After nil) or changed.
+// Distances are symmetric, so each pair appears once with From < To.
+type DistanceChange struct {
+	From   string   `json:"from"`
+	To     string   `json:"to"`
+	Before *float64 `json:"before"`
+	After  *float64 `json:"after"`
+}
+
+// AirportChange is an airport whose master data changed
+type AirportChange struct {
+	Before db.Airport `json:"before"`
+	After  db.Airport `json:"after"`
+}
+
+// Summary counts the changes in a Diff
+type Summary struct {
+	RoutesGained        int `json:"routesGained"`
+	RoutesLost          int `json:"routesLost"`
+	DistancesAdded      int `json:"distancesAdded"`
+	DistancesRemoved    int `json:"distancesRemoved"`
+	DistancesChanged    int `json:"distancesChanged"`
+	CityAirportsAdded   int `json:"cityAirportsAdded"`
+	CityAirportsRemoved int `json:"cityAirportsRemoved"`
+	AirportsAdded       int `json:"airportsAdded"`
+	AirportsRemoved     int `json:"airportsRemoved"`
+	AirportsChanged     int `json:"airportsChanged"`
+}
+
+// Diff is what changed between two versions of the route data, sorted by code
+type Diff struct {
+	Summary             Summary          `json:"summary"`
+	Routes              []CityRoutes     `json:"routes"`
+	Distances           []DistanceChange `json:"distances"`
+	CityAirportsAdded   []db.CityAirport `json:"cityAirportsAdded"`
+	CityAirportsRemoved []db.CityAirport `json:"cityAirportsRemoved"`
+	AirportsAdded       []db.Airport     `json:"airportsAdded"`
+	AirportsRemoved     []db.Airport     `json:"airportsRemoved"`
+	AirportsChanged     []AirportChange  `json:"airportsChanged"`
+}
+
+// Empty reports whether nothing changed
+func (d *Diff) Empty() bool {
+	return d.Summary == Summary{}
+}
+
+// Compare loads both versions and diffs them
+func Compare(ctx context.Context, from, to db.RouteData) (*Diff, error) {
+	a, err := load(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := load(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+	d := &Diff{
+		Routes:              []CityRoutes{},
+		Distances:           []DistanceChange{},
+		CityAirportsAdded:   []db.CityAirport{},
+		CityAirportsRemoved: []db.CityAirport{},
+		AirportsAdded:       []db.Airport{},
+		AirportsRemoved:     []db.Airport{},
+		AirportsChanged:     []AirportChange{},
+	}
+	d.diffRoutes(a.routes, b.routes)
+	d.diffDistances(a.distances, b.distances)
+	for c := range b.cityAirports {
+		if !a.cityAirports[c] {
+			d.CityAirportsAdded = append(d.CityAirportsAdded, c)
+		}
+	}
+	for c := range a.cityAirports {
+		if !b.cityAirports[c] {
+			d.CityAirportsRemoved = append(d.CityAirportsRemoved, c)
+		}
+	}
+	for code, after := range b.airports {
+		before, ok := a.airports[code]
+		switch {
+		case !ok:
+			d.AirportsAdded = append(d.AirportsAdded, after)
+		case before != after:
+			d.AirportsChanged = append(d.AirportsChanged, AirportChange{Before: before, After: after})
+		}
+	}
+	for code, before := range a.airports {
+		if _, ok := b.airports[code]; !ok {
+			d.AirportsRemoved = append(d.AirportsRemoved, before)
+		}
+	}
+	d.sort()
+	d.Summary.CityAirportsAdded = len(d.CityAirportsAdded)
+	d.Summary.CityAirportsRemoved = len(d.CityAirportsRemoved)
+	d.Summary.AirportsAdded = len(d.AirportsAdded)
+	d.Summary.AirportsRemoved = len(d.AirportsRemoved)
+	d.Summary.AirportsChanged = len(d.AirportsChanged)
+	return d, nil
+}
+
+type routeKey struct{ city, alliance string }
+
+type pair [2]string
+
+// data is one version of the route data indexed for comparison
+type data struct {
+	cityAirports map[db.CityAirport]bool
+	routes       map[routeKey]map[string]bool
+	distances    map[pair]float64
+	airports     map[string]db.Airport
+}
+
+func load(ctx context.Context, src db.RouteData) (*data, error) {
+	// ReferenceVersion fails for a snapshot that doesn't exist, instead of diffing empty lists
+	if _, err := src.ReferenceVersion(ctx); err != nil {
+		return nil, err
+	}
+	x := &data{
+		cityAirports: make(map[db.CityAirport]bool),
+		routes:       make(map[routeKey]map[string]bool),
+		distances:    make(map[pair]float64),
+		airports:     make(map[string]db.Airport),
+	}
+	cities, err := src.ListCityAirports(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range cities {
+		x.cityAirports[c] = true
+	}
+	routes, err := src.ListRoutes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range routes {
+		k := routeKey{r.From, r.Alliance}
+		if x.routes[k] == nil {
+			x.routes[k] = make(map[string]bool)
+		}
+		x.routes[k][r.To] = true
+	}
+	distances, err := src.ListDistances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// A pair may be stored in either direction or both; the From < To row wins
+	for _, d := range distances {
+		p, forward := pair{d.From, d.To}, d.From < d.To
+		if !forward {
+			p = pair{d.To, d.From}
+		}
+		if _, seen := x.distances[p]; !seen || forward {
+			x.distances[p] = d.Miles
+		}
+	}
+	airports, err := src.ListAirports(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range airports {
+		x.airports[a.IATA] = a
+	}
+	return x, nil
+}
+
+func (d *Diff) diffRoutes(a, b map[routeKey]map[string]bool) {
+	keys := make(map[routeKey]bool)
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+	for k := range keys {
+		change := CityRoutes{City: k.city, Alliance: k.alliance, Gained: []string{}, Lost: []string{}}
+		for to := range b[k] {
+			if !a[k][to] {
+				change.Gained = append(change.Gained, to)
+			}
+		}
+		for to := range a[k] {
+			if !b[k][to] {
+				change.Lost = append(change.Lost, to)
+			}
+		}
+		if len(change.Gained) == 0 && len(change.Lost) == 0 {
+			continue
+		}
+		sort.Strings(change.Gained)
+		sort.Strings(change.Lost)
+		d.Summary.RoutesGained += len(change.Gained)
+		d.Summary.RoutesLost += len(change.Lost)
+		d.Routes = append(d.Routes, change)
+	}
+}
+
+func (d *Diff) diffDistances(a, b map[pair]float64) {
+	for p, after := range b {
+		before, ok := a[p]
+		switch {
+		case !ok:
+			d.Summary.DistancesAdded++
+			d.Distances = append(d.Distances, DistanceChange{From: p[0], To: p[1], After: &after})
+		case math.Abs(before-after) > distanceEpsilon:
+			d.Summary.DistancesChanged++
+			d.Distances = append(d.Distances, DistanceChange{From: p[0], To: p[1], Before: &before, After: &after})
+		}
+	}
+	for p, before := range a {
+		if _, ok := b[p]; !ok {
+			d.Summary.DistancesRemoved++
+			d.Distances = append(d.Distances, DistanceChange{From: p[0], To: p[1], Before: &before})
+		}
+	}
+}
+
+func (d *Diff) sort() {
+	sort.Slice(d.Routes, func(i, j int) bool {
+		if d.Routes[i].City != d.Routes[j].City {
+			return d.Routes[i].City < d.Routes[j].City
+		}
+		return d.Routes[i].Alliance < d.Routes[j].Alliance
+	})
+	sort.Slice(d.Distances, func(i, j int) bool {
+		if d.Distances[i].From != d.Distances[j].From {
+			return d.Distances[i].From < d.Distances[j].From
+		}
+		return d.Distances[i].To < d.Distances[j].To
+	})
+	for _, cs := range [][]db.CityAirport{d.CityAirportsAdded, d.CityAirportsRemoved} {
+		sort.Slice(cs, func(i, j int) bool {
+			if cs[i].CityCode != cs[j].CityCode {
+				return cs[i].CityCode < cs[j].CityCode
+			}
+			return cs[i].AirportCode < cs[j].AirportCode
+		})
+	}
+	for _, as := range [][]db.Airport{d.AirportsAdded, d.AirportsRemoved} {
+		sort.Slice(as, func(i, j int) bool { return as[i].IATA < as[j].IATA })
+	}
+	sort.Slice(d.AirportsChanged, func(i, j int) bool { return d.AirportsChanged[i].After.IATA < d.AirportsChanged[j].After.IATA })
+}
+
+// String is a one-line summary of the counts
+func (s Summary) String() string {
+	return fmt.Sprintf("routes +%d -%d, distances +%d -%d ~%d, city airports +%d -%d, airports +%d -%d ~%d",
+		s.RoutesGained, s.RoutesLost, s.DistancesAdded, s.DistancesRemoved, s.DistancesChanged,
+		s.CityAirportsAdded, s.CityAirportsRemoved, s.AirportsAdded, s.AirportsRemoved, s.AirportsChanged)
+}
+
+// WriteText prints the diff one change per line: + added, - removed, ~ changed
+func (d *Diff) WriteText(w io.Writer) error {
+	var err error
+	printf := func(format string, args ...interface{}) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+	printf("%s\n", d.Summary)
+	for _, r := range d.Routes {
+		printf("route %s %s:", r.City, r.Alliance)
+		for _, to := range r.Gained {
+			printf(" +%s", to)
+		}
+		for _, to := range r.Lost {
+			printf(" -%s", to)
+		}
+		printf("\n")
+	}
+	for _, x := range d.Distances {
+		switch {
+		case x.Before == nil:
+			printf("distance + %s-%s %.1f\n", x.From, x.To, *x.After)
+		case x.After == nil:
+			printf("distance - %s-%s %.1f\n", x.From, x.To, *x.Before)
+		default:
+			printf("distance ~ %s-%s %.1f -> %.1f\n", x.From, x.To, *x.Before, *x.After)
+		}
+	}
+	for _, c := range d.CityAirportsAdded {
+		printf("city + %s/%s\n", c.CityCode, c.AirportCode)
+	}
+	for _, c := range d.CityAirportsRemoved {
+		printf("city - %s/%s\n", c.CityCode, c.AirportCode)
+	}
+	for _, a := range d.AirportsAdded {
+		printf("airport + %s %s\n", a.IATA, a.Name)
+	}
+	for _, a := range d.AirportsRemoved {
+		printf("airport - %s %s\n", a.IATA, a.Name)
+	}
+	for _, a := range d.AirportsChanged {
+		printf("airport ~ %s %s\n", a.After.IATA, a.After.Name)
+	}
+	return err
+}
+
+// Recorded is the outcome of Record
+type Recorded struct {
+	Snapshot *db.Snapshot // the new snapshot, or the previous one when nothing changed
+	Previous *db.Snapshot // nil for the first snapshot
+	Changes  *Diff        // since Previous; nil for the first snapshot
+	Created  bool
+}
+
+// Store is what Record needs: the live route data and snapshots of it
+type Store interface {
+	db.RouteData
+	db.SnapshotStore
+}
+
+// Record snapshots the live route data after an import, unless it is unchanged since the
+// newest snapshot, and reports the changes since that snapshot
+func Record(ctx context.Context, s Store, source string) (*Recorded, error) {
+	snapshots, err := s.ListSnapshots(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r := &Recorded{}
+	if len(snapshots) > 0 {
+		r.Previous = &snapshots[0]
+		if r.Changes, err = Compare(ctx, s.SnapshotData(r.Previous.ID), s); err != nil {
+			return nil, err
+		}
+		if r.Changes.Empty() {
+			r.Snapshot = r.Previous
+			return r, nil
+		}
+	}
+	if r.Snapshot, err = s.CreateSnapshot(ctx, source); err != nil {
+		return nil, err
+	}
+	r.Created = true
+	return r, nil
+}
+
+// String describes the outcome for command output
+func (r *Recorded) String() string {
+	switch {
+	case !r.Created:
+		return fmt.Sprintf("route data unchanged since snapshot %d", r.Snapshot.ID)
+	case r.Previous == nil:
+		return fmt.Sprintf("snapshot %d created (first snapshot)", r.Snapshot.ID)
+	default:
+		return fmt.Sprintf("snapshot %d created; since snapshot %d: %s", r.Snapshot.ID, r.Previous.ID, r.Changes.Summary)
+	}
+}
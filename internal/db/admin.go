@@ -0,0 +1,414 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Airline is a row of the airlines table
+type Airline struct {
+	IATA     string `json:"iata"`
+	ICAO     string `json:"icao"`
+	Name     string `json:"name"`
+	Country  string `json:"country"`
+	Alliance string `json:"alliance"`
+	Active   bool   `json:"active"`
+}
+
+// AuditEntry records one admin change to reference data
+type AuditEntry struct {
+	ID     int64           `json:"id"`
+	At     time.Time       `json:"at"`
+	Actor  string          `json:"actor"`
+	Action string          `json:"action"` // create, update or delete
+	Entity string          `json:"entity"` // airport, city, route, distance or airline
+	Key    string          `json:"key"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// CodeUsage says where a city or airport code appears in the reference tables
+type CodeUsage struct {
+	Airport   *Airport `json:"airport,omitempty"` // airports row for the code
+	Cities    []string `json:"cities"`            // cities the code is mapped to as an airport
+	Airports  []string `json:"airports"`          // airports mapped to the code as a city
+	Routes    int      `json:"routes"`            // city_routes rows from or to the code
+	Distances int      `json:"distances"`         // distances rows from or to the code
+}
+
+// Known reports whether the code is an airport or a city
+func (u *CodeUsage) Known() bool {
+	return u.Airport != nil || len(u.Cities) > 0 || len(u.Airports) > 0
+}
+
+// City is the city the code belongs to (the code itself for cities and unmapped airports)
+func (u *CodeUsage) City(code string) string {
+	if len(u.Cities) > 0 {
+		return u.Cities[0]
+	}
+	if u.Airport != nil && u.Airport.CityCode != "" {
+		return u.Airport.CityCode
+	}
+	return code
+}
+
+// CodeUsage looks a city or airport code up in every reference table
+func (d *DB) CodeUsage(ctx context.Context, code string) (*CodeUsage, error) {
+	u := &CodeUsage{Cities: []string{}, Airports: []string{}}
+	var a Airport
+	err := d.QueryRowContext(ctx, queryAirport, code).
+		Scan(&a.IATA, &a.Name, &a.CityCode, &a.Country, &a.Continent, &a.Latitude, &a.Longitude)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, ContextError(ctx, err)
+	}
+	if err == nil {
+		u.Airport = &a
+	}
+	for _, q := range []struct {
+		query string
+		out   *[]string
+	}{
+		{"SELECT city_code FROM iata_cities WHERE airport_code = ? ORDER BY city_code", &u.Cities},
+		{"SELECT airport_code FROM iata_cities WHERE city_code = ? ORDER BY airport_code", &u.Airports},
+	} {
+		rows, err := d.QueryContext(ctx, q.query, code)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var c string
+			if err := rows.Scan(&c); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			*q.out = append(*q.out, c)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, ContextError(ctx, err)
+		}
+	}
+	if err := d.QueryRowContext(ctx, "SELECT COUNT(*) FROM city_routes WHERE city_iata = ? OR route_to = ?", code, code).Scan(&u.Routes); err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	if err := d.QueryRowContext(ctx, "SELECT COUNT(*) FROM distances WHERE from_iata = ? OR to_iata = ?", code, code).Scan(&u.Distances); err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	return u, nil
+}
+
+// ListAirlines returns every airline ordered by IATA code
+func (d *DB) ListAirlines(ctx context.Context) ([]Airline, error) {
+	rows, err := d.QueryContext(ctx, "SELECT iata_code, icao_code, name, country, alliance, active FROM airlines ORDER BY iata_code")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Airline{}
+	for rows.Next() {
+		var a Airline
+		if err := rows.Scan(&a.IATA, &a.ICAO, &a.Name, &a.Country, &a.Alliance, &a.Active); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, ContextError(ctx, rows.Err())
+}
+
+// ListAudit returns the newest audit entries first, optionally for one entity type
+func (d *DB) ListAudit(ctx context.Context, entity string, limit int) ([]AuditEntry, error) {
+	query := "SELECT id, created_at, actor, action, entity, entity_key, before_json, after_json FROM audit_log"
+	var args []interface{}
+	if entity != "" {
+		query += " WHERE entity = ?"
+		args = append(args, entity)
+	}
+	query += " ORDER BY id DESC LIMIT ?"
+	args = append(args, limit)
+	rows, err := d.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		var before, after sql.NullString
+		if err := rows.Scan(&e.ID, &e.At, &e.Actor, &e.Action, &e.Entity, &e.Key, &before, &after); err != nil {
+			return nil, err
+		}
+		if before.Valid {
+			e.Before = json.RawMessage(before.String)
+		}
+		if after.Valid {
+			e.After = json.RawMessage(after.String)
+		}
+		out = append(out, e)
+	}
+	return out, ContextError(ctx, rows.Err())
+}
+
+// audited runs a reference data change and its audit_log row in one transaction. change
+// returns the row before and after (nil when absent); when both are nil nothing changed,
+// no entry is written and changed is false.
+func (d *DB) audited(ctx context.Context, actor, entity, key string, change func(tx *sql.Tx) (before, after interface{}, err error)) (changed bool, err error) {
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return false, ContextError(ctx, err)
+	}
+	defer tx.Rollback()
+	before, after, err := change(tx)
+	if err != nil {
+		return false, ContextError(ctx, err)
+	}
+	if before == nil && after == nil {
+		return false, nil
+	}
+	action := "update"
+	if before == nil {
+		action = "create"
+	} else if after == nil {
+		action = "delete"
+	}
+	beforeJSON, err := nullJSON(before)
+	if err != nil {
+		return false, err
+	}
+	afterJSON, err := nullJSON(after)
+	if err != nil {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, d.Rebind(`INSERT INTO audit_log (actor, action, entity, entity_key, before_json, after_json, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`), actor, action, entity, key, beforeJSON, afterJSON, time.Now().UTC()); err != nil {
+		return false, ContextError(ctx, err)
+	}
+	return true, ContextError(ctx, tx.Commit())
+}
+
+func nullJSON(v interface{}) (sql.NullString, error) {
+	if v == nil {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+// exists runs a COUNT query on tx and reports whether it found rows
+func (d *DB) exists(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) (bool, error) {
+	var n int
+	err := tx.QueryRowContext(ctx, d.Rebind(query), args...).Scan(&n)
+	return n > 0, err
+}
+
+// PutAirport creates or replaces an airport
+func (d *DB) PutAirport(ctx context.Context, actor string, a Airport) error {
+	_, err := d.audited(ctx, actor, "airport", a.IATA, func(tx *sql.Tx) (interface{}, interface{}, error) {
+		var old Airport
+		var before interface{}
+		err := tx.QueryRowContext(ctx, d.Rebind(queryAirport), a.IATA).
+			Scan(&old.IATA, &old.Name, &old.CityCode, &old.Country, &old.Continent, &old.Latitude, &old.Longitude)
+		switch {
+		case err == sql.ErrNoRows:
+		case err != nil:
+			return nil, nil, err
+		case old == a:
+			return nil, nil, nil
+		default:
+			before = old
+		}
+		_, err = tx.ExecContext(ctx, d.Rebind(`INSERT INTO airports (iata_code, name, city_code, country, continent, latitude, longitude)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (iata_code) DO UPDATE SET name = excluded.name, city_code = excluded.city_code, country = excluded.country,
+				continent = excluded.continent, latitude = excluded.latitude, longitude = excluded.longitude`),
+			a.IATA, a.Name, a.CityCode, a.Country, a.Continent, a.Latitude, a.Longitude)
+		return before, a, err
+	})
+	return err
+}
+
+// DeleteAirport removes an airport; ok is false if it doesn't exist
+func (d *DB) DeleteAirport(ctx context.Context, actor, code string) (ok bool, err error) {
+	return d.audited(ctx, actor, "airport", code, func(tx *sql.Tx) (interface{}, interface{}, error) {
+		var old Airport
+		err := tx.QueryRowContext(ctx, d.Rebind(queryAirport), code).
+			Scan(&old.IATA, &old.Name, &old.CityCode, &old.Country, &old.Continent, &old.Latitude, &old.Longitude)
+		if err == sql.ErrNoRows {
+			return nil, nil, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		_, err = tx.ExecContext(ctx, d.Rebind("DELETE FROM airports WHERE iata_code = ?"), code)
+		return old, nil, err
+	})
+}
+
+// AddCityAirport maps an airport to a city; ok is false if the mapping already exists
+func (d *DB) AddCityAirport(ctx context.Context, actor string, c CityAirport) (ok bool, err error) {
+	return d.audited(ctx, actor, "city", c.CityCode+"/"+c.AirportCode, func(tx *sql.Tx) (interface{}, interface{}, error) {
+		found, err := d.exists(ctx, tx, "SELECT COUNT(*) FROM iata_cities WHERE city_code = ? AND airport_code = ?", c.CityCode, c.AirportCode)
+		if err != nil || found {
+			return nil, nil, err
+		}
+		_, err = tx.ExecContext(ctx, d.Rebind("INSERT INTO iata_cities (city_code, airport_code) VALUES (?, ?)"), c.CityCode, c.AirportCode)
+		return nil, c, err
+	})
+}
+
+// DeleteCityAirport removes an airport from a city; ok is false if it isn't mapped there
+func (d *DB) DeleteCityAirport(ctx context.Context, actor string, c CityAirport) (ok bool, err error) {
+	return d.audited(ctx, actor, "city", c.CityCode+"/"+c.AirportCode, func(tx *sql.Tx) (interface{}, interface{}, error) {
+		res, err := tx.ExecContext(ctx, d.Rebind("DELETE FROM iata_cities WHERE city_code = ? AND airport_code = ?"), c.CityCode, c.AirportCode)
+		if err != nil {
+			return nil, nil, err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return nil, nil, err
+		}
+		return c, nil, nil
+	})
+}
+
+// AddRoute adds a city_routes row; ok is false if it already exists
+func (d *DB) AddRoute(ctx context.Context, actor string, r Route) (ok bool, err error) {
+	return d.audited(ctx, actor, "route", routeKey(r), func(tx *sql.Tx) (interface{}, interface{}, error) {
+		found, err := d.exists(ctx, tx, "SELECT COUNT(*) FROM city_routes WHERE city_iata = ? AND alliance = ? AND route_to = ?", r.From, r.Alliance, r.To)
+		if err != nil || found {
+			return nil, nil, err
+		}
+		_, err = tx.ExecContext(ctx, d.Rebind("INSERT INTO city_routes (city_iata, alliance, route_to) VALUES (?, ?, ?)"), r.From, r.Alliance, r.To)
+		return nil, r, err
+	})
+}
+
+// DeleteRoute removes a city_routes row; ok is false if it doesn't exist
+func (d *DB) DeleteRoute(ctx context.Context, actor string, r Route) (ok bool, err error) {
+	return d.audited(ctx, actor, "route", routeKey(r), func(tx *sql.Tx) (interface{}, interface{}, error) {
+		res, err := tx.ExecContext(ctx, d.Rebind("DELETE FROM city_routes WHERE city_iata = ? AND alliance = ? AND route_to = ?"), r.From, r.Alliance, r.To)
+		if err != nil {
+			return nil, nil, err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return nil, nil, err
+		}
+		return r, nil, nil
+	})
+}
+
+func routeKey(r Route) string {
+	return fmt.Sprintf("%s-%s/%s", r.From, r.To, r.Alliance)
+}
+
+// PutDistance sets the distance between two codes. A stored reverse row is updated too,
+// so the pair stays symmetric.
+func (d *DB) PutDistance(ctx context.Context, actor string, x Distance) error {
+	_, err := d.audited(ctx, actor, "distance", x.From+"-"+x.To, func(tx *sql.Tx) (interface{}, interface{}, error) {
+		var before interface{}
+		var miles float64
+		err := tx.QueryRowContext(ctx, d.Rebind(`SELECT distance_miles FROM distances
+			WHERE (from_iata = ? AND to_iata = ?) OR (from_iata = ? AND to_iata = ?) ORDER BY from_iata = ? DESC LIMIT 1`),
+			x.From, x.To, x.To, x.From, x.From).Scan(&miles)
+		switch {
+		case err == sql.ErrNoRows:
+		case err != nil:
+			return nil, nil, err
+		case miles == x.Miles:
+			return nil, nil, nil
+		default:
+			before = Distance{From: x.From, To: x.To, Miles: miles}
+		}
+		if _, err := tx.ExecContext(ctx, d.Rebind("UPDATE distances SET distance_miles = ? WHERE from_iata = ? AND to_iata = ?"), x.Miles, x.To, x.From); err != nil {
+			return nil, nil, err
+		}
+		reverse, err := d.exists(ctx, tx, "SELECT COUNT(*) FROM distances WHERE from_iata = ? AND to_iata = ?", x.To, x.From)
+		if err != nil {
+			return nil, nil, err
+		}
+		forward, err := d.exists(ctx, tx, "SELECT COUNT(*) FROM distances WHERE from_iata = ? AND to_iata = ?", x.From, x.To)
+		if err != nil {
+			return nil, nil, err
+		}
+		if forward || !reverse {
+			_, err = tx.ExecContext(ctx, d.Rebind(`INSERT INTO distances (from_iata, to_iata, distance_miles) VALUES (?, ?, ?)
+				ON CONFLICT (from_iata, to_iata) DO UPDATE SET distance_miles = excluded.distance_miles`), x.From, x.To, x.Miles)
+		}
+		return before, x, err
+	})
+	return err
+}
+
+// DeleteDistance removes the distance between two codes in both directions; ok is false if there was none
+func (d *DB) DeleteDistance(ctx context.Context, actor, from, to string) (ok bool, err error) {
+	return d.audited(ctx, actor, "distance", from+"-"+to, func(tx *sql.Tx) (interface{}, interface{}, error) {
+		var miles float64
+		err := tx.QueryRowContext(ctx, d.Rebind(`SELECT distance_miles FROM distances
+			WHERE (from_iata = ? AND to_iata = ?) OR (from_iata = ? AND to_iata = ?) ORDER BY from_iata = ? DESC LIMIT 1`),
+			from, to, to, from, from).Scan(&miles)
+		if err == sql.ErrNoRows {
+			return nil, nil, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		_, err = tx.ExecContext(ctx, d.Rebind(`DELETE FROM distances
+			WHERE (from_iata = ? AND to_iata = ?) OR (from_iata = ? AND to_iata = ?)`), from, to, to, from)
+		return Distance{From: from, To: to, Miles: miles}, nil, err
+	})
+}
+
+// PutAirline creates or replaces an airline
+func (d *DB) PutAirline(ctx context.Context, actor string, a Airline) error {
+	_, err := d.audited(ctx, actor, "airline", a.IATA, func(tx *sql.Tx) (interface{}, interface{}, error) {
+		var old Airline
+		var before interface{}
+		err := tx.QueryRowContext(ctx, d.Rebind("SELECT iata_code, icao_code, name, country, alliance, active FROM airlines WHERE iata_code = ?"), a.IATA).
+			Scan(&old.IATA, &old.ICAO, &old.Name, &old.Country, &old.Alliance, &old.Active)
+		switch {
+		case err == sql.ErrNoRows:
+		case err != nil:
+			return nil, nil, err
+		case old == a:
+			return nil, nil, nil
+		default:
+			before = old
+		}
+		_, err = tx.ExecContext(ctx, d.Rebind(`INSERT INTO airlines (iata_code, icao_code, name, country, alliance, active)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT (iata_code) DO UPDATE SET icao_code = excluded.icao_code, name = excluded.name, country = excluded.country,
+				alliance = excluded.alliance, active = excluded.active`),
+			a.IATA, a.ICAO, a.Name, a.Country, a.Alliance, boolInt(a.Active))
+		return before, a, err
+	})
+	return err
+}
+
+// DeleteAirline removes an airline; ok is false if it doesn't exist
+func (d *DB) DeleteAirline(ctx context.Context, actor, code string) (ok bool, err error) {
+	return d.audited(ctx, actor, "airline", code, func(tx *sql.Tx) (interface{}, interface{}, error) {
+		var old Airline
+		err := tx.QueryRowContext(ctx, d.Rebind("SELECT iata_code, icao_code, name, country, alliance, active FROM airlines WHERE iata_code = ?"), code).
+			Scan(&old.IATA, &old.ICAO, &old.Name, &old.Country, &old.Alliance, &old.Active)
+		if err == sql.ErrNoRows {
+			return nil, nil, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		_, err = tx.ExecContext(ctx, d.Rebind("DELETE FROM airlines WHERE iata_code = ?"), code)
+		return old, nil, err
+	})
+}
+
+// boolInt stores a bool in an INTEGER flag column (PostgreSQL won't cast it implicitly)
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
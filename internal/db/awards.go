@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"log"
+	"strings"
+)
+
+// AwardProgram is a loyalty program and its award routing rules
+type AwardProgram struct {
+	Code           string `json:"code"`
+	Name           string `json:"name"`
+	Alliance       string `json:"alliance"`
+	ChartType      string `json:"chartType"` // distance, distance_total or zone
+	OneWayAllowed  bool   `json:"oneWayAllowed"`
+	OpenJawAllowed bool   `json:"openJawAllowed"`
+	MaxStopovers   int    `json:"maxStopovers"`
+	StopoverPoints int    `json:"stopoverPoints"`
+}
+
+// AwardBand is one mileage band of a distance-based chart
+type AwardBand struct {
+	Cabin    string
+	MinMiles int
+	MaxMiles int
+	Points   int
+}
+
+// AwardZonePrice is the one-way price between two zones
+type AwardZonePrice struct {
+	Cabin    string
+	FromZone string
+	ToZone   string
+	Points   int
+}
+
+// GetAwardPrograms returns award programs by code, or all programs when codes is empty
+func (d *DB) GetAwardPrograms(ctx context.Context, codes []string) ([]AwardProgram, error) {
+	query := "SELECT code, name, alliance, chart_type, one_way_allowed, open_jaw_allowed, max_stopovers, stopover_points FROM award_programs"
+	var args []interface{}
+	if len(codes) > 0 {
+		query += " WHERE code IN (?" + strings.Repeat(",?", len(codes)-1) + ")"
+		for _, c := range codes {
+			args = append(args, c)
+		}
+	}
+	query += " ORDER BY code"
+	rows, err := d.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var programs []AwardProgram
+	for rows.Next() {
+		var p AwardProgram
+		if err := rows.Scan(&p.Code, &p.Name, &p.Alliance, &p.ChartType, &p.OneWayAllowed, &p.OpenJawAllowed, &p.MaxStopovers, &p.StopoverPoints); err != nil {
+			log.Println(err)
+			continue
+		}
+		programs = append(programs, p)
+	}
+	return programs, ContextError(ctx, rows.Err())
+}
+
+// GetAwardBands returns the distance bands of a program ordered by cabin and mileage
+func (d *DB) GetAwardBands(ctx context.Context, program string) ([]AwardBand, error) {
+	rows, err := d.QueryContext(ctx, "SELECT cabin, min_miles, max_miles, points FROM award_distance_bands WHERE program = ? ORDER BY cabin, min_miles", program)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var bands []AwardBand
+	for rows.Next() {
+		var b AwardBand
+		if err := rows.Scan(&b.Cabin, &b.MinMiles, &b.MaxMiles, &b.Points); err != nil {
+			log.Println(err)
+			continue
+		}
+		bands = append(bands, b)
+	}
+	return bands, ContextError(ctx, rows.Err())
+}
+
+// GetAwardZones returns map of "area_type:area" (e.g. "country:JP", "continent:EU") -> zone for a program
+func (d *DB) GetAwardZones(ctx context.Context, program string) (map[string]string, error) {
+	rows, err := d.QueryContext(ctx, "SELECT area_type || ':' || area, zone FROM award_zones WHERE program = ?", program)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	zones := make(map[string]string)
+	for rows.Next() {
+		var area, zone string
+		if err := rows.Scan(&area, &zone); err != nil {
+			log.Println(err)
+			continue
+		}
+		zones[area] = zone
+	}
+	return zones, ContextError(ctx, rows.Err())
+}
+
+// GetAwardZonePrices returns the zone-to-zone prices of a program
+func (d *DB) GetAwardZonePrices(ctx context.Context, program string) ([]AwardZonePrice, error) {
+	rows, err := d.QueryContext(ctx, "SELECT cabin, from_zone, to_zone, points FROM award_zone_prices WHERE program = ?", program)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var prices []AwardZonePrice
+	for rows.Next() {
+		var p AwardZonePrice
+		if err := rows.Scan(&p.Cabin, &p.FromZone, &p.ToZone, &p.Points); err != nil {
+			log.Println(err)
+			continue
+		}
+		prices = append(prices, p)
+	}
+	return prices, ContextError(ctx, rows.Err())
+}
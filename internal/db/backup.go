@@ -0,0 +1,39 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"os"
+)
+
+// ErrBackupUnsupported is returned by BackupTo on PostgreSQL, which is backed up with pg_dump
+var ErrBackupUnsupported = errors.New("online backup is only supported on SQLite; use pg_dump for PostgreSQL")
+
+// BackupTo writes a consistent copy of the live SQLite database to path with VACUUM INTO.
+// Readers and writers keep running; the copy is compacted and needs no WAL file.
+// path must not exist yet.
+func (d *DB) BackupTo(ctx context.Context, path string) error {
+	if d.dialect != SQLite {
+		return ErrBackupUnsupported
+	}
+	if _, err := d.ExecContext(ctx, "VACUUM INTO ?", path); err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+// IntegrityCheck runs SQLite's integrity_check and returns its first complaint, if any
+func (d *DB) IntegrityCheck(ctx context.Context) error {
+	if d.dialect != SQLite {
+		return nil
+	}
+	var result string
+	if err := d.QueryRowContext(ctx, "PRAGMA integrity_check(1)").Scan(&result); err != nil {
+		return ContextError(ctx, err)
+	}
+	if result != "ok" {
+		return errors.New("integrity check failed: " + result)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package db_test
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	dbfiles "triangle_travel/db"
+	"triangle_travel/internal/db"
+	"triangle_travel/internal/migrate"
+)
+
+// benchUsers is how many distinct phone numbers the benchmark clients log in with
+const benchUsers = 500
+
+// BenchmarkSessions runs a concurrent login / My Flights / airport lookup workload against
+// scratch SQLite databases with the driver defaults, with db.DefaultOptions, and with
+// DefaultOptions plus prepared statements. One op is a login followed by five authenticated
+// requests; failed Store calls ("database is locked" with the defaults) are reported as
+// failed/op rather than failing the benchmark.
+func BenchmarkSessions(b *testing.B) {
+	configs := []struct {
+		name    string
+		opts    db.Options
+		prepare bool
+	}{
+		{"defaults", db.Options{}, false},
+		{"tuned", db.DefaultOptions(), false},
+		{"prepared", db.DefaultOptions(), true},
+	}
+	for _, c := range configs {
+		b.Run(c.name, func(b *testing.B) {
+			database, airports := benchDB(b, c.opts)
+			if c.prepare {
+				if err := database.PrepareStatements(context.Background()); err != nil {
+					b.Fatal(err)
+				}
+			}
+			var seed atomic.Int64
+			var failed atomic.Int64
+			var mu sync.Mutex
+			failures := make(map[string]int)
+			// 16 clients on 8 cores, like the server under load
+			b.SetParallelism(2)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				rnd := rand.New(rand.NewSource(seed.Add(1)))
+				for pb.Next() {
+					for _, err := range session(context.Background(), database, rnd, airports) {
+						failed.Add(1)
+						mu.Lock()
+						failures[errorKey(err)]++
+						mu.Unlock()
+					}
+				}
+			})
+			b.StopTimer()
+			b.ReportMetric(float64(failed.Load())/float64(b.N), "failed/op")
+			msgs := make([]string, 0, len(failures))
+			for msg, n := range failures {
+				msgs = append(msgs, fmt.Sprintf("%6d  %s", n, msg))
+			}
+			sort.Strings(msgs)
+			for _, msg := range msgs {
+				b.Log(msg)
+			}
+		})
+	}
+}
+
+// benchDB returns a scratch SQLite database with the migrations and seed data applied, and
+// the airport codes to look up
+func benchDB(b *testing.B, opts db.Options) (*db.DB, []string) {
+	b.Helper()
+	dir := b.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "db"), 0755); err != nil {
+		b.Fatal(err)
+	}
+	database, err := db.New(dir, opts)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { database.Close() })
+	migrations, err := migrate.Load(dbfiles.Migrations("", string(database.Dialect())))
+	if err != nil {
+		b.Fatal(err)
+	}
+	if _, err := migrate.Up(database, migrations, 0); err != nil {
+		b.Fatal(err)
+	}
+	seedData, err := dbfiles.SeedData("")
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := migrate.RefreshReference(database, string(seedData)); err != nil {
+		b.Fatal(err)
+	}
+	airports, err := database.ListAirports(context.Background())
+	if err != nil {
+		b.Fatal(err)
+	}
+	codes := []string{"JFK"}
+	for _, a := range airports {
+		codes = append(codes, a.IATA)
+	}
+	return database, codes
+}
+
+// session logs a random user in and makes five authenticated requests, returning the errors
+// of failed Store calls. A failed login step ends the session early.
+func session(ctx context.Context, database *db.DB, rnd *rand.Rand, airports []string) []error {
+	var errs []error
+	op := func(err error) bool {
+		if err != nil {
+			errs = append(errs, err)
+		}
+		return err == nil
+	}
+	phone := fmt.Sprintf("+1555%07d", rnd.Intn(benchUsers))
+	token := fmt.Sprintf("bench-%d-%d", rnd.Int63(), time.Now().UnixNano())
+	var user *db.User
+	otp := db.OTPCode{Phone: phone, CodeHash: "bench", SentAt: time.Now(), ExpiresAt: time.Now().Add(5 * time.Minute)}
+	ok := op(func() error { _, err := database.SaveOTP(ctx, otp, 0); return err }()) &&
+		op(func() error { _, err := database.CountOTPAttempt(ctx, phone); return err }()) &&
+		op(func() error { _, err := database.GetOTP(ctx, phone); return err }()) &&
+		op(func() (err error) { user, err = database.EnsureUser(ctx, phone); return err }()) &&
+		op(func() error {
+			now := time.Now()
+			s := &db.Session{UserID: user.ID, CreatedAt: now, LastUsedAt: now,
+				AccessExpiresAt: now.Add(time.Hour), ExpiresAt: now.Add(time.Hour)}
+			return database.CreateSession(ctx, s, token, token+"-refresh")
+		}()) &&
+		op(database.DeleteOTP(ctx, phone))
+	if !ok {
+		return errs
+	}
+	for i := 0; i < 5; i++ {
+		op(func() error { _, err := database.GetSession(ctx, token); return err }())
+		switch rnd.Intn(3) {
+		case 0:
+			op(func() error { _, err := database.ListBookedFlights(ctx, user.ID); return err }())
+		case 1:
+			op(database.AddBookedFlight(ctx, &db.BookedFlight{
+				UserID: user.ID, Airline: "BA", FlightNumber: "BA178",
+				FromIATA: "JFK", ToIATA: "LHR", DepartureDate: "2026-06-01",
+			}))
+		default:
+			op(func() error { _, err := database.GetAirport(ctx, airports[rnd.Intn(len(airports))]); return err }())
+		}
+	}
+	return errs
+}
+
+// errorKey groups errors by message without per-call details
+func errorKey(err error) string {
+	msg := err.Error()
+	if strings.Contains(msg, "database is locked") {
+		return "database is locked"
+	}
+	return msg
+}
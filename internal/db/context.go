@@ -0,0 +1,28 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrTimeout is returned when a query runs past its context deadline
+var ErrTimeout = errors.New("database query timed out")
+
+// ErrCanceled is returned when a query's context is canceled (client gone, server shutting down)
+var ErrCanceled = errors.New("database query canceled")
+
+// ContextError returns err wrapped in ErrTimeout or ErrCanceled when ctx is done, else err.
+// Drivers report an interrupted query in their own ways, so the context decides.
+func ContextError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return fmt.Errorf("%w: %v", ErrTimeout, err)
+	case context.Canceled:
+		return fmt.Errorf("%w: %v", ErrCanceled, err)
+	}
+	return err
+}
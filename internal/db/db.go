@@ -1,55 +1,58 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"log"
-	"path/filepath"
-
-	_ "modernc.org/sqlite"
 )
 
-// DB wraps sql.DB for triangle travel data
+// DB wraps sql.DB for triangle travel data. It implements Store for SQLite and PostgreSQL;
+// queries are written with ? placeholders and rebound per dialect.
 type DB struct {
 	*sql.DB
+	dialect Dialect
+	stmts   map[string]*sql.Stmt // hot queries prepared by PrepareStatements, keyed by ? query text
 }
 
-// New opens the SQLite database at dataDir/db/data.sqlite3
-func New(dataDir string) (*DB, error) {
-	path := filepath.Join(dataDir, "db", "data.sqlite3")
-	db, err := sql.Open("sqlite", path)
-	if err != nil {
-		return nil, err
-	}
-	if err := db.Ping(); err != nil {
-		db.Close()
-		return nil, err
-	}
-	return &DB{db}, nil
-}
+// Lookups behind searches and the route graph watcher; PrepareStatements prepares them
+const (
+	queryCityForAirport  = "SELECT city_code FROM iata_cities WHERE airport_code = ? LIMIT 1"
+	queryAirportsForCity = "SELECT airport_code FROM iata_cities WHERE city_code = ? ORDER BY airport_code"
+	queryDistancesFrom   = `SELECT to_iata, distance_miles FROM distances WHERE from_iata = ?
+		UNION ALL
+		SELECT r.from_iata, r.distance_miles FROM distances r
+		WHERE r.to_iata = ? AND NOT EXISTS (
+			SELECT 1 FROM distances f WHERE f.from_iata = r.to_iata AND f.to_iata = r.from_iata)`
+	queryRoutesFrom       = "SELECT route_to FROM city_routes WHERE city_iata = ? AND alliance = ? ORDER BY route_to"
+	airportCols           = "iata_code, name, city_code, country, continent, latitude, longitude"
+	queryAirport          = "SELECT " + airportCols + " FROM airports WHERE iata_code = ?"
+	queryCityAirport      = "SELECT " + airportCols + " FROM airports WHERE city_code = ? ORDER BY iata_code LIMIT 1"
+	queryReferenceVersion = "SELECT version FROM reference_version WHERE id = 1"
+)
 
 // GetCityForAirport returns the city_code for an airport IATA code, or the code itself if not found
-func (d *DB) GetCityForAirport(airportCode string) (string, error) {
+func (d *DB) GetCityForAirport(ctx context.Context, airportCode string) (string, error) {
 	var city string
-	err := d.QueryRow("SELECT city_code FROM iata_cities WHERE airport_code = ? LIMIT 1", airportCode).Scan(&city)
+	err := d.QueryRowContext(ctx, queryCityForAirport, airportCode).Scan(&city)
 	if err == sql.ErrNoRows {
 		return airportCode, nil // treat airport as its own city
 	}
 	if err != nil {
-		return "", err
+		return "", ContextError(ctx, err)
 	}
 	return city, nil
 }
 
 // SameCity returns true if both airport codes belong to the same city
-func (d *DB) SameCity(a, b string) (bool, error) {
+func (d *DB) SameCity(ctx context.Context, a, b string) (bool, error) {
 	if a == b {
 		return true, nil
 	}
-	cityA, err := d.GetCityForAirport(a)
+	cityA, err := d.GetCityForAirport(ctx, a)
 	if err != nil {
 		return false, err
 	}
-	cityB, err := d.GetCityForAirport(b)
+	cityB, err := d.GetCityForAirport(ctx, b)
 	if err != nil {
 		return false, err
 	}
@@ -57,8 +60,8 @@ func (d *DB) SameCity(a, b string) (bool, error) {
 }
 
 // GetAirportsForCity returns airport IATA codes for a city code
-func (d *DB) GetAirportsForCity(cityCode string) ([]string, error) {
-	rows, err := d.Query("SELECT airport_code FROM iata_cities WHERE city_code = ? ORDER BY airport_code", cityCode)
+func (d *DB) GetAirportsForCity(ctx context.Context, cityCode string) ([]string, error) {
+	rows, err := d.QueryContext(ctx, queryAirportsForCity, cityCode)
 	if err != nil {
 		return nil, err
 	}
@@ -72,15 +75,19 @@ func (d *DB) GetAirportsForCity(cityCode string) ([]string, error) {
 		}
 		airports = append(airports, code)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, ContextError(ctx, err)
+	}
 	if len(airports) == 0 {
 		airports = []string{cityCode}
 	}
 	return airports, nil
 }
 
-// GetDistancesFrom returns map of to_iata -> distance_miles for an airport
-func (d *DB) GetDistancesFrom(fromIata string) (map[string]float64, error) {
-	rows, err := d.Query("SELECT to_iata, distance_miles FROM distances WHERE from_iata = ?", fromIata)
+// GetDistancesFrom returns map of other IATA -> distance_miles for an airport. Distances are
+// symmetric, so pairs stored in either direction count; a forward row wins over its reverse.
+func (d *DB) GetDistancesFrom(ctx context.Context, fromIata string) (map[string]float64, error) {
+	rows, err := d.QueryContext(ctx, queryDistancesFrom, fromIata, fromIata)
 	if err != nil {
 		return nil, err
 	}
@@ -95,12 +102,12 @@ func (d *DB) GetDistancesFrom(fromIata string) (map[string]float64, error) {
 		}
 		result[to] = dist
 	}
-	return result, nil
+	return result, ContextError(ctx, rows.Err())
 }
 
 // GetRoutesFrom returns list of destination IATA codes for city+alliance
-func (d *DB) GetRoutesFrom(cityIata, alliance string) ([]string, error) {
-	rows, err := d.Query("SELECT route_to FROM city_routes WHERE city_iata = ? AND alliance = ? ORDER BY route_to", cityIata, alliance)
+func (d *DB) GetRoutesFrom(ctx context.Context, cityIata, alliance string) ([]string, error) {
+	rows, err := d.QueryContext(ctx, queryRoutesFrom, cityIata, alliance)
 	if err != nil {
 		return nil, err
 	}
@@ -114,22 +121,25 @@ func (d *DB) GetRoutesFrom(cityIata, alliance string) ([]string, error) {
 		}
 		routes = append(routes, to)
 	}
-	return routes, nil
+	return routes, ContextError(ctx, rows.Err())
 }
 
 // GetRoutesFromWithFallback checks city first, then each airport in city
-func (d *DB) GetRoutesFromWithFallback(cityIata, alliance string) ([]string, error) {
-	routes, err := d.GetRoutesFrom(cityIata, alliance)
+func (d *DB) GetRoutesFromWithFallback(ctx context.Context, cityIata, alliance string) ([]string, error) {
+	routes, err := d.GetRoutesFrom(ctx, cityIata, alliance)
 	if err != nil || len(routes) > 0 {
 		return routes, err
 	}
-	airports, err := d.GetAirportsForCity(cityIata)
+	airports, err := d.GetAirportsForCity(ctx, cityIata)
 	if err != nil {
 		return nil, err
 	}
 	seen := make(map[string]bool)
 	for _, apt := range airports {
-		r, _ := d.GetRoutesFrom(apt, alliance)
+		r, err := d.GetRoutesFrom(ctx, apt, alliance)
+		if err != nil {
+			return nil, err
+		}
 		for _, dest := range r {
 			if !seen[dest] {
 				seen[dest] = true
@@ -141,12 +151,12 @@ func (d *DB) GetRoutesFromWithFallback(cityIata, alliance string) ([]string, err
 }
 
 // HasDirectRoute checks if there's a route from fromIata to any of toIatas for the alliance
-func (d *DB) HasDirectRoute(fromIata, alliance string, toIatas map[string]bool) map[string]bool {
+func (d *DB) HasDirectRoute(ctx context.Context, fromIata, alliance string, toIatas map[string]bool) map[string]bool {
 	result := make(map[string]bool)
 	for k := range toIatas {
 		result[k] = false
 	}
-	routes, err := d.GetRoutesFrom(fromIata, alliance)
+	routes, err := d.GetRoutesFrom(ctx, fromIata, alliance)
 	if err != nil {
 		return result
 	}
@@ -155,12 +165,12 @@ func (d *DB) HasDirectRoute(fromIata, alliance string, toIatas map[string]bool)
 			result[r] = true
 		}
 	}
-	airports, _ := d.GetAirportsForCity(fromIata)
+	airports, _ := d.GetAirportsForCity(ctx, fromIata)
 	for _, apt := range airports {
 		if apt == fromIata {
 			continue
 		}
-		r, _ := d.GetRoutesFrom(apt, alliance)
+		r, _ := d.GetRoutesFrom(ctx, apt, alliance)
 		for _, dest := range r {
 			if toIatas[dest] {
 				result[dest] = true
@@ -169,3 +179,85 @@ func (d *DB) HasDirectRoute(fromIata, alliance string, toIatas map[string]bool)
 	}
 	return result
 }
+
+// StopoverProgram is an airline's free hub stopover offer
+type StopoverProgram struct {
+	AirlineCode      string `json:"airlineCode"`
+	Airline          string `json:"airline"`
+	Program          string `json:"program"`
+	Alliance         string `json:"alliance"`
+	HubCity          string `json:"hubCity"`
+	MaxNights        int    `json:"maxNights"`
+	FareRestrictions string `json:"fareRestrictions"`
+}
+
+// GetStopoverPrograms returns free stopover programs for an alliance.
+// "None" (any) returns every program; "ALL" returns programs of alliance members only.
+func (d *DB) GetStopoverPrograms(ctx context.Context, alliance string) ([]StopoverProgram, error) {
+	query := "SELECT airline_code, airline, program, alliance, hub_city, max_nights, fare_restrictions FROM stopover_programs"
+	var args []interface{}
+	switch alliance {
+	case "", "None":
+	case "ALL":
+		query += " WHERE alliance != 'None'"
+	default:
+		query += " WHERE alliance = ?"
+		args = append(args, alliance)
+	}
+	query += " ORDER BY hub_city, airline_code"
+	rows, err := d.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var programs []StopoverProgram
+	for rows.Next() {
+		var p StopoverProgram
+		if err := rows.Scan(&p.AirlineCode, &p.Airline, &p.Program, &p.Alliance, &p.HubCity, &p.MaxNights, &p.FareRestrictions); err != nil {
+			log.Println(err)
+			continue
+		}
+		programs = append(programs, p)
+	}
+	return programs, ContextError(ctx, rows.Err())
+}
+
+// Airport is a row of the airports master table
+type Airport struct {
+	IATA      string  `json:"iata"`
+	Name      string  `json:"name"`
+	CityCode  string  `json:"cityCode"`
+	Country   string  `json:"country"`
+	Continent string  `json:"continent"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// GetAirport returns an airport by IATA code; city codes resolve to their first airport.
+// Returns nil when the code is unknown.
+func (d *DB) GetAirport(ctx context.Context, code string) (*Airport, error) {
+	var a Airport
+	err := d.QueryRowContext(ctx, queryAirport, code).
+		Scan(&a.IATA, &a.Name, &a.CityCode, &a.Country, &a.Continent, &a.Latitude, &a.Longitude)
+	if err == sql.ErrNoRows {
+		err = d.QueryRowContext(ctx, queryCityAirport, code).
+			Scan(&a.IATA, &a.Name, &a.CityCode, &a.Country, &a.Continent, &a.Latitude, &a.Longitude)
+	}
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	return &a, nil
+}
+
+// ReferenceVersion returns the counter bumped on every change to iata_cities, city_routes or distances
+func (d *DB) ReferenceVersion(ctx context.Context) (int64, error) {
+	var v int64
+	err := d.QueryRowContext(ctx, queryReferenceVersion).Scan(&v)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return v, ContextError(ctx, err)
+}
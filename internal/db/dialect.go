@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// Dialect is the SQL flavour of the underlying database
+type Dialect string
+
+// Supported dialects
+const (
+	SQLite   Dialect = "sqlite"
+	Postgres Dialect = "postgres"
+)
+
+// Dialect returns the SQL flavour of the database
+func (d *DB) Dialect() Dialect {
+	return d.dialect
+}
+
+// Rebind rewrites ? placeholders for the database's dialect ($1, $2, ... on PostgreSQL).
+// Use it for statements run on a *sql.Tx; the DB methods rebind on their own.
+func (d *DB) Rebind(query string) string {
+	if d.dialect != Postgres || !strings.Contains(query, "?") {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	quoted := false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == '?' && !quoted:
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Query runs a query written with ? placeholders
+func (d *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return d.DB.Query(d.Rebind(query), args...)
+}
+
+// QueryRow runs a single-row query written with ? placeholders
+func (d *DB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return d.DB.QueryRow(d.Rebind(query), args...)
+}
+
+// Exec runs a statement written with ? placeholders
+func (d *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return d.DB.Exec(d.Rebind(query), args...)
+}
+
+// QueryContext is Query bound to ctx, using the prepared statement for hot queries;
+// errors after ctx is done are ErrTimeout or ErrCanceled
+func (d *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if stmt, ok := d.stmts[query]; ok {
+		rows, err := stmt.QueryContext(ctx, args...)
+		return rows, ContextError(ctx, err)
+	}
+	rows, err := d.DB.QueryContext(ctx, d.Rebind(query), args...)
+	return rows, ContextError(ctx, err)
+}
+
+// QueryRowContext is QueryRow bound to ctx; wrap Scan errors with ContextError
+func (d *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if stmt, ok := d.stmts[query]; ok {
+		return stmt.QueryRowContext(ctx, args...)
+	}
+	return d.DB.QueryRowContext(ctx, d.Rebind(query), args...)
+}
+
+// ExecContext is Exec bound to ctx; errors after ctx is done are ErrTimeout or ErrCanceled
+func (d *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if stmt, ok := d.stmts[query]; ok {
+		res, err := stmt.ExecContext(ctx, args...)
+		return res, ContextError(ctx, err)
+	}
+	res, err := d.DB.ExecContext(ctx, d.Rebind(query), args...)
+	return res, ContextError(ctx, err)
+}
+
+// TableExists reports whether a table exists in the database (current schema on PostgreSQL)
+func (d *DB) TableExists(name string) (bool, error) {
+	query := "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?"
+	if d.dialect == Postgres {
+		query = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?"
+	}
+	var n int
+	err := d.QueryRow(query, name).Scan(&n)
+	return n > 0, err
+}
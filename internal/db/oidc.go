@@ -0,0 +1,160 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// OIDCIdentity links an account at an OpenID Connect provider (issuer + subject) to a user
+type OIDCIdentity struct {
+	Issuer      string     `json:"issuer"`
+	Subject     string     `json:"subject"`
+	UserID      int64      `json:"-"`
+	Email       string     `json:"email,omitempty"` // as the provider last reported it
+	CreatedAt   time.Time  `json:"createdAt"`
+	LastLoginAt *time.Time `json:"lastLoginAt"`
+}
+
+// OIDCLogin is a sign-in waiting for the identity provider's callback; only its state's hash
+// is stored
+type OIDCLogin struct {
+	StateHash    string
+	Nonce        string
+	CodeVerifier string // PKCE
+	UserID       int64  // the user linking an identity; 0 for sign-in
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+}
+
+// SaveOIDCLogin stores a login in flight, dropping expired ones on the way
+func (d *DB) SaveOIDCLogin(ctx context.Context, l OIDCLogin) error {
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return ContextError(ctx, err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, d.Rebind("DELETE FROM oidc_logins WHERE expires_at <= ?"), l.CreatedAt.UTC()); err != nil {
+		return ContextError(ctx, err)
+	}
+	var userID interface{}
+	if l.UserID != 0 {
+		userID = l.UserID
+	}
+	if _, err := tx.ExecContext(ctx, d.Rebind(`INSERT INTO oidc_logins (state_hash, nonce, code_verifier, user_id, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)`), l.StateHash, l.Nonce, l.CodeVerifier, userID, l.CreatedAt.UTC(), l.ExpiresAt.UTC()); err != nil {
+		return ContextError(ctx, err)
+	}
+	return ContextError(ctx, tx.Commit())
+}
+
+// TakeOIDCLogin removes and returns the unexpired login with a state hash, or nil if there is
+// none; each state is answered once
+func (d *DB) TakeOIDCLogin(ctx context.Context, stateHash string, at time.Time) (*OIDCLogin, error) {
+	l := OIDCLogin{StateHash: stateHash}
+	var userID sql.NullInt64
+	err := d.QueryRowContext(ctx, `DELETE FROM oidc_logins WHERE state_hash = ? AND expires_at > ?
+		RETURNING nonce, code_verifier, user_id, created_at, expires_at`, stateHash, at.UTC()).
+		Scan(&l.Nonce, &l.CodeVerifier, &userID, &l.CreatedAt, &l.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	l.UserID = userID.Int64
+	return &l, nil
+}
+
+// EnsureOIDCUser returns the user an identity is linked to, recording the sign-in, or creates
+// one for it on its first sign-in. email is the provider's verified address for the identity
+// ("" if it has none); a new user gets it unless another user already has it, since accounts
+// aren't merged.
+func (d *DB) EnsureOIDCUser(ctx context.Context, ident OIDCIdentity, email string) (*User, error) {
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	defer tx.Rollback()
+	at := ident.CreatedAt.UTC()
+	var userID int64
+	err = tx.QueryRowContext(ctx, d.Rebind(`UPDATE oidc_identities SET email = ?, last_login_at = ?
+		WHERE issuer = ? AND subject = ? RETURNING user_id`), ident.Email, at, ident.Issuer, ident.Subject).Scan(&userID)
+	switch {
+	case err == sql.ErrNoRows:
+		var newEmail interface{}
+		if email != "" {
+			var owner int64
+			err := tx.QueryRowContext(ctx, d.Rebind("SELECT id FROM users WHERE email = ?"), email).Scan(&owner)
+			switch {
+			case err == sql.ErrNoRows:
+				newEmail = email
+			case err != nil:
+				return nil, ContextError(ctx, err)
+			}
+		}
+		if err := tx.QueryRowContext(ctx, d.Rebind("INSERT INTO users (email) VALUES (?) RETURNING id"), newEmail).Scan(&userID); err != nil {
+			return nil, ContextError(ctx, err)
+		}
+		if _, err := tx.ExecContext(ctx, d.Rebind(`INSERT INTO oidc_identities (issuer, subject, user_id, email, created_at, last_login_at)
+			VALUES (?, ?, ?, ?, ?, ?)`), ident.Issuer, ident.Subject, userID, ident.Email, at, at); err != nil {
+			return nil, ContextError(ctx, err)
+		}
+	case err != nil:
+		return nil, ContextError(ctx, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	return d.GetUser(ctx, userID)
+}
+
+// LinkOIDCIdentity links an identity to a user so it can sign in with it; ErrIdentityInUse if
+// it already belongs to another user
+func (d *DB) LinkOIDCIdentity(ctx context.Context, ident OIDCIdentity) error {
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return ContextError(ctx, err)
+	}
+	defer tx.Rollback()
+	var owner int64
+	err = tx.QueryRowContext(ctx, d.Rebind("SELECT user_id FROM oidc_identities WHERE issuer = ? AND subject = ?"),
+		ident.Issuer, ident.Subject).Scan(&owner)
+	switch {
+	case err == nil && owner != ident.UserID:
+		return ErrIdentityInUse
+	case err == nil:
+		_, err = tx.ExecContext(ctx, d.Rebind("UPDATE oidc_identities SET email = ? WHERE issuer = ? AND subject = ?"),
+			ident.Email, ident.Issuer, ident.Subject)
+	case err == sql.ErrNoRows:
+		_, err = tx.ExecContext(ctx, d.Rebind(`INSERT INTO oidc_identities (issuer, subject, user_id, email, created_at)
+			VALUES (?, ?, ?, ?, ?)`), ident.Issuer, ident.Subject, ident.UserID, ident.Email, ident.CreatedAt.UTC())
+	}
+	if err != nil {
+		return ContextError(ctx, err)
+	}
+	return ContextError(ctx, tx.Commit())
+}
+
+// ListOIDCIdentities returns the identities linked to a user, oldest first
+func (d *DB) ListOIDCIdentities(ctx context.Context, userID int64) ([]OIDCIdentity, error) {
+	rows, err := d.QueryContext(ctx, `SELECT issuer, subject, user_id, email, created_at, last_login_at
+		FROM oidc_identities WHERE user_id = ? ORDER BY created_at, issuer, subject`, userID)
+	if err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	defer rows.Close()
+	out := []OIDCIdentity{}
+	for rows.Next() {
+		var ident OIDCIdentity
+		var lastLogin sql.NullTime
+		if err := rows.Scan(&ident.Issuer, &ident.Subject, &ident.UserID, &ident.Email, &ident.CreatedAt, &lastLogin); err != nil {
+			return nil, ContextError(ctx, err)
+		}
+		if lastLogin.Valid {
+			ident.LastLoginAt = &lastLogin.Time
+		}
+		out = append(out, ident)
+	}
+	return out, ContextError(ctx, rows.Err())
+}
@@ -0,0 +1,174 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Passkey is a WebAuthn credential a user signs in with
+type Passkey struct {
+	ID             int64      `json:"id"`
+	UserID         int64      `json:"-"`
+	CredentialID   string     `json:"-"` // base64url
+	PublicKey      []byte     `json:"-"` // COSE_Key
+	SignCount      uint32     `json:"-"`
+	BackupEligible bool       `json:"backupEligible"` // synced passkey (e.g. iCloud Keychain, Google Password Manager)
+	BackedUp       bool       `json:"backedUp"`
+	Transports     string     `json:"transports"` // comma-separated hints for the browser, e.g. "internal,hybrid"
+	Name           string     `json:"name"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	LastUsedAt     *time.Time `json:"lastUsedAt"`
+}
+
+// Passkey challenge purposes
+const (
+	PasskeyRegister = "register"
+	PasskeyLogin    = "login"
+)
+
+// PasskeyChallenge is a challenge issued for a passkey ceremony; only its hash is stored
+type PasskeyChallenge struct {
+	Hash      string
+	Purpose   string // PasskeyRegister or PasskeyLogin
+	UserID    int64  // the user registering; 0 for sign-in
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// ErrPasskeyExists is returned when registering a credential that is already registered
+var ErrPasskeyExists = errors.New("passkey already registered")
+
+const passkeyCols = "id, user_id, credential_id, public_key, sign_count, backup_eligible, backed_up, transports, name, created_at, last_used_at"
+
+// SavePasskeyChallenge stores a challenge, dropping expired ones on the way
+func (d *DB) SavePasskeyChallenge(ctx context.Context, ch PasskeyChallenge) error {
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return ContextError(ctx, err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, d.Rebind("DELETE FROM passkey_challenges WHERE expires_at <= ?"), ch.CreatedAt.UTC()); err != nil {
+		return ContextError(ctx, err)
+	}
+	var userID interface{}
+	if ch.UserID != 0 {
+		userID = ch.UserID
+	}
+	if _, err := tx.ExecContext(ctx, d.Rebind(`INSERT INTO passkey_challenges (challenge_hash, purpose, user_id, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?)`), ch.Hash, ch.Purpose, userID, ch.CreatedAt.UTC(), ch.ExpiresAt.UTC()); err != nil {
+		return ContextError(ctx, err)
+	}
+	return ContextError(ctx, tx.Commit())
+}
+
+// TakePasskeyChallenge removes and returns an unexpired challenge issued for purpose, or nil
+// if there is none; each challenge is answered once
+func (d *DB) TakePasskeyChallenge(ctx context.Context, hash, purpose string, at time.Time) (*PasskeyChallenge, error) {
+	ch := PasskeyChallenge{Hash: hash, Purpose: purpose}
+	var userID sql.NullInt64
+	err := d.QueryRowContext(ctx, `DELETE FROM passkey_challenges
+		WHERE challenge_hash = ? AND purpose = ? AND expires_at > ?
+		RETURNING user_id, created_at, expires_at`, hash, purpose, at.UTC()).
+		Scan(&userID, &ch.CreatedAt, &ch.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	ch.UserID = userID.Int64
+	return &ch, nil
+}
+
+// AddPasskey stores a new passkey and sets its ID; ErrPasskeyExists if its credential ID is
+// already registered (to anyone)
+func (d *DB) AddPasskey(ctx context.Context, p *Passkey) error {
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return ContextError(ctx, err)
+	}
+	defer tx.Rollback()
+	var existing int64
+	err = tx.QueryRowContext(ctx, d.Rebind("SELECT id FROM passkeys WHERE credential_id = ?"), p.CredentialID).Scan(&existing)
+	switch {
+	case err == nil:
+		return ErrPasskeyExists
+	case err != sql.ErrNoRows:
+		return ContextError(ctx, err)
+	}
+	err = tx.QueryRowContext(ctx, d.Rebind(`INSERT INTO passkeys (user_id, credential_id, public_key, sign_count,
+		backup_eligible, backed_up, transports, name, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`),
+		p.UserID, p.CredentialID, p.PublicKey, int64(p.SignCount), p.BackupEligible, p.BackedUp, p.Transports, p.Name,
+		p.CreatedAt.UTC()).Scan(&p.ID)
+	if err != nil {
+		return ContextError(ctx, err)
+	}
+	return ContextError(ctx, tx.Commit())
+}
+
+// GetPasskey returns the passkey with a credential ID, or nil if there is none
+func (d *DB) GetPasskey(ctx context.Context, credentialID string) (*Passkey, error) {
+	rows, err := d.QueryContext(ctx, "SELECT "+passkeyCols+" FROM passkeys WHERE credential_id = ?", credentialID)
+	if err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	keys, err := scanPasskeys(ctx, rows)
+	if err != nil || len(keys) == 0 {
+		return nil, err
+	}
+	return &keys[0], nil
+}
+
+// ListPasskeys returns a user's passkeys, oldest first
+func (d *DB) ListPasskeys(ctx context.Context, userID int64) ([]Passkey, error) {
+	rows, err := d.QueryContext(ctx, "SELECT "+passkeyCols+" FROM passkeys WHERE user_id = ? ORDER BY created_at, id", userID)
+	if err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	return scanPasskeys(ctx, rows)
+}
+
+func scanPasskeys(ctx context.Context, rows *sql.Rows) ([]Passkey, error) {
+	defer rows.Close()
+	keys := []Passkey{}
+	for rows.Next() {
+		var p Passkey
+		var count int64
+		var lastUsed sql.NullTime
+		if err := rows.Scan(&p.ID, &p.UserID, &p.CredentialID, &p.PublicKey, &count, &p.BackupEligible, &p.BackedUp,
+			&p.Transports, &p.Name, &p.CreatedAt, &lastUsed); err != nil {
+			return nil, ContextError(ctx, err)
+		}
+		p.SignCount = uint32(count)
+		if lastUsed.Valid {
+			p.LastUsedAt = &lastUsed.Time
+		}
+		keys = append(keys, p)
+	}
+	return keys, ContextError(ctx, rows.Err())
+}
+
+// UsePasskey records a sign-in with a passkey: its new sign count, backup state and time. ok is
+// false if the sign count changed since prevCount was read, i.e. another sign-in with the same
+// count won the race.
+func (d *DB) UsePasskey(ctx context.Context, id int64, prevCount, signCount uint32, backedUp bool, at time.Time) (ok bool, err error) {
+	res, err := d.ExecContext(ctx, `UPDATE passkeys SET sign_count = ?, backed_up = ?, last_used_at = ?
+		WHERE id = ? AND sign_count = ?`, int64(signCount), backedUp, at.UTC(), id, int64(prevCount))
+	if err != nil {
+		return false, ContextError(ctx, err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DeletePasskey removes one of a user's passkeys; ok is false if it isn't theirs
+func (d *DB) DeletePasskey(ctx context.Context, userID, id int64) (ok bool, err error) {
+	res, err := d.ExecContext(ctx, "DELETE FROM passkeys WHERE id = ? AND user_id = ?", id, userID)
+	if err != nil {
+		return false, ContextError(ctx, err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
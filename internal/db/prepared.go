@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// hotQueries run on nearly every request, so they are parsed and planned once rather than per call
+var hotQueries = []string{
+	queryUserByPhone, queryUserByEmail, queryEnsureUser, queryEnsureEmail,
+	queryCreateSession, querySession, queryTouchSession,
+	querySaveOTP, queryGetOTP, queryCountOTP, queryDeleteOTP, queryUseOTP,
+	queryGetOTPLockout, querySaveOTPLockout, queryDeleteOTPLockout,
+	queryListFlights, queryAddFlight, queryDeleteFlight,
+	queryCityForAirport, queryAirportsForCity, queryDistancesFrom, queryRoutesFrom,
+	queryAirport, queryCityAirport, queryReferenceVersion,
+}
+
+// PrepareStatements prepares the hot queries; QueryContext, QueryRowContext and ExecContext then
+// run them as prepared statements. Call it once after migrations, before serving requests.
+func (d *DB) PrepareStatements(ctx context.Context) error {
+	stmts := make(map[string]*sql.Stmt, len(hotQueries))
+	for _, q := range hotQueries {
+		stmt, err := d.DB.PrepareContext(ctx, d.Rebind(q))
+		if err != nil {
+			for _, s := range stmts {
+				s.Close()
+			}
+			return fmt.Errorf("prepare %q: %w", q, err)
+		}
+		stmts[q] = stmt
+	}
+	d.stmts = stmts
+	return nil
+}
+
+// Close closes the prepared statements and the database
+func (d *DB) Close() error {
+	for _, s := range d.stmts {
+		s.Close()
+	}
+	d.stmts = nil
+	return d.DB.Close()
+}
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// Profile is a user's name and travel preferences; searches they make use the preferences as
+// defaults. Lists are codes in the user's order of preference.
+type Profile struct {
+	DisplayName       string     `json:"displayName"`
+	HomeAirports      []string   `json:"homeAirports"`      // IATA airport or city codes; the first is the default start
+	PreferredAlliance string     `json:"preferredAlliance"` // a city_routes alliance; "" for no preference
+	PreferredCabin    string     `json:"preferredCabin"`    // economy, premium, business or first; "" for no preference
+	LoyaltyPrograms   []string   `json:"loyaltyPrograms"`   // award program codes
+	Nationalities     []string   `json:"nationalities"`     // ISO 3166-1 alpha-2 passport countries
+	GroundRadius      float64    `json:"groundRadius"`      // miles you'd drive or take the train; 0 for the search default
+	UpdatedAt         *time.Time `json:"updatedAt"`         // nil until the profile is first saved
+}
+
+// GetProfile returns a user's profile; a user who never saved one gets an empty profile
+func (d *DB) GetProfile(ctx context.Context, userID int64) (*Profile, error) {
+	var p Profile
+	var homes, programs, nationalities string
+	var updated time.Time
+	err := d.QueryRowContext(ctx, `SELECT display_name, home_airports, preferred_alliance, preferred_cabin,
+		loyalty_programs, nationalities, ground_radius_miles, updated_at FROM user_profiles WHERE user_id = ?`, userID).
+		Scan(&p.DisplayName, &homes, &p.PreferredAlliance, &p.PreferredCabin, &programs, &nationalities, &p.GroundRadius, &updated)
+	if err == sql.ErrNoRows {
+		return &Profile{HomeAirports: []string{}, LoyaltyPrograms: []string{}, Nationalities: []string{}}, nil
+	}
+	if err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	p.HomeAirports, p.LoyaltyPrograms, p.Nationalities = splitCodes(homes), splitCodes(programs), splitCodes(nationalities)
+	p.UpdatedAt = &updated
+	return &p, nil
+}
+
+// SaveProfile replaces a user's profile and sets p.UpdatedAt to at
+func (d *DB) SaveProfile(ctx context.Context, userID int64, p *Profile, at time.Time) error {
+	at = at.UTC()
+	_, err := d.ExecContext(ctx, `INSERT INTO user_profiles (user_id, display_name, home_airports, preferred_alliance,
+			preferred_cabin, loyalty_programs, nationalities, ground_radius_miles, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET display_name = excluded.display_name, home_airports = excluded.home_airports,
+			preferred_alliance = excluded.preferred_alliance, preferred_cabin = excluded.preferred_cabin,
+			loyalty_programs = excluded.loyalty_programs, nationalities = excluded.nationalities,
+			ground_radius_miles = excluded.ground_radius_miles, updated_at = excluded.updated_at`,
+		userID, p.DisplayName, strings.Join(p.HomeAirports, ","), p.PreferredAlliance, p.PreferredCabin,
+		strings.Join(p.LoyaltyPrograms, ","), strings.Join(p.Nationalities, ","), p.GroundRadius, at)
+	if err != nil {
+		return ContextError(ctx, err)
+	}
+	p.UpdatedAt = &at
+	return nil
+}
+
+// splitCodes splits a comma-separated column; "" is an empty list
+func splitCodes(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
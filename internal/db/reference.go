@@ -0,0 +1,131 @@
+package db
+
+import "context"
+
+// CityAirport maps one airport to its city
+type CityAirport struct {
+	CityCode    string `json:"city"`
+	AirportCode string `json:"airport"`
+}
+
+// Route is one city_routes row
+type Route struct {
+	From     string `json:"from"`
+	Alliance string `json:"alliance"`
+	To       string `json:"to"`
+}
+
+// Distance is one distances row (stored in one direction; lookups treat it as symmetric)
+type Distance struct {
+	From  string  `json:"from"`
+	To    string  `json:"to"`
+	Miles float64 `json:"miles"`
+}
+
+// ListCities returns the distinct city codes
+func (d *DB) ListCities(ctx context.Context) ([]string, error) {
+	rows, err := d.QueryContext(ctx, "SELECT DISTINCT city_code FROM iata_cities ORDER BY city_code")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var cities []string
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		cities = append(cities, code)
+	}
+	return cities, ContextError(ctx, rows.Err())
+}
+
+// ListCityAirports returns every iata_cities row ordered by city and airport
+func (d *DB) ListCityAirports(ctx context.Context) ([]CityAirport, error) {
+	return d.listCityAirports(ctx, "SELECT city_code, airport_code FROM iata_cities ORDER BY city_code, airport_code")
+}
+
+// ListRoutes returns every city_routes row ordered by origin, alliance and destination
+func (d *DB) ListRoutes(ctx context.Context) ([]Route, error) {
+	return d.listRoutes(ctx, "SELECT city_iata, alliance, route_to FROM city_routes ORDER BY city_iata, alliance, route_to")
+}
+
+// ListDistances returns every distances row as stored, ordered by from and to
+func (d *DB) ListDistances(ctx context.Context) ([]Distance, error) {
+	return d.listDistances(ctx, "SELECT from_iata, to_iata, distance_miles FROM distances ORDER BY from_iata, to_iata")
+}
+
+// ListAirports returns every airport ordered by IATA code
+func (d *DB) ListAirports(ctx context.Context) ([]Airport, error) {
+	return d.listAirports(ctx, "SELECT iata_code, name, city_code, country, continent, latitude, longitude FROM airports ORDER BY iata_code")
+}
+
+// The list helpers scan the live tables and their snapshot copies alike
+
+func (d *DB) listCityAirports(ctx context.Context, query string, args ...interface{}) ([]CityAirport, error) {
+	rows, err := d.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []CityAirport
+	for rows.Next() {
+		var c CityAirport
+		if err := rows.Scan(&c.CityCode, &c.AirportCode); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, ContextError(ctx, rows.Err())
+}
+
+func (d *DB) listRoutes(ctx context.Context, query string, args ...interface{}) ([]Route, error) {
+	rows, err := d.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Route
+	for rows.Next() {
+		var r Route
+		if err := rows.Scan(&r.From, &r.Alliance, &r.To); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, ContextError(ctx, rows.Err())
+}
+
+func (d *DB) listDistances(ctx context.Context, query string, args ...interface{}) ([]Distance, error) {
+	rows, err := d.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Distance
+	for rows.Next() {
+		var x Distance
+		if err := rows.Scan(&x.From, &x.To, &x.Miles); err != nil {
+			return nil, err
+		}
+		out = append(out, x)
+	}
+	return out, ContextError(ctx, rows.Err())
+}
+
+func (d *DB) listAirports(ctx context.Context, query string, args ...interface{}) ([]Airport, error) {
+	rows, err := d.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Airport
+	for rows.Next() {
+		var a Airport
+		if err := rows.Scan(&a.IATA, &a.Name, &a.CityCode, &a.Country, &a.Continent, &a.Latitude, &a.Longitude); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, ContextError(ctx, rows.Err())
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"log"
+)
+
+// RTWRuleSet is a round-the-world ticket rule set; zero numeric limits are not enforced
+type RTWRuleSet struct {
+	Code                 string `json:"code"`
+	Name                 string `json:"name"`
+	Alliance             string `json:"alliance"`
+	MinContinents        int    `json:"minContinents"`
+	MaxContinents        int    `json:"maxContinents"`
+	MaxMiles             int    `json:"maxMiles"`
+	MinSegments          int    `json:"minSegments"`
+	MaxSegments          int    `json:"maxSegments"`
+	OneDirection         bool   `json:"oneDirection"`
+	MaxAtlanticCrossings int    `json:"maxAtlanticCrossings"`
+	MaxPacificCrossings  int    `json:"maxPacificCrossings"`
+}
+
+const rtwColumns = "code, name, alliance, min_continents, max_continents, max_miles, min_segments, max_segments, one_direction, max_atlantic_crossings, max_pacific_crossings"
+
+func scanRTWRuleSet(row interface{ Scan(...interface{}) error }) (RTWRuleSet, error) {
+	var r RTWRuleSet
+	err := row.Scan(&r.Code, &r.Name, &r.Alliance, &r.MinContinents, &r.MaxContinents, &r.MaxMiles,
+		&r.MinSegments, &r.MaxSegments, &r.OneDirection, &r.MaxAtlanticCrossings, &r.MaxPacificCrossings)
+	return r, err
+}
+
+// GetRTWRuleSets returns all round-the-world rule sets
+func (d *DB) GetRTWRuleSets(ctx context.Context) ([]RTWRuleSet, error) {
+	rows, err := d.QueryContext(ctx, "SELECT "+rtwColumns+" FROM rtw_rule_sets ORDER BY code")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var sets []RTWRuleSet
+	for rows.Next() {
+		r, err := scanRTWRuleSet(rows)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		sets = append(sets, r)
+	}
+	return sets, ContextError(ctx, rows.Err())
+}
+
+// GetRTWRuleSet returns one rule set by code, or nil if unknown
+func (d *DB) GetRTWRuleSet(ctx context.Context, code string) (*RTWRuleSet, error) {
+	r, err := scanRTWRuleSet(d.QueryRowContext(ctx, "SELECT "+rtwColumns+" FROM rtw_rule_sets WHERE code = ?", code))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	return &r, nil
+}
@@ -0,0 +1,174 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrSnapshotNotFound is returned when reading a snapshot that doesn't exist
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// Snapshot describes a frozen copy of the route data: iata_cities, city_routes, distances and airports
+type Snapshot struct {
+	ID           int64     `json:"id"`
+	Source       string    `json:"source"`    // what produced the data, e.g. "seed" or the import files
+	CreatedAt    time.Time `json:"createdAt"` // when the snapshot was taken, right after the import
+	Version      int64     `json:"referenceVersion"`
+	CityAirports int       `json:"cityAirports"`
+	Routes       int       `json:"routes"`
+	Distances    int       `json:"distances"`
+	Airports     int       `json:"airports"`
+}
+
+const snapshotCols = "id, source, created_at, reference_version, city_airports, routes, distances, airports"
+
+// snapshotCopies copies each live route table into its snapshot table (the cast tells
+// PostgreSQL the type of a parameter in a select list)
+var snapshotCopies = []string{
+	`INSERT INTO snapshot_iata_cities (snapshot_id, city_code, airport_code)
+		SELECT CAST(? AS BIGINT), city_code, airport_code FROM iata_cities`,
+	`INSERT INTO snapshot_city_routes (snapshot_id, city_iata, alliance, route_to)
+		SELECT CAST(? AS BIGINT), city_iata, alliance, route_to FROM city_routes`,
+	`INSERT INTO snapshot_distances (snapshot_id, from_iata, to_iata, distance_miles)
+		SELECT CAST(? AS BIGINT), from_iata, to_iata, distance_miles FROM distances`,
+	`INSERT INTO snapshot_airports (snapshot_id, iata_code, name, city_code, country, continent, latitude, longitude)
+		SELECT CAST(? AS BIGINT), iata_code, name, city_code, country, continent, latitude, longitude FROM airports`,
+}
+
+// CreateSnapshot copies the current route data into a new snapshot in one transaction
+func (d *DB) CreateSnapshot(ctx context.Context, source string) (*Snapshot, error) {
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	defer tx.Rollback()
+	s := &Snapshot{Source: source, CreatedAt: time.Now().UTC()}
+	if err := tx.QueryRowContext(ctx, d.Rebind(queryReferenceVersion)).Scan(&s.Version); err != nil && err != sql.ErrNoRows {
+		return nil, ContextError(ctx, err)
+	}
+	err = tx.QueryRowContext(ctx, d.Rebind(`INSERT INTO dataset_snapshots
+		(source, created_at, reference_version, city_airports, routes, distances, airports)
+		VALUES (?, ?, ?, 0, 0, 0, 0) RETURNING id`), s.Source, s.CreatedAt, s.Version).Scan(&s.ID)
+	if err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	counts := []*int{&s.CityAirports, &s.Routes, &s.Distances, &s.Airports}
+	for i, query := range snapshotCopies {
+		res, err := tx.ExecContext(ctx, d.Rebind(query), s.ID)
+		if err != nil {
+			return nil, ContextError(ctx, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		*counts[i] = int(n)
+	}
+	if _, err := tx.ExecContext(ctx, d.Rebind(`UPDATE dataset_snapshots
+		SET city_airports = ?, routes = ?, distances = ?, airports = ? WHERE id = ?`),
+		s.CityAirports, s.Routes, s.Distances, s.Airports, s.ID); err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	return s, nil
+}
+
+// ListSnapshots returns every snapshot, newest first
+func (d *DB) ListSnapshots(ctx context.Context) ([]Snapshot, error) {
+	rows, err := d.QueryContext(ctx, "SELECT "+snapshotCols+" FROM dataset_snapshots ORDER BY id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []Snapshot{}
+	for rows.Next() {
+		var s Snapshot
+		if err := rows.Scan(&s.ID, &s.Source, &s.CreatedAt, &s.Version, &s.CityAirports, &s.Routes, &s.Distances, &s.Airports); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, ContextError(ctx, rows.Err())
+}
+
+// GetSnapshot returns a snapshot by ID, or nil if there is none
+func (d *DB) GetSnapshot(ctx context.Context, id int64) (*Snapshot, error) {
+	var s Snapshot
+	err := d.QueryRowContext(ctx, "SELECT "+snapshotCols+" FROM dataset_snapshots WHERE id = ?", id).
+		Scan(&s.ID, &s.Source, &s.CreatedAt, &s.Version, &s.CityAirports, &s.Routes, &s.Distances, &s.Airports)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	return &s, nil
+}
+
+// DeleteSnapshot removes a snapshot and its rows; ok is false if it doesn't exist
+func (d *DB) DeleteSnapshot(ctx context.Context, id int64) (ok bool, err error) {
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return false, ContextError(ctx, err)
+	}
+	defer tx.Rollback()
+	// Delete the rows explicitly; foreign keys (and so ON DELETE CASCADE) may be off in SQLite
+	for _, table := range []string{"snapshot_iata_cities", "snapshot_city_routes", "snapshot_distances", "snapshot_airports"} {
+		if _, err := tx.ExecContext(ctx, d.Rebind("DELETE FROM "+table+" WHERE snapshot_id = ?"), id); err != nil {
+			return false, ContextError(ctx, err)
+		}
+	}
+	res, err := tx.ExecContext(ctx, d.Rebind("DELETE FROM dataset_snapshots WHERE id = ?"), id)
+	if err != nil {
+		return false, ContextError(ctx, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, ContextError(ctx, tx.Commit())
+}
+
+// SnapshotData reads one snapshot's copy of the route data. Its ReferenceVersion is the
+// version the live data had when the snapshot was taken.
+func (d *DB) SnapshotData(id int64) RouteData {
+	return snapshotData{d: d, id: id}
+}
+
+type snapshotData struct {
+	d  *DB
+	id int64
+}
+
+func (s snapshotData) ReferenceVersion(ctx context.Context) (int64, error) {
+	var v int64
+	err := s.d.QueryRowContext(ctx, "SELECT reference_version FROM dataset_snapshots WHERE id = ?", s.id).Scan(&v)
+	if err == sql.ErrNoRows {
+		return 0, ErrSnapshotNotFound
+	}
+	return v, ContextError(ctx, err)
+}
+
+func (s snapshotData) ListCityAirports(ctx context.Context) ([]CityAirport, error) {
+	return s.d.listCityAirports(ctx, `SELECT city_code, airport_code FROM snapshot_iata_cities
+		WHERE snapshot_id = ? ORDER BY city_code, airport_code`, s.id)
+}
+
+func (s snapshotData) ListRoutes(ctx context.Context) ([]Route, error) {
+	return s.d.listRoutes(ctx, `SELECT city_iata, alliance, route_to FROM snapshot_city_routes
+		WHERE snapshot_id = ? ORDER BY city_iata, alliance, route_to`, s.id)
+}
+
+func (s snapshotData) ListDistances(ctx context.Context) ([]Distance, error) {
+	return s.d.listDistances(ctx, `SELECT from_iata, to_iata, distance_miles FROM snapshot_distances
+		WHERE snapshot_id = ? ORDER BY from_iata, to_iata`, s.id)
+}
+
+func (s snapshotData) ListAirports(ctx context.Context) ([]Airport, error) {
+	return s.d.listAirports(ctx, `SELECT iata_code, name, city_code, country, continent, latitude, longitude FROM snapshot_airports
+		WHERE snapshot_id = ? ORDER BY iata_code`, s.id)
+}
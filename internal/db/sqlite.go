@@ -0,0 +1,126 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Options tunes the connection pool and, on SQLite, the pragmas every connection runs.
+// The zero value keeps the driver defaults.
+type Options struct {
+	JournalMode string        // SQLite journal_mode: WAL, DELETE, TRUNCATE, ...; empty keeps the file's mode
+	Synchronous string        // SQLite synchronous: OFF, NORMAL, FULL or EXTRA; empty keeps FULL
+	BusyTimeout time.Duration // how long SQLite waits for a lock before failing with "database is locked"
+	ForeignKeys bool          // enforce FOREIGN KEY constraints on SQLite (PostgreSQL always does)
+	ImmediateTx bool          // begin SQLite transactions with BEGIN IMMEDIATE so writers queue on BusyTimeout
+
+	MaxOpenConns    int           // pool limit; 0 is unlimited
+	MaxIdleConns    int           // idle connections kept open; 0 keeps database/sql's default of 2
+	ConnMaxIdleTime time.Duration // close connections idle this long; 0 keeps them
+}
+
+// DefaultOptions is the production configuration: WAL lets readers run alongside a writer,
+// and concurrent writers wait for each other instead of failing.
+func DefaultOptions() Options {
+	return Options{
+		JournalMode:     "WAL",
+		Synchronous:     "NORMAL",
+		BusyTimeout:     5 * time.Second,
+		ForeignKeys:     true,
+		ImmediateTx:     true,
+		MaxOpenConns:    8,
+		MaxIdleConns:    8,
+		ConnMaxIdleTime: 5 * time.Minute,
+	}
+}
+
+var (
+	journalModes = []string{"DELETE", "TRUNCATE", "PERSIST", "MEMORY", "WAL", "OFF"}
+	syncLevels   = []string{"OFF", "NORMAL", "FULL", "EXTRA"}
+)
+
+// sqliteDSN builds a modernc.org/sqlite DSN that applies opts to every new connection
+func sqliteDSN(path string, opts Options) (string, error) {
+	q := url.Values{}
+	if opts.BusyTimeout > 0 {
+		// First, so the pragmas below already wait for locks
+		q.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", opts.BusyTimeout.Milliseconds()))
+	}
+	if opts.JournalMode != "" {
+		mode, err := oneOf("journal mode", opts.JournalMode, journalModes)
+		if err != nil {
+			return "", err
+		}
+		q.Add("_pragma", "journal_mode("+mode+")")
+	}
+	if opts.Synchronous != "" {
+		level, err := oneOf("synchronous level", opts.Synchronous, syncLevels)
+		if err != nil {
+			return "", err
+		}
+		q.Add("_pragma", "synchronous("+level+")")
+	}
+	if opts.ForeignKeys {
+		q.Add("_pragma", "foreign_keys(1)")
+	}
+	if opts.ImmediateTx {
+		q.Set("_txlock", "immediate")
+	}
+	if len(q) == 0 {
+		return path, nil
+	}
+	return "file:" + path + "?" + q.Encode(), nil
+}
+
+func oneOf(what, value string, allowed []string) (string, error) {
+	v := strings.ToUpper(value)
+	for _, a := range allowed {
+		if v == a {
+			return v, nil
+		}
+	}
+	return "", fmt.Errorf("unknown SQLite %s %q (want one of %s)", what, value, strings.Join(allowed, ", "))
+}
+
+// applyPool sets the connection pool limits from opts
+func applyPool(db *sql.DB, opts Options) {
+	db.SetMaxOpenConns(opts.MaxOpenConns)
+	if opts.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(opts.MaxIdleConns)
+	}
+	db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+}
+
+// New opens the SQLite database at dataDir/db/data.sqlite3 configured by opts
+func New(dataDir string, opts Options) (*DB, error) {
+	return NewFile(Path(dataDir), opts)
+}
+
+// Path returns the SQLite database file under dataDir
+func Path(dataDir string) string {
+	return filepath.Join(dataDir, "db", "data.sqlite3")
+}
+
+// NewFile opens the SQLite database file at path configured by opts
+func NewFile(path string, opts Options) (*DB, error) {
+	dsn, err := sqliteDSN(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	applyPool(db, opts)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &DB{DB: db, dialect: SQLite}, nil
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// RouteData is the reference data the route graph is built from: the live tables, or a
+// dataset snapshot of them (see SnapshotStore.SnapshotData)
+type RouteData interface {
+	ReferenceVersion(ctx context.Context) (int64, error)
+	ListCityAirports(ctx context.Context) ([]CityAirport, error)
+	ListRoutes(ctx context.Context) ([]Route, error)
+	ListDistances(ctx context.Context) ([]Distance, error)
+	ListAirports(ctx context.Context) ([]Airport, error)
+}
+
+// ReferenceStore reads airports, cities, routes, distances and program data
+type ReferenceStore interface {
+	RouteData
+	GetCityForAirport(ctx context.Context, airportCode string) (string, error)
+	SameCity(ctx context.Context, a, b string) (bool, error)
+	GetAirportsForCity(ctx context.Context, cityCode string) ([]string, error)
+	GetDistancesFrom(ctx context.Context, fromIata string) (map[string]float64, error)
+	GetRoutesFrom(ctx context.Context, cityIata, alliance string) ([]string, error)
+	GetRoutesFromWithFallback(ctx context.Context, cityIata, alliance string) ([]string, error)
+	HasDirectRoute(ctx context.Context, fromIata, alliance string, toIatas map[string]bool) map[string]bool
+	GetAirport(ctx context.Context, code string) (*Airport, error)
+	ListCities(ctx context.Context) ([]string, error)
+
+	GetStopoverPrograms(ctx context.Context, alliance string) ([]StopoverProgram, error)
+	GetAwardPrograms(ctx context.Context, codes []string) ([]AwardProgram, error)
+	GetAwardBands(ctx context.Context, program string) ([]AwardBand, error)
+	GetAwardZones(ctx context.Context, program string) (map[string]string, error)
+	GetAwardZonePrices(ctx context.Context, program string) ([]AwardZonePrice, error)
+	GetRTWRuleSets(ctx context.Context) ([]RTWRuleSet, error)
+	GetRTWRuleSet(ctx context.Context, code string) (*RTWRuleSet, error)
+}
+
+// AuthStore keeps users, sessions, one-time codes, email sign-in links, passkeys and OpenID
+// Connect identities
+type AuthStore interface {
+	GetUser(ctx context.Context, id int64) (*User, error)
+	GetUserByPhone(ctx context.Context, phone string) (*User, error)
+	GetUserByEmail(ctx context.Context, email string) (*User, error)
+	EnsureUser(ctx context.Context, phone string) (*User, error)
+	EnsureUserByEmail(ctx context.Context, email string) (*User, error)
+	SetUserPhone(ctx context.Context, userID int64, phone string) error
+	SetUserEmail(ctx context.Context, userID int64, email string) error
+	DeleteUser(ctx context.Context, userID int64) (ok bool, err error)
+	CreateSession(ctx context.Context, s *Session, accessHash, refreshHash string) error
+	GetSession(ctx context.Context, accessHash string) (*Session, error)
+	RotateRefreshToken(ctx context.Context, refreshHash string, r TokenRotation) (*Session, error)
+	TouchSession(ctx context.Context, id int64, ip string, at time.Time) error
+	ListSessions(ctx context.Context, userID int64) ([]Session, error)
+	DeleteSession(ctx context.Context, userID, id int64) (ok bool, err error)
+	SaveOTP(ctx context.Context, o OTPCode, cooldown time.Duration) (ok bool, err error)
+	GetOTP(ctx context.Context, phone string) (*OTPCode, error)
+	CountOTPAttempt(ctx context.Context, phone string) (*OTPCode, error)
+	DeleteOTP(ctx context.Context, phone string) error
+	UseOTP(ctx context.Context, phone, codeHash string) (ok bool, err error)
+	GetOTPLockout(ctx context.Context, phone string) (*OTPLockout, error)
+	SaveOTPLockout(ctx context.Context, l OTPLockout) error
+	DeleteOTPLockout(ctx context.Context, phone string) error
+	SaveEmailLink(ctx context.Context, l EmailLink, cooldown time.Duration) (ok bool, err error)
+	LastEmailLink(ctx context.Context, email string) (time.Time, error)
+	UseEmailLink(ctx context.Context, tokenHash string, at time.Time) (*EmailLink, error)
+	DeleteEmailLink(ctx context.Context, tokenHash string) error
+	SavePasskeyChallenge(ctx context.Context, ch PasskeyChallenge) error
+	TakePasskeyChallenge(ctx context.Context, hash, purpose string, at time.Time) (*PasskeyChallenge, error)
+	AddPasskey(ctx context.Context, p *Passkey) error
+	GetPasskey(ctx context.Context, credentialID string) (*Passkey, error)
+	ListPasskeys(ctx context.Context, userID int64) ([]Passkey, error)
+	UsePasskey(ctx context.Context, id int64, prevCount, signCount uint32, backedUp bool, at time.Time) (ok bool, err error)
+	DeletePasskey(ctx context.Context, userID, id int64) (ok bool, err error)
+	SaveOIDCLogin(ctx context.Context, l OIDCLogin) error
+	TakeOIDCLogin(ctx context.Context, stateHash string, at time.Time) (*OIDCLogin, error)
+	EnsureOIDCUser(ctx context.Context, ident OIDCIdentity, email string) (*User, error)
+	LinkOIDCIdentity(ctx context.Context, ident OIDCIdentity) error
+	ListOIDCIdentities(ctx context.Context, userID int64) ([]OIDCIdentity, error)
+}
+
+// FlightStore keeps the flights users entered
+type FlightStore interface {
+	ListBookedFlights(ctx context.Context, userID int64) ([]BookedFlight, error)
+	AddBookedFlight(ctx context.Context, f *BookedFlight) error
+	DeleteBookedFlight(ctx context.Context, userID, id int64) (ok bool, err error)
+}
+
+// ProfileStore keeps users' names and travel preferences
+type ProfileStore interface {
+	GetProfile(ctx context.Context, userID int64) (*Profile, error)
+	SaveProfile(ctx context.Context, userID int64, p *Profile, at time.Time) error
+}
+
+// AdminStore edits reference data; every change is written to the audit log with its actor
+type AdminStore interface {
+	CodeUsage(ctx context.Context, code string) (*CodeUsage, error)
+	ListAirlines(ctx context.Context) ([]Airline, error)
+	ListAudit(ctx context.Context, entity string, limit int) ([]AuditEntry, error)
+
+	PutAirport(ctx context.Context, actor string, a Airport) error
+	DeleteAirport(ctx context.Context, actor, code string) (ok bool, err error)
+	AddCityAirport(ctx context.Context, actor string, c CityAirport) (ok bool, err error)
+	DeleteCityAirport(ctx context.Context, actor string, c CityAirport) (ok bool, err error)
+	AddRoute(ctx context.Context, actor string, r Route) (ok bool, err error)
+	DeleteRoute(ctx context.Context, actor string, r Route) (ok bool, err error)
+	PutDistance(ctx context.Context, actor string, x Distance) error
+	DeleteDistance(ctx context.Context, actor, from, to string) (ok bool, err error)
+	PutAirline(ctx context.Context, actor string, a Airline) error
+	DeleteAirline(ctx context.Context, actor, code string) (ok bool, err error)
+}
+
+// SnapshotStore keeps frozen copies of the route data so versions can be diffed and searches pinned
+type SnapshotStore interface {
+	CreateSnapshot(ctx context.Context, source string) (*Snapshot, error)
+	ListSnapshots(ctx context.Context) ([]Snapshot, error)
+	GetSnapshot(ctx context.Context, id int64) (*Snapshot, error)
+	DeleteSnapshot(ctx context.Context, id int64) (ok bool, err error)
+	SnapshotData(id int64) RouteData
+}
+
+// Store is everything the API needs from a database. *DB implements it for SQLite and PostgreSQL.
+type Store interface {
+	ReferenceStore
+	AuthStore
+	FlightStore
+	ProfileStore
+	AdminStore
+	SnapshotStore
+	Dialect() Dialect
+	Close() error
+}
+
+var _ Store = (*DB)(nil)
@@ -0,0 +1,42 @@
+-- Small reference data set the conformance checks assert against
+
+INSERT INTO iata_cities (city_code, airport_code) VALUES
+('LON','LCY'),('LON','LHR'),('NYC','JFK'),('NYC','LGA');
+
+INSERT INTO distances (from_iata, to_iata, distance_miles) VALUES
+('LHR','SOU',66.0),('SOU','LHR',70.0),('LON','SOU',68.0),('JFK','PHL',94.5);
+
+INSERT INTO city_routes (city_iata, alliance, route_to) VALUES
+('LON','ONE_WORLD','MAD'),('LON','ONE_WORLD','JFK'),
+('LHR','None','JFK'),('LHR','None','DOH'),('LCY','None','AMS'),('LCY','None','DOH');
+
+INSERT INTO stopover_programs (airline_code, airline, program, alliance, hub_city, max_nights, fare_restrictions) VALUES
+('QR','Qatar Airways','Discover Qatar','ONE_WORLD','DOH',4,'Any fare'),
+('FI','Icelandair','Stopover','None','REK',7,'');
+
+INSERT INTO airports (iata_code, name, city_code, country, continent, latitude, longitude) VALUES
+('LHR','London Heathrow','LON','GB','EU',51.47,-0.4543),
+('LCY','London City','LON','GB','EU',51.5048,0.0495),
+('SOU','Southampton','SOU','GB','EU',50.9503,-1.3568),
+('JFK','New York JFK','NYC','US','NA',40.6413,-73.7781),
+('DOH','Doha Hamad','DOH','QA','AS',25.2731,51.6081);
+
+INSERT INTO award_programs (code, name, alliance, chart_type, one_way_allowed, open_jaw_allowed, max_stopovers, stopover_points) VALUES
+('BA_AVIOS','British Airways Avios','ONE_WORLD','distance',1,1,0,0),
+('NH_MILEAGE_CLUB','ANA Mileage Club','STAR_ALLIANCE','zone',0,1,1,0);
+
+INSERT INTO award_distance_bands (program, cabin, min_miles, max_miles, points) VALUES
+('BA_AVIOS','economy',0,650,9000),('BA_AVIOS','economy',651,1150,11000),('BA_AVIOS','business',0,650,15500);
+
+INSERT INTO award_zones (program, area_type, area, zone) VALUES
+('NH_MILEAGE_CLUB','continent','EU','EU'),('NH_MILEAGE_CLUB','country','GB','EU'),('NH_MILEAGE_CLUB','continent','NA','NA');
+
+INSERT INTO award_zone_prices (program, cabin, from_zone, to_zone, points) VALUES
+('NH_MILEAGE_CLUB','economy','EU','NA',55000);
+
+INSERT INTO rtw_rule_sets (code, name, alliance, min_continents, max_continents, max_miles, min_segments, max_segments, one_direction, max_atlantic_crossings, max_pacific_crossings) VALUES
+('STAR_RTW_29K','Star Alliance RTW 29k','STAR_ALLIANCE',3,0,29000,3,16,1,1,1),
+('OW_EXPLORER','oneworld Explorer','ONE_WORLD',3,6,0,3,16,0,1,1);
+
+INSERT INTO airlines (iata_code, icao_code, name, country, alliance, active) VALUES
+('BA','BAW','British Airways','GB','ONE_WORLD',1);
@@ -0,0 +1,1255 @@
+// Package storetest is the conformance suite every db.Store implementation must pass. Load
+// Fixture into a freshly migrated database, then call Run; TestStore does both for SQLite
+// and, when TEST_DATABASE_URL is set, PostgreSQL.
+package storetest
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"triangle_travel/internal/db"
+)
+
+// Fixture is the reference data the checks expect (load with migrate.RefreshReference)
+//
+//go:embed fixture.sql
+var Fixture string
+
+// Result is the outcome of one check; Err is nil when it passed
+type Result struct {
+	Name string
+	Err  error
+}
+
+type check struct {
+	name string
+	fn   func(ctx context.Context, s db.Store) error
+}
+
+var checks = []check{
+	{"cities", checkCities},
+	{"airports-for-city", checkAirportsForCity},
+	{"distances-symmetric", checkDistances},
+	{"routes", checkRoutes},
+	{"airports", checkAirports},
+	{"stopover-programs", checkStopoverPrograms},
+	{"award-charts", checkAwardCharts},
+	{"rtw-rule-sets", checkRTWRuleSets},
+	{"reference-version", checkReferenceVersion},
+	{"users", checkUsers},
+	{"sessions", checkSessions},
+	{"refresh-tokens", checkRefreshTokens},
+	{"otp-codes", checkOTPCodes},
+	{"email-login", checkEmailLogin},
+	{"passkeys", checkPasskeys},
+	{"oidc", checkOIDC},
+	{"profiles", checkProfiles},
+	{"delete-user", checkDeleteUser},
+	{"booked-flights", checkBookedFlights},
+	{"admin-audit", checkAdminAudit},
+	{"snapshots", checkSnapshots},
+}
+
+// Run executes every check against s. The database must hold Fixture and no users.
+func Run(ctx context.Context, s db.Store) []Result {
+	results := make([]Result, 0, len(checks))
+	for _, c := range checks {
+		results = append(results, Result{Name: c.name, Err: c.fn(ctx, s)})
+	}
+	return results
+}
+
+func expect(what string, got, want interface{}) error {
+	if !reflect.DeepEqual(got, want) {
+		return fmt.Errorf("%s: got %v, want %v", what, got, want)
+	}
+	return nil
+}
+
+// first returns the first non-nil error
+func first(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sorted(s []string) []string {
+	out := append([]string(nil), s...)
+	sort.Strings(out)
+	return out
+}
+
+func checkCities(ctx context.Context, s db.Store) error {
+	cities, err := s.ListCities(ctx)
+	if err != nil {
+		return err
+	}
+	city, err := s.GetCityForAirport(ctx, "LHR")
+	if err != nil {
+		return err
+	}
+	unknown, err := s.GetCityForAirport(ctx, "ZZZ")
+	if err != nil {
+		return err
+	}
+	same, err := s.SameCity(ctx, "LCY", "LHR")
+	if err != nil {
+		return err
+	}
+	different, err := s.SameCity(ctx, "LHR", "JFK")
+	if err != nil {
+		return err
+	}
+	rows, err := s.ListCityAirports(ctx)
+	if err != nil {
+		return err
+	}
+	return first(
+		expect("ListCities", cities, []string{"LON", "NYC"}),
+		expect("GetCityForAirport(LHR)", city, "LON"),
+		expect("GetCityForAirport(ZZZ)", unknown, "ZZZ"),
+		expect("SameCity(LCY, LHR)", same, true),
+		expect("SameCity(LHR, JFK)", different, false),
+		expect("ListCityAirports", len(rows), 4),
+	)
+}
+
+func checkAirportsForCity(ctx context.Context, s db.Store) error {
+	lon, err := s.GetAirportsForCity(ctx, "LON")
+	if err != nil {
+		return err
+	}
+	unknown, err := s.GetAirportsForCity(ctx, "SOU")
+	if err != nil {
+		return err
+	}
+	return first(
+		expect("GetAirportsForCity(LON)", lon, []string{"LCY", "LHR"}),
+		expect("GetAirportsForCity(SOU)", unknown, []string{"SOU"}),
+	)
+}
+
+func checkDistances(ctx context.Context, s db.Store) error {
+	fromSOU, err := s.GetDistancesFrom(ctx, "SOU")
+	if err != nil {
+		return err
+	}
+	fromLHR, err := s.GetDistancesFrom(ctx, "LHR")
+	if err != nil {
+		return err
+	}
+	fromPHL, err := s.GetDistancesFrom(ctx, "PHL")
+	if err != nil {
+		return err
+	}
+	rows, err := s.ListDistances(ctx)
+	if err != nil {
+		return err
+	}
+	return first(
+		// SOU-LHR is stored both ways (forward row wins); LON-SOU only as LON -> SOU
+		expect("GetDistancesFrom(SOU)", fromSOU, map[string]float64{"LHR": 70, "LON": 68}),
+		expect("GetDistancesFrom(LHR)", fromLHR, map[string]float64{"SOU": 66}),
+		expect("GetDistancesFrom(PHL)", fromPHL, map[string]float64{"JFK": 94.5}),
+		expect("ListDistances", len(rows), 4),
+	)
+}
+
+func checkRoutes(ctx context.Context, s db.Store) error {
+	direct, err := s.GetRoutesFrom(ctx, "LON", "ONE_WORLD")
+	if err != nil {
+		return err
+	}
+	none, err := s.GetRoutesFrom(ctx, "LON", "None")
+	if err != nil {
+		return err
+	}
+	fallback, err := s.GetRoutesFromWithFallback(ctx, "LON", "None")
+	if err != nil {
+		return err
+	}
+	has := s.HasDirectRoute(ctx, "LON", "None", map[string]bool{"AMS": true, "MAD": true})
+	rows, err := s.ListRoutes(ctx)
+	if err != nil {
+		return err
+	}
+	return first(
+		expect("GetRoutesFrom(LON, ONE_WORLD)", direct, []string{"JFK", "MAD"}),
+		expect("GetRoutesFrom(LON, None)", len(none), 0),
+		expect("GetRoutesFromWithFallback(LON, None)", sorted(fallback), []string{"AMS", "DOH", "JFK"}),
+		expect("HasDirectRoute(LON, None)", has, map[string]bool{"AMS": true, "MAD": false}),
+		expect("ListRoutes", len(rows), 6),
+	)
+}
+
+func checkAirports(ctx context.Context, s db.Store) error {
+	lhr, err := s.GetAirport(ctx, "LHR")
+	if err != nil {
+		return err
+	}
+	byCity, err := s.GetAirport(ctx, "LON")
+	if err != nil {
+		return err
+	}
+	unknown, err := s.GetAirport(ctx, "ZZZ")
+	if err != nil {
+		return err
+	}
+	all, err := s.ListAirports(ctx)
+	if err != nil {
+		return err
+	}
+	want := &db.Airport{IATA: "LHR", Name: "London Heathrow", CityCode: "LON", Country: "GB", Continent: "EU", Latitude: 51.47, Longitude: -0.4543}
+	var lonCode string
+	if byCity != nil {
+		lonCode = byCity.IATA
+	}
+	return first(
+		expect("GetAirport(LHR)", lhr, want),
+		expect("GetAirport(LON)", lonCode, "LCY"),
+		expect("GetAirport(ZZZ)", unknown, (*db.Airport)(nil)),
+		expect("ListAirports", len(all), 5),
+	)
+}
+
+func checkStopoverPrograms(ctx context.Context, s db.Store) error {
+	var errs []error
+	for alliance, want := range map[string]int{"None": 2, "ALL": 1, "ONE_WORLD": 1, "SKY_TEAM": 0} {
+		programs, err := s.GetStopoverPrograms(ctx, alliance)
+		if err != nil {
+			return err
+		}
+		errs = append(errs, expect("GetStopoverPrograms("+alliance+")", len(programs), want))
+	}
+	qr, err := s.GetStopoverPrograms(ctx, "ONE_WORLD")
+	if err != nil {
+		return err
+	}
+	if len(qr) == 1 {
+		errs = append(errs, expect("GetStopoverPrograms(ONE_WORLD)[0]", qr[0], db.StopoverProgram{
+			AirlineCode: "QR", Airline: "Qatar Airways", Program: "Discover Qatar", Alliance: "ONE_WORLD",
+			HubCity: "DOH", MaxNights: 4, FareRestrictions: "Any fare",
+		}))
+	}
+	return first(errs...)
+}
+
+func checkAwardCharts(ctx context.Context, s db.Store) error {
+	all, err := s.GetAwardPrograms(ctx, nil)
+	if err != nil {
+		return err
+	}
+	ana, err := s.GetAwardPrograms(ctx, []string{"NH_MILEAGE_CLUB"})
+	if err != nil {
+		return err
+	}
+	bands, err := s.GetAwardBands(ctx, "BA_AVIOS")
+	if err != nil {
+		return err
+	}
+	zones, err := s.GetAwardZones(ctx, "NH_MILEAGE_CLUB")
+	if err != nil {
+		return err
+	}
+	prices, err := s.GetAwardZonePrices(ctx, "NH_MILEAGE_CLUB")
+	if err != nil {
+		return err
+	}
+	return first(
+		expect("GetAwardPrograms(nil)", len(all), 2),
+		expect("GetAwardPrograms(NH_MILEAGE_CLUB)", ana, []db.AwardProgram{{
+			Code: "NH_MILEAGE_CLUB", Name: "ANA Mileage Club", Alliance: "STAR_ALLIANCE", ChartType: "zone",
+			OneWayAllowed: false, OpenJawAllowed: true, MaxStopovers: 1,
+		}}),
+		expect("GetAwardBands(BA_AVIOS)", bands, []db.AwardBand{
+			{Cabin: "business", MinMiles: 0, MaxMiles: 650, Points: 15500},
+			{Cabin: "economy", MinMiles: 0, MaxMiles: 650, Points: 9000},
+			{Cabin: "economy", MinMiles: 651, MaxMiles: 1150, Points: 11000},
+		}),
+		expect("GetAwardZones(NH_MILEAGE_CLUB)", zones, map[string]string{"continent:EU": "EU", "country:GB": "EU", "continent:NA": "NA"}),
+		expect("GetAwardZonePrices(NH_MILEAGE_CLUB)", prices, []db.AwardZonePrice{{Cabin: "economy", FromZone: "EU", ToZone: "NA", Points: 55000}}),
+	)
+}
+
+func checkRTWRuleSets(ctx context.Context, s db.Store) error {
+	sets, err := s.GetRTWRuleSets(ctx)
+	if err != nil {
+		return err
+	}
+	ow, err := s.GetRTWRuleSet(ctx, "OW_EXPLORER")
+	if err != nil {
+		return err
+	}
+	unknown, err := s.GetRTWRuleSet(ctx, "NOPE")
+	if err != nil {
+		return err
+	}
+	var codes []string
+	for _, r := range sets {
+		codes = append(codes, r.Code)
+	}
+	return first(
+		expect("GetRTWRuleSets", codes, []string{"OW_EXPLORER", "STAR_RTW_29K"}),
+		expect("GetRTWRuleSet(OW_EXPLORER)", ow, &db.RTWRuleSet{
+			Code: "OW_EXPLORER", Name: "oneworld Explorer", Alliance: "ONE_WORLD", MinContinents: 3, MaxContinents: 6,
+			MinSegments: 3, MaxSegments: 16, OneDirection: false, MaxAtlanticCrossings: 1, MaxPacificCrossings: 1,
+		}),
+		expect("GetRTWRuleSet(NOPE)", unknown, (*db.RTWRuleSet)(nil)),
+	)
+}
+
+func checkReferenceVersion(ctx context.Context, s db.Store) error {
+	v, err := s.ReferenceVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if v <= 0 {
+		return fmt.Errorf("ReferenceVersion: got %d after loading the fixture, want > 0 (triggers missing?)", v)
+	}
+	return nil
+}
+
+func checkUsers(ctx context.Context, s db.Store) error {
+	const phone = "+15550001001"
+	missing, err := s.GetUserByPhone(ctx, phone)
+	if err != nil {
+		return err
+	}
+	created, err := s.EnsureUser(ctx, phone)
+	if err != nil {
+		return err
+	}
+	again, err := s.EnsureUser(ctx, phone)
+	if err != nil {
+		return err
+	}
+	found, err := s.GetUserByPhone(ctx, phone)
+	if err != nil {
+		return err
+	}
+	if created == nil || created.ID == 0 {
+		return fmt.Errorf("EnsureUser: got %v, want a user with an ID", created)
+	}
+	return first(
+		expect("GetUserByPhone before EnsureUser", missing, (*db.User)(nil)),
+		expect("EnsureUser twice", again, created),
+		expect("GetUserByPhone", found, created),
+	)
+}
+
+func checkSessions(ctx context.Context, s db.Store) error {
+	user, err := s.EnsureUser(ctx, "+15550001002")
+	if err != nil {
+		return err
+	}
+	other, err := s.EnsureUser(ctx, "+15550001012")
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	live := &db.Session{UserID: user.ID, UserAgent: "storetest", IP: "192.0.2.1",
+		CreatedAt: now.Add(-time.Hour), LastUsedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)}
+	second := &db.Session{UserID: user.ID, CreatedAt: now, LastUsedAt: now.Add(-time.Minute), ExpiresAt: now.Add(time.Hour)}
+	expired := &db.Session{UserID: user.ID, CreatedAt: now, LastUsedAt: now, ExpiresAt: now.Add(-time.Minute)}
+	if err := first(
+		s.CreateSession(ctx, live, "storetest-live", "storetest-live-refresh"),
+		s.CreateSession(ctx, second, "storetest-second", "storetest-second-refresh"),
+		s.CreateSession(ctx, expired, "storetest-expired", "storetest-expired-refresh"),
+	); err != nil {
+		return err
+	}
+	got, err := s.GetSession(ctx, "storetest-live")
+	if err != nil {
+		return err
+	}
+	if got == nil {
+		return fmt.Errorf("GetSession(live): got nil")
+	}
+	expiredGot, err := s.GetSession(ctx, "storetest-expired")
+	if err != nil {
+		return err
+	}
+	unknown, err := s.GetSession(ctx, "storetest-unknown")
+	if err != nil {
+		return err
+	}
+	if err := s.TouchSession(ctx, live.ID, "192.0.2.2", now); err != nil {
+		return err
+	}
+	listed, err := s.ListSessions(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	var order []int64
+	var ip string
+	for _, l := range listed {
+		order = append(order, l.ID)
+		if l.ID == live.ID {
+			ip = l.IP
+			if d := l.LastUsedAt.Sub(now); d > time.Second || d < -time.Second {
+				return fmt.Errorf("ListSessions: last_used_at %v after TouchSession, want %v", l.LastUsedAt, now)
+			}
+		}
+	}
+	otherDeleted, err := s.DeleteSession(ctx, other.ID, second.ID)
+	if err != nil {
+		return err
+	}
+	deleted, err := s.DeleteSession(ctx, user.ID, second.ID)
+	if err != nil {
+		return err
+	}
+	afterDelete, err := s.GetSession(ctx, "storetest-second")
+	if err != nil {
+		return err
+	}
+	return first(
+		expect("GetSession(live)", []interface{}{got.ID, got.UserID, got.UserAgent, got.IP}, []interface{}{live.ID, user.ID, "storetest", "192.0.2.1"}),
+		expect("GetSession(expired)", expiredGot, (*db.Session)(nil)),
+		expect("GetSession(unknown)", unknown, (*db.Session)(nil)),
+		expect("ListSessions order (touched first, expired left out)", order, []int64{live.ID, second.ID}),
+		expect("ListSessions IP after TouchSession", ip, "192.0.2.2"),
+		expect("DeleteSession(other user's)", otherDeleted, false),
+		expect("DeleteSession", deleted, true),
+		expect("GetSession after DeleteSession", afterDelete, (*db.Session)(nil)),
+	)
+}
+
+func checkRefreshTokens(ctx context.Context, s db.Store) error {
+	user, err := s.EnsureUser(ctx, "+15550001013")
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	session := &db.Session{UserID: user.ID, CreatedAt: now, LastUsedAt: now,
+		AccessExpiresAt: now.Add(time.Minute), ExpiresAt: now.Add(time.Hour)}
+	stale := &db.Session{UserID: user.ID, CreatedAt: now, LastUsedAt: now,
+		AccessExpiresAt: now.Add(-time.Hour), ExpiresAt: now.Add(-time.Minute)}
+	if err := first(
+		s.CreateSession(ctx, session, "rt-access-1", "rt-refresh-1"),
+		s.CreateSession(ctx, stale, "rt-stale-access", "rt-stale-refresh"),
+	); err != nil {
+		return err
+	}
+	rotation := func(n string) db.TokenRotation {
+		return db.TokenRotation{AccessHash: "rt-access-" + n, RefreshHash: "rt-refresh-" + n, IP: "192.0.2.3", At: now,
+			AccessExpiresAt: now.Add(15 * time.Minute), ExpiresAt: now.Add(2 * time.Hour), ForgetUsedBefore: now.Add(-time.Hour)}
+	}
+	rotated, err := s.RotateRefreshToken(ctx, "rt-refresh-1", rotation("2"))
+	if err != nil {
+		return err
+	}
+	if rotated == nil {
+		return fmt.Errorf("RotateRefreshToken: got nil for the current refresh token")
+	}
+	if d := rotated.ExpiresAt.Sub(now.Add(2 * time.Hour)); d > time.Second || d < -time.Second {
+		return fmt.Errorf("RotateRefreshToken: expires_at %v, want %v", rotated.ExpiresAt, now.Add(2*time.Hour))
+	}
+	oldAccess, err := s.GetSession(ctx, "rt-access-1")
+	if err != nil {
+		return err
+	}
+	newAccess, err := s.GetSession(ctx, "rt-access-2")
+	if err != nil {
+		return err
+	}
+	if newAccess == nil {
+		return fmt.Errorf("GetSession: got nil for the rotated access token")
+	}
+	unknown, err := s.RotateRefreshToken(ctx, "rt-refresh-unknown", rotation("x"))
+	if err != nil {
+		return err
+	}
+	expired, err := s.RotateRefreshToken(ctx, "rt-stale-refresh", rotation("y"))
+	if err != nil {
+		return err
+	}
+	// Replaying the first refresh token signs the whole session out
+	_, reuseErr := s.RotateRefreshToken(ctx, "rt-refresh-1", rotation("3"))
+	revoked, err := s.GetSession(ctx, "rt-access-2")
+	if err != nil {
+		return err
+	}
+	afterRevoke, err := s.RotateRefreshToken(ctx, "rt-refresh-2", rotation("4"))
+	if err != nil {
+		return err
+	}
+	return first(
+		expect("RotateRefreshToken session", []interface{}{rotated.ID, rotated.UserID, rotated.IP}, []interface{}{session.ID, user.ID, "192.0.2.3"}),
+		expect("GetSession(old access token) after rotation", oldAccess, (*db.Session)(nil)),
+		expect("GetSession(new access token) ID", newAccess.ID, session.ID),
+		expect("RotateRefreshToken(unknown)", unknown, (*db.Session)(nil)),
+		expect("RotateRefreshToken(expired session)", expired, (*db.Session)(nil)),
+		expect("RotateRefreshToken(reused) error", errors.Is(reuseErr, db.ErrRefreshTokenReused), true),
+		expect("GetSession after reuse", revoked, (*db.Session)(nil)),
+		expect("RotateRefreshToken(latest) after reuse", afterRevoke, (*db.Session)(nil)),
+	)
+}
+
+func checkOTPCodes(ctx context.Context, s db.Store) error {
+	const phone = "+15550001003"
+	sent := time.Now().Add(-5 * time.Minute)
+	expires := sent.Add(10 * time.Minute)
+	first1, err := s.SaveOTP(ctx, db.OTPCode{Phone: phone, CodeHash: "hash-1", SentAt: sent, ExpiresAt: expires}, time.Minute)
+	if err != nil {
+		return err
+	}
+	// Within the cooldown the earlier code stays
+	early, err := s.SaveOTP(ctx, db.OTPCode{Phone: phone, CodeHash: "hash-2", SentAt: sent.Add(30 * time.Second), ExpiresAt: expires}, time.Minute)
+	if err != nil {
+		return err
+	}
+	if _, err := s.CountOTPAttempt(ctx, phone); err != nil {
+		return err
+	}
+	counted, err := s.CountOTPAttempt(ctx, phone)
+	if err != nil {
+		return err
+	}
+	if counted == nil {
+		return fmt.Errorf("CountOTPAttempt: got nil after SaveOTP")
+	}
+	if d := counted.ExpiresAt.Sub(expires); d > time.Second || d < -time.Second {
+		return fmt.Errorf("CountOTPAttempt: expires_at %v, want %v", counted.ExpiresAt, expires)
+	}
+	// After it a new code replaces the old one and its attempts
+	later, err := s.SaveOTP(ctx, db.OTPCode{Phone: phone, CodeHash: "hash-3", SentAt: sent.Add(2 * time.Minute), ExpiresAt: expires}, time.Minute)
+	if err != nil {
+		return err
+	}
+	otp, err := s.GetOTP(ctx, phone)
+	if err != nil {
+		return err
+	}
+	if otp == nil {
+		return fmt.Errorf("GetOTP: got nil after SaveOTP")
+	}
+	unknown, err := s.CountOTPAttempt(ctx, "+15550001099")
+	if err != nil {
+		return err
+	}
+	// Only the code checked can be consumed, and only once
+	replaced, err1 := s.UseOTP(ctx, phone, "hash-1")
+	used, err2 := s.UseOTP(ctx, phone, "hash-3")
+	reused, err3 := s.UseOTP(ctx, phone, "hash-3")
+	if err := errors.Join(err1, err2, err3); err != nil {
+		return err
+	}
+	if _, err := s.SaveOTP(ctx, db.OTPCode{Phone: phone, CodeHash: "hash-4", SentAt: sent.Add(4 * time.Minute), ExpiresAt: expires}, time.Minute); err != nil {
+		return err
+	}
+	if err := s.DeleteOTP(ctx, phone); err != nil {
+		return err
+	}
+	deleted, err := s.GetOTP(ctx, phone)
+	if err != nil {
+		return err
+	}
+
+	noLockout, err := s.GetOTPLockout(ctx, phone)
+	if err != nil {
+		return err
+	}
+	until := time.Now().Add(10 * time.Minute)
+	if err := first(
+		s.SaveOTPLockout(ctx, db.OTPLockout{Phone: phone, Lockouts: 1, LockedUntil: time.Now()}),
+		s.SaveOTPLockout(ctx, db.OTPLockout{Phone: phone, Lockouts: 2, LockedUntil: until}),
+	); err != nil {
+		return err
+	}
+	lockout, err := s.GetOTPLockout(ctx, phone)
+	if err != nil {
+		return err
+	}
+	if lockout == nil {
+		return fmt.Errorf("GetOTPLockout: got nil after SaveOTPLockout")
+	}
+	if d := lockout.LockedUntil.Sub(until); d > time.Second || d < -time.Second {
+		return fmt.Errorf("GetOTPLockout: locked_until %v, want %v", lockout.LockedUntil, until)
+	}
+	if err := s.DeleteOTPLockout(ctx, phone); err != nil {
+		return err
+	}
+	cleared, err := s.GetOTPLockout(ctx, phone)
+	if err != nil {
+		return err
+	}
+	return first(
+		expect("SaveOTP first", first1, true),
+		expect("SaveOTP within cooldown", early, false),
+		expect("CountOTPAttempt code and attempts", []interface{}{counted.CodeHash, counted.Attempts}, []interface{}{"hash-1", 2}),
+		expect("SaveOTP after cooldown", later, true),
+		expect("GetOTP code and attempts after new code", []interface{}{otp.CodeHash, otp.Attempts}, []interface{}{"hash-3", 0}),
+		expect("CountOTPAttempt(unknown)", unknown, (*db.OTPCode)(nil)),
+		expect("UseOTP(replaced code)", replaced, false),
+		expect("UseOTP", used, true),
+		expect("UseOTP twice", reused, false),
+		expect("GetOTP after DeleteOTP", deleted, (*db.OTPCode)(nil)),
+		expect("GetOTPLockout before SaveOTPLockout", noLockout, (*db.OTPLockout)(nil)),
+		expect("GetOTPLockout lockouts", lockout.Lockouts, 2),
+		expect("GetOTPLockout after DeleteOTPLockout", cleared, (*db.OTPLockout)(nil)),
+	)
+}
+
+func checkEmailLogin(ctx context.Context, s db.Store) error {
+	const email, phone = "traveller@example.com", "+15550001014"
+	created, err := s.EnsureUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if created == nil || created.ID == 0 {
+		return fmt.Errorf("EnsureUserByEmail: got %v, want a user with an ID", created)
+	}
+	again, err := s.EnsureUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	// Linking a phone makes the user findable by either
+	if err := s.SetUserPhone(ctx, created.ID, phone); err != nil {
+		return err
+	}
+	byPhone, err := s.GetUserByPhone(ctx, phone)
+	if err != nil {
+		return err
+	}
+	other, err := s.EnsureUser(ctx, "+15550001015")
+	if err != nil {
+		return err
+	}
+	taken := s.SetUserEmail(ctx, other.ID, email)
+	if err := s.SetUserEmail(ctx, other.ID, "other@example.com"); err != nil {
+		return err
+	}
+	otherUser, err := s.GetUser(ctx, other.ID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	link := db.EmailLink{TokenHash: "link-1", Email: email, CreatedAt: now.Add(-2 * time.Minute), ExpiresAt: now.Add(10 * time.Minute)}
+	saved, err := s.SaveEmailLink(ctx, link, time.Minute)
+	if err != nil {
+		return err
+	}
+	early, err := s.SaveEmailLink(ctx, db.EmailLink{TokenHash: "link-2", Email: email,
+		CreatedAt: link.CreatedAt.Add(30 * time.Second), ExpiresAt: link.ExpiresAt}, time.Minute)
+	if err != nil {
+		return err
+	}
+	linking, err := s.SaveEmailLink(ctx, db.EmailLink{TokenHash: "link-3", Email: "new@example.com", UserID: other.ID,
+		CreatedAt: now, ExpiresAt: now.Add(10 * time.Minute)}, time.Minute)
+	if err != nil {
+		return err
+	}
+	last, err := s.LastEmailLink(ctx, email)
+	if err != nil {
+		return err
+	}
+	if d := last.Sub(link.CreatedAt); d > time.Second || d < -time.Second {
+		return fmt.Errorf("LastEmailLink: %v, want %v", last, link.CreatedAt)
+	}
+	used, err := s.UseEmailLink(ctx, "link-1", now)
+	if err != nil {
+		return err
+	}
+	if used == nil {
+		return fmt.Errorf("UseEmailLink: got nil for a fresh link")
+	}
+	usedTwice, err := s.UseEmailLink(ctx, "link-1", now)
+	if err != nil {
+		return err
+	}
+	expired, err := s.UseEmailLink(ctx, "link-3", now.Add(time.Hour))
+	if err != nil {
+		return err
+	}
+	linked, err := s.UseEmailLink(ctx, "link-3", now)
+	if err != nil {
+		return err
+	}
+	if linked == nil {
+		return fmt.Errorf("UseEmailLink: got nil for a linking link")
+	}
+	if err := s.DeleteEmailLink(ctx, "link-3"); err != nil {
+		return err
+	}
+	return first(
+		expect("EnsureUserByEmail twice", again, created),
+		expect("GetUserByPhone after SetUserPhone", byPhone, &db.User{ID: created.ID, Phone: phone, Email: email}),
+		expect("SetUserEmail to another user's address", taken, db.ErrIdentityInUse),
+		expect("GetUser after SetUserEmail", otherUser, &db.User{ID: other.ID, Phone: "+15550001015", Email: "other@example.com"}),
+		expect("SaveEmailLink first", saved, true),
+		expect("SaveEmailLink within cooldown", early, false),
+		expect("SaveEmailLink to another address", linking, true),
+		expect("UseEmailLink email and user", []interface{}{used.Email, used.UserID}, []interface{}{email, int64(0)}),
+		expect("UseEmailLink twice", usedTwice, (*db.EmailLink)(nil)),
+		expect("UseEmailLink after expiry", expired, (*db.EmailLink)(nil)),
+		expect("UseEmailLink linking user", []interface{}{linked.Email, linked.UserID}, []interface{}{"new@example.com", other.ID}),
+	)
+}
+
+func checkPasskeys(ctx context.Context, s db.Store) error {
+	owner, err := s.EnsureUser(ctx, "+15550001016")
+	if err != nil {
+		return err
+	}
+	other, err := s.EnsureUser(ctx, "+15550001017")
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, ch := range []db.PasskeyChallenge{
+		{Hash: "pk-register", Purpose: db.PasskeyRegister, UserID: owner.ID, CreatedAt: now, ExpiresAt: now.Add(5 * time.Minute)},
+		{Hash: "pk-login", Purpose: db.PasskeyLogin, CreatedAt: now, ExpiresAt: now.Add(5 * time.Minute)},
+	} {
+		if err := s.SavePasskeyChallenge(ctx, ch); err != nil {
+			return err
+		}
+	}
+	wrongPurpose, err := s.TakePasskeyChallenge(ctx, "pk-register", db.PasskeyLogin, now)
+	if err != nil {
+		return err
+	}
+	register, err := s.TakePasskeyChallenge(ctx, "pk-register", db.PasskeyRegister, now)
+	if err != nil {
+		return err
+	}
+	if register == nil {
+		return fmt.Errorf("TakePasskeyChallenge: got nil for a fresh challenge")
+	}
+	registerTwice, err := s.TakePasskeyChallenge(ctx, "pk-register", db.PasskeyRegister, now)
+	if err != nil {
+		return err
+	}
+	expired, err := s.TakePasskeyChallenge(ctx, "pk-login", db.PasskeyLogin, now.Add(time.Hour))
+	if err != nil {
+		return err
+	}
+	login, err := s.TakePasskeyChallenge(ctx, "pk-login", db.PasskeyLogin, now)
+	if err != nil {
+		return err
+	}
+	if login == nil {
+		return fmt.Errorf("TakePasskeyChallenge: got nil for a sign-in challenge")
+	}
+
+	key := &db.Passkey{UserID: owner.ID, CredentialID: "cred-1", PublicKey: []byte{0xa5, 0x01, 0x02}, SignCount: 3,
+		BackupEligible: true, Transports: "internal,hybrid", Name: "Phone", CreatedAt: now}
+	if err := s.AddPasskey(ctx, key); err != nil {
+		return err
+	}
+	if key.ID == 0 {
+		return fmt.Errorf("AddPasskey: ID not set")
+	}
+	dup := s.AddPasskey(ctx, &db.Passkey{UserID: other.ID, CredentialID: "cred-1", PublicKey: []byte{0xa5}, CreatedAt: now})
+	got, err := s.GetPasskey(ctx, "cred-1")
+	if err != nil {
+		return err
+	}
+	if got == nil {
+		return fmt.Errorf("GetPasskey: got nil for a registered credential")
+	}
+	missing, err := s.GetPasskey(ctx, "cred-unknown")
+	if err != nil {
+		return err
+	}
+	used, err := s.UsePasskey(ctx, key.ID, 3, 4, true, now)
+	if err != nil {
+		return err
+	}
+	// A second sign-in that read the old count loses the race
+	stale, err := s.UsePasskey(ctx, key.ID, 3, 4, true, now)
+	if err != nil {
+		return err
+	}
+	listed, err := s.ListPasskeys(ctx, owner.ID)
+	if err != nil {
+		return err
+	}
+	if len(listed) != 1 || listed[0].LastUsedAt == nil {
+		return fmt.Errorf("ListPasskeys: got %+v, want one used passkey", listed)
+	}
+	notTheirs, err := s.DeletePasskey(ctx, other.ID, key.ID)
+	if err != nil {
+		return err
+	}
+	deleted, err := s.DeletePasskey(ctx, owner.ID, key.ID)
+	if err != nil {
+		return err
+	}
+	remaining, err := s.ListPasskeys(ctx, owner.ID)
+	if err != nil {
+		return err
+	}
+	return first(
+		expect("TakePasskeyChallenge wrong purpose", wrongPurpose, (*db.PasskeyChallenge)(nil)),
+		expect("TakePasskeyChallenge user", register.UserID, owner.ID),
+		expect("TakePasskeyChallenge twice", registerTwice, (*db.PasskeyChallenge)(nil)),
+		expect("TakePasskeyChallenge after expiry", expired, (*db.PasskeyChallenge)(nil)),
+		expect("TakePasskeyChallenge sign-in user", login.UserID, int64(0)),
+		expect("AddPasskey duplicate credential", dup, db.ErrPasskeyExists),
+		expect("GetPasskey", []interface{}{got.ID, got.UserID, got.PublicKey, got.SignCount, got.BackupEligible, got.BackedUp, got.Transports, got.Name},
+			[]interface{}{key.ID, owner.ID, []byte{0xa5, 0x01, 0x02}, uint32(3), true, false, "internal,hybrid", "Phone"}),
+		expect("GetPasskey unknown", missing, (*db.Passkey)(nil)),
+		expect("UsePasskey", used, true),
+		expect("UsePasskey stale count", stale, false),
+		expect("ListPasskeys after use", []interface{}{listed[0].SignCount, listed[0].BackedUp}, []interface{}{uint32(4), true}),
+		expect("DeletePasskey another user's", notTheirs, false),
+		expect("DeletePasskey", deleted, true),
+		expect("ListPasskeys after delete", len(remaining), 0),
+	)
+}
+
+func checkOIDC(ctx context.Context, s db.Store) error {
+	const issuer = "https://login.example.com"
+	now := time.Now()
+	linker, err := s.EnsureUser(ctx, "+15550001018")
+	if err != nil {
+		return err
+	}
+	for _, l := range []db.OIDCLogin{
+		{StateHash: "state-1", Nonce: "nonce-1", CodeVerifier: "verifier-1", CreatedAt: now, ExpiresAt: now.Add(10 * time.Minute)},
+		{StateHash: "state-2", Nonce: "nonce-2", CodeVerifier: "verifier-2", UserID: linker.ID, CreatedAt: now, ExpiresAt: now.Add(10 * time.Minute)},
+	} {
+		if err := s.SaveOIDCLogin(ctx, l); err != nil {
+			return err
+		}
+	}
+	login, err := s.TakeOIDCLogin(ctx, "state-1", now)
+	if err != nil {
+		return err
+	}
+	if login == nil {
+		return fmt.Errorf("TakeOIDCLogin: got nil for a fresh login")
+	}
+	loginTwice, err := s.TakeOIDCLogin(ctx, "state-1", now)
+	if err != nil {
+		return err
+	}
+	expired, err := s.TakeOIDCLogin(ctx, "state-2", now.Add(time.Hour))
+	if err != nil {
+		return err
+	}
+	linking, err := s.TakeOIDCLogin(ctx, "state-2", now)
+	if err != nil {
+		return err
+	}
+	if linking == nil {
+		return fmt.Errorf("TakeOIDCLogin: got nil for a linking login")
+	}
+
+	// The first sign-in creates a user with the verified email; later ones find it
+	ident := db.OIDCIdentity{Issuer: issuer, Subject: "sub-1", Email: "sso@example.com", CreatedAt: now}
+	created, err := s.EnsureOIDCUser(ctx, ident, "sso@example.com")
+	if err != nil {
+		return err
+	}
+	again, err := s.EnsureOIDCUser(ctx, ident, "sso@example.com")
+	if err != nil {
+		return err
+	}
+	// A second identity claiming the same email gets its own user without it
+	other, err := s.EnsureOIDCUser(ctx, db.OIDCIdentity{Issuer: issuer, Subject: "sub-2", Email: "sso@example.com", CreatedAt: now}, "sso@example.com")
+	if err != nil {
+		return err
+	}
+	linked := s.LinkOIDCIdentity(ctx, db.OIDCIdentity{Issuer: issuer, Subject: "sub-3", UserID: linker.ID, CreatedAt: now})
+	relinked := s.LinkOIDCIdentity(ctx, db.OIDCIdentity{Issuer: issuer, Subject: "sub-3", UserID: linker.ID, CreatedAt: now})
+	taken := s.LinkOIDCIdentity(ctx, db.OIDCIdentity{Issuer: issuer, Subject: "sub-1", UserID: linker.ID, CreatedAt: now})
+	linkedUser, err := s.EnsureOIDCUser(ctx, db.OIDCIdentity{Issuer: issuer, Subject: "sub-3", CreatedAt: now}, "")
+	if err != nil {
+		return err
+	}
+	idents, err := s.ListOIDCIdentities(ctx, created.ID)
+	if err != nil {
+		return err
+	}
+	if len(idents) != 1 || idents[0].LastLoginAt == nil {
+		return fmt.Errorf("ListOIDCIdentities: got %+v, want one identity that signed in", idents)
+	}
+	return first(
+		expect("TakeOIDCLogin", []interface{}{login.Nonce, login.CodeVerifier, login.UserID}, []interface{}{"nonce-1", "verifier-1", int64(0)}),
+		expect("TakeOIDCLogin twice", loginTwice, (*db.OIDCLogin)(nil)),
+		expect("TakeOIDCLogin after expiry", expired, (*db.OIDCLogin)(nil)),
+		expect("TakeOIDCLogin linking user", linking.UserID, linker.ID),
+		expect("EnsureOIDCUser new user", created.Email, "sso@example.com"),
+		expect("EnsureOIDCUser twice", again, created),
+		expect("EnsureOIDCUser email taken", []interface{}{other.ID != created.ID, other.Email}, []interface{}{true, ""}),
+		expect("LinkOIDCIdentity", linked, nil),
+		expect("LinkOIDCIdentity again", relinked, nil),
+		expect("LinkOIDCIdentity another user's", taken, db.ErrIdentityInUse),
+		expect("EnsureOIDCUser linked identity", linkedUser.ID, linker.ID),
+		expect("ListOIDCIdentities", []interface{}{idents[0].Issuer, idents[0].Subject, idents[0].Email}, []interface{}{issuer, "sub-1", "sso@example.com"}),
+	)
+}
+
+func checkProfiles(ctx context.Context, s db.Store) error {
+	user, err := s.EnsureUser(ctx, "+15550001019")
+	if err != nil {
+		return err
+	}
+	empty, err := s.GetProfile(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	p := db.Profile{DisplayName: "Ada", HomeAirports: []string{"JFK", "EWR"}, PreferredAlliance: "ONE_WORLD", PreferredCabin: "business",
+		LoyaltyPrograms: []string{"BA_AVIOS"}, Nationalities: []string{"US", "GB"}, GroundRadius: 150}
+	at := time.Now().Truncate(time.Second)
+	if err := s.SaveProfile(ctx, user.ID, &p, at); err != nil {
+		return err
+	}
+	saved, err := s.GetProfile(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	// Saving again replaces the whole profile, lists included
+	if err := s.SaveProfile(ctx, user.ID, &db.Profile{DisplayName: "Ada L."}, at.Add(time.Minute)); err != nil {
+		return err
+	}
+	replaced, err := s.GetProfile(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if saved.UpdatedAt == nil || !saved.UpdatedAt.Equal(at) {
+		return fmt.Errorf("GetProfile: got updatedAt %v, want %v", saved.UpdatedAt, at)
+	}
+	saved.UpdatedAt = p.UpdatedAt
+	return first(
+		expect("GetProfile before saving", []interface{}{empty.DisplayName, empty.HomeAirports, empty.UpdatedAt}, []interface{}{"", []string{}, (*time.Time)(nil)}),
+		expect("GetProfile", *saved, p),
+		expect("GetProfile after replacing", []interface{}{replaced.DisplayName, replaced.HomeAirports, replaced.LoyaltyPrograms, replaced.GroundRadius},
+			[]interface{}{"Ada L.", []string{}, []string{}, 0.0}),
+	)
+}
+
+func checkDeleteUser(ctx context.Context, s db.Store) error {
+	const phone = "+15550001020"
+	gone, err := s.EnsureUser(ctx, phone)
+	if err != nil {
+		return err
+	}
+	if err := s.SetUserEmail(ctx, gone.ID, "gone@example.com"); err != nil {
+		return err
+	}
+	kept, err := s.EnsureUser(ctx, "+15550001021")
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	session := &db.Session{UserID: gone.ID, CreatedAt: now, LastUsedAt: now, AccessExpiresAt: now.Add(time.Minute), ExpiresAt: now.Add(time.Hour)}
+	keptSession := &db.Session{UserID: kept.ID, CreatedAt: now, LastUsedAt: now, AccessExpiresAt: now.Add(time.Minute), ExpiresAt: now.Add(time.Hour)}
+	if err := first(
+		s.CreateSession(ctx, session, "del-access", "del-refresh"),
+		s.CreateSession(ctx, keptSession, "del-kept-access", "del-kept-refresh"),
+		s.AddBookedFlight(ctx, &db.BookedFlight{UserID: gone.ID, Airline: "BA", FlightNumber: "BA1", FromIATA: "LHR", ToIATA: "JFK", DepartureDate: "2026-06-01"}),
+		s.AddBookedFlight(ctx, &db.BookedFlight{UserID: kept.ID, Airline: "BA", FlightNumber: "BA2", FromIATA: "JFK", ToIATA: "LHR", DepartureDate: "2026-06-02"}),
+		s.SaveProfile(ctx, gone.ID, &db.Profile{DisplayName: "Gone"}, now),
+		s.AddPasskey(ctx, &db.Passkey{UserID: gone.ID, CredentialID: "del-cred", PublicKey: []byte{0xa5}, CreatedAt: now}),
+		s.SavePasskeyChallenge(ctx, db.PasskeyChallenge{Hash: "del-challenge", Purpose: db.PasskeyRegister, UserID: gone.ID, CreatedAt: now, ExpiresAt: now.Add(time.Minute)}),
+		s.LinkOIDCIdentity(ctx, db.OIDCIdentity{Issuer: "https://login.example.com", Subject: "del-sub", UserID: gone.ID, CreatedAt: now}),
+		s.SaveOIDCLogin(ctx, db.OIDCLogin{StateHash: "del-state", UserID: gone.ID, CreatedAt: now, ExpiresAt: now.Add(time.Minute)}),
+		s.SaveOTPLockout(ctx, db.OTPLockout{Phone: phone, Lockouts: 1, LockedUntil: now.Add(time.Hour)}),
+	); err != nil {
+		return err
+	}
+	if _, err := s.SaveEmailLink(ctx, db.EmailLink{TokenHash: "del-link", Email: "gone@example.com", CreatedAt: now, ExpiresAt: now.Add(time.Minute)}, 0); err != nil {
+		return err
+	}
+	if _, err := s.SaveOTP(ctx, db.OTPCode{Phone: phone, CodeHash: "del-hash", SentAt: now, ExpiresAt: now.Add(time.Minute)}, 0); err != nil {
+		return err
+	}
+
+	deleted, err := s.DeleteUser(ctx, gone.ID)
+	if err != nil {
+		return err
+	}
+	again, err := s.DeleteUser(ctx, gone.ID)
+	if err != nil {
+		return err
+	}
+	user, err1 := s.GetUser(ctx, gone.ID)
+	byEmail, err2 := s.GetUserByEmail(ctx, "gone@example.com")
+	sess, err3 := s.GetSession(ctx, "del-access")
+	refreshed, err4 := s.RotateRefreshToken(ctx, "del-refresh", db.TokenRotation{AccessHash: "del-access-2", RefreshHash: "del-refresh-2",
+		AccessExpiresAt: now.Add(time.Minute), ExpiresAt: now.Add(time.Hour), At: now})
+	flights, err5 := s.ListBookedFlights(ctx, gone.ID)
+	profile, err6 := s.GetProfile(ctx, gone.ID)
+	key, err7 := s.GetPasskey(ctx, "del-cred")
+	challenge, err8 := s.TakePasskeyChallenge(ctx, "del-challenge", db.PasskeyRegister, now)
+	idents, err9 := s.ListOIDCIdentities(ctx, gone.ID)
+	login, err10 := s.TakeOIDCLogin(ctx, "del-state", now)
+	link, err11 := s.UseEmailLink(ctx, "del-link", now)
+	otp, err12 := s.GetOTP(ctx, phone)
+	lockout, err13 := s.GetOTPLockout(ctx, phone)
+	keptSess, err14 := s.GetSession(ctx, "del-kept-access")
+	keptFlights, err15 := s.ListBookedFlights(ctx, kept.ID)
+	if err := errors.Join(err1, err2, err3, err4, err5, err6, err7, err8, err9, err10, err11, err12, err13, err14, err15); err != nil {
+		return err
+	}
+	return first(
+		expect("DeleteUser", deleted, true),
+		expect("DeleteUser twice", again, false),
+		expect("GetUser after delete", user, (*db.User)(nil)),
+		expect("GetUserByEmail after delete", byEmail, (*db.User)(nil)),
+		expect("GetSession after delete", sess, (*db.Session)(nil)),
+		expect("RotateRefreshToken after delete", refreshed, (*db.Session)(nil)),
+		expect("ListBookedFlights after delete", len(flights), 0),
+		expect("GetProfile after delete", profile.UpdatedAt, (*time.Time)(nil)),
+		expect("GetPasskey after delete", key, (*db.Passkey)(nil)),
+		expect("TakePasskeyChallenge after delete", challenge, (*db.PasskeyChallenge)(nil)),
+		expect("ListOIDCIdentities after delete", len(idents), 0),
+		expect("TakeOIDCLogin after delete", login, (*db.OIDCLogin)(nil)),
+		expect("UseEmailLink after delete", link, (*db.EmailLink)(nil)),
+		expect("GetOTP after delete", otp, (*db.OTPCode)(nil)),
+		expect("GetOTPLockout after delete", lockout != nil && lockout.Lockouts == 1, true),
+		expect("other user's session", keptSess != nil, true),
+		expect("other user's flights", len(keptFlights), 1),
+	)
+}
+
+func checkBookedFlights(ctx context.Context, s db.Store) error {
+	owner, err := s.EnsureUser(ctx, "+15550001004")
+	if err != nil {
+		return err
+	}
+	other, err := s.EnsureUser(ctx, "+15550001005")
+	if err != nil {
+		return err
+	}
+	later := &db.BookedFlight{UserID: owner.ID, Airline: "BA", FlightNumber: "BA117", FromIATA: "LHR", ToIATA: "JFK", DepartureDate: "2026-06-02"}
+	sooner := &db.BookedFlight{UserID: owner.ID, Airline: "AA", FlightNumber: "AA100", FromIATA: "JFK", ToIATA: "LHR", DepartureDate: "2026-06-01", DepartureTime: "18:30", Confirmation: "ABC123"}
+	for _, f := range []*db.BookedFlight{later, sooner} {
+		if err := s.AddBookedFlight(ctx, f); err != nil {
+			return err
+		}
+		if f.ID == 0 {
+			return fmt.Errorf("AddBookedFlight: ID not set")
+		}
+	}
+	list, err := s.ListBookedFlights(ctx, owner.ID)
+	if err != nil {
+		return err
+	}
+	otherList, err := s.ListBookedFlights(ctx, other.ID)
+	if err != nil {
+		return err
+	}
+	stolen, err := s.DeleteBookedFlight(ctx, other.ID, sooner.ID)
+	if err != nil {
+		return err
+	}
+	deleted, err := s.DeleteBookedFlight(ctx, owner.ID, sooner.ID)
+	if err != nil {
+		return err
+	}
+	again, err := s.DeleteBookedFlight(ctx, owner.ID, sooner.ID)
+	if err != nil {
+		return err
+	}
+	return first(
+		expect("ListBookedFlights", list, []db.BookedFlight{*sooner, *later}),
+		expect("ListBookedFlights(other user)", len(otherList), 0),
+		expect("DeleteBookedFlight(other user)", stolen, false),
+		expect("DeleteBookedFlight(owner)", deleted, true),
+		expect("DeleteBookedFlight(already deleted)", again, false),
+	)
+}
+
+// auditActions lists the actions of the newest audit entries for entity, newest first
+func auditActions(ctx context.Context, s db.Store, entity string) ([]string, error) {
+	entries, err := s.ListAudit(ctx, entity, 10)
+	if err != nil {
+		return nil, err
+	}
+	actions := []string{}
+	for _, e := range entries {
+		if e.Actor != "storetest" {
+			return nil, fmt.Errorf("ListAudit: actor %q, want storetest", e.Actor)
+		}
+		actions = append(actions, e.Action)
+	}
+	return actions, nil
+}
+
+// checkAdminAudit edits rows that aren't in the fixture and removes them again
+func checkAdminAudit(ctx context.Context, s db.Store) error {
+	const actor = "storetest"
+	airline := db.Airline{IATA: "Z9", Name: "Storetest Air", Alliance: "None", Active: true}
+	renamed := airline
+	renamed.Name = "Storetest Airways"
+	for _, a := range []db.Airline{airline, airline, renamed} {
+		if err := s.PutAirline(ctx, actor, a); err != nil {
+			return err
+		}
+	}
+	airlines, err := s.ListAirlines(ctx)
+	if err != nil {
+		return err
+	}
+	var stored *db.Airline
+	for i := range airlines {
+		if airlines[i].IATA == airline.IATA {
+			stored = &airlines[i]
+		}
+	}
+	if stored == nil {
+		return fmt.Errorf("ListAirlines: %s missing after PutAirline", airline.IATA)
+	}
+	airlineDeleted, err := s.DeleteAirline(ctx, actor, airline.IATA)
+	if err != nil {
+		return err
+	}
+
+	version, err := s.ReferenceVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if err := s.PutAirport(ctx, actor, db.Airport{IATA: "ZZA", Name: "Storetest", CityCode: "ZZA", Country: "US", Continent: "NA", Latitude: 1, Longitude: 1}); err != nil {
+		return err
+	}
+	bumped, err := s.ReferenceVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := s.DeleteAirport(ctx, actor, "ZZA"); err != nil {
+		return err
+	}
+
+	// A stored reverse row is updated instead of adding a second direction
+	if err := s.PutDistance(ctx, actor, db.Distance{From: "ZZA", To: "ZZB", Miles: 100}); err != nil {
+		return err
+	}
+	if err := s.PutDistance(ctx, actor, db.Distance{From: "ZZB", To: "ZZA", Miles: 120}); err != nil {
+		return err
+	}
+	fromA, err := s.GetDistancesFrom(ctx, "ZZA")
+	if err != nil {
+		return err
+	}
+	fromB, err := s.GetDistancesFrom(ctx, "ZZB")
+	if err != nil {
+		return err
+	}
+	distanceDeleted, err := s.DeleteDistance(ctx, actor, "ZZB", "ZZA")
+	if err != nil {
+		return err
+	}
+	remaining, err := s.GetDistancesFrom(ctx, "ZZA")
+	if err != nil {
+		return err
+	}
+
+	airlineActions, err := auditActions(ctx, s, "airline")
+	if err != nil {
+		return err
+	}
+	airportActions, err := auditActions(ctx, s, "airport")
+	if err != nil {
+		return err
+	}
+	distanceActions, err := auditActions(ctx, s, "distance")
+	if err != nil {
+		return err
+	}
+	return first(
+		expect("ListAirlines after PutAirline", *stored, renamed),
+		expect("DeleteAirline", airlineDeleted, true),
+		expect("ReferenceVersion bumped by PutAirport", bumped > version, true),
+		expect("GetDistancesFrom(ZZA) after PutDistance both ways", fromA["ZZB"], 120.0),
+		expect("GetDistancesFrom(ZZB) after PutDistance both ways", fromB["ZZA"], 120.0),
+		expect("DeleteDistance", distanceDeleted, true),
+		expect("GetDistancesFrom(ZZA) after DeleteDistance", len(remaining), 0),
+		expect("audit actions for airline (unchanged put not logged)", airlineActions, []string{"delete", "update", "create"}),
+		expect("audit actions for airport", airportActions, []string{"delete", "create"}),
+		expect("audit actions for distance", distanceActions, []string{"delete", "update", "create"}),
+	)
+}
+
+func checkSnapshots(ctx context.Context, s db.Store) error {
+	created, err := s.CreateSnapshot(ctx, "storetest")
+	if err != nil {
+		return err
+	}
+	version, err := s.ReferenceVersion(ctx)
+	if err != nil {
+		return err
+	}
+	got, err := s.GetSnapshot(ctx, created.ID)
+	if err != nil {
+		return err
+	}
+	if got == nil {
+		return fmt.Errorf("GetSnapshot(%d): not found after CreateSnapshot", created.ID)
+	}
+	list, err := s.ListSnapshots(ctx)
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 || list[0].ID != created.ID {
+		return fmt.Errorf("ListSnapshots: newest is not snapshot %d", created.ID)
+	}
+	snap := s.SnapshotData(created.ID)
+	snapVersion, err := snap.ReferenceVersion(ctx)
+	if err != nil {
+		return err
+	}
+	errs := []error{
+		expect("snapshot source", got.Source, "storetest"),
+		expect("snapshot reference version", snapVersion, version),
+	}
+	for _, lists := range []struct {
+		name       string
+		live, copy func(ctx context.Context) (interface{}, int, error)
+		count      int
+	}{
+		{"city airports", wrap(s.ListCityAirports), wrap(snap.ListCityAirports), got.CityAirports},
+		{"routes", wrap(s.ListRoutes), wrap(snap.ListRoutes), got.Routes},
+		{"distances", wrap(s.ListDistances), wrap(snap.ListDistances), got.Distances},
+		{"airports", wrap(s.ListAirports), wrap(snap.ListAirports), got.Airports},
+	} {
+		live, n, err := lists.live(ctx)
+		if err != nil {
+			return err
+		}
+		copied, _, err := lists.copy(ctx)
+		if err != nil {
+			return err
+		}
+		errs = append(errs, expect("snapshot "+lists.name, copied, live), expect("snapshot "+lists.name+" count", lists.count, n))
+	}
+	deleted, err := s.DeleteSnapshot(ctx, created.ID)
+	if err != nil {
+		return err
+	}
+	gone, err := s.GetSnapshot(ctx, created.ID)
+	if err != nil {
+		return err
+	}
+	_, missingErr := snap.ReferenceVersion(ctx)
+	routes, err := snap.ListRoutes(ctx)
+	if err != nil {
+		return err
+	}
+	return first(append(errs,
+		expect("DeleteSnapshot", deleted, true),
+		expect("GetSnapshot after DeleteSnapshot", gone, (*db.Snapshot)(nil)),
+		expect("ReferenceVersion of a deleted snapshot is ErrSnapshotNotFound", errors.Is(missingErr, db.ErrSnapshotNotFound), true),
+		expect("snapshot routes after DeleteSnapshot", len(routes), 0),
+	)...)
+}
+
+// wrap adapts a list method so checkSnapshots can compare every table the same way
+func wrap[T any](list func(ctx context.Context) ([]T, error)) func(ctx context.Context) (interface{}, int, error) {
+	return func(ctx context.Context) (interface{}, int, error) {
+		rows, err := list(ctx)
+		return rows, len(rows), err
+	}
+}
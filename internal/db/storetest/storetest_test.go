@@ -0,0 +1,167 @@
+package storetest
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	dbfiles "triangle_travel/db"
+	"triangle_travel/internal/db"
+	"triangle_travel/internal/importer"
+	"triangle_travel/internal/migrate"
+)
+
+// TestStore runs the conformance suite against a scratch SQLite database and, when
+// TEST_DATABASE_URL is set, against a scratch schema in PostgreSQL
+func TestStore(t *testing.T) {
+	for _, driver := range []string{"sqlite", "postgres"} {
+		t.Run(driver, func(t *testing.T) {
+			database := open(t, driver)
+			if err := migrate.RefreshReference(database, Fixture); err != nil {
+				t.Fatal(err)
+			}
+			// Run the suite through the prepared statements, as the server does
+			if err := database.PrepareStatements(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range Run(context.Background(), database) {
+				t.Run(r.Name, func(t *testing.T) {
+					if r.Err != nil {
+						t.Error(r.Err)
+					}
+				})
+			}
+		})
+	}
+}
+
+// TestImport loads a small OpenFlights extract twice on each driver; the second run merges
+// into the rows of the first
+func TestImport(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"countries.dat": `"Switzerland","CH","SZ"` + "\n",
+		"airports.dat": `1678,"Zurich Airport","Zurich","Switzerland","ZRH","LSZH",47.4647,8.54917,1416,1,"E","Europe/Zurich","airport","OurAirports"` + "\n" +
+			`1665,"Geneva Cointrin International Airport","Geneva","Switzerland","GVA","LSGG",46.2381,6.10895,1411,1,"E","Europe/Zurich","airport","OurAirports"` + "\n",
+		"airlines.dat": `4559,"Swiss International Air Lines","\N","LX","SWR","SWISS","Switzerland","Y"` + "\n",
+		"routes.dat": `LX,4559,ZRH,1678,GVA,1665,,0,320` + "\n" +
+			`LX,4559,GVA,1665,ZRH,1678,,0,320` + "\n" +
+			`LX,4559,ZRH,1678,GVA,1665,Y,0,321` + "\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	src := importer.Sources{
+		Airports:  filepath.Join(dir, "airports.dat"),
+		Airlines:  filepath.Join(dir, "airlines.dat"),
+		Routes:    filepath.Join(dir, "routes.dat"),
+		Countries: filepath.Join(dir, "countries.dat"),
+	}
+
+	for _, driver := range []string{"sqlite", "postgres"} {
+		t.Run(driver, func(t *testing.T) {
+			database := open(t, driver)
+			// Each route is stored under None, STAR_ALLIANCE and ALL; the repeated one only once
+			for run, wantRoutes := range []int{6, 0} {
+				stats, err := importer.Run(database, src, false)
+				if err != nil {
+					t.Fatalf("run %d: %v", run+1, err)
+				}
+				if stats.Airports != 2 || stats.Routes != wantRoutes {
+					t.Errorf("run %d: %d airports and %d routes imported, want 2 and %d", run+1, stats.Airports, stats.Routes, wantRoutes)
+				}
+			}
+			ctx := context.Background()
+			routes, err := database.GetRoutesFrom(ctx, "ZRH", "STAR_ALLIANCE")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := expect("STAR_ALLIANCE routes from ZRH", routes, []string{"GVA"}); err != nil {
+				t.Error(err)
+			}
+			city, err := database.GetCityForAirport(ctx, "GVA")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := expect("GVA city", city, "GVA"); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// open returns a freshly migrated scratch database for driver, skipping PostgreSQL when
+// TEST_DATABASE_URL isn't set
+func open(t *testing.T, driver string) *db.DB {
+	t.Helper()
+	var database *db.DB
+	switch driver {
+	case "sqlite":
+		dir := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(dir, "db"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		var err error
+		if database, err = db.New(dir, db.DefaultOptions()); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { database.Close() })
+	case "postgres":
+		base := os.Getenv("TEST_DATABASE_URL")
+		if base == "" {
+			t.Skip("set TEST_DATABASE_URL to run against PostgreSQL")
+		}
+		database = openPostgres(t, base)
+	}
+	migrations, err := migrate.Load(dbfiles.Migrations("", string(database.Dialect())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrate.Up(database, migrations, 0); err != nil {
+		t.Fatal(err)
+	}
+	return database
+}
+
+// openPostgres creates a throwaway schema and connects with it as the search path
+func openPostgres(t *testing.T, base string) *db.DB {
+	admin, err := db.NewPostgres(base, db.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := fmt.Sprintf("storetest_%d", time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		admin.Close()
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Logf("drop schema %s: %v", schema, err)
+		}
+		admin.Close()
+	})
+	database, err := db.NewPostgres(withSearchPath(base, schema), db.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return database
+}
+
+// withSearchPath adds search_path to a URL or keyword/value DSN
+func withSearchPath(dsn, schema string) string {
+	if u, err := url.Parse(dsn); err == nil && strings.Contains(dsn, "://") {
+		q := u.Query()
+		q.Set("search_path", schema)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return dsn + " search_path=" + schema
+}
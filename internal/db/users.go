@@ -0,0 +1,598 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// User is an account. It signs in with its phone number, its email address, a passkey or a
+// single sign-on identity. Phone and email may both be empty: a user created by single sign-on
+// gets no email when the provider has no verified one or another account already has it.
+type User struct {
+	ID    int64  `json:"id"`
+	Phone string `json:"phone,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+// ErrIdentityInUse is returned when adding a phone number or email address to a user that
+// already belongs to another
+var ErrIdentityInUse = errors.New("phone number or email already belongs to another user")
+
+// EmailLink is a pending sign-in link sent to an email address; only its token's hash is
+// stored. A link with a UserID adds the address to that user instead of signing in.
+type EmailLink struct {
+	TokenHash string
+	Email     string
+	UserID    int64 // 0 for a sign-in link
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// Session is a signed-in device. Its tokens aren't stored, only their hashes: the current
+// access token's on the session and every refresh token issued to it in refresh_tokens.
+type Session struct {
+	ID              int64     `json:"id"`
+	UserID          int64     `json:"-"`
+	UserAgent       string    `json:"userAgent"`
+	IP              string    `json:"ip"`
+	CreatedAt       time.Time `json:"createdAt"`
+	LastUsedAt      time.Time `json:"lastUsedAt"` // updated at most every few minutes, see TouchSession
+	AccessExpiresAt time.Time `json:"-"`
+	ExpiresAt       time.Time `json:"expiresAt"` // moves forward with each refresh
+}
+
+// TokenRotation is what refreshing a session hands out: the hashes of its new tokens and how
+// long they last
+type TokenRotation struct {
+	AccessHash       string
+	RefreshHash      string
+	AccessExpiresAt  time.Time
+	ExpiresAt        time.Time // the session's new expiry
+	IP               string
+	At               time.Time
+	ForgetUsedBefore time.Time // refresh tokens used before this are no longer kept for reuse detection
+}
+
+// ErrRefreshTokenReused is returned for a refresh token that was already exchanged; the
+// session it belonged to has been signed out
+var ErrRefreshTokenReused = errors.New("refresh token reused")
+
+// OTPCode is the pending one-time code for a phone number; only a salted hash of it is stored
+type OTPCode struct {
+	Phone     string
+	CodeHash  string
+	Attempts  int // verification attempts made against this code
+	SentAt    time.Time
+	ExpiresAt time.Time
+}
+
+// OTPLockout blocks logins for a phone number after too many wrong codes
+type OTPLockout struct {
+	Phone       string
+	Lockouts    int // consecutive lockouts, for backoff
+	LockedUntil time.Time
+}
+
+// BookedFlight is a flight a user entered in My Flights
+type BookedFlight struct {
+	ID            int64  `json:"id"`
+	UserID        int64  `json:"-"`
+	Airline       string `json:"airline"`
+	FlightNumber  string `json:"flight_number"`
+	FromIATA      string `json:"from_iata"`
+	ToIATA        string `json:"to_iata"`
+	DepartureDate string `json:"departure_date"`
+	DepartureTime string `json:"departure_time"`
+	Confirmation  string `json:"confirmation"`
+}
+
+// Queries run on every login or authenticated request; PrepareStatements prepares them
+const (
+	queryUserByPhone   = "SELECT id, phone, email FROM users WHERE phone = ?"
+	queryUserByEmail   = "SELECT id, phone, email FROM users WHERE email = ?"
+	queryEnsureUser    = "INSERT INTO users (phone) VALUES (?) ON CONFLICT (phone) DO NOTHING"
+	queryEnsureEmail   = "INSERT INTO users (email) VALUES (?) ON CONFLICT (email) DO NOTHING"
+	queryCreateSession = `INSERT INTO sessions (user_id, token_hash, user_agent, ip, created_at, last_used_at, access_expires_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`
+	querySession = `SELECT id, user_id, user_agent, ip, created_at, last_used_at, access_expires_at, expires_at
+		FROM sessions WHERE token_hash = ? AND expires_at > ?`
+	queryTouchSession = "UPDATE sessions SET last_used_at = ?, ip = ? WHERE id = ?"
+	querySaveOTP      = `INSERT INTO otp_codes (phone, code_hash, attempts, sent_at, expires_at) VALUES (?, ?, 0, ?, ?)
+		ON CONFLICT (phone) DO UPDATE SET code_hash = excluded.code_hash, attempts = 0,
+			sent_at = excluded.sent_at, expires_at = excluded.expires_at
+		WHERE otp_codes.sent_at <= ?`
+	queryGetOTP         = "SELECT code_hash, attempts, sent_at, expires_at FROM otp_codes WHERE phone = ?"
+	queryCountOTP       = "UPDATE otp_codes SET attempts = attempts + 1 WHERE phone = ?"
+	queryDeleteOTP      = "DELETE FROM otp_codes WHERE phone = ?"
+	queryUseOTP         = "DELETE FROM otp_codes WHERE phone = ? AND code_hash = ?"
+	queryGetOTPLockout  = "SELECT lockouts, locked_until FROM otp_lockouts WHERE phone = ?"
+	querySaveOTPLockout = `INSERT INTO otp_lockouts (phone, lockouts, locked_until) VALUES (?, ?, ?)
+		ON CONFLICT (phone) DO UPDATE SET lockouts = excluded.lockouts, locked_until = excluded.locked_until`
+	queryDeleteOTPLockout = "DELETE FROM otp_lockouts WHERE phone = ?"
+	queryListFlights      = `SELECT id, airline, flight_number, from_iata, to_iata, departure_date, departure_time, confirmation
+		FROM booked_flights WHERE user_id = ? ORDER BY departure_date, departure_time, id`
+	queryAddFlight = `INSERT INTO booked_flights (user_id, airline, flight_number, from_iata, to_iata, departure_date, departure_time, confirmation)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`
+	queryDeleteFlight = "DELETE FROM booked_flights WHERE id = ? AND user_id = ?"
+)
+
+// GetUserByPhone returns the user with a phone number, or nil if there is none
+func (d *DB) GetUserByPhone(ctx context.Context, phone string) (*User, error) {
+	return d.getUser(ctx, queryUserByPhone, phone)
+}
+
+// GetUserByEmail returns the user with an email address, or nil if there is none
+func (d *DB) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	return d.getUser(ctx, queryUserByEmail, email)
+}
+
+// GetUser returns a user by ID, or nil if there is none
+func (d *DB) GetUser(ctx context.Context, id int64) (*User, error) {
+	return d.getUser(ctx, "SELECT id, phone, email FROM users WHERE id = ?", id)
+}
+
+func (d *DB) getUser(ctx context.Context, query string, arg interface{}) (*User, error) {
+	var u User
+	var phone, email sql.NullString
+	err := d.QueryRowContext(ctx, query, arg).Scan(&u.ID, &phone, &email)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	u.Phone, u.Email = phone.String, email.String
+	return &u, nil
+}
+
+// EnsureUser returns the user with a phone number, creating it if needed
+func (d *DB) EnsureUser(ctx context.Context, phone string) (*User, error) {
+	if _, err := d.ExecContext(ctx, queryEnsureUser, phone); err != nil {
+		return nil, err
+	}
+	return d.GetUserByPhone(ctx, phone)
+}
+
+// EnsureUserByEmail returns the user with an email address, creating it if needed
+func (d *DB) EnsureUserByEmail(ctx context.Context, email string) (*User, error) {
+	if _, err := d.ExecContext(ctx, queryEnsureEmail, email); err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	return d.GetUserByEmail(ctx, email)
+}
+
+// SetUserPhone gives a user a phone number, replacing any it had; ErrIdentityInUse if
+// another user has it
+func (d *DB) SetUserPhone(ctx context.Context, userID int64, phone string) error {
+	return d.setIdentity(ctx, userID, "phone", phone)
+}
+
+// SetUserEmail gives a user an email address, replacing any it had; ErrIdentityInUse if
+// another user has it
+func (d *DB) SetUserEmail(ctx context.Context, userID int64, email string) error {
+	return d.setIdentity(ctx, userID, "email", email)
+}
+
+// setIdentity sets the phone or email column; the check spares callers a dialect-specific
+// unique violation in the usual case, and the UNIQUE constraint still catches a race
+func (d *DB) setIdentity(ctx context.Context, userID int64, column, value string) error {
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return ContextError(ctx, err)
+	}
+	defer tx.Rollback()
+	var owner int64
+	err = tx.QueryRowContext(ctx, d.Rebind("SELECT id FROM users WHERE "+column+" = ?"), value).Scan(&owner)
+	switch {
+	case err == nil && owner != userID:
+		return ErrIdentityInUse
+	case err != nil && err != sql.ErrNoRows:
+		return ContextError(ctx, err)
+	}
+	if _, err := tx.ExecContext(ctx, d.Rebind("UPDATE users SET "+column+" = ? WHERE id = ?"), value, userID); err != nil {
+		return ContextError(ctx, err)
+	}
+	return ContextError(ctx, tx.Commit())
+}
+
+// CreateSession stores a session with the hashes of its access and first refresh token and
+// sets its ID
+func (d *DB) CreateSession(ctx context.Context, s *Session, accessHash, refreshHash string) error {
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return ContextError(ctx, err)
+	}
+	defer tx.Rollback()
+	err = tx.QueryRowContext(ctx, d.Rebind(queryCreateSession), s.UserID, accessHash, s.UserAgent, s.IP,
+		s.CreatedAt.UTC(), s.LastUsedAt.UTC(), s.AccessExpiresAt.UTC(), s.ExpiresAt.UTC()).Scan(&s.ID)
+	if err != nil {
+		return ContextError(ctx, err)
+	}
+	if _, err := tx.ExecContext(ctx, d.Rebind("INSERT INTO refresh_tokens (token_hash, session_id, created_at) VALUES (?, ?, ?)"),
+		refreshHash, s.ID, s.CreatedAt.UTC()); err != nil {
+		return ContextError(ctx, err)
+	}
+	return ContextError(ctx, tx.Commit())
+}
+
+// GetSession returns the unexpired session whose access token has a hash, or nil if there is
+// none. The access token itself may have expired; see AccessExpiresAt.
+func (d *DB) GetSession(ctx context.Context, accessHash string) (*Session, error) {
+	var s Session
+	err := d.QueryRowContext(ctx, querySession, accessHash, time.Now().UTC()).Scan(
+		&s.ID, &s.UserID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastUsedAt, &s.AccessExpiresAt, &s.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	return &s, nil
+}
+
+// TouchSession records that a session was used at a time from an IP
+func (d *DB) TouchSession(ctx context.Context, id int64, ip string, at time.Time) error {
+	_, err := d.ExecContext(ctx, queryTouchSession, at.UTC(), ip, id)
+	return err
+}
+
+// ListSessions returns a user's unexpired sessions, most recently used first
+func (d *DB) ListSessions(ctx context.Context, userID int64) ([]Session, error) {
+	rows, err := d.QueryContext(ctx, `SELECT id, user_id, user_agent, ip, created_at, last_used_at, expires_at
+		FROM sessions WHERE user_id = ? AND expires_at > ? ORDER BY last_used_at DESC, id DESC`, userID, time.Now().UTC())
+	if err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	defer rows.Close()
+	sessions := []Session{}
+	for rows.Next() {
+		var s Session
+		if err := rows.Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, ContextError(ctx, rows.Err())
+}
+
+// DeleteSession ends one of a user's sessions and its refresh tokens; ok is false if the user
+// has no such session
+func (d *DB) DeleteSession(ctx context.Context, userID, id int64) (ok bool, err error) {
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return false, ContextError(ctx, err)
+	}
+	defer tx.Rollback()
+	res, err := tx.ExecContext(ctx, d.Rebind("DELETE FROM sessions WHERE id = ? AND user_id = ?"), id, userID)
+	if err != nil {
+		return false, ContextError(ctx, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	if err := d.deleteRefreshTokens(ctx, tx, id); err != nil {
+		return false, err
+	}
+	return true, ContextError(ctx, tx.Commit())
+}
+
+// RotateRefreshToken exchanges a refresh token for the new tokens in r, moving its session's
+// expiry forward, and returns the session. It returns nil if the token is unknown or its
+// session has expired. A token that was already exchanged signs its session out (whoever
+// holds a copy can't use it either) and returns ErrRefreshTokenReused.
+func (d *DB) RotateRefreshToken(ctx context.Context, refreshHash string, r TokenRotation) (*Session, error) {
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	defer tx.Rollback()
+	var sessionID int64
+	var usedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, d.Rebind("SELECT session_id, used_at FROM refresh_tokens WHERE token_hash = ?"), refreshHash).
+		Scan(&sessionID, &usedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	// Claim the token; losing a race to a concurrent exchange counts as reuse too
+	claimed := int64(0)
+	if !usedAt.Valid {
+		res, err := tx.ExecContext(ctx, d.Rebind("UPDATE refresh_tokens SET used_at = ? WHERE token_hash = ? AND used_at IS NULL"),
+			r.At.UTC(), refreshHash)
+		if err != nil {
+			return nil, ContextError(ctx, err)
+		}
+		if claimed, err = res.RowsAffected(); err != nil {
+			return nil, err
+		}
+	}
+	if claimed == 0 {
+		if _, err := tx.ExecContext(ctx, d.Rebind("DELETE FROM sessions WHERE id = ?"), sessionID); err != nil {
+			return nil, ContextError(ctx, err)
+		}
+		if err := d.deleteRefreshTokens(ctx, tx, sessionID); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, ContextError(ctx, err)
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	res, err := tx.ExecContext(ctx, d.Rebind(`UPDATE sessions SET token_hash = ?, access_expires_at = ?, expires_at = ?,
+		last_used_at = ?, ip = ? WHERE id = ? AND expires_at > ?`),
+		r.AccessHash, r.AccessExpiresAt.UTC(), r.ExpiresAt.UTC(), r.At.UTC(), r.IP, sessionID, r.At.UTC())
+	if err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, d.Rebind("INSERT INTO refresh_tokens (token_hash, session_id, created_at) VALUES (?, ?, ?)"),
+		r.RefreshHash, sessionID, r.At.UTC()); err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	if _, err := tx.ExecContext(ctx, d.Rebind("DELETE FROM refresh_tokens WHERE session_id = ? AND used_at < ?"),
+		sessionID, r.ForgetUsedBefore.UTC()); err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	var s Session
+	err = tx.QueryRowContext(ctx, d.Rebind(`SELECT id, user_id, user_agent, ip, created_at, last_used_at, access_expires_at, expires_at
+		FROM sessions WHERE id = ?`), sessionID).
+		Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastUsedAt, &s.AccessExpiresAt, &s.ExpiresAt)
+	if err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	return &s, nil
+}
+
+// deleteRefreshTokens removes a session's refresh tokens (the foreign key cascades too, but
+// SQLite only enforces it with foreign keys on)
+func (d *DB) deleteRefreshTokens(ctx context.Context, tx *sql.Tx, sessionID int64) error {
+	_, err := tx.ExecContext(ctx, d.Rebind("DELETE FROM refresh_tokens WHERE session_id = ?"), sessionID)
+	return ContextError(ctx, err)
+}
+
+// SaveOTP stores the hashed code for a phone number, replacing any earlier one and resetting
+// its attempts, unless the earlier one was sent less than cooldown ago; ok is false then
+func (d *DB) SaveOTP(ctx context.Context, o OTPCode, cooldown time.Duration) (ok bool, err error) {
+	res, err := d.ExecContext(ctx, querySaveOTP, o.Phone, o.CodeHash, o.SentAt.UTC(), o.ExpiresAt.UTC(),
+		o.SentAt.Add(-cooldown).UTC())
+	if err != nil {
+		return false, ContextError(ctx, err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetOTP returns the pending code for a phone number, or nil if there is none
+func (d *DB) GetOTP(ctx context.Context, phone string) (*OTPCode, error) {
+	o := OTPCode{Phone: phone}
+	err := d.QueryRowContext(ctx, queryGetOTP, phone).Scan(&o.CodeHash, &o.Attempts, &o.SentAt, &o.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	return &o, nil
+}
+
+// CountOTPAttempt records a verification attempt against a phone number's pending code and
+// returns the code with the attempt included, or nil if there is none. The count is taken
+// before the code is checked, so concurrent guesses can't share an attempt.
+func (d *DB) CountOTPAttempt(ctx context.Context, phone string) (*OTPCode, error) {
+	res, err := d.ExecContext(ctx, queryCountOTP, phone)
+	if err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return nil, err
+	}
+	return d.GetOTP(ctx, phone)
+}
+
+// DeleteOTP removes the pending code for a phone number
+func (d *DB) DeleteOTP(ctx context.Context, phone string) error {
+	_, err := d.ExecContext(ctx, queryDeleteOTP, phone)
+	return err
+}
+
+// UseOTP consumes a phone number's pending code if it is still the one with codeHash. ok is
+// false when it was already used or replaced, so of concurrent logins with one code only the
+// first succeeds.
+func (d *DB) UseOTP(ctx context.Context, phone, codeHash string) (ok bool, err error) {
+	res, err := d.ExecContext(ctx, queryUseOTP, phone, codeHash)
+	if err != nil {
+		return false, ContextError(ctx, err)
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// GetOTPLockout returns a phone number's latest lockout, or nil if it has none
+func (d *DB) GetOTPLockout(ctx context.Context, phone string) (*OTPLockout, error) {
+	l := OTPLockout{Phone: phone}
+	err := d.QueryRowContext(ctx, queryGetOTPLockout, phone).Scan(&l.Lockouts, &l.LockedUntil)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	return &l, nil
+}
+
+// SaveOTPLockout stores a phone number's lockout, replacing any earlier one
+func (d *DB) SaveOTPLockout(ctx context.Context, l OTPLockout) error {
+	_, err := d.ExecContext(ctx, querySaveOTPLockout, l.Phone, l.Lockouts, l.LockedUntil.UTC())
+	return err
+}
+
+// DeleteOTPLockout forgets a phone number's lockouts (after a successful login)
+func (d *DB) DeleteOTPLockout(ctx context.Context, phone string) error {
+	_, err := d.ExecContext(ctx, queryDeleteOTPLockout, phone)
+	return err
+}
+
+// SaveEmailLink stores a sign-in link unless another was sent to the same address less than
+// cooldown ago; ok is false then. The address's expired links are dropped on the way.
+func (d *DB) SaveEmailLink(ctx context.Context, l EmailLink, cooldown time.Duration) (ok bool, err error) {
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return false, ContextError(ctx, err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, d.Rebind("DELETE FROM email_links WHERE email = ? AND expires_at <= ?"),
+		l.Email, l.CreatedAt.UTC()); err != nil {
+		return false, ContextError(ctx, err)
+	}
+	var last time.Time
+	err = tx.QueryRowContext(ctx, d.Rebind("SELECT created_at FROM email_links WHERE email = ? ORDER BY created_at DESC LIMIT 1"),
+		l.Email).Scan(&last)
+	switch {
+	case err == nil && l.CreatedAt.Sub(last) < cooldown:
+		return false, nil
+	case err != nil && err != sql.ErrNoRows:
+		return false, ContextError(ctx, err)
+	}
+	var userID interface{}
+	if l.UserID != 0 {
+		userID = l.UserID
+	}
+	if _, err := tx.ExecContext(ctx, d.Rebind(`INSERT INTO email_links (token_hash, email, user_id, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?)`), l.TokenHash, l.Email, userID, l.CreatedAt.UTC(), l.ExpiresAt.UTC()); err != nil {
+		return false, ContextError(ctx, err)
+	}
+	return true, ContextError(ctx, tx.Commit())
+}
+
+// LastEmailLink returns when the newest link to an address was sent, or the zero time
+func (d *DB) LastEmailLink(ctx context.Context, email string) (time.Time, error) {
+	var last time.Time
+	err := d.QueryRowContext(ctx, "SELECT created_at FROM email_links WHERE email = ? ORDER BY created_at DESC LIMIT 1",
+		email).Scan(&last)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	return last, ContextError(ctx, err)
+}
+
+// UseEmailLink marks an unexpired, unused link as used and returns it, or nil if there is no
+// such link; a link works once even when two requests race for it
+func (d *DB) UseEmailLink(ctx context.Context, tokenHash string, at time.Time) (*EmailLink, error) {
+	l := EmailLink{TokenHash: tokenHash}
+	var userID sql.NullInt64
+	err := d.QueryRowContext(ctx, `UPDATE email_links SET used_at = ?
+		WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?
+		RETURNING email, user_id, created_at, expires_at`, at.UTC(), tokenHash, at.UTC()).
+		Scan(&l.Email, &userID, &l.CreatedAt, &l.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, ContextError(ctx, err)
+	}
+	l.UserID = userID.Int64
+	return &l, nil
+}
+
+// DeleteEmailLink removes a link, e.g. one that couldn't be delivered
+func (d *DB) DeleteEmailLink(ctx context.Context, tokenHash string) error {
+	_, err := d.ExecContext(ctx, "DELETE FROM email_links WHERE token_hash = ?", tokenHash)
+	return ContextError(ctx, err)
+}
+
+// ListBookedFlights returns a user's flights ordered by departure
+func (d *DB) ListBookedFlights(ctx context.Context, userID int64) ([]BookedFlight, error) {
+	rows, err := d.QueryContext(ctx, queryListFlights, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var flights []BookedFlight
+	for rows.Next() {
+		f := BookedFlight{UserID: userID}
+		var departureTime, confirmation sql.NullString
+		if err := rows.Scan(&f.ID, &f.Airline, &f.FlightNumber, &f.FromIATA, &f.ToIATA, &f.DepartureDate, &departureTime, &confirmation); err != nil {
+			return nil, err
+		}
+		f.DepartureTime = departureTime.String
+		f.Confirmation = confirmation.String
+		flights = append(flights, f)
+	}
+	return flights, ContextError(ctx, rows.Err())
+}
+
+// AddBookedFlight stores a flight for f.UserID and sets f.ID
+func (d *DB) AddBookedFlight(ctx context.Context, f *BookedFlight) error {
+	err := d.QueryRowContext(ctx, queryAddFlight, f.UserID, f.Airline, f.FlightNumber, f.FromIATA, f.ToIATA, f.DepartureDate, f.DepartureTime, f.Confirmation).Scan(&f.ID)
+	return ContextError(ctx, err)
+}
+
+// DeleteBookedFlight removes a flight if it belongs to the user; ok is false if it doesn't exist
+func (d *DB) DeleteBookedFlight(ctx context.Context, userID, id int64) (ok bool, err error) {
+	res, err := d.ExecContext(ctx, queryDeleteFlight, id, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteUser removes a user and everything tied to them in one transaction: sessions and
+// their refresh tokens, flights, profile, passkeys, single sign-on identities, pending sign-ins
+// and links, and login codes for their phone number. ok is false if there is no such user.
+// Nothing is kept to anonymize: other tables don't refer to users. A lockout on the phone
+// number stays, so deleting an account can't reset an attacker's backoff.
+func (d *DB) DeleteUser(ctx context.Context, userID int64) (ok bool, err error) {
+	tx, err := d.BeginTx(ctx, nil)
+	if err != nil {
+		return false, ContextError(ctx, err)
+	}
+	defer tx.Rollback()
+	var phone, email sql.NullString
+	err = tx.QueryRowContext(ctx, d.Rebind("SELECT phone, email FROM users WHERE id = ?"), userID).Scan(&phone, &email)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, ContextError(ctx, err)
+	}
+	for _, q := range []string{
+		"DELETE FROM refresh_tokens WHERE session_id IN (SELECT id FROM sessions WHERE user_id = ?)",
+		"DELETE FROM sessions WHERE user_id = ?",
+		"DELETE FROM booked_flights WHERE user_id = ?",
+		"DELETE FROM user_profiles WHERE user_id = ?",
+		"DELETE FROM passkeys WHERE user_id = ?",
+		"DELETE FROM passkey_challenges WHERE user_id = ?",
+		"DELETE FROM oidc_identities WHERE user_id = ?",
+		"DELETE FROM oidc_logins WHERE user_id = ?",
+		"DELETE FROM email_links WHERE user_id = ?",
+	} {
+		if _, err := tx.ExecContext(ctx, d.Rebind(q), userID); err != nil {
+			return false, ContextError(ctx, err)
+		}
+	}
+	if email.Valid {
+		if _, err := tx.ExecContext(ctx, d.Rebind("DELETE FROM email_links WHERE email = ?"), email.String); err != nil {
+			return false, ContextError(ctx, err)
+		}
+	}
+	if phone.Valid {
+		if _, err := tx.ExecContext(ctx, d.Rebind("DELETE FROM otp_codes WHERE phone = ?"), phone.String); err != nil {
+			return false, ContextError(ctx, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, d.Rebind("DELETE FROM users WHERE id = ?"), userID); err != nil {
+		return false, ContextError(ctx, err)
+	}
+	return true, ContextError(ctx, tx.Commit())
+}
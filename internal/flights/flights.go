@@ -1,8 +1,12 @@
 package flights
 
 import (
+	"context"
+	"sort"
 	"strings"
+	"triangle_travel/internal/awards"
 	"triangle_travel/internal/db"
+	"triangle_travel/internal/routegraph"
 )
 
 // FlightSearch represents search parameters
@@ -13,56 +17,212 @@ type FlightSearch struct {
 	EndDate   string `json:"endDate"`
 	Cabin     string `json:"cabin"`
 	Alliance  string `json:"alliance"`
+	// FreeStopover surfaces hubs where an airline offers a free stopover
+	FreeStopover bool `json:"freeStopover"`
+	// AwardPrograms prices each triangle in points under these loyalty programs
+	AwardPrograms []string `json:"awardPrograms"`
+	// GroundRadius is how far, in miles, drive-then-fly triangles reach from the destination
+	GroundRadius float64 `json:"groundRadius"`
 }
 
-// Normalize ensures uppercase and defaults
-func (f *FlightSearch) Normalize() {
+// Cabins are the cabin classes a search can ask for, cheapest first
+var Cabins = []string{"economy", "premium", "business", "first"}
+
+// DefaultGroundRadius is the drive-then-fly reach of a search that doesn't set one
+const DefaultGroundRadius = 300
+
+// Normalize ensures uppercase and defaults. Fields left empty are filled from defaults, the
+// searching user's profile, when given: the start from its first home airport, then cabin,
+// alliance, award programs and ground radius; whatever is still empty gets economy, no
+// alliance and DefaultGroundRadius. AwardPrograms is only filled when nil, so an explicit
+// empty list still means no award pricing.
+func (f *FlightSearch) Normalize(defaults *db.Profile) {
+	if defaults != nil {
+		if strings.TrimSpace(f.Start) == "" && len(defaults.HomeAirports) > 0 {
+			f.Start = defaults.HomeAirports[0]
+		}
+		if strings.TrimSpace(f.Cabin) == "" {
+			f.Cabin = defaults.PreferredCabin
+		}
+		if f.Alliance == "" {
+			f.Alliance = defaults.PreferredAlliance
+		}
+		if f.AwardPrograms == nil && len(defaults.LoyaltyPrograms) > 0 {
+			f.AwardPrograms = defaults.LoyaltyPrograms
+		}
+		if f.GroundRadius <= 0 {
+			f.GroundRadius = defaults.GroundRadius
+		}
+	}
 	f.Start = strings.ToUpper(strings.TrimSpace(f.Start))
 	f.End = strings.ToUpper(strings.TrimSpace(f.End))
+	f.Cabin = strings.ToLower(strings.TrimSpace(f.Cabin))
 	if f.Cabin == "" {
 		f.Cabin = "economy"
 	}
 	if f.Alliance == "" {
 		f.Alliance = "None"
 	}
+	if f.GroundRadius <= 0 {
+		f.GroundRadius = DefaultGroundRadius
+	}
 }
 
 // TriangleResult holds places to explore
 type TriangleResult struct {
-	DriveThenFly map[string]float64 `json:"driveThenFly"` // IATA -> distance or price delta
-	FlyThenFly   map[string]float64 `json:"flyThenFly"`   // IATA -> price delta
-	AvgPrice     float64            `json:"avgPrice"`     // placeholder, -1 if unknown
+	DriveThenFly map[string]float64        `json:"driveThenFly"` // IATA -> distance or price delta
+	FlyThenFly   map[string]float64        `json:"flyThenFly"`   // IATA -> price delta
+	AvgPrice     float64                   `json:"avgPrice"`     // placeholder, -1 if unknown
+	Stopovers    []StopoverCandidate       `json:"stopovers,omitempty"`
+	Awards       map[string][]awards.Quote `json:"awards,omitempty"`   // via IATA -> points cost per program
+	Snapshot     int64                     `json:"snapshot,omitempty"` // dataset snapshot the search was pinned to
+	// AwardsSkipped lists codes award pricing had to skip because the airports table has no
+	// location for them (a via here means no quotes for that triangle; the start or end, none at all)
+	AwardsSkipped []string `json:"awardsSkipped,omitempty"`
+}
+
+// StopoverCandidate is a hub where a free stopover program applies
+type StopoverCandidate struct {
+	Hub           string `json:"hub"`
+	Airline       string `json:"airline"`
+	AirlineCode   string `json:"airlineCode"`
+	Program       string `json:"program"`
+	MaxNights     int    `json:"maxNights"`
+	Conditions    string `json:"conditions"`
+	DirectFromEnd bool   `json:"directFromEnd"` // hub is also a direct route from the destination
 }
 
-// Explore returns triangle travel options from the database
-func Explore(database *db.DB, args FlightSearch) (*TriangleResult, error) {
-	args.Normalize()
+// Explore returns triangle travel options from the route graph (stopovers and awards from the
+// database). Callers with a profile to default from normalize args first.
+func Explore(ctx context.Context, database db.ReferenceStore, graph *routegraph.Graph, args FlightSearch) (*TriangleResult, error) {
+	args.Normalize(nil)
 
 	result := &TriangleResult{
 		DriveThenFly: make(map[string]float64),
 		FlyThenFly:   make(map[string]float64),
 		AvgPrice:     -1,
+		Snapshot:     graph.Snapshot,
 	}
 
 	// Distances: places you can drive/train to then fly
-	distances, err := database.GetDistancesFrom(args.End)
-	if err != nil {
-		return result, err
-	}
-	for iata, dist := range distances {
-		if dist >= 55 && dist <= 300 {
+	for iata, dist := range graph.DistancesFrom(args.End) {
+		if dist >= 55 && dist <= args.GroundRadius {
 			result.DriveThenFly[iata] = dist
 		}
 	}
 
 	// City routes: places you can fly to then fly out of
-	routes, err := database.GetRoutesFromWithFallback(args.End, args.Alliance)
-	if err != nil {
-		return result, err
-	}
-	for _, iata := range routes {
+	for _, iata := range graph.RoutesFromWithFallback(args.End, args.Alliance) {
 		result.FlyThenFly[iata] = 0 // DB doesn't have price; frontend can link to Kayak
 	}
 
+	if args.FreeStopover {
+		stopovers, err := freeStopovers(ctx, database, graph, args)
+		if err != nil {
+			return result, err
+		}
+		result.Stopovers = stopovers
+	}
+
+	if len(args.AwardPrograms) > 0 {
+		quotes, err := awardQuotes(ctx, database, args, result)
+		if err != nil {
+			return result, err
+		}
+		result.Awards = quotes
+	}
+
 	return result, nil
 }
+
+// freeStopovers lists stopover programs whose hub lies on the trip: the route data has to join
+// the start to the hub and the hub to the end, so the stopover can be taken on the way out or
+// back. Hubs in the start or end city aren't stopovers.
+func freeStopovers(ctx context.Context, database db.ReferenceStore, graph *routegraph.Graph, args FlightSearch) ([]StopoverCandidate, error) {
+	programs, err := database.GetStopoverPrograms(ctx, args.Alliance)
+	if err != nil {
+		return nil, err
+	}
+	startCity := graph.CityForAirport(args.Start)
+	endCity := graph.CityForAirport(args.End)
+	var candidates []StopoverCandidate
+	for _, p := range programs {
+		if p.HubCity == startCity || p.HubCity == endCity {
+			continue
+		}
+		if !graph.Connects(args.Start, p.HubCity, args.Alliance) || !graph.Connects(p.HubCity, args.End, args.Alliance) {
+			continue
+		}
+		candidates = append(candidates, StopoverCandidate{
+			Hub:           p.HubCity,
+			Airline:       p.Airline,
+			AirlineCode:   p.AirlineCode,
+			Program:       p.Program,
+			MaxNights:     p.MaxNights,
+			Conditions:    p.FareRestrictions,
+			DirectFromEnd: graph.Flies(args.End, p.HubCity, args.Alliance),
+		})
+	}
+	return candidates, nil
+}
+
+// awardQuotes prices every triangle in result under the requested award programs.
+// Drive-then-fly triangles are priced as open jaws; fly-then-fly ones include the End -> via flight.
+// Codes without airport data can't be priced and are listed once in result.AwardsSkipped.
+func awardQuotes(ctx context.Context, database db.ReferenceStore, args FlightSearch, result *TriangleResult) (map[string][]awards.Quote, error) {
+	charts, err := awards.LoadCharts(ctx, database, args.AwardPrograms)
+	if err != nil {
+		return nil, err
+	}
+	start, err := database.GetAirport(ctx, args.Start)
+	if err != nil {
+		return nil, err
+	}
+	end, err := database.GetAirport(ctx, args.End)
+	if err != nil {
+		return nil, err
+	}
+	for code, a := range map[string]*db.Airport{args.Start: start, args.End: end} {
+		if a == nil {
+			result.AwardsSkipped = append(result.AwardsSkipped, code)
+		}
+	}
+	if start == nil || end == nil {
+		sort.Strings(result.AwardsSkipped)
+		return nil, nil
+	}
+
+	quotes := make(map[string][]awards.Quote)
+	skipped := make(map[string]bool)
+	price := func(via string, hop bool) error {
+		if _, done := quotes[via]; done || skipped[via] {
+			return nil
+		}
+		viaAirport, err := database.GetAirport(ctx, via)
+		if err != nil {
+			return err
+		}
+		if viaAirport == nil {
+			skipped[via] = true
+			result.AwardsSkipped = append(result.AwardsSkipped, via)
+			return nil
+		}
+		t := awards.Triangle{Start: start, End: end, Via: viaAirport, Hop: hop}
+		for _, c := range charts {
+			quotes[via] = append(quotes[via], c.Price(t, args.Cabin))
+		}
+		return nil
+	}
+	for via := range result.DriveThenFly {
+		if err := price(via, false); err != nil {
+			return nil, err
+		}
+	}
+	for via := range result.FlyThenFly {
+		if err := price(via, true); err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(result.AwardsSkipped)
+	return quotes, nil
+}
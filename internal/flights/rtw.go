@@ -0,0 +1,315 @@
+package flights
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"triangle_travel/internal/db"
+	"triangle_travel/internal/geo"
+	"triangle_travel/internal/routegraph"
+)
+
+// maxRTWExpansions bounds the route search done by SuggestRTW
+const maxRTWExpansions = 20000
+
+// RTWViolation is one broken rule of a round-the-world itinerary
+type RTWViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	Segment int    `json:"segment,omitempty"` // 1-based segment, omitted for whole-itinerary rules
+}
+
+// RTWCheck is the validation result for one proposed itinerary
+type RTWCheck struct {
+	Itinerary  []string       `json:"itinerary"`
+	Segments   int            `json:"segments"`
+	Miles      float64        `json:"miles"`
+	Continents []string       `json:"continents"`
+	Direction  string         `json:"direction,omitempty"` // east or west
+	Valid      bool           `json:"valid"`
+	Violations []RTWViolation `json:"violations"`
+}
+
+// rtwLookup memoizes airport queries for one planner call; cities and routes come from the graph
+type rtwLookup struct {
+	ctx      context.Context
+	database db.ReferenceStore
+	graph    *routegraph.Graph
+	alliance string
+	airports map[string]*db.Airport
+}
+
+func newRTWLookup(ctx context.Context, database db.ReferenceStore, graph *routegraph.Graph, alliance string) *rtwLookup {
+	return &rtwLookup{
+		ctx:      ctx,
+		database: database,
+		graph:    graph,
+		alliance: alliance,
+		airports: make(map[string]*db.Airport),
+	}
+}
+
+func (l *rtwLookup) airport(code string) (*db.Airport, error) {
+	if a, ok := l.airports[code]; ok {
+		return a, nil
+	}
+	a, err := l.database.GetAirport(l.ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	l.airports[code] = a
+	return a, nil
+}
+
+func (l *rtwLookup) city(code string) string {
+	return l.graph.CityForAirport(code)
+}
+
+func (l *rtwLookup) routesFrom(code string) []string {
+	return l.graph.RoutesFromWithFallback(l.city(code), l.alliance)
+}
+
+// flies reports whether the alliance has a route from one code to the other, landing at any
+// airport of the destination city; the same routes SuggestRTW walks
+func (l *rtwLookup) flies(from, to string) bool {
+	toCity := l.city(to)
+	for _, dest := range l.routesFrom(from) {
+		if dest == to || l.city(dest) == toCity {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateRTW checks an itinerary (airport or city codes, origin repeated at the end) against a rule set.
+// Every segment has to be a city_routes route of the rule set's alliance.
+func ValidateRTW(ctx context.Context, database db.ReferenceStore, graph *routegraph.Graph, rules db.RTWRuleSet, itinerary []string) (*RTWCheck, error) {
+	return validateRTW(newRTWLookup(ctx, database, graph, rules.Alliance), rules, itinerary)
+}
+
+func validateRTW(l *rtwLookup, rules db.RTWRuleSet, itinerary []string) (*RTWCheck, error) {
+	check := &RTWCheck{Violations: []RTWViolation{}}
+	for _, code := range itinerary {
+		check.Itinerary = append(check.Itinerary, strings.ToUpper(strings.TrimSpace(code)))
+	}
+	violate := func(rule string, segment int, format string, args ...interface{}) {
+		check.Violations = append(check.Violations, RTWViolation{Rule: rule, Segment: segment, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(check.Itinerary) < 2 {
+		violate("segments", 0, "itinerary needs at least two points")
+		return check, nil
+	}
+	check.Segments = len(check.Itinerary) - 1
+
+	originCity := l.city(check.Itinerary[0])
+	finalCity := l.city(check.Itinerary[check.Segments])
+	if originCity != finalCity {
+		violate("closed", 0, "itinerary must return to its origin city %s", originCity)
+	}
+	if rules.MinSegments > 0 && check.Segments < rules.MinSegments {
+		violate("segments", 0, "%d segments, minimum is %d", check.Segments, rules.MinSegments)
+	}
+	if rules.MaxSegments > 0 && check.Segments > rules.MaxSegments {
+		violate("segments", 0, "%d segments, maximum is %d", check.Segments, rules.MaxSegments)
+	}
+
+	airports := make([]*db.Airport, len(check.Itinerary))
+	seenContinent := make(map[string]bool)
+	for i, code := range check.Itinerary {
+		a, err := l.airport(code)
+		if err != nil {
+			return nil, err
+		}
+		if a == nil {
+			violate("airport", 0, "unknown airport %s", code)
+			continue
+		}
+		airports[i] = a
+		if !seenContinent[a.Continent] {
+			seenContinent[a.Continent] = true
+			check.Continents = append(check.Continents, a.Continent)
+		}
+	}
+
+	var lonTotal float64
+	var atlantic, pacific int
+	geometryComplete := true
+	for i := 1; i < len(airports); i++ {
+		if !l.flies(check.Itinerary[i-1], check.Itinerary[i]) {
+			violate("route", i, "no %s route %s-%s", rules.Alliance, check.Itinerary[i-1], check.Itinerary[i])
+		}
+		from, to := airports[i-1], airports[i]
+		if from == nil || to == nil {
+			geometryComplete = false
+			continue
+		}
+		check.Miles += geo.DistanceMiles(from.Latitude, from.Longitude, to.Latitude, to.Longitude)
+		delta := lonDelta(from.Longitude, to.Longitude)
+		lonTotal += delta
+
+		switch oceanCrossed(from.Continent, to.Continent) {
+		case "atlantic":
+			atlantic++
+		case "pacific":
+			pacific++
+		}
+		if from.Continent == to.Continent || !rules.OneDirection {
+			continue
+		}
+		dir := "east"
+		if delta < 0 {
+			dir = "west"
+		}
+		if check.Direction == "" {
+			check.Direction = dir
+		} else if dir != check.Direction {
+			violate("direction", i, "%s-%s travels %s but the itinerary travels %s between continents", from.IATA, to.IATA, dir, check.Direction)
+		}
+	}
+	check.Miles = math.Round(check.Miles*10) / 10
+
+	if rules.MinContinents > 0 && len(check.Continents) < rules.MinContinents {
+		violate("continents", 0, "visits %d continents, minimum is %d", len(check.Continents), rules.MinContinents)
+	}
+	if rules.MaxContinents > 0 && len(check.Continents) > rules.MaxContinents {
+		violate("continents", 0, "visits %d continents, maximum is %d", len(check.Continents), rules.MaxContinents)
+	}
+	if rules.MaxMiles > 0 && check.Miles > float64(rules.MaxMiles) {
+		violate("miles", 0, "%.0f miles exceeds the %d mile cap", check.Miles, rules.MaxMiles)
+	}
+	if geometryComplete {
+		if math.Abs(lonTotal) < 180 {
+			violate("circumnavigation", 0, "itinerary does not circle the globe")
+		}
+		if atlantic == 0 || pacific == 0 {
+			violate("crossings", 0, "must cross both the Atlantic and the Pacific")
+		}
+	}
+	if rules.MaxAtlanticCrossings > 0 && atlantic > rules.MaxAtlanticCrossings {
+		violate("crossings", 0, "crosses the Atlantic %d times, maximum is %d", atlantic, rules.MaxAtlanticCrossings)
+	}
+	if rules.MaxPacificCrossings > 0 && pacific > rules.MaxPacificCrossings {
+		violate("crossings", 0, "crosses the Pacific %d times, maximum is %d", pacific, rules.MaxPacificCrossings)
+	}
+
+	check.Valid = len(check.Violations) == 0
+	return check, nil
+}
+
+// SuggestRTW searches city_routes for valid itineraries starting and ending at origin
+func SuggestRTW(ctx context.Context, database db.ReferenceStore, graph *routegraph.Graph, rules db.RTWRuleSet, origin string, limit int) ([]*RTWCheck, error) {
+	l := newRTWLookup(ctx, database, graph, rules.Alliance)
+	origin = strings.ToUpper(strings.TrimSpace(origin))
+	originCity := l.city(origin)
+	maxSegments := rules.MaxSegments
+	if maxSegments <= 0 {
+		maxSegments = 16
+	}
+
+	var found []*RTWCheck
+	expansions := 0
+	path := []string{origin}
+	visited := map[string]bool{originCity: true}
+
+	var walk func(miles float64, direction string) error
+	walk = func(miles float64, direction string) error {
+		if len(found) >= limit || expansions >= maxRTWExpansions || len(path)-1 >= maxSegments {
+			return nil
+		}
+		// Airport lookups are memoized, so the search can run long without touching the database
+		if err := ctx.Err(); err != nil {
+			return db.ContextError(ctx, err)
+		}
+		expansions++
+		here := path[len(path)-1]
+		from, err := l.airport(here)
+		if err != nil || from == nil {
+			return err
+		}
+		for _, dest := range l.routesFrom(here) {
+			to, err := l.airport(dest)
+			if err != nil {
+				return err
+			}
+			if to == nil {
+				continue
+			}
+			destCity := l.city(dest)
+			legMiles := geo.DistanceMiles(from.Latitude, from.Longitude, to.Latitude, to.Longitude)
+			if rules.MaxMiles > 0 && miles+legMiles > float64(rules.MaxMiles) {
+				continue
+			}
+			dir := direction
+			if rules.OneDirection && from.Continent != to.Continent {
+				legDir := "east"
+				if lonDelta(from.Longitude, to.Longitude) < 0 {
+					legDir = "west"
+				}
+				if dir != "" && legDir != dir {
+					continue
+				}
+				dir = legDir
+			}
+
+			path = append(path, dest)
+			if destCity == originCity {
+				check, err := validateRTW(l, rules, path)
+				if err != nil {
+					return err
+				}
+				if check.Valid {
+					found = append(found, check)
+				}
+			} else if !visited[destCity] {
+				visited[destCity] = true
+				if err := walk(miles+legMiles, dir); err != nil {
+					return err
+				}
+				visited[destCity] = false
+			}
+			path = path[:len(path)-1]
+			if len(found) >= limit {
+				break
+			}
+		}
+		return nil
+	}
+	if err := walk(0, ""); err != nil {
+		return nil, err
+	}
+	return found, nil
+}
+
+// lonDelta returns the signed shortest longitude change from a to b (positive is eastbound)
+func lonDelta(a, b float64) float64 {
+	d := b - a
+	for d > 180 {
+		d -= 360
+	}
+	for d < -180 {
+		d += 360
+	}
+	return d
+}
+
+// oceanCrossed classifies an intercontinental segment as an Atlantic or Pacific crossing
+func oceanCrossed(fromContinent, toContinent string) string {
+	americas := func(c string) bool { return c == "NA" || c == "SA" }
+	if americas(fromContinent) == americas(toContinent) {
+		return ""
+	}
+	other := toContinent
+	if americas(toContinent) {
+		other = fromContinent
+	}
+	switch other {
+	case "EU", "AF":
+		return "atlantic"
+	case "AS", "OC":
+		return "pacific"
+	}
+	return ""
+}
@@ -0,0 +1,136 @@
+package flights
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	dbfiles "triangle_travel/db"
+	"triangle_travel/internal/db"
+	"triangle_travel/internal/migrate"
+	"triangle_travel/internal/routegraph"
+)
+
+// seeded opens a scratch SQLite database with the migrations and seed data applied
+func seeded(t *testing.T) (*db.DB, *routegraph.Graph) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "db"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	database, err := db.New(dir, db.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	migrations, err := migrate.Load(dbfiles.Migrations("", string(database.Dialect())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrate.Up(database, migrations, 0); err != nil {
+		t.Fatal(err)
+	}
+	seedData, err := dbfiles.SeedData("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrate.RefreshReference(database, string(seedData)); err != nil {
+		t.Fatal(err)
+	}
+	graph, err := routegraph.Load(context.Background(), database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return database, graph
+}
+
+func ruleSet(t *testing.T, database *db.DB, code string) db.RTWRuleSet {
+	t.Helper()
+	rules, err := database.GetRTWRuleSets(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range rules {
+		if r.Code == code {
+			return r
+		}
+	}
+	t.Fatalf("no rule set %s in the seed data", code)
+	return db.RTWRuleSet{}
+}
+
+func TestValidateRTWRoutes(t *testing.T) {
+	database, graph := seeded(t)
+	rules := ruleSet(t, database, "OW_EXPLORER")
+
+	tests := []struct {
+		name      string
+		itinerary []string
+		valid     bool
+		noRoute   []int // segments reported as having no route
+	}{
+		{"flown by the alliance", []string{"JFK", "LHR", "DOH", "SYD", "LAX", "JFK"}, true, nil},
+		{"city codes", []string{"NYC", "LON", "DOH", "HKG", "TYO", "LAX", "NYC"}, true, nil},
+		{"unflown segments", []string{"JFK", "LHR", "DXB", "SYD", "LAX", "JFK"}, false, []int{2, 3}},
+		{"alliance without the route", []string{"JFK", "MIA", "JFK"}, false, []int{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check, err := ValidateRTW(context.Background(), database, graph, rules, tt.itinerary)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if check.Valid != tt.valid {
+				t.Errorf("valid = %v, want %v (violations %+v)", check.Valid, tt.valid, check.Violations)
+			}
+			var noRoute []int
+			for _, v := range check.Violations {
+				if v.Rule == "route" {
+					noRoute = append(noRoute, v.Segment)
+				}
+			}
+			if len(noRoute) != len(tt.noRoute) {
+				t.Fatalf("route violations on segments %v, want %v", noRoute, tt.noRoute)
+			}
+			for i := range noRoute {
+				if noRoute[i] != tt.noRoute[i] {
+					t.Fatalf("route violations on segments %v, want %v", noRoute, tt.noRoute)
+				}
+			}
+		})
+	}
+}
+
+func TestSuggestRTW(t *testing.T) {
+	database, graph := seeded(t)
+	rules := ruleSet(t, database, "OW_EXPLORER")
+
+	for _, origin := range []string{"JFK", "NYC"} {
+		t.Run(origin, func(t *testing.T) {
+			found, err := SuggestRTW(context.Background(), database, graph, rules, origin, 5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(found) == 0 {
+				t.Fatal("no itineraries suggested")
+			}
+			for _, check := range found {
+				if !check.Valid {
+					t.Errorf("%v suggested but invalid: %+v", check.Itinerary, check.Violations)
+				}
+				if check.Itinerary[0] != origin {
+					t.Errorf("%v doesn't start at %s", check.Itinerary, origin)
+				}
+				// A suggestion has to pass validation on its own, route by route
+				again, err := ValidateRTW(context.Background(), database, graph, rules, check.Itinerary)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !again.Valid {
+					t.Errorf("%v fails validation: %+v", check.Itinerary, again.Violations)
+				}
+			}
+		})
+	}
+}
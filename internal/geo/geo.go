@@ -0,0 +1,16 @@
+package geo
+
+import "math"
+
+// EarthRadiusMiles is the mean Earth radius used for great-circle distances
+const EarthRadiusMiles = 3958.8
+
+// DistanceMiles returns the great-circle distance in miles between two lat/lon points (degrees)
+func DistanceMiles(lat1, lon1, lat2, lon2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadiusMiles * math.Asin(math.Sqrt(a))
+}
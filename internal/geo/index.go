@@ -0,0 +1,131 @@
+package geo
+
+import (
+	"math"
+	"sort"
+)
+
+// milesPerDegree is the length of one degree of latitude
+const milesPerDegree = EarthRadiusMiles * math.Pi / 180
+
+// halfCircumference is the farthest any two points can be apart
+const halfCircumference = EarthRadiusMiles * math.Pi
+
+// Point is an indexed location
+type Point struct {
+	ID  string
+	Lat float64
+	Lon float64
+}
+
+// Hit is a point found by a query with its distance from the query location
+type Hit struct {
+	Point
+	Miles float64
+}
+
+// Index is a fixed-grid spatial index over lat/lon points; read-only after NewIndex
+type Index struct {
+	cellDeg  float64
+	lonCells int
+	cells    map[[2]int][]Point
+}
+
+// NewIndex buckets points into cellDeg x cellDeg degree cells (1 if cellDeg <= 0)
+func NewIndex(points []Point, cellDeg float64) *Index {
+	if cellDeg <= 0 {
+		cellDeg = 1
+	}
+	ix := &Index{
+		cellDeg:  cellDeg,
+		lonCells: int(math.Ceil(360 / cellDeg)),
+		cells:    make(map[[2]int][]Point),
+	}
+	for _, p := range points {
+		k := ix.cell(p.Lat, p.Lon)
+		ix.cells[k] = append(ix.cells[k], p)
+	}
+	return ix
+}
+
+func (ix *Index) latCell(lat float64) int {
+	return int(math.Floor((math.Max(-90, math.Min(90, lat)) + 90) / ix.cellDeg))
+}
+
+func (ix *Index) lonCell(lon float64) int {
+	c := int(math.Floor((lon + 180) / ix.cellDeg))
+	return ((c % ix.lonCells) + ix.lonCells) % ix.lonCells
+}
+
+func (ix *Index) cell(lat, lon float64) [2]int {
+	return [2]int{ix.latCell(lat), ix.lonCell(lon)}
+}
+
+// Within returns every point within miles of lat/lon, nearest first
+func (ix *Index) Within(lat, lon, miles float64) []Hit {
+	latSpan := miles / milesPerDegree
+	minLat, maxLat := math.Max(-90, lat-latSpan), math.Min(90, lat+latSpan)
+
+	// Longitude degrees shrink towards the poles; near them, or for huge radii, scan every column
+	allLon := true
+	var lonSpan float64
+	if maxAbs := math.Max(math.Abs(minLat), math.Abs(maxLat)); maxAbs < 89 {
+		lonSpan = miles / (milesPerDegree * math.Cos(maxAbs*math.Pi/180))
+		allLon = lonSpan >= 180
+	}
+
+	var hits []Hit
+	visit := func(latC, lonC int) {
+		for _, p := range ix.cells[[2]int{latC, lonC}] {
+			if d := DistanceMiles(lat, lon, p.Lat, p.Lon); d <= miles {
+				hits = append(hits, Hit{Point: p, Miles: d})
+			}
+		}
+	}
+	for latC := ix.latCell(minLat); latC <= ix.latCell(maxLat); latC++ {
+		if allLon {
+			for lonC := 0; lonC < ix.lonCells; lonC++ {
+				visit(latC, lonC)
+			}
+			continue
+		}
+		first := ix.lonCell(lon - lonSpan)
+		n := int(math.Ceil(2*lonSpan/ix.cellDeg)) + 1
+		if n > ix.lonCells {
+			n = ix.lonCells
+		}
+		for i := 0; i < n; i++ {
+			visit(latC, (first+i)%ix.lonCells)
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Miles != hits[j].Miles {
+			return hits[i].Miles < hits[j].Miles
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	return hits
+}
+
+// Nearest returns up to k points closest to lat/lon, nearest first. maxMiles > 0 caps the distance.
+func (ix *Index) Nearest(lat, lon float64, k int, maxMiles float64) []Hit {
+	if k <= 0 {
+		return nil
+	}
+	limit := halfCircumference
+	if maxMiles > 0 && maxMiles < limit {
+		limit = maxMiles
+	}
+	// Grow the radius until it holds k points; those are then the k nearest
+	radius := math.Min(50, limit)
+	for {
+		hits := ix.Within(lat, lon, radius)
+		if len(hits) >= k || radius >= limit {
+			if len(hits) > k {
+				hits = hits[:k]
+			}
+			return hits
+		}
+		radius = math.Min(radius*4, limit)
+	}
+}
@@ -0,0 +1,380 @@
+// Package importer loads OpenFlights and OurAirports data files into the reference tables.
+package importer
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"triangle_travel/internal/db"
+)
+
+// Sources lists the dataset files to import; empty paths are skipped
+type Sources struct {
+	OurAirports string // OurAirports airports.csv
+	Airports    string // OpenFlights airports.dat (fills codes missing from OurAirports)
+	Airlines    string // OpenFlights airlines.dat
+	Routes      string // OpenFlights routes.dat
+	Countries   string // OpenFlights countries.dat, maps country names in .dat files to ISO codes
+}
+
+// FileStats counts rows read, imported and skipped (by reason) for one file
+type FileStats struct {
+	File     string
+	Read     int
+	Imported int
+	Skipped  map[string]int
+}
+
+func (s *FileStats) skip(reason string) {
+	if s.Skipped == nil {
+		s.Skipped = make(map[string]int)
+	}
+	s.Skipped[reason]++
+}
+
+// Stats summarizes an import run
+type Stats struct {
+	Files        []*FileStats
+	Airports     int
+	Cities       int // distinct city codes written to iata_cities
+	MetroCities  int // city codes grouping more than one airport
+	Airlines     int
+	Routes       int // city_routes rows inserted
+	SkippedTotal int
+}
+
+// String renders the statistics for the command line
+func (s *Stats) String() string {
+	var b strings.Builder
+	for _, f := range s.Files {
+		fmt.Fprintf(&b, "%s: read %d, imported %d", f.File, f.Read, f.Imported)
+		reasons := make([]string, 0, len(f.Skipped))
+		for r := range f.Skipped {
+			reasons = append(reasons, r)
+		}
+		sort.Strings(reasons)
+		for _, r := range reasons {
+			fmt.Fprintf(&b, ", skipped %d (%s)", f.Skipped[r], r)
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "airports: %d, cities: %d (%d metro), airlines: %d, city_routes: %d\n",
+		s.Airports, s.Cities, s.MetroCities, s.Airlines, s.Routes)
+	return b.String()
+}
+
+// Run imports the given sources in one transaction, on either SQLite or PostgreSQL. With
+// replace, the tables fed by a given source are emptied first; otherwise rows are merged and
+// existing city mappings kept.
+func Run(database *db.DB, src Sources, replace bool) (*Stats, error) {
+	stats := &Stats{}
+	newStats := func(path string) *FileStats {
+		fs := &FileStats{File: path}
+		stats.Files = append(stats.Files, fs)
+		return fs
+	}
+
+	countries := map[string]string{}
+	if src.Countries != "" {
+		var err error
+		if countries, err = readOpenFlightsCountries(src.Countries); err != nil {
+			return nil, err
+		}
+	}
+
+	airports := make(map[string]airportRecord)
+	if src.OurAirports != "" {
+		fs := newStats(src.OurAirports)
+		recs, err := readOurAirports(src.OurAirports, fs)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range recs {
+			airports[a.IATA] = a
+		}
+	}
+	if src.Airports != "" {
+		fs := newStats(src.Airports)
+		recs, err := readOpenFlightsAirports(src.Airports, countries, fs)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range recs {
+			if _, ok := airports[a.IATA]; ok {
+				fs.skip("already imported")
+				continue
+			}
+			airports[a.IATA] = a
+		}
+	}
+
+	var airlines []airlineRecord
+	var airlinesStats *FileStats
+	if src.Airlines != "" {
+		airlinesStats = newStats(src.Airlines)
+		var err error
+		if airlines, err = readOpenFlightsAirlines(src.Airlines, countries, airlinesStats); err != nil {
+			return nil, err
+		}
+	}
+
+	var routes []routeRecord
+	var routesStats *FileStats
+	if src.Routes != "" {
+		routesStats = newStats(src.Routes)
+		var err error
+		if routes, err = readOpenFlightsRoutes(src.Routes, routesStats); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := database.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if replace {
+		var tables []string
+		if len(airports) > 0 {
+			tables = append(tables, "airports", "iata_cities")
+		}
+		if src.Airlines != "" {
+			tables = append(tables, "airlines")
+		}
+		if src.Routes != "" {
+			tables = append(tables, "city_routes")
+		}
+		for _, t := range tables {
+			if _, err := tx.Exec("DELETE FROM " + t); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Existing airport -> city mappings win over derived ones
+	cityOf := make(map[string]string)
+	if err := loadPairs(tx, "SELECT airport_code, city_code FROM iata_cities", cityOf); err != nil {
+		return nil, err
+	}
+	knownAirport := make(map[string]bool)
+	rows, err := tx.Query("SELECT iata_code FROM airports")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err == nil {
+			knownAirport[code] = true
+		}
+	}
+	rows.Close()
+
+	if len(airports) > 0 {
+		deriveCities(airports, routes, cityOf)
+		stmt, err := tx.Prepare(database.Rebind(`
+			INSERT INTO airports (iata_code, name, city_code, country, continent, latitude, longitude)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(iata_code) DO UPDATE SET name = excluded.name, city_code = excluded.city_code,
+				country = excluded.country, continent = excluded.continent,
+				latitude = excluded.latitude, longitude = excluded.longitude
+		`))
+		if err != nil {
+			return nil, err
+		}
+		defer stmt.Close()
+		cityStmt, err := tx.Prepare(database.Rebind("INSERT INTO iata_cities (city_code, airport_code) VALUES (?, ?) ON CONFLICT DO NOTHING"))
+		if err != nil {
+			return nil, err
+		}
+		defer cityStmt.Close()
+		for _, a := range airports {
+			city := cityOf[a.IATA]
+			if _, err := stmt.Exec(a.IATA, a.Name, city, a.Country, a.Continent, a.Latitude, a.Longitude); err != nil {
+				return nil, err
+			}
+			if _, err := cityStmt.Exec(city, a.IATA); err != nil {
+				return nil, err
+			}
+			knownAirport[a.IATA] = true
+			stats.Airports++
+		}
+		for _, fs := range stats.Files {
+			if fs.File == src.OurAirports || fs.File == src.Airports {
+				fs.Imported = fs.Read - skippedTotal(fs)
+			}
+		}
+	}
+
+	alliances := make(map[string]string)
+	activeAirline := make(map[string]bool)
+	if len(airlines) > 0 {
+		stmt, err := tx.Prepare(database.Rebind(`
+			INSERT INTO airlines (iata_code, icao_code, name, country, alliance, active)
+			VALUES (?, ?, ?, ?, ?, 1)
+			ON CONFLICT(iata_code) DO UPDATE SET icao_code = excluded.icao_code, name = excluded.name,
+				country = excluded.country, alliance = excluded.alliance, active = 1
+		`))
+		if err != nil {
+			return nil, err
+		}
+		defer stmt.Close()
+		for _, a := range airlines {
+			if activeAirline[a.IATA] {
+				airlinesStats.skip("duplicate IATA code")
+				continue
+			}
+			alliance := allianceMembers[a.IATA]
+			if alliance == "" {
+				alliance = "None"
+			}
+			if _, err := stmt.Exec(a.IATA, a.ICAO, a.Name, a.Country, alliance); err != nil {
+				return nil, err
+			}
+			activeAirline[a.IATA] = true
+			airlinesStats.Imported++
+			stats.Airlines++
+		}
+	}
+	rows, err = tx.Query("SELECT iata_code, alliance FROM airlines WHERE active = 1")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var code, alliance string
+		if err := rows.Scan(&code, &alliance); err == nil {
+			alliances[code] = alliance
+			activeAirline[code] = true
+		}
+	}
+	rows.Close()
+
+	if len(routes) > 0 {
+		stmt, err := tx.Prepare(database.Rebind("INSERT INTO city_routes (city_iata, alliance, route_to) VALUES (?, ?, ?) ON CONFLICT DO NOTHING"))
+		if err != nil {
+			return nil, err
+		}
+		defer stmt.Close()
+		for _, r := range routes {
+			if len(activeAirline) > 0 && !activeAirline[r.Airline] {
+				routesStats.skip("defunct or unknown airline")
+				continue
+			}
+			if !knownAirport[r.From] || !knownAirport[r.To] {
+				routesStats.skip("unknown airport")
+				continue
+			}
+			fromCity, toCity := cityOf[r.From], cityOf[r.To]
+			if fromCity == "" {
+				fromCity = r.From
+			}
+			if toCity == "" {
+				toCity = r.To
+			}
+			if fromCity == toCity {
+				routesStats.skip("same city")
+				continue
+			}
+			keys := []string{"None"}
+			if a := alliances[r.Airline]; a != "" && a != "None" {
+				keys = append(keys, a, "ALL")
+			}
+			for _, alliance := range keys {
+				res, err := stmt.Exec(fromCity, alliance, r.To)
+				if err != nil {
+					return nil, err
+				}
+				if n, _ := res.RowsAffected(); n > 0 {
+					stats.Routes++
+				}
+			}
+			routesStats.Imported++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	members := make(map[string]int)
+	for _, city := range cityOf {
+		members[city]++
+	}
+	stats.Cities = len(members)
+	for _, n := range members {
+		if n > 1 {
+			stats.MetroCities++
+		}
+	}
+	for _, fs := range stats.Files {
+		stats.SkippedTotal += skippedTotal(fs)
+	}
+	return stats, nil
+}
+
+func skippedTotal(fs *FileStats) int {
+	n := 0
+	for _, c := range fs.Skipped {
+		n += c
+	}
+	return n
+}
+
+func loadPairs(tx *sql.Tx, query string, into map[string]string) error {
+	rows, err := tx.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var k, v string
+		if err := rows.Scan(&k, &v); err != nil {
+			return err
+		}
+		into[k] = v
+	}
+	return rows.Err()
+}
+
+// deriveCities assigns a city code to every imported airport lacking one. Known metro codes
+// come first; otherwise airports sharing a municipality and country form a metro named after
+// the member with the most routes (large airports preferred); a lone airport is its own city.
+func deriveCities(airports map[string]airportRecord, routes []routeRecord, cityOf map[string]string) {
+	routeCount := make(map[string]int)
+	for _, r := range routes {
+		routeCount[r.From]++
+	}
+	groups := make(map[string][]string)
+	for code, a := range airports {
+		if _, ok := cityOf[code]; ok {
+			continue
+		}
+		if metro, ok := metroCodes[code]; ok {
+			cityOf[code] = metro
+			continue
+		}
+		if a.Municipality == "" {
+			cityOf[code] = code
+			continue
+		}
+		key := a.Country + "|" + strings.ToLower(a.Municipality)
+		groups[key] = append(groups[key], code)
+	}
+	for _, codes := range groups {
+		sort.Slice(codes, func(i, j int) bool {
+			a, b := airports[codes[i]], airports[codes[j]]
+			if a.Large != b.Large {
+				return a.Large
+			}
+			if routeCount[a.IATA] != routeCount[b.IATA] {
+				return routeCount[a.IATA] > routeCount[b.IATA]
+			}
+			return a.IATA < b.IATA
+		})
+		for _, code := range codes {
+			cityOf[code] = codes[0]
+		}
+	}
+}
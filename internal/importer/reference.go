@@ -0,0 +1,59 @@
+package importer
+
+// allianceMembers maps airline IATA code -> alliance. Neither OpenFlights nor OurAirports
+// carry alliance membership, so it is kept here and applied on import.
+var allianceMembers = map[string]string{
+	// oneworld
+	"AA": "ONE_WORLD", "AS": "ONE_WORLD", "AY": "ONE_WORLD", "BA": "ONE_WORLD", "CX": "ONE_WORLD",
+	"FJ": "ONE_WORLD", "IB": "ONE_WORLD", "JL": "ONE_WORLD", "MH": "ONE_WORLD", "QF": "ONE_WORLD",
+	"QR": "ONE_WORLD", "RJ": "ONE_WORLD", "AT": "ONE_WORLD", "UL": "ONE_WORLD", "WY": "ONE_WORLD",
+	// SkyTeam
+	"AF": "SKY_TEAM", "AM": "SKY_TEAM", "AR": "SKY_TEAM", "CI": "SKY_TEAM", "DL": "SKY_TEAM",
+	"GA": "SKY_TEAM", "KE": "SKY_TEAM", "KL": "SKY_TEAM", "KQ": "SKY_TEAM", "ME": "SKY_TEAM",
+	"MF": "SKY_TEAM", "MU": "SKY_TEAM", "RO": "SKY_TEAM", "SV": "SKY_TEAM", "UX": "SKY_TEAM",
+	"VN": "SKY_TEAM", "VS": "SKY_TEAM", "OK": "SKY_TEAM",
+	// Star Alliance
+	"A3": "STAR_ALLIANCE", "AC": "STAR_ALLIANCE", "AI": "STAR_ALLIANCE", "AV": "STAR_ALLIANCE",
+	"BR": "STAR_ALLIANCE", "CA": "STAR_ALLIANCE", "CM": "STAR_ALLIANCE", "ET": "STAR_ALLIANCE",
+	"JP": "STAR_ALLIANCE", "LH": "STAR_ALLIANCE", "LO": "STAR_ALLIANCE", "LX": "STAR_ALLIANCE",
+	"MS": "STAR_ALLIANCE", "NH": "STAR_ALLIANCE", "NZ": "STAR_ALLIANCE", "OS": "STAR_ALLIANCE",
+	"OU": "STAR_ALLIANCE", "OZ": "STAR_ALLIANCE", "SA": "STAR_ALLIANCE", "SK": "STAR_ALLIANCE",
+	"SN": "STAR_ALLIANCE", "SQ": "STAR_ALLIANCE", "TG": "STAR_ALLIANCE", "TK": "STAR_ALLIANCE",
+	"TP": "STAR_ALLIANCE", "UA": "STAR_ALLIANCE", "ZH": "STAR_ALLIANCE",
+}
+
+// metroCodes maps airport IATA code -> IATA metropolitan area code for well-known
+// multi-airport cities whose airports sit in different municipalities.
+var metroCodes = map[string]string{
+	"JFK": "NYC", "LGA": "NYC", "EWR": "NYC",
+	"ORD": "CHI", "MDW": "CHI",
+	"IAD": "WAS", "DCA": "WAS", "BWI": "WAS",
+	"DFW": "DFW", "DAL": "DFW",
+	"IAH": "HOU", "HOU": "HOU",
+	"DTW": "DTT",
+	"YYZ": "YTO", "YTZ": "YTO", "YHM": "YTO",
+	"YUL": "YMQ", "YMX": "YMQ", "YHU": "YMQ",
+	"LHR": "LON", "LGW": "LON", "LCY": "LON", "STN": "LON", "LTN": "LON", "SEN": "LON",
+	"CDG": "PAR", "ORY": "PAR", "LBG": "PAR", "BVA": "PAR",
+	"MXP": "MIL", "LIN": "MIL", "BGY": "MIL",
+	"FCO": "ROM", "CIA": "ROM",
+	"ARN": "STO", "BMA": "STO", "NYO": "STO",
+	"SVO": "MOW", "DME": "MOW", "VKO": "MOW",
+	"IST": "IST", "SAW": "IST",
+	"KEF": "REK", "RKV": "REK",
+	"HND": "TYO", "NRT": "TYO",
+	"KIX": "OSA", "ITM": "OSA", "UKB": "OSA",
+	"ICN": "SEL", "GMP": "SEL",
+	"PEK": "BJS", "PKX": "BJS",
+	"PVG": "SHA", "SHA": "SHA",
+	"DXB": "DXB", "DWC": "DXB",
+	"GRU": "SAO", "CGH": "SAO", "VCP": "SAO",
+	"GIG": "RIO", "SDU": "RIO",
+	"EZE": "BUE", "AEP": "BUE",
+}
+
+// southAmerica lists ISO countries placed in SA when the continent comes from a time zone
+var southAmerica = map[string]bool{
+	"AR": true, "BO": true, "BR": true, "CL": true, "CO": true, "EC": true, "FK": true,
+	"GF": true, "GY": true, "PE": true, "PY": true, "SR": true, "UY": true, "VE": true,
+}
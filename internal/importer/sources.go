@@ -0,0 +1,251 @@
+package importer
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// airportRecord is an airport row normalized from either dataset
+type airportRecord struct {
+	IATA         string
+	Name         string
+	Municipality string
+	Country      string // ISO alpha-2
+	Continent    string
+	Latitude     float64
+	Longitude    float64
+	Large        bool
+}
+
+type airlineRecord struct {
+	IATA    string
+	ICAO    string
+	Name    string
+	Country string
+	Active  bool
+}
+
+type routeRecord struct {
+	Airline string
+	From    string
+	To      string
+}
+
+// readCSV calls fn for every record of a CSV file; header skips the first line
+func readCSV(path string, header bool, fn func(rec []string, col func(string) string)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	index := map[string]int{}
+	first := true
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if first && header {
+			for i, name := range rec {
+				index[name] = i
+			}
+			first = false
+			continue
+		}
+		first = false
+		fn(rec, func(name string) string {
+			i, ok := index[name]
+			if !ok || i >= len(rec) {
+				return ""
+			}
+			return rec[i]
+		})
+	}
+}
+
+// field returns column i of an OpenFlights record with "\N" mapped to ""
+func field(rec []string, i int) string {
+	if i >= len(rec) {
+		return ""
+	}
+	v := strings.TrimSpace(rec[i])
+	if v == `\N` {
+		return ""
+	}
+	return v
+}
+
+func validIATA(code string, length int) bool {
+	if len(code) != length {
+		return false
+	}
+	for _, r := range code {
+		if (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// readOurAirports parses OurAirports airports.csv; closed and IATA-less airports are skipped
+func readOurAirports(path string, stats *FileStats) ([]airportRecord, error) {
+	var out []airportRecord
+	err := readCSV(path, true, func(rec []string, col func(string) string) {
+		stats.Read++
+		iata := strings.ToUpper(strings.TrimSpace(col("iata_code")))
+		kind := col("type")
+		switch {
+		case kind == "closed":
+			stats.skip("closed")
+			return
+		case !validIATA(iata, 3):
+			stats.skip("no IATA code")
+			return
+		case kind != "large_airport" && kind != "medium_airport" && kind != "small_airport":
+			stats.skip("not an airport")
+			return
+		}
+		lat, errLat := strconv.ParseFloat(col("latitude_deg"), 64)
+		lon, errLon := strconv.ParseFloat(col("longitude_deg"), 64)
+		if errLat != nil || errLon != nil {
+			stats.skip("bad coordinates")
+			return
+		}
+		out = append(out, airportRecord{
+			IATA:         iata,
+			Name:         col("name"),
+			Municipality: col("municipality"),
+			Country:      strings.ToUpper(col("iso_country")),
+			Continent:    strings.ToUpper(col("continent")),
+			Latitude:     lat,
+			Longitude:    lon,
+			Large:        kind == "large_airport",
+		})
+	})
+	return out, err
+}
+
+// readOpenFlightsCountries parses countries.dat into country name -> ISO alpha-2
+func readOpenFlightsCountries(path string) (map[string]string, error) {
+	countries := make(map[string]string)
+	err := readCSV(path, false, func(rec []string, _ func(string) string) {
+		if iso := field(rec, 1); iso != "" {
+			countries[field(rec, 0)] = iso
+		}
+	})
+	return countries, err
+}
+
+// readOpenFlightsAirports parses airports.dat; countries maps country names to ISO codes
+func readOpenFlightsAirports(path string, countries map[string]string, stats *FileStats) ([]airportRecord, error) {
+	var out []airportRecord
+	err := readCSV(path, false, func(rec []string, _ func(string) string) {
+		stats.Read++
+		iata := strings.ToUpper(field(rec, 4))
+		if !validIATA(iata, 3) {
+			stats.skip("no IATA code")
+			return
+		}
+		if t := field(rec, 12); t != "" && t != "airport" {
+			stats.skip("not an airport")
+			return
+		}
+		lat, errLat := strconv.ParseFloat(field(rec, 6), 64)
+		lon, errLon := strconv.ParseFloat(field(rec, 7), 64)
+		if errLat != nil || errLon != nil {
+			stats.skip("bad coordinates")
+			return
+		}
+		iso := countries[field(rec, 3)]
+		if iso == "" {
+			stats.skip("unknown country")
+			return
+		}
+		out = append(out, airportRecord{
+			IATA:         iata,
+			Name:         field(rec, 1),
+			Municipality: field(rec, 2),
+			Country:      iso,
+			Continent:    continentFromTZ(field(rec, 11), iso),
+			Latitude:     lat,
+			Longitude:    lon,
+		})
+	})
+	return out, err
+}
+
+// continentFromTZ derives a continent code from an Olson time zone name
+func continentFromTZ(tz, iso string) string {
+	region, _, _ := strings.Cut(tz, "/")
+	switch region {
+	case "Europe", "Atlantic", "Arctic":
+		return "EU"
+	case "Africa":
+		return "AF"
+	case "Asia", "Indian":
+		return "AS"
+	case "Australia", "Pacific":
+		return "OC"
+	case "Antarctica":
+		return "AN"
+	case "America":
+		if southAmerica[iso] {
+			return "SA"
+		}
+		return "NA"
+	}
+	return ""
+}
+
+// readOpenFlightsAirlines parses airlines.dat; inactive and IATA-less airlines are skipped
+func readOpenFlightsAirlines(path string, countries map[string]string, stats *FileStats) ([]airlineRecord, error) {
+	var out []airlineRecord
+	err := readCSV(path, false, func(rec []string, _ func(string) string) {
+		stats.Read++
+		iata := strings.ToUpper(field(rec, 3))
+		if !validIATA(iata, 2) {
+			stats.skip("no IATA code")
+			return
+		}
+		if field(rec, 7) != "Y" {
+			stats.skip("defunct")
+			return
+		}
+		country := countries[field(rec, 6)]
+		out = append(out, airlineRecord{
+			IATA:    iata,
+			ICAO:    field(rec, 4),
+			Name:    field(rec, 1),
+			Country: country,
+			Active:  true,
+		})
+	})
+	return out, err
+}
+
+// readOpenFlightsRoutes parses routes.dat keeping non-stop routes only
+func readOpenFlightsRoutes(path string, stats *FileStats) ([]routeRecord, error) {
+	var out []routeRecord
+	err := readCSV(path, false, func(rec []string, _ func(string) string) {
+		stats.Read++
+		if stops := field(rec, 7); stops != "" && stops != "0" {
+			stats.skip("not non-stop")
+			return
+		}
+		out = append(out, routeRecord{
+			Airline: strings.ToUpper(field(rec, 0)),
+			From:    strings.ToUpper(field(rec, 2)),
+			To:      strings.ToUpper(field(rec, 4)),
+		})
+	})
+	return out, err
+}
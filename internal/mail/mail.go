@@ -0,0 +1,135 @@
+// Package mail delivers email (sign-in links). Sender has implementations for development
+// (Console, File), SMTP for a real mail server, and Server, a local SMTP stand-in so the SMTP
+// sender can be exercised without one.
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	netmail "net/mail"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sender delivers one email
+type Sender interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// Message is an email: a plain-text body to one recipient. Senders fill in From.
+type Message struct {
+	ID      string    `json:"id,omitempty"`
+	From    string    `json:"from,omitempty"`
+	To      string    `json:"to"`
+	Subject string    `json:"subject"`
+	Body    string    `json:"body"`
+	Created time.Time `json:"created"`
+}
+
+// Kinds of sender selectable by configuration
+const (
+	KindConsole = "console"
+	KindFile    = "file"
+	KindSMTP    = "smtp"
+)
+
+// Config selects and configures a Sender
+type Config struct {
+	Kind string // console, file or smtp
+	File string // file: path messages are appended to
+
+	// smtp: server host:port, credentials (empty user skips AUTH) and sender address
+	SMTPAddr     string
+	SMTPUser     string
+	SMTPPassword string
+	From         string
+}
+
+// New returns the Sender cfg selects
+func New(cfg Config) (Sender, error) {
+	switch cfg.Kind {
+	case KindConsole:
+		return Console{}, nil
+	case KindFile:
+		if cfg.File == "" {
+			return nil, fmt.Errorf("mail: file sender needs a path")
+		}
+		return &File{Path: cfg.File}, nil
+	case KindSMTP:
+		if cfg.SMTPAddr == "" || cfg.From == "" {
+			return nil, fmt.Errorf("mail: smtp sender needs a server address and from address")
+		}
+		if _, err := netmail.ParseAddress(cfg.From); err != nil {
+			return nil, fmt.Errorf("mail: from address %q: %v", cfg.From, err)
+		}
+		return &SMTP{Addr: cfg.SMTPAddr, Username: cfg.SMTPUser, Password: cfg.SMTPPassword, From: cfg.From}, nil
+	default:
+		return nil, fmt.Errorf("mail: unknown sender %q (want %s, %s or %s)", cfg.Kind, KindConsole, KindFile, KindSMTP)
+	}
+}
+
+// Console logs messages instead of sending them (development only: links end up in the log)
+type Console struct{}
+
+// Send logs the message
+func (Console) Send(ctx context.Context, m Message) error {
+	log.Printf("Email to %s: %s\n%s", m.To, m.Subject, m.Body)
+	return nil
+}
+
+// File appends messages to a file as JSON lines, for development and scripted logins
+type File struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+// Send appends the message
+func (f *File) Send(ctx context.Context, m Message) error {
+	m.Created = time.Now().UTC()
+	line, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out, err := os.OpenFile(f.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := out.Write(append(line, '\n')); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// AddressError is an email address that can't be used; its message is meant for the person
+// who typed it
+type AddressError struct {
+	Msg string
+}
+
+func (e *AddressError) Error() string { return e.Msg }
+
+// NormalizeAddress checks a bare address (no display name) and lowercases it, so one mailbox
+// is one identity however it's typed
+func NormalizeAddress(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", &AddressError{Msg: "Email address required"}
+	}
+	a, err := netmail.ParseAddress(s)
+	if err != nil || a.Name != "" || a.Address != s || len(s) > 254 {
+		return "", &AddressError{Msg: "Not a valid email address"}
+	}
+	_, domain, _ := strings.Cut(a.Address, "@")
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, "[") {
+		return "", &AddressError{Msg: "Not a valid email address"}
+	}
+	return strings.ToLower(a.Address), nil
+}
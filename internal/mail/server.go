@@ -0,0 +1,271 @@
+package mail
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	netmail "net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is a local SMTP stand-in: it speaks enough SMTP for the SMTP sender (EHLO, AUTH PLAIN,
+// MAIL, RCPT, DATA) and keeps messages in memory instead of delivering them. These recipients
+// fail the way real mailboxes do:
+//
+//	rejected@example.com  550, no such mailbox
+//	full@example.com      552, mailbox full
+//	tempfail@example.com  451, try again later
+//
+// Its HTTP handler lists what was received: GET /messages?to= (newest last), DELETE /messages.
+type Server struct {
+	Hostname  string // in the greeting; empty uses localhost
+	Username  string // AUTH PLAIN credentials to require; empty accepts mail without AUTH
+	Password  string
+	OnMessage func(Message) // called for each received message, if set
+
+	mu       sync.Mutex
+	messages []Message
+}
+
+var serverFailures = map[string]string{
+	"rejected@example.com": "550 5.1.1 Mailbox unavailable",
+	"full@example.com":     "552 5.2.2 Mailbox full",
+	"tempfail@example.com": "451 4.3.0 Temporary failure, try again later",
+}
+
+// maxMessageSize is the largest message DATA accepts
+const maxMessageSize = 1 << 20
+
+// ListenAndServe accepts SMTP connections on addr
+func (s *Server) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts SMTP connections on l until it is closed
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.session(conn)
+	}
+}
+
+// Messages returns the messages received so far, optionally only those to one address
+func (s *Server) Messages(to string) []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []Message{}
+	for _, m := range s.messages {
+		if to == "" || strings.EqualFold(m.To, to) {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// Handler returns the HTTP routes listing and clearing received messages
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /messages", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.Messages(r.URL.Query().Get("to")))
+	})
+	mux.HandleFunc("DELETE /messages", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.messages = nil
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+// session runs one SMTP conversation
+func (s *Server) session(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	hostname := s.Hostname
+	if hostname == "" {
+		hostname = "localhost"
+	}
+	reply := func(line string) error { return tp.PrintfLine("%s", line) }
+	if reply("220 "+hostname+" ESMTP triangle_travel stand-in") != nil {
+		return
+	}
+	authed := s.Username == ""
+	var from string
+	var rcpts []string
+	for {
+		conn.SetDeadline(time.Now().Add(5 * time.Minute))
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			err = reply("250-" + hostname + "\r\n250-8BITMIME\r\n250-SIZE " + strconv.Itoa(maxMessageSize) + "\r\n250 AUTH PLAIN")
+		case "HELO":
+			err = reply("250 " + hostname)
+		case "AUTH":
+			mech, initial, _ := strings.Cut(arg, " ")
+			if !strings.EqualFold(mech, "PLAIN") {
+				err = reply("504 5.5.4 Unrecognized authentication type")
+				break
+			}
+			if initial == "" {
+				if reply("334 ") != nil {
+					return
+				}
+				if initial, err = tp.ReadLine(); err != nil {
+					return
+				}
+			}
+			if s.checkPlain(initial) {
+				authed = true
+				err = reply("235 2.7.0 Authentication successful")
+			} else {
+				err = reply("535 5.7.8 Authentication credentials invalid")
+			}
+		case "MAIL":
+			addr, ok := pathArg(arg, "FROM:")
+			switch {
+			case !authed:
+				err = reply("530 5.7.0 Authentication required")
+			case !ok:
+				err = reply("501 5.5.4 Syntax: MAIL FROM:<address>")
+			default:
+				from, rcpts = addr, nil
+				err = reply("250 2.1.0 OK")
+			}
+		case "RCPT":
+			addr, ok := pathArg(arg, "TO:")
+			switch {
+			case from == "":
+				err = reply("503 5.5.1 MAIL first")
+			case !ok || addr == "":
+				err = reply("501 5.5.4 Syntax: RCPT TO:<address>")
+			case serverFailures[strings.ToLower(addr)] != "":
+				err = reply(serverFailures[strings.ToLower(addr)])
+			default:
+				rcpts = append(rcpts, addr)
+				err = reply("250 2.1.5 OK")
+			}
+		case "DATA":
+			if len(rcpts) == 0 {
+				err = reply("503 5.5.1 RCPT first")
+				break
+			}
+			if reply("354 End data with <CR><LF>.<CR><LF>") != nil {
+				return
+			}
+			dot := tp.DotReader()
+			data, readErr := io.ReadAll(io.LimitReader(dot, maxMessageSize+1))
+			if readErr != nil {
+				return
+			}
+			io.Copy(io.Discard, dot)
+			if len(data) > maxMessageSize {
+				err = reply("552 5.3.4 Message too big")
+			} else {
+				id := s.receive(from, rcpts, data)
+				err = reply("250 2.0.0 OK: queued as " + id)
+			}
+			from, rcpts = "", nil
+		case "RSET":
+			from, rcpts = "", nil
+			err = reply("250 2.0.0 OK")
+		case "NOOP":
+			err = reply("250 2.0.0 OK")
+		case "QUIT":
+			reply("221 2.0.0 Bye")
+			return
+		default:
+			err = reply("502 5.5.2 Command not recognized")
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// checkPlain checks an AUTH PLAIN response: base64 of authzid NUL user NUL password
+func (s *Server) checkPlain(initial string) bool {
+	b, err := base64.StdEncoding.DecodeString(initial)
+	if err != nil {
+		return false
+	}
+	parts := strings.Split(string(b), "\x00")
+	if len(parts) != 3 {
+		return false
+	}
+	if s.Username == "" {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(parts[1]), []byte(s.Username)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(parts[2]), []byte(s.Password)) == 1
+}
+
+// receive records a message once per recipient and returns its queue ID
+func (s *Server) receive(from string, rcpts []string, data []byte) string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	id := strings.ToUpper(hex.EncodeToString(b))
+	m := Message{ID: id, From: from, Created: time.Now().UTC(), Body: string(data)}
+	if parsed, err := netmail.ReadMessage(strings.NewReader(string(data))); err == nil {
+		var dec mime.WordDecoder
+		if subject, err := dec.DecodeHeader(parsed.Header.Get("Subject")); err == nil {
+			m.Subject = subject
+		}
+		body := parsed.Body
+		if strings.EqualFold(parsed.Header.Get("Content-Transfer-Encoding"), "quoted-printable") {
+			body = quotedprintable.NewReader(body)
+		}
+		if text, err := io.ReadAll(body); err == nil {
+			m.Body = strings.ReplaceAll(string(text), "\r\n", "\n")
+		}
+	}
+	for _, to := range rcpts {
+		m.To = to
+		s.mu.Lock()
+		s.messages = append(s.messages, m)
+		s.mu.Unlock()
+		if s.OnMessage != nil {
+			s.OnMessage(m)
+		}
+	}
+	return id
+}
+
+// pathArg reads the address from "FROM:<addr>" or "TO:<addr>", ignoring any parameters after it
+func pathArg(arg, prefix string) (string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", false
+	}
+	rest := strings.TrimSpace(arg[len(prefix):])
+	if !strings.HasPrefix(rest, "<") {
+		return "", false
+	}
+	addr, _, ok := strings.Cut(rest[1:], ">")
+	return addr, ok
+}
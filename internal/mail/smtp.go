@@ -0,0 +1,146 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Error is a reply the SMTP server refused a message with
+type Error struct {
+	Code    int    // SMTP reply code, e.g. 550
+	Message string // the server's reply text
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("smtp: %d %s", e.Code, e.Message)
+}
+
+// Rejected reports whether the server refused the recipient or message permanently (5xx), so
+// retrying won't help; 4xx replies and connection errors are temporary
+func (e *Error) Rejected() bool {
+	return e.Code >= 500
+}
+
+// SMTP sends messages through an SMTP server (or Server), upgrading to TLS when the server
+// offers STARTTLS. Credentials are only sent over TLS, or to localhost.
+type SMTP struct {
+	Addr     string // host:port
+	Username string // empty skips AUTH
+	Password string
+	From     string
+	Timeout  time.Duration // for the whole exchange; zero means 10s
+}
+
+// Send delivers the message; a refusal comes back as *Error
+func (s *SMTP) Send(ctx context.Context, m Message) error {
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return fmt.Errorf("smtp: server address: %w", err)
+	}
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	// net/smtp has no context support; closing the connection unblocks it when ctx ends first
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return smtpError(ctx, err)
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return smtpError(ctx, err)
+		}
+	}
+	if s.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.Username, s.Password, host)); err != nil {
+			return smtpError(ctx, err)
+		}
+	}
+	from, err := netmail.ParseAddress(s.From)
+	if err != nil {
+		return fmt.Errorf("smtp: from address: %w", err)
+	}
+	if err := c.Mail(from.Address); err != nil {
+		return smtpError(ctx, err)
+	}
+	if err := c.Rcpt(m.To); err != nil {
+		return smtpError(ctx, err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return smtpError(ctx, err)
+	}
+	if _, err := w.Write(compose(s.From, from.Address, m)); err != nil {
+		return smtpError(ctx, err)
+	}
+	if err := w.Close(); err != nil {
+		return smtpError(ctx, err)
+	}
+	return smtpError(ctx, c.Quit())
+}
+
+// smtpError turns a server reply into *Error and reports a connection closed by ctx as ctx's error
+func smtpError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	var reply *textproto.Error
+	if errors.As(err, &reply) {
+		return &Error{Code: reply.Code, Message: reply.Msg}
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("smtp: %w", ctx.Err())
+	}
+	return fmt.Errorf("smtp: %w", err)
+}
+
+// compose renders a plain-text message with CRLF line endings, quoted-printable so any UTF-8
+// body survives 7-bit relays
+func compose(from, fromAddr string, m Message) []byte {
+	var b bytes.Buffer
+	id := make([]byte, 16)
+	rand.Read(id)
+	_, domain, _ := strings.Cut(fromAddr, "@")
+	header := func(k, v string) { b.WriteString(k + ": " + v + "\r\n") }
+	header("From", from)
+	header("To", m.To)
+	header("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", "<"+hex.EncodeToString(id)+"@"+domain+">")
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	b.WriteString("\r\n")
+	qp := quotedprintable.NewWriter(&b)
+	qp.Write([]byte(strings.ReplaceAll(strings.ReplaceAll(m.Body, "\r\n", "\n"), "\n", "\r\n")))
+	qp.Close()
+	return b.Bytes()
+}
@@ -0,0 +1,291 @@
+// Package migrate applies numbered up/down SQL migrations and tracks them in schema_migrations.
+// Migration files are named NNNN_name.up.sql and NNNN_name.down.sql; each dialect has its own set.
+//
+// A SQLite script containing the line "-- migrate:foreign-keys off" runs with foreign keys off,
+// as rebuilding a table that others reference requires; the foreign keys are checked before it
+// commits.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"triangle_travel/internal/db"
+)
+
+// ErrPending is returned by Check when the database is missing migrations
+var ErrPending = errors.New("database has pending migrations")
+
+// Migration is one numbered schema change
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status describes one migration and whether it's applied
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt string
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Load reads migrations from the root of fsys, ordered by version
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*Migration)
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+		version, _ := strconv.Atoi(m[1])
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		mig := byVersion[version]
+		if mig == nil {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %04d has two names: %s and %s", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func ensureTable(db *db.DB) error {
+	timestamp := "DATETIME"
+	if db.Dialect() == "postgres" {
+		timestamp = "TIMESTAMPTZ"
+	}
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at ` + timestamp + ` DEFAULT CURRENT_TIMESTAMP
+	)`)
+	return err
+}
+
+// applied returns version -> applied_at for applied migrations (empty if the table is missing)
+func applied(db *db.DB) (map[int]string, error) {
+	exists, err := db.TableExists("schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[int]string)
+	if !exists {
+		return result, nil
+	}
+	rows, err := db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v int
+		var at sql.NullString
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, err
+		}
+		result[v] = at.String
+	}
+	return result, rows.Err()
+}
+
+// GetStatus lists every known migration and whether it has been applied
+func GetStatus(db *db.DB, migrations []Migration) ([]Status, error) {
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Status, 0, len(migrations))
+	for _, m := range migrations {
+		at, ok := done[m.Version]
+		out = append(out, Status{Version: m.Version, Name: m.Name, Applied: ok, AppliedAt: at})
+	}
+	return out, nil
+}
+
+// Up applies pending migrations in order, each in its own transaction. steps <= 0 applies all.
+// It returns the versions applied.
+func Up(db *db.DB, migrations []Migration, steps int) ([]int, error) {
+	if err := ensureTable(db); err != nil {
+		return nil, err
+	}
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+	var ran []int
+	for _, m := range migrations {
+		if _, ok := done[m.Version]; ok {
+			continue
+		}
+		if steps > 0 && len(ran) >= steps {
+			break
+		}
+		if err := run(db, m.Up, func(tx *sql.Tx) error {
+			_, err := tx.Exec(db.Rebind("INSERT INTO schema_migrations (version, name) VALUES (?, ?)"), m.Version, m.Name)
+			return err
+		}); err != nil {
+			return ran, fmt.Errorf("migration %04d_%s up: %w", m.Version, m.Name, err)
+		}
+		ran = append(ran, m.Version)
+	}
+	return ran, nil
+}
+
+// Down reverts the latest applied migrations; steps <= 0 reverts one. It returns the versions reverted.
+func Down(db *db.DB, migrations []Migration, steps int) ([]int, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+	var reverted []int
+	for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+		m := migrations[i]
+		if _, ok := done[m.Version]; !ok {
+			continue
+		}
+		if m.Down == "" {
+			return reverted, fmt.Errorf("migration %04d_%s has no down file", m.Version, m.Name)
+		}
+		if err := run(db, m.Down, func(tx *sql.Tx) error {
+			_, err := tx.Exec(db.Rebind("DELETE FROM schema_migrations WHERE version = ?"), m.Version)
+			return err
+		}); err != nil {
+			return reverted, fmt.Errorf("migration %04d_%s down: %w", m.Version, m.Name, err)
+		}
+		reverted = append(reverted, m.Version)
+	}
+	return reverted, nil
+}
+
+// foreignKeysOff marks a SQLite script that must run with foreign keys off
+var foreignKeysOff = regexp.MustCompile(`(?m)^--\s*migrate:foreign-keys off\s*$`)
+
+func run(database *db.DB, script string, record func(*sql.Tx) error) error {
+	ctx := context.Background()
+	// The pragma is per connection and ignored inside a transaction, so pin one connection
+	conn, err := database.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	fkOff := database.Dialect() == db.SQLite && foreignKeysOff.MatchString(script)
+	if fkOff {
+		var enabled bool
+		if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&enabled); err != nil {
+			return err
+		}
+		if enabled {
+			if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+				return err
+			}
+			defer conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+		}
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if strings.TrimSpace(script) != "" {
+		if _, err := tx.Exec(script); err != nil {
+			return err
+		}
+	}
+	if fkOff {
+		if err := checkForeignKeys(tx); err != nil {
+			return err
+		}
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkForeignKeys fails if any row references a missing parent
+func checkForeignKeys(tx *sql.Tx) error {
+	rows, err := tx.Query("PRAGMA foreign_key_check")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var problems []string
+	for rows.Next() {
+		var table, parent string
+		var rowid sql.NullInt64
+		var fk int
+		if err := rows.Scan(&table, &rowid, &parent, &fk); err != nil {
+			return err
+		}
+		problems = append(problems, fmt.Sprintf("%s row %d references missing %s", table, rowid.Int64, parent))
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("foreign key check: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Check returns ErrPending (wrapped with the missing versions) unless every migration is applied,
+// and an error if the database has migrations this binary doesn't know about.
+func Check(db *db.DB, migrations []Migration) error {
+	done, err := applied(db)
+	if err != nil {
+		return err
+	}
+	known := make(map[int]bool, len(migrations))
+	var pending []string
+	for _, m := range migrations {
+		known[m.Version] = true
+		if _, ok := done[m.Version]; !ok {
+			pending = append(pending, fmt.Sprintf("%04d_%s", m.Version, m.Name))
+		}
+	}
+	for v := range done {
+		if !known[v] {
+			return fmt.Errorf("database has migration %04d which this build doesn't know; upgrade the server", v)
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %s", ErrPending, strings.Join(pending, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package migrate
+
+import "triangle_travel/internal/db"
+
+// ReferenceTables are the tables rebuilt from seed data; user tables are never listed here
+var ReferenceTables = []string{
+	"iata_cities",
+	"distances",
+	"city_routes",
+	"stopover_programs",
+	"airports",
+	"award_programs",
+	"award_distance_bands",
+	"award_zones",
+	"award_zone_prices",
+	"rtw_rule_sets",
+	"airlines",
+}
+
+// RefreshReference empties the reference tables and loads seedSQL in one transaction,
+// leaving users, sessions and booked flights untouched
+func RefreshReference(db *db.DB, seedSQL string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, t := range ReferenceTables {
+		if _, err := tx.Exec("DELETE FROM " + t); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(seedSQL); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,177 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// jws is a compact-serialized JSON Web Signature (an ID token), not yet verified
+type jws struct {
+	header struct {
+		Alg   string `json:"alg"`
+		KeyID string `json:"kid"`
+	}
+	payload      []byte
+	signingInput []byte // header.payload, as signed
+	signature    []byte
+}
+
+func parseJWS(raw string) (*jws, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: not a signed JWT", ErrInvalidToken)
+	}
+	var tok jws
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidToken, err)
+	}
+	if err := json.Unmarshal(header, &tok.header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidToken, err)
+	}
+	if tok.payload, err = base64.RawURLEncoding.DecodeString(parts[1]); err != nil {
+		return nil, fmt.Errorf("%w: payload: %v", ErrInvalidToken, err)
+	}
+	if tok.signature, err = base64.RawURLEncoding.DecodeString(parts[2]); err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrInvalidToken, err)
+	}
+	tok.signingInput = []byte(parts[0] + "." + parts[1])
+	return &tok, nil
+}
+
+// publicKey is a provider signing key from its JWKS. Only asymmetric algorithms are accepted:
+// "none" and the HMAC ones (keyed with the client secret) never verify.
+type publicKey interface {
+	alg() string
+	verify(alg string, signingInput, sig []byte) error
+}
+
+type rsaKey struct{ *rsa.PublicKey }
+
+func (rsaKey) alg() string { return "RS256" }
+
+func (k rsaKey) verify(alg string, signingInput, sig []byte) error {
+	if alg != "RS256" {
+		return fmt.Errorf("%w: algorithm %q doesn't match an RSA key", ErrInvalidToken, alg)
+	}
+	digest := sha256.Sum256(signingInput)
+	if rsa.VerifyPKCS1v15(k.PublicKey, crypto.SHA256, digest[:], sig) != nil {
+		return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+	return nil
+}
+
+type ecKey struct{ *ecdsa.PublicKey }
+
+func (ecKey) alg() string { return "ES256" }
+
+func (k ecKey) verify(alg string, signingInput, sig []byte) error {
+	if alg != "ES256" {
+		return fmt.Errorf("%w: algorithm %q doesn't match a P-256 key", ErrInvalidToken, alg)
+	}
+	// JWS carries the ECDSA signature as r || s, each 32 bytes
+	if len(sig) != 64 {
+		return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+	digest := sha256.Sum256(signingInput)
+	r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+	if !ecdsa.Verify(k.PublicKey, digest[:], r, s) {
+		return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+	return nil
+}
+
+// jwkSet is a JSON Web Key Set
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+type jwk struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	Alg     string `json:"alg"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+// keys returns the set's signing keys by key ID; keys it can't use are skipped
+func (s jwkSet) keys() map[string]publicKey {
+	out := map[string]publicKey{}
+	for _, k := range s.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil || (k.Alg != "" && k.Alg != key.alg()) {
+			continue
+		}
+		out[k.KeyID] = key
+	}
+	return out
+}
+
+func (k jwk) publicKey() (publicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err1 := base64.RawURLEncoding.DecodeString(k.N)
+		e, err2 := base64.RawURLEncoding.DecodeString(k.E)
+		if err1 != nil || err2 != nil || len(e) == 0 || len(e) > 4 {
+			return nil, errors.New("malformed RSA key")
+		}
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if pub.N.BitLen() < 2048 {
+			return nil, errors.New("RSA key shorter than 2048 bits")
+		}
+		return rsaKey{pub}, nil
+	case "EC":
+		if k.Curve != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err1 := base64.RawURLEncoding.DecodeString(k.X)
+		y, err2 := base64.RawURLEncoding.DecodeString(k.Y)
+		if err1 != nil || err2 != nil || len(x) != 32 || len(y) != 32 {
+			return nil, errors.New("malformed EC key")
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, errors.New("EC point not on curve")
+		}
+		return ecKey{pub}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+	}
+}
+
+// pickKey finds the key a token names. A token without a key ID can use the only key for its
+// algorithm.
+func pickKey(keys map[string]publicKey, kid, alg string) (publicKey, bool) {
+	if k, ok := keys[kid]; ok {
+		return k, true
+	}
+	if kid != "" {
+		return nil, false
+	}
+	var found publicKey
+	for _, k := range keys {
+		if k.alg() == alg {
+			if found != nil {
+				return nil, false
+			}
+			found = k
+		}
+	}
+	return found, found != nil
+}
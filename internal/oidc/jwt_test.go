@@ -0,0 +1,397 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	testClientID     = "triangle"
+	testClientSecret = "client-secret"
+	testNonce        = "nonce-1"
+)
+
+// mockProvider serves a Mock over HTTP and returns it with a Provider configured for it and
+// a count of the key set requests it has served
+func mockProvider(t *testing.T) (*Mock, *Provider, *atomic.Int32) {
+	t.Helper()
+	var m *Mock
+	var jwksReads atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/jwks" {
+			jwksReads.Add(1)
+		}
+		m.Handler().ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	m, err := NewMock(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.ClientID, m.ClientSecret = testClientID, testClientSecret
+	p, err := New(Config{Issuer: srv.URL, ClientID: testClientID, ClientSecret: testClientSecret,
+		RedirectURL: "http://localhost:8080/api/auth/oidc/callback", Client: srv.Client()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, p, &jwksReads
+}
+
+// encodeJWT serializes header and claims and signs them with sign, which may be nil for no
+// signature
+func encodeJWT(t *testing.T, header, claims map[string]interface{}, sign func(input []byte) []byte) string {
+	t.Helper()
+	h, err := json.Marshal(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	var sig []byte
+	if sign != nil {
+		sig = sign([]byte(input))
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey) func([]byte) []byte {
+	return func(input []byte) []byte {
+		digest := sha256.Sum256(input)
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sig
+	}
+}
+
+func signHS256(secret []byte) func([]byte) []byte {
+	return func(input []byte) []byte {
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(input)
+		return mac.Sum(nil)
+	}
+}
+
+// mockClaims are the claims Mock issues for a sign-in, changed by edit
+func mockClaims(m *Mock, edit func(map[string]interface{})) map[string]interface{} {
+	now := time.Now()
+	c := map[string]interface{}{
+		"iss": m.Issuer, "sub": MockSubject("ada@example.com"), "aud": testClientID,
+		"iat": now.Unix(), "exp": now.Add(5 * time.Minute).Unix(), "nonce": testNonce,
+		"email": "ada@example.com", "email_verified": true,
+	}
+	if edit != nil {
+		edit(c)
+	}
+	return c
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	m, p, _ := mockProvider(t)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spki, err := x509.MarshalPKIXPublicKey(&m.key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs256 := map[string]interface{}{"alg": "RS256", "typ": "JWT", "kid": m.keyID}
+	header := func(alg, kid string) map[string]interface{} {
+		return map[string]interface{}{"alg": alg, "typ": "JWT", "kid": kid}
+	}
+	signed := func(edit func(map[string]interface{})) string {
+		tok, err := m.sign(mockClaims(m, edit))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tok
+	}
+	valid := signed(nil)
+	parts := strings.Split(valid, ".")
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"`+m.Issuer+`","sub":"mallory"}`)) + "." + parts[2]
+
+	tests := []struct {
+		name  string
+		token string
+		nonce string
+		err   string // empty when the token verifies
+	}{
+		{"issued by the mock", valid, testNonce, ""},
+		{"no key ID", encodeJWT(t, header("RS256", ""), mockClaims(m, nil), signRS256(t, m.key)), testNonce, ""},
+		{"audience list with azp", signed(func(c map[string]interface{}) {
+			c["aud"], c["azp"] = []string{testClientID, "other"}, testClientID
+		}), testNonce, ""},
+
+		{"alg none", encodeJWT(t, header("none", m.keyID), mockClaims(m, nil), nil), testNonce, "doesn't match an RSA key"},
+		{"alg none without a key ID", encodeJWT(t, header("none", ""), mockClaims(m, nil), nil), testNonce, "unknown signing key"},
+		{"HS256 with the client secret", encodeJWT(t, header("HS256", m.keyID), mockClaims(m, nil), signHS256([]byte(testClientSecret))), testNonce, "doesn't match an RSA key"},
+		{"HS256 with the public key", encodeJWT(t, header("HS256", m.keyID), mockClaims(m, nil), signHS256(spki)), testNonce, "doesn't match an RSA key"},
+		{"ES256 naming the RSA key", encodeJWT(t, header("ES256", m.keyID), mockClaims(m, nil), signRS256(t, m.key)), testNonce, "doesn't match an RSA key"},
+		{"unknown key ID", encodeJWT(t, header("RS256", "rotated"), mockClaims(m, nil), signRS256(t, m.key)), testNonce, "unknown signing key"},
+		{"no key for the algorithm", encodeJWT(t, header("ES256", ""), mockClaims(m, nil), nil), testNonce, "unknown signing key"},
+		{"signed by another key", encodeJWT(t, rs256, mockClaims(m, nil), signRS256(t, other)), testNonce, "bad signature"},
+		{"claims changed after signing", tampered, testNonce, "bad signature"},
+		{"unsigned", parts[0] + "." + parts[1] + ".", testNonce, "bad signature"},
+		{"two parts", parts[0] + "." + parts[1], testNonce, "not a signed JWT"},
+		{"header not base64", "!." + parts[1] + "." + parts[2], testNonce, "header"},
+
+		{"other issuer", signed(func(c map[string]interface{}) { c["iss"] = "https://evil.example" }), testNonce, "issued by"},
+		{"issuer with a trailing slash", signed(func(c map[string]interface{}) { c["iss"] = m.Issuer + "/" }), testNonce, "issued by"},
+		{"no subject", signed(func(c map[string]interface{}) { delete(c, "sub") }), testNonce, "no subject"},
+		{"other audience", signed(func(c map[string]interface{}) { c["aud"] = "someone-else" }), testNonce, "not issued to this client"},
+		{"audience list without azp", signed(func(c map[string]interface{}) { c["aud"] = []string{testClientID, "other"} }), testNonce, "authorized party"},
+		{"expired", signed(func(c map[string]interface{}) { c["exp"] = time.Now().Add(-2 * clockSkew).Unix() }), testNonce, "expired"},
+		{"no expiry", signed(func(c map[string]interface{}) { delete(c, "exp") }), testNonce, "expired"},
+		{"issued in the future", signed(func(c map[string]interface{}) { c["iat"] = time.Now().Add(2 * clockSkew).Unix() }), testNonce, "future"},
+		{"other nonce", valid, "nonce-2", "nonce"},
+		{"no nonce in the token", signed(func(c map[string]interface{}) { delete(c, "nonce") }), testNonce, "nonce"},
+		{"no nonce expected", signed(func(c map[string]interface{}) { c["nonce"] = "" }), "", "nonce"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := p.VerifyIDToken(context.Background(), tt.token, tt.nonce, time.Now())
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.err == "":
+				if claims.Subject != MockSubject("ada@example.com") || claims.Email != "ada@example.com" || !claims.EmailVerified {
+					t.Errorf("claims %+v", claims)
+				}
+			case !errors.Is(err, ErrInvalidToken) || !strings.Contains(err.Error(), tt.err):
+				t.Errorf("error %v, want ErrInvalidToken with %q", err, tt.err)
+			}
+		})
+	}
+}
+
+// An unknown key ID reads the key set again, but at most once per keyRefetchInterval
+func TestKeyRotation(t *testing.T) {
+	m, p, jwksReads := mockProvider(t)
+	verify := func() error {
+		tok, err := m.sign(mockClaims(m, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = p.VerifyIDToken(context.Background(), tok, testNonce, time.Now())
+		return err
+	}
+	if err := verify(); err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(); err != nil || jwksReads.Load() != 1 {
+		t.Fatalf("second token: %v after %d key set reads, want 1", err, jwksReads.Load())
+	}
+
+	rotated, err := NewMock(m.Issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.key, m.keyID = rotated.key, rotated.keyID
+	if err := verify(); !errors.Is(err, ErrInvalidToken) || jwksReads.Load() != 1 {
+		t.Fatalf("rotated key within the refetch interval: %v after %d key set reads, want ErrInvalidToken after 1", err, jwksReads.Load())
+	}
+	p.mu.Lock()
+	p.keysChecked = time.Now().Add(-keyRefetchInterval)
+	p.mu.Unlock()
+	if err := verify(); err != nil || jwksReads.Load() != 2 {
+		t.Fatalf("rotated key after the refetch interval: %v after %d key set reads, want 2", err, jwksReads.Load())
+	}
+}
+
+func TestJWKSetKeys(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shortRSA, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b64 := base64.RawURLEncoding.EncodeToString
+	rsaJWK := func(kid string, key *rsa.PrivateKey) jwk {
+		return jwk{KeyType: "RSA", KeyID: kid, N: b64(key.N.Bytes()), E: "AQAB"}
+	}
+	x, y := ecKey.X.FillBytes(make([]byte, 32)), ecKey.Y.FillBytes(make([]byte, 32))
+	ecJWK := jwk{KeyType: "EC", KeyID: "ec", Curve: "P-256", X: b64(x), Y: b64(y)}
+
+	tests := []struct {
+		name string
+		key  jwk
+		alg  string // the key's algorithm, or empty when it is skipped
+	}{
+		{"RSA", rsaJWK("k", rsaKey), "RS256"},
+		{"RSA for signing", func() jwk { k := rsaJWK("k", rsaKey); k.Use, k.Alg = "sig", "RS256"; return k }(), "RS256"},
+		{"EC", ecJWK, "ES256"},
+		{"RSA for encryption", func() jwk { k := rsaJWK("k", rsaKey); k.Use = "enc"; return k }(), ""},
+		{"RSA marked PS256", func() jwk { k := rsaJWK("k", rsaKey); k.Alg = "PS256"; return k }(), ""},
+		{"RSA under 2048 bits", rsaJWK("k", shortRSA), ""},
+		{"RSA without an exponent", func() jwk { k := rsaJWK("k", rsaKey); k.E = ""; return k }(), ""},
+		{"P-384", func() jwk { k := ecJWK; k.Curve = "P-384"; return k }(), ""},
+		{"EC point off the curve", func() jwk { k := ecJWK; k.Y = b64(x); return k }(), ""},
+		{"EC coordinate too short", func() jwk { k := ecJWK; k.X = b64(x[1:]); return k }(), ""},
+		{"symmetric", jwk{KeyType: "oct", KeyID: "k"}, ""},
+	}
+	for _, tt := range tests {
+		keys := jwkSet{Keys: []jwk{tt.key}}.keys()
+		k, ok := keys[tt.key.KeyID]
+		if ok != (tt.alg != "") || ok && k.alg() != tt.alg {
+			t.Errorf("%s: got %v (%d keys), want %q", tt.name, k, len(keys), tt.alg)
+		}
+	}
+}
+
+func TestPickKey(t *testing.T) {
+	a, b, c := rsaKey{}, rsaKey{}, ecKey{}
+	tests := []struct {
+		name     string
+		keys     map[string]publicKey
+		kid, alg string
+		want     publicKey
+	}{
+		{"by key ID", map[string]publicKey{"a": a, "c": c}, "c", "RS256", c},
+		{"unknown key ID", map[string]publicKey{"a": a}, "b", "RS256", nil},
+		{"only key for the algorithm", map[string]publicKey{"a": a, "c": c}, "", "RS256", a},
+		{"no key for the algorithm", map[string]publicKey{"a": a}, "", "ES256", nil},
+		{"several keys for the algorithm", map[string]publicKey{"a": a, "b": b}, "", "RS256", nil},
+		{"no keys", nil, "", "RS256", nil},
+	}
+	for _, tt := range tests {
+		k, ok := pickKey(tt.keys, tt.kid, tt.alg)
+		if ok != (tt.want != nil) || k != tt.want {
+			t.Errorf("%s: got %v, %v; want %v", tt.name, k, ok, tt.want)
+		}
+	}
+}
+
+func TestECKeyVerify(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := []byte("header.payload")
+	digest := sha256.Sum256(input)
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	asn1, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		alg  string
+		sig  []byte
+		ok   bool
+	}{
+		{"r || s", "ES256", sig, true},
+		{"ASN.1", "ES256", asn1, false},
+		{"RS256", "RS256", sig, false},
+		{"none", "none", nil, false},
+		{"truncated", "ES256", sig[:63], false},
+	}
+	for _, tt := range tests {
+		err := ecKey{&key.PublicKey}.verify(tt.alg, input, tt.sig)
+		if (err == nil) != tt.ok || err != nil && !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+// authorize signs in at the mock as email and returns the code it redirects back with
+func authorize(t *testing.T, p *Provider, email, nonce, verifier string) string {
+	t.Helper()
+	authURL, err := p.AuthCodeURL(context.Background(), "state-1", nonce, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	form := u.Query()
+	form.Set("email", email)
+	client := *p.client
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	resp, err := client.PostForm(strings.Split(authURL, "?")[0], form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	back, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.StatusCode != http.StatusFound || back.Query().Get("code") == "" {
+		t.Fatalf("authorize: %s, redirected to %q", resp.Status, resp.Header.Get("Location"))
+	}
+	return back.Query().Get("code")
+}
+
+// The code flow against the mock: PKCE and the nonce tie the code to the sign-in that asked
+// for it
+func TestExchange(t *testing.T) {
+	_, p, _ := mockProvider(t)
+	ctx := context.Background()
+
+	code := authorize(t, p, "unverified@example.com", testNonce, "verifier-1")
+	claims, err := p.Exchange(ctx, code, "verifier-1", testNonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != MockSubject("unverified@example.com") || claims.EmailVerified {
+		t.Errorf("claims %+v", claims)
+	}
+	var tokenErr *TokenError
+	if _, err := p.Exchange(ctx, code, "verifier-1", testNonce); !errors.As(err, &tokenErr) || !tokenErr.Rejected() {
+		t.Errorf("code used twice: %v, want invalid_grant", err)
+	}
+
+	tests := []struct {
+		name     string
+		verifier string
+		nonce    string
+		rejected bool // by the token endpoint; otherwise the ID token fails verification
+	}{
+		{"PKCE verifier mismatch", "verifier-2", testNonce, true},
+		{"no PKCE verifier", "", testNonce, true},
+		{"nonce mismatch", "verifier-1", "nonce-2", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := authorize(t, p, "ada@example.com", testNonce, "verifier-1")
+			_, err := p.Exchange(ctx, code, tt.verifier, tt.nonce)
+			if tt.rejected && (!errors.As(err, &tokenErr) || !tokenErr.Rejected()) {
+				t.Errorf("%v, want invalid_grant", err)
+			}
+			if !tt.rejected && !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("%v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,292 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"html/template"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mock is a local OpenID Connect identity provider for trying sign-in offline. It serves
+// discovery, a JWKS, an authorization page where you type any email to sign in as, and a token
+// endpoint that checks the client, redirect URI and PKCE verifier before issuing an RS256 ID
+// token. The subject is derived from the email, so signing in as the same address again is the
+// same identity. These addresses behave specially:
+//
+//	unverified@example.com  email_verified is false
+//
+// It keeps codes in memory and generates a new signing key each time it starts.
+type Mock struct {
+	Issuer       string                  // the URL it is served at, e.g. http://localhost:9090
+	ClientID     string                  // empty accepts any client
+	ClientSecret string                  // checked when ClientID is set; empty for a public client
+	OnLogin      func(sub, email string) // called for each approved sign-in, if set
+
+	key   *rsa.PrivateKey
+	keyID string
+
+	mu    sync.Mutex
+	codes map[string]mockCode
+}
+
+type mockCode struct {
+	clientID    string
+	redirectURI string
+	challenge   string
+	nonce       string
+	email       string
+	name        string
+	expires     time.Time
+}
+
+// mockCodeLifetime is how long an authorization code can be exchanged
+const mockCodeLifetime = time.Minute
+
+// NewMock returns a mock provider served at issuer, with a fresh 2048-bit signing key
+func NewMock(issuer string) (*Mock, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	kid := make([]byte, 8)
+	if _, err := rand.Read(kid); err != nil {
+		return nil, err
+	}
+	return &Mock{Issuer: strings.TrimRight(issuer, "/"), key: key, keyID: hex.EncodeToString(kid), codes: map[string]mockCode{}}, nil
+}
+
+// Handler returns the provider's HTTP routes
+func (m *Mock) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", m.discovery)
+	mux.HandleFunc("GET /jwks", m.jwks)
+	mux.HandleFunc("GET /authorize", m.authorizePage)
+	mux.HandleFunc("POST /authorize", m.authorize)
+	mux.HandleFunc("POST /token", m.token)
+	return mux
+}
+
+// MockSubject is the subject Mock signs an email in as
+func MockSubject(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(email)))
+	return "mock-" + hex.EncodeToString(sum[:8])
+}
+
+func (m *Mock) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                m.Issuer,
+		"authorization_endpoint":                m.Issuer + "/authorize",
+		"token_endpoint":                        m.Issuer + "/token",
+		"jwks_uri":                              m.Issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"scopes_supported":                      []string{"openid", "email", "profile"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+	})
+}
+
+func (m *Mock) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := m.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA", "use": "sig", "alg": "RS256", "kid": m.keyID,
+		"n": base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+		"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}}})
+}
+
+var mockLoginPage = template.Must(template.New("login").Parse(`<!doctype html>
+<html><head><meta charset="utf-8"><title>Mock identity provider</title>
+<style>body{font-family:system-ui,sans-serif;max-width:22rem;margin:4rem auto}input,button{display:block;width:100%;margin:.5rem 0;padding:.5rem;font-size:1rem}</style>
+</head><body>
+<h1>Mock sign-in</h1>
+<p>Signing in to <strong>{{.ClientID}}</strong>. Type any email; nothing is checked.</p>
+<form method="post" action="authorize">
+{{range $k, $v := .Params}}<input type="hidden" name="{{$k}}" value="{{$v}}">
+{{end}}<input type="email" name="email" placeholder="you@example.com" required autofocus>
+<input type="text" name="name" placeholder="Name (optional)">
+<button type="submit">Sign in</button>
+<button type="submit" name="deny" value="1" formnovalidate>Cancel</button>
+</form>
+</body></html>
+`))
+
+// authorizePage checks the authorization request and shows the sign-in form, carrying the
+// request's parameters along
+func (m *Mock) authorizePage(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if msg := m.checkAuthorize(q); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	params := map[string]string{}
+	for _, k := range []string{"client_id", "redirect_uri", "state", "nonce", "code_challenge", "code_challenge_method", "scope"} {
+		params[k] = q.Get(k)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	mockLoginPage.Execute(w, map[string]interface{}{"ClientID": q.Get("client_id"), "Params": params})
+}
+
+// authorize approves (or, with deny set, refuses) the sign-in and sends the browser back to the
+// client with a code
+func (m *Mock) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "malformed form", http.StatusBadRequest)
+		return
+	}
+	f := r.PostForm
+	f.Set("response_type", "code")
+	if msg := m.checkAuthorize(f); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	back := url.Values{"state": {f.Get("state")}}
+	email := strings.TrimSpace(f.Get("email"))
+	if f.Get("deny") != "" {
+		back.Set("error", "access_denied")
+	} else if email == "" {
+		http.Error(w, "email required", http.StatusBadRequest)
+		return
+	} else {
+		code := make([]byte, 24)
+		rand.Read(code)
+		c := hex.EncodeToString(code)
+		m.mu.Lock()
+		m.codes[c] = mockCode{clientID: f.Get("client_id"), redirectURI: f.Get("redirect_uri"), challenge: f.Get("code_challenge"),
+			nonce: f.Get("nonce"), email: email, name: strings.TrimSpace(f.Get("name")), expires: time.Now().Add(mockCodeLifetime)}
+		m.mu.Unlock()
+		back.Set("code", c)
+		if m.OnLogin != nil {
+			m.OnLogin(MockSubject(email), email)
+		}
+	}
+	sep := "?"
+	if strings.Contains(f.Get("redirect_uri"), "?") {
+		sep = "&"
+	}
+	http.Redirect(w, r, f.Get("redirect_uri")+sep+back.Encode(), http.StatusFound)
+}
+
+// checkAuthorize returns what's wrong with an authorization request, or ""
+func (m *Mock) checkAuthorize(q url.Values) string {
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	switch {
+	case q.Get("response_type") != "code":
+		return "response_type must be code"
+	case m.ClientID != "" && q.Get("client_id") != m.ClientID:
+		return "unknown client_id"
+	case err != nil || redirect.Host == "" || redirect.Fragment != "":
+		return "redirect_uri must be an absolute URL"
+	case !strings.Contains(" "+q.Get("scope")+" ", " openid "):
+		return "scope must include openid"
+	case q.Get("state") == "" || q.Get("nonce") == "":
+		return "state and nonce required"
+	case q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256":
+		return "PKCE with code_challenge_method=S256 required"
+	}
+	return ""
+}
+
+func (m *Mock) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, http.StatusBadRequest, "invalid_request", "malformed form")
+		return
+	}
+	f := r.PostForm
+	clientID, secret, basic := r.BasicAuth()
+	if basic {
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID, secret = f.Get("client_id"), f.Get("client_secret")
+	}
+	if m.ClientID != "" && (clientID != m.ClientID ||
+		subtle.ConstantTimeCompare([]byte(secret), []byte(m.ClientSecret)) != 1) {
+		tokenError(w, http.StatusUnauthorized, "invalid_client", "unknown client or wrong secret")
+		return
+	}
+	if f.Get("grant_type") != "authorization_code" {
+		tokenError(w, http.StatusBadRequest, "unsupported_grant_type", "")
+		return
+	}
+	m.mu.Lock()
+	code, ok := m.codes[f.Get("code")]
+	delete(m.codes, f.Get("code")) // a code works once, even when the exchange fails
+	m.mu.Unlock()
+	switch {
+	case !ok || time.Now().After(code.expires):
+		tokenError(w, http.StatusBadRequest, "invalid_grant", "unknown, used or expired code")
+		return
+	case code.clientID != clientID || code.redirectURI != f.Get("redirect_uri"):
+		tokenError(w, http.StatusBadRequest, "invalid_grant", "code was issued to another client or redirect_uri")
+		return
+	case CodeChallenge(f.Get("code_verifier")) != code.challenge:
+		tokenError(w, http.StatusBadRequest, "invalid_grant", "code_verifier doesn't match code_challenge")
+		return
+	}
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss": m.Issuer, "sub": MockSubject(code.email), "aud": clientID, "iat": now.Unix(), "exp": now.Add(5 * time.Minute).Unix(),
+		"auth_time": now.Unix(), "nonce": code.nonce, "email": code.email,
+		"email_verified": !strings.EqualFold(code.email, "unverified@example.com"),
+	}
+	if code.name != "" {
+		claims["name"] = code.name
+	}
+	idToken, err := m.sign(claims)
+	if err != nil {
+		tokenError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	// The access token is for show: the mock has no userinfo or other APIs to call with it
+	accessToken := make([]byte, 24)
+	rand.Read(accessToken)
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]interface{}{"access_token": hex.EncodeToString(accessToken),
+		"token_type": "Bearer", "expires_in": 300, "id_token": idToken})
+}
+
+// sign returns claims as an RS256 JWS signed with the mock's key
+func (m *Mock) sign(claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": m.keyID})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, m.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func tokenError(w http.ResponseWriter, status int, code, description string) {
+	body := map[string]string{"error": code}
+	if description != "" {
+		body["error_description"] = description
+	}
+	writeJSON(w, status, body)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,395 @@
+// Package oidc is an OpenID Connect relying party for the authorization code flow with PKCE.
+// A Provider reads the identity provider's discovery document and signing keys (JWKS) on first
+// use, builds the authorization URL, trades the returned code for an ID token and verifies it:
+// signature, issuer, audience, expiry and nonce. Mock is a local identity provider for trying the
+// flow offline.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoginLifetime is how long a sign-in may take between redirecting to the provider and its callback
+const LoginLifetime = 10 * time.Minute
+
+const (
+	// discoveryLifetime is how long discovery and keys are kept before being read again
+	discoveryLifetime = 24 * time.Hour
+	// keyRefetchInterval limits reading the keys again for an unknown key ID (provider key rotation)
+	keyRefetchInterval = time.Minute
+	// clockSkew is tolerated between our clock and the provider's on exp and iat
+	clockSkew = time.Minute
+	// maxResponse bounds what is read from the provider
+	maxResponse = 1 << 20
+)
+
+// ErrInvalidToken is an ID token that failed verification
+var ErrInvalidToken = errors.New("oidc: invalid ID token")
+
+// TokenError is an error response from the token endpoint, e.g. invalid_grant for a code that
+// was already used or has expired
+type TokenError struct {
+	Status      int
+	Code        string
+	Description string
+}
+
+func (e *TokenError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("oidc: token endpoint: %s: %s (status %d)", e.Code, e.Description, e.Status)
+	}
+	return fmt.Sprintf("oidc: token endpoint: %s (status %d)", e.Code, e.Status)
+}
+
+// Rejected reports whether the provider refused the code itself, so the user has to start over;
+// other errors are the provider's or our configuration's
+func (e *TokenError) Rejected() bool {
+	return e.Code == "invalid_grant"
+}
+
+// Config configures a Provider
+type Config struct {
+	Issuer       string // e.g. https://login.example.com; must be https except on localhost
+	ClientID     string
+	ClientSecret string // empty for a public client (PKCE only)
+	RedirectURL  string // where the provider sends the browser back, registered with it
+	Name         string // shown on the sign-in button; empty uses "SSO"
+	Scopes       []string
+	Client       *http.Client // nil uses a client with a 10s timeout
+}
+
+// Metadata is the part of the discovery document the flow uses
+type Metadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	CodeChallengeMethods  []string `json:"code_challenge_methods_supported"`
+}
+
+// Claims are the verified ID token claims the app uses
+type Claims struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Provider is one configured identity provider
+type Provider struct {
+	cfg    Config
+	client *http.Client
+
+	mu          sync.Mutex
+	meta        *Metadata
+	metaAt      time.Time
+	keys        map[string]publicKey
+	keysAt      time.Time
+	keysChecked time.Time
+}
+
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
+
+// New checks cfg and returns its Provider; nothing is fetched until the first sign-in
+func New(cfg Config) (*Provider, error) {
+	u, err := url.Parse(cfg.Issuer)
+	if err != nil || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("oidc: issuer %q isn't an absolute URL", cfg.Issuer)
+	}
+	if u.Scheme != "https" && !isLocalhost(u.Hostname()) {
+		return nil, fmt.Errorf("oidc: issuer %q must use https", cfg.Issuer)
+	}
+	if cfg.ClientID == "" {
+		return nil, errors.New("oidc: client ID required")
+	}
+	if r, err := url.Parse(cfg.RedirectURL); err != nil || r.Host == "" {
+		return nil, fmt.Errorf("oidc: redirect URL %q isn't an absolute URL", cfg.RedirectURL)
+	}
+	if cfg.Name == "" {
+		cfg.Name = "SSO"
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	} else if !slices.Contains(cfg.Scopes, "openid") {
+		cfg.Scopes = append([]string{"openid"}, cfg.Scopes...)
+	}
+	client := cfg.Client
+	if client == nil {
+		client = defaultClient
+	}
+	return &Provider{cfg: cfg, client: client}, nil
+}
+
+func isLocalhost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
+
+// Issuer returns the configured issuer URL
+func (p *Provider) Issuer() string { return p.cfg.Issuer }
+
+// Name returns the provider's display name
+func (p *Provider) Name() string { return p.cfg.Name }
+
+// RandomString returns 256 random bits, base64url-encoded: for state, nonce and PKCE verifiers
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CodeChallenge is the PKCE S256 challenge for a verifier
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the provider URL to send the browser to for signing in
+func (p *Provider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {CodeChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return meta.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange trades an authorization code for its verified ID token claims. A refusal from the
+// token endpoint comes back as *TokenError, a bad ID token as ErrInvalidToken.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Claims, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	if p.cfg.ClientSecret == "" {
+		form.Set("client_id", p.cfg.ClientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: token endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+	var payload struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	decodeErr := json.NewDecoder(io.LimitReader(resp.Body, maxResponse)).Decode(&payload)
+	if resp.StatusCode >= 300 || payload.Error != "" {
+		tokenErr := &TokenError{Status: resp.StatusCode, Code: payload.Error, Description: payload.ErrorDescription}
+		if tokenErr.Code == "" {
+			tokenErr.Code = resp.Status
+		}
+		return nil, tokenErr
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("oidc: token endpoint: %w", decodeErr)
+	}
+	if payload.IDToken == "" {
+		return nil, errors.New("oidc: token endpoint returned no ID token")
+	}
+	return p.VerifyIDToken(ctx, payload.IDToken, nonce, time.Now())
+}
+
+// VerifyIDToken checks an ID token's signature against the provider's keys and its claims
+// against this client, the nonce the sign-in was started with and the time
+func (p *Provider) VerifyIDToken(ctx context.Context, raw, nonce string, now time.Time) (*Claims, error) {
+	tok, err := parseJWS(raw)
+	if err != nil {
+		return nil, err
+	}
+	key, err := p.key(ctx, tok.header.KeyID, tok.header.Alg)
+	if err != nil {
+		return nil, err
+	}
+	if err := key.verify(tok.header.Alg, tok.signingInput, tok.signature); err != nil {
+		return nil, err
+	}
+	var c struct {
+		Issuer        string   `json:"iss"`
+		Subject       string   `json:"sub"`
+		Audience      audience `json:"aud"`
+		AuthorizedBy  string   `json:"azp"`
+		Expiry        float64  `json:"exp"`
+		IssuedAt      float64  `json:"iat"`
+		Nonce         string   `json:"nonce"`
+		Email         string   `json:"email"`
+		EmailVerified flexBool `json:"email_verified"`
+		Name          string   `json:"name"`
+	}
+	if err := json.Unmarshal(tok.payload, &c); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrInvalidToken, err)
+	}
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case c.Issuer != meta.Issuer:
+		return nil, fmt.Errorf("%w: issued by %q, not %q", ErrInvalidToken, c.Issuer, meta.Issuer)
+	case c.Subject == "":
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidToken)
+	case !slices.Contains(c.Audience, p.cfg.ClientID):
+		return nil, fmt.Errorf("%w: not issued to this client", ErrInvalidToken)
+	case len(c.Audience) > 1 && c.AuthorizedBy != p.cfg.ClientID:
+		return nil, fmt.Errorf("%w: authorized party %q isn't this client", ErrInvalidToken, c.AuthorizedBy)
+	case c.Expiry == 0 || now.After(time.Unix(int64(c.Expiry), 0).Add(clockSkew)):
+		return nil, fmt.Errorf("%w: expired", ErrInvalidToken)
+	case c.IssuedAt != 0 && time.Unix(int64(c.IssuedAt), 0).After(now.Add(clockSkew)):
+		return nil, fmt.Errorf("%w: issued in the future", ErrInvalidToken)
+	case nonce == "" || c.Nonce != nonce:
+		return nil, fmt.Errorf("%w: nonce doesn't match the sign-in", ErrInvalidToken)
+	}
+	return &Claims{Issuer: c.Issuer, Subject: c.Subject, Email: c.Email, EmailVerified: bool(c.EmailVerified), Name: c.Name}, nil
+}
+
+// discover returns the provider's discovery document, reading it on first use and once a day
+func (p *Provider) discover(ctx context.Context) (*Metadata, error) {
+	p.mu.Lock()
+	if p.meta != nil && time.Since(p.metaAt) < discoveryLifetime {
+		meta := p.meta
+		p.mu.Unlock()
+		return meta, nil
+	}
+	p.mu.Unlock()
+
+	var meta Metadata
+	if err := p.getJSON(ctx, strings.TrimRight(p.cfg.Issuer, "/")+"/.well-known/openid-configuration", &meta); err != nil {
+		return nil, fmt.Errorf("oidc: discovery: %w", err)
+	}
+	switch {
+	case meta.Issuer != p.cfg.Issuer:
+		return nil, fmt.Errorf("oidc: discovery: issuer %q doesn't match the configured %q", meta.Issuer, p.cfg.Issuer)
+	case meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "":
+		return nil, errors.New("oidc: discovery: missing authorization, token or JWKS endpoint")
+	case len(meta.CodeChallengeMethods) > 0 && !slices.Contains(meta.CodeChallengeMethods, "S256"):
+		return nil, errors.New("oidc: discovery: provider doesn't support PKCE S256")
+	}
+	p.mu.Lock()
+	p.meta, p.metaAt = &meta, time.Now()
+	p.mu.Unlock()
+	return &meta, nil
+}
+
+// key returns the provider's signing key with an ID, reading the key set again when the ID
+// is unknown (the provider may have rotated keys) at most once per keyRefetchInterval
+func (p *Provider) key(ctx context.Context, kid, alg string) (publicKey, error) {
+	p.mu.Lock()
+	keys, fresh := p.keys, time.Since(p.keysAt) < discoveryLifetime
+	recentlyChecked := time.Since(p.keysChecked) < keyRefetchInterval
+	p.mu.Unlock()
+	if k, ok := pickKey(keys, kid, alg); ok && fresh {
+		return k, nil
+	}
+	if keys != nil && fresh && recentlyChecked {
+		return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidToken, kid)
+	}
+
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var set jwkSet
+	if err := p.getJSON(ctx, meta.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("oidc: keys: %w", err)
+	}
+	keys = set.keys()
+	now := time.Now()
+	p.mu.Lock()
+	p.keys, p.keysAt, p.keysChecked = keys, now, now
+	p.mu.Unlock()
+	if k, ok := pickKey(keys, kid, alg); ok {
+		return k, nil
+	}
+	return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidToken, kid)
+}
+
+func (p *Provider) getJSON(ctx context.Context, u string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxResponse)).Decode(v)
+}
+
+// audience is the aud claim: a single string or an array of them
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*a = audience{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// flexBool is a boolean claim some providers send as the string "true"
+type flexBool bool
+
+func (f *flexBool) UnmarshalJSON(b []byte) error {
+	switch string(b) {
+	case "true", `"true"`:
+		*f = true
+	default:
+		*f = false
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package phone
+
+// country is a region's numbering rules: its calling code and how many digits its national
+// significant numbers (after the calling code, without trunk prefix) may have
+type country struct {
+	iso      string // ISO 3166-1 alpha-2 (XK for Kosovo, AC for Ascension)
+	code     string // country calling code, without +
+	min, max int
+	keepZero bool // national numbers may start with 0 (elsewhere a leading 0 is a trunk prefix)
+}
+
+// countries lists every geographic calling code. Where several regions share a code the main one
+// comes first; regionFor picks the others out by leading digits where that is reliable (NANP
+// area codes, Kazakhstan under +7) and otherwise reports the main one.
+var countries = []country{
+	{"US", "1", 10, 10, false}, {"CA", "1", 10, 10, false}, {"BS", "1", 10, 10, false}, {"BB", "1", 10, 10, false},
+	{"AI", "1", 10, 10, false}, {"AG", "1", 10, 10, false}, {"VG", "1", 10, 10, false}, {"VI", "1", 10, 10, false},
+	{"KY", "1", 10, 10, false}, {"BM", "1", 10, 10, false}, {"GD", "1", 10, 10, false}, {"TC", "1", 10, 10, false},
+	{"JM", "1", 10, 10, false}, {"MS", "1", 10, 10, false}, {"MP", "1", 10, 10, false}, {"GU", "1", 10, 10, false},
+	{"AS", "1", 10, 10, false}, {"SX", "1", 10, 10, false}, {"LC", "1", 10, 10, false}, {"DM", "1", 10, 10, false},
+	{"VC", "1", 10, 10, false}, {"PR", "1", 10, 10, false}, {"DO", "1", 10, 10, false}, {"TT", "1", 10, 10, false},
+	{"KN", "1", 10, 10, false},
+	{"RU", "7", 10, 10, false}, {"KZ", "7", 10, 10, false},
+
+	{"EG", "20", 8, 10, false}, {"ZA", "27", 9, 9, false}, {"GR", "30", 10, 10, false}, {"NL", "31", 9, 9, false},
+	{"BE", "32", 8, 9, false}, {"FR", "33", 9, 9, false}, {"ES", "34", 9, 9, false}, {"HU", "36", 8, 9, false},
+	{"IT", "39", 6, 11, true}, {"VA", "39", 6, 11, true}, {"RO", "40", 9, 9, false}, {"CH", "41", 9, 9, false},
+	{"AT", "43", 4, 13, false}, {"GB", "44", 7, 10, false}, {"GG", "44", 10, 10, false}, {"JE", "44", 10, 10, false},
+	{"IM", "44", 10, 10, false}, {"DK", "45", 8, 8, false}, {"SE", "46", 7, 10, false}, {"NO", "47", 5, 8, false},
+	{"SJ", "47", 8, 8, false}, {"PL", "48", 9, 9, false}, {"DE", "49", 6, 15, false},
+	{"PE", "51", 8, 9, false}, {"MX", "52", 10, 10, false}, {"CU", "53", 6, 8, false}, {"AR", "54", 10, 11, false},
+	{"BR", "55", 10, 11, false}, {"CL", "56", 9, 9, false}, {"CO", "57", 8, 10, false}, {"VE", "58", 10, 10, false},
+	{"MY", "60", 8, 10, false}, {"AU", "61", 9, 9, false}, {"CX", "61", 9, 9, false}, {"CC", "61", 9, 9, false},
+	{"ID", "62", 7, 12, false}, {"PH", "63", 8, 10, false}, {"NZ", "64", 8, 10, false}, {"SG", "65", 8, 8, false},
+	{"TH", "66", 8, 9, false},
+	{"JP", "81", 9, 10, false}, {"KR", "82", 8, 10, false}, {"VN", "84", 9, 10, false}, {"CN", "86", 10, 11, false},
+	{"TR", "90", 10, 10, false}, {"IN", "91", 10, 10, false}, {"PK", "92", 9, 10, false}, {"AF", "93", 9, 9, false},
+	{"LK", "94", 9, 9, false}, {"MM", "95", 7, 10, false}, {"IR", "98", 10, 10, false},
+
+	{"SS", "211", 9, 9, false}, {"MA", "212", 9, 9, false}, {"EH", "212", 9, 9, false}, {"DZ", "213", 8, 9, false},
+	{"TN", "216", 8, 8, false}, {"LY", "218", 9, 9, false}, {"GM", "220", 7, 7, false}, {"SN", "221", 9, 9, false},
+	{"MR", "222", 8, 8, false}, {"ML", "223", 8, 8, false}, {"GN", "224", 8, 9, false}, {"CI", "225", 10, 10, true},
+	{"BF", "226", 8, 8, false}, {"NE", "227", 8, 8, false}, {"TG", "228", 8, 8, false}, {"BJ", "229", 8, 10, true},
+	{"MU", "230", 7, 8, false}, {"LR", "231", 7, 9, false}, {"SL", "232", 8, 8, false}, {"GH", "233", 9, 9, false},
+	{"NG", "234", 8, 10, false}, {"TD", "235", 8, 8, false}, {"CF", "236", 8, 8, false}, {"CM", "237", 9, 9, false},
+	{"CV", "238", 7, 7, false}, {"ST", "239", 7, 7, false}, {"GQ", "240", 9, 9, false}, {"GA", "241", 7, 8, true},
+	{"CG", "242", 9, 9, true}, {"CD", "243", 9, 9, false}, {"AO", "244", 9, 9, false}, {"GW", "245", 7, 9, false},
+	{"IO", "246", 7, 7, false}, {"AC", "247", 5, 6, false}, {"SC", "248", 7, 7, false}, {"SD", "249", 9, 9, false},
+	{"RW", "250", 9, 9, false}, {"ET", "251", 9, 9, false}, {"SO", "252", 7, 9, false}, {"DJ", "253", 8, 8, false},
+	{"KE", "254", 9, 10, false}, {"TZ", "255", 9, 9, false}, {"UG", "256", 9, 9, false}, {"BI", "257", 8, 8, false},
+	{"MZ", "258", 8, 9, false}, {"ZM", "260", 9, 9, false}, {"MG", "261", 9, 9, false}, {"RE", "262", 9, 9, false},
+	{"YT", "262", 9, 9, false}, {"ZW", "263", 8, 10, false}, {"NA", "264", 8, 9, false}, {"MW", "265", 7, 9, false},
+	{"LS", "266", 8, 8, false}, {"BW", "267", 7, 8, false}, {"SZ", "268", 8, 8, false}, {"KM", "269", 7, 7, false},
+	{"SH", "290", 4, 5, false}, {"TA", "290", 4, 5, false}, {"ER", "291", 7, 7, false}, {"AW", "297", 7, 7, false},
+	{"FO", "298", 6, 6, false}, {"GL", "299", 6, 6, false},
+
+	{"GI", "350", 8, 8, false}, {"PT", "351", 9, 9, false}, {"LU", "352", 4, 11, false}, {"IE", "353", 7, 9, false},
+	{"IS", "354", 7, 9, false}, {"AL", "355", 8, 9, false}, {"MT", "356", 8, 8, false}, {"CY", "357", 8, 8, false},
+	{"FI", "358", 5, 12, false}, {"AX", "358", 5, 12, false}, {"BG", "359", 8, 9, false}, {"LT", "370", 8, 8, false},
+	{"LV", "371", 8, 8, false}, {"EE", "372", 7, 8, false}, {"MD", "373", 8, 8, false}, {"AM", "374", 8, 8, false},
+	{"BY", "375", 9, 10, false}, {"AD", "376", 6, 9, false}, {"MC", "377", 8, 9, false}, {"SM", "378", 6, 10, true},
+	{"UA", "380", 9, 9, false}, {"RS", "381", 8, 10, false}, {"ME", "382", 8, 8, false}, {"XK", "383", 8, 9, false},
+	{"HR", "385", 8, 9, false}, {"SI", "386", 8, 8, false}, {"BA", "387", 8, 9, false}, {"MK", "389", 8, 8, false},
+	{"CZ", "420", 9, 9, false}, {"SK", "421", 9, 9, false}, {"LI", "423", 7, 9, false},
+
+	{"FK", "500", 5, 5, false}, {"BZ", "501", 7, 7, false}, {"GT", "502", 8, 8, false}, {"SV", "503", 8, 8, false},
+	{"HN", "504", 8, 8, false}, {"NI", "505", 8, 8, false}, {"CR", "506", 8, 8, false}, {"PA", "507", 7, 8, false},
+	{"PM", "508", 6, 6, false}, {"HT", "509", 8, 8, false}, {"GP", "590", 9, 9, false}, {"BL", "590", 9, 9, false},
+	{"MF", "590", 9, 9, false}, {"BO", "591", 8, 8, false}, {"GY", "592", 7, 7, false}, {"EC", "593", 8, 9, false},
+	{"GF", "594", 9, 9, false}, {"PY", "595", 7, 9, false}, {"MQ", "596", 9, 9, false}, {"SR", "597", 6, 7, false},
+	{"UY", "598", 8, 8, false}, {"CW", "599", 7, 8, false}, {"BQ", "599", 7, 7, false},
+
+	{"TL", "670", 7, 8, false}, {"NF", "672", 6, 6, false}, {"BN", "673", 7, 7, false}, {"NR", "674", 7, 7, false},
+	{"PG", "675", 7, 8, false}, {"TO", "676", 5, 7, false}, {"SB", "677", 5, 7, false}, {"VU", "678", 5, 7, false},
+	{"FJ", "679", 7, 7, false}, {"PW", "680", 7, 7, false}, {"WF", "681", 6, 6, false}, {"CK", "682", 5, 5, false},
+	{"NU", "683", 4, 7, false}, {"WS", "685", 5, 7, false}, {"KI", "686", 5, 8, false}, {"NC", "687", 6, 6, false},
+	{"TV", "688", 5, 7, false}, {"PF", "689", 6, 8, false}, {"TK", "690", 4, 7, false}, {"FM", "691", 7, 7, false},
+	{"MH", "692", 7, 7, false},
+
+	{"KP", "850", 8, 10, false}, {"HK", "852", 8, 8, false}, {"MO", "853", 8, 8, false}, {"KH", "855", 8, 9, false},
+	{"LA", "856", 8, 10, false}, {"BD", "880", 8, 10, false}, {"TW", "886", 8, 9, false},
+
+	{"MV", "960", 7, 7, false}, {"LB", "961", 7, 8, false}, {"JO", "962", 8, 9, false}, {"SY", "963", 8, 9, false},
+	{"IQ", "964", 8, 10, false}, {"KW", "965", 8, 8, false}, {"SA", "966", 9, 9, false}, {"YE", "967", 7, 9, false},
+	{"OM", "968", 8, 8, false}, {"PS", "970", 8, 9, false}, {"AE", "971", 8, 9, false}, {"IL", "972", 8, 9, false},
+	{"BH", "973", 8, 8, false}, {"QA", "974", 8, 8, false}, {"BT", "975", 7, 8, false}, {"MN", "976", 8, 8, false},
+	{"NP", "977", 8, 10, false}, {"TJ", "992", 9, 9, false}, {"TM", "993", 8, 8, false}, {"AZ", "994", 9, 9, false},
+	{"GE", "995", 9, 9, false}, {"KG", "996", 9, 9, false}, {"UZ", "998", 9, 9, false},
+}
+
+// nanpAreas maps the geographic NANP area codes in service, or announced as overlays, to their
+// region. regionFor reports an area code missing here as unknown rather than guessing the US, so
+// new overlays have to be added here before numbers on them can sign in under a country list.
+var nanpAreas = map[string]string{
+	"242": "BS", "246": "BB", "264": "AI", "268": "AG", "284": "VG", "340": "VI", "345": "KY", "441": "BM",
+	"473": "GD", "649": "TC", "658": "JM", "876": "JM", "664": "MS", "670": "MP", "671": "GU", "684": "AS",
+	"721": "SX", "758": "LC", "767": "DM", "784": "VC", "787": "PR", "939": "PR", "809": "DO", "829": "DO",
+	"849": "DO", "868": "TT", "869": "KN",
+
+	"204": "CA", "226": "CA", "236": "CA", "249": "CA", "250": "CA", "257": "CA", "263": "CA", "289": "CA",
+	"306": "CA", "343": "CA", "354": "CA", "365": "CA", "367": "CA", "368": "CA", "382": "CA", "387": "CA",
+	"403": "CA", "416": "CA", "418": "CA", "428": "CA", "431": "CA", "437": "CA", "438": "CA", "450": "CA",
+	"460": "CA", "468": "CA", "474": "CA", "506": "CA", "514": "CA", "519": "CA", "548": "CA", "579": "CA",
+	"581": "CA", "584": "CA", "587": "CA", "600": "CA", "604": "CA", "613": "CA", "639": "CA", "647": "CA",
+	"672": "CA", "683": "CA", "705": "CA", "709": "CA", "742": "CA", "753": "CA", "778": "CA", "780": "CA",
+	"782": "CA", "807": "CA", "819": "CA", "825": "CA", "867": "CA", "873": "CA", "879": "CA", "902": "CA",
+	"905": "CA", "942": "CA",
+
+	"201": "US", "202": "US", "203": "US", "205": "US", "206": "US", "207": "US", "208": "US", "209": "US",
+	"210": "US", "212": "US", "213": "US", "214": "US", "215": "US", "216": "US", "217": "US", "218": "US",
+	"219": "US", "220": "US", "223": "US", "224": "US", "225": "US", "227": "US", "228": "US", "229": "US",
+	"231": "US", "234": "US", "235": "US", "239": "US", "240": "US", "248": "US", "251": "US", "252": "US",
+	"253": "US", "254": "US", "256": "US", "260": "US", "262": "US", "267": "US", "269": "US", "270": "US",
+	"272": "US", "274": "US", "276": "US", "279": "US", "281": "US", "283": "US", "301": "US", "302": "US",
+	"303": "US", "304": "US", "305": "US", "307": "US", "308": "US", "309": "US", "310": "US", "312": "US",
+	"313": "US", "314": "US", "315": "US", "316": "US", "317": "US", "318": "US", "319": "US", "320": "US",
+	"321": "US", "323": "US", "324": "US", "325": "US", "326": "US", "327": "US", "329": "US", "330": "US",
+	"331": "US", "332": "US", "334": "US", "336": "US", "337": "US", "339": "US", "341": "US", "346": "US",
+	"347": "US", "350": "US", "351": "US", "352": "US", "353": "US", "360": "US", "361": "US", "363": "US",
+	"364": "US", "369": "US", "380": "US", "385": "US", "386": "US", "401": "US", "402": "US", "404": "US",
+	"405": "US", "406": "US", "407": "US", "408": "US", "409": "US", "410": "US", "412": "US", "413": "US",
+	"414": "US", "415": "US", "417": "US", "419": "US", "423": "US", "424": "US", "425": "US", "430": "US",
+	"432": "US", "434": "US", "435": "US", "436": "US", "440": "US", "442": "US", "443": "US", "445": "US",
+	"447": "US", "448": "US", "458": "US", "463": "US", "464": "US", "469": "US", "470": "US", "472": "US",
+	"475": "US", "478": "US", "479": "US", "480": "US", "484": "US", "501": "US", "502": "US", "503": "US",
+	"504": "US", "505": "US", "507": "US", "508": "US", "509": "US", "510": "US", "512": "US", "513": "US",
+	"515": "US", "516": "US", "517": "US", "518": "US", "520": "US", "530": "US", "531": "US", "534": "US",
+	"539": "US", "540": "US", "541": "US", "551": "US", "557": "US", "559": "US", "561": "US", "562": "US",
+	"563": "US", "564": "US", "567": "US", "570": "US", "571": "US", "572": "US", "573": "US", "574": "US",
+	"575": "US", "580": "US", "582": "US", "585": "US", "586": "US", "601": "US", "602": "US", "603": "US",
+	"605": "US", "606": "US", "607": "US", "608": "US", "609": "US", "610": "US", "612": "US", "614": "US",
+	"615": "US", "616": "US", "617": "US", "618": "US", "619": "US", "620": "US", "623": "US", "624": "US",
+	"626": "US", "628": "US", "629": "US", "630": "US", "631": "US", "636": "US", "640": "US", "641": "US",
+	"645": "US", "646": "US", "650": "US", "651": "US", "656": "US", "657": "US", "659": "US", "660": "US",
+	"661": "US", "662": "US", "667": "US", "669": "US", "678": "US", "679": "US", "680": "US", "681": "US",
+	"682": "US", "686": "US", "689": "US", "701": "US", "702": "US", "703": "US", "704": "US", "706": "US",
+	"707": "US", "708": "US", "712": "US", "713": "US", "714": "US", "715": "US", "716": "US", "717": "US",
+	"718": "US", "719": "US", "720": "US", "724": "US", "725": "US", "726": "US", "727": "US", "728": "US",
+	"730": "US", "731": "US", "732": "US", "734": "US", "737": "US", "738": "US", "740": "US", "743": "US",
+	"747": "US", "754": "US", "757": "US", "760": "US", "762": "US", "763": "US", "765": "US", "769": "US",
+	"770": "US", "771": "US", "772": "US", "773": "US", "774": "US", "775": "US", "779": "US", "781": "US",
+	"785": "US", "786": "US", "801": "US", "802": "US", "803": "US", "804": "US", "805": "US", "806": "US",
+	"808": "US", "810": "US", "812": "US", "813": "US", "814": "US", "815": "US", "816": "US", "817": "US",
+	"818": "US", "820": "US", "821": "US", "826": "US", "828": "US", "830": "US", "831": "US", "832": "US",
+	"835": "US", "837": "US", "838": "US", "839": "US", "840": "US", "843": "US", "845": "US", "847": "US",
+	"848": "US", "850": "US", "854": "US", "856": "US", "857": "US", "858": "US", "859": "US", "860": "US",
+	"861": "US", "862": "US", "863": "US", "864": "US", "865": "US", "870": "US", "872": "US", "878": "US",
+	"901": "US", "903": "US", "904": "US", "906": "US", "907": "US", "908": "US", "909": "US", "910": "US",
+	"912": "US", "913": "US", "914": "US", "915": "US", "916": "US", "917": "US", "918": "US", "919": "US",
+	"920": "US", "924": "US", "925": "US", "928": "US", "929": "US", "930": "US", "931": "US", "934": "US",
+	"936": "US", "937": "US", "938": "US", "940": "US", "941": "US", "943": "US", "945": "US", "947": "US",
+	"948": "US", "949": "US", "951": "US", "952": "US", "954": "US", "956": "US", "959": "US", "970": "US",
+	"971": "US", "972": "US", "973": "US", "978": "US", "979": "US", "980": "US", "983": "US", "984": "US",
+	"985": "US", "986": "US", "989": "US",
+}
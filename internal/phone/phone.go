@@ -0,0 +1,243 @@
+// Package phone parses phone numbers into E.164 (+ country code + national number, digits only)
+// and checks them against each country's length rules and the countries logins are allowed from.
+package phone
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Number is a parsed phone number
+type Number struct {
+	E164        string // e.g. +447911123456
+	Country     string // ISO 3166-1 alpha-2 region, e.g. GB; "" for an area code of +1 not in nanpAreas
+	CallingCode string // e.g. 44
+	National    string // national significant number, e.g. 7911123456
+}
+
+// Error is a number that can't be used; its message is meant for the person who typed it
+type Error struct {
+	Msg string
+}
+
+func (e *Error) Error() string { return e.Msg }
+
+func invalid(format string, args ...interface{}) error {
+	return &Error{Msg: fmt.Sprintf(format, args...)}
+}
+
+// byCode and byISO index countries; a calling code's first entry is its main region
+var (
+	byCode = map[string][]country{}
+	byISO  = map[string]country{}
+)
+
+func init() {
+	for _, c := range countries {
+		byCode[c.code] = append(byCode[c.code], c)
+		byISO[c.iso] = c
+	}
+}
+
+// KnownCountry reports whether iso is a region with a calling code
+func KnownCountry(iso string) bool {
+	_, ok := byISO[strings.ToUpper(iso)]
+	return ok
+}
+
+// Parse reads a number typed in any common international form: +44 7911 123456, 0044...,
+// 011 44... (from the US), or +44 (0)7911 123456. Without an international prefix the number
+// is taken as national to defaultCountry (an ISO code; empty requires the prefix), with its
+// trunk 0 or NANP 1 dropped. 011 is only the international prefix when defaultCountry is in
+// the NANP: no area code there starts with 0, while elsewhere 011 begins national numbers
+// (0113 in Leeds).
+func Parse(s, defaultCountry string) (*Number, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, invalid("Phone number required")
+	}
+	s = strings.Replace(s, "(0)", "", 1)
+	international := strings.HasPrefix(s, "+")
+	var digits strings.Builder
+	for i, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && i == 0, r == ' ', r == '-', r == '.', r == '(', r == ')', r == '/':
+		default:
+			return nil, invalid("Phone numbers can only contain digits, spaces, dashes, dots and parentheses")
+		}
+	}
+	d := digits.String()
+	if !international && strings.HasPrefix(d, "00") {
+		d, international = d[2:], true
+	}
+	if international {
+		return parseInternational(d)
+	}
+	if defaultCountry == "" {
+		return nil, invalid("Include the country code, e.g. +44 7911 123456")
+	}
+	c, ok := byISO[strings.ToUpper(defaultCountry)]
+	if !ok {
+		return nil, fmt.Errorf("phone: unknown default country %q", defaultCountry)
+	}
+	if c.code == "1" && strings.HasPrefix(d, "011") {
+		return parseInternational(d[3:])
+	}
+	if c.code == "1" && len(d) == 11 && d[0] == '1' {
+		d = d[1:]
+	}
+	return build(c.code, d)
+}
+
+// parseInternational splits digits after the international prefix into calling code and
+// national number; calling codes are prefix-free, so at most one of 1-3 digits matches
+func parseInternational(d string) (*Number, error) {
+	for n := 1; n <= 3 && n < len(d); n++ {
+		if _, ok := byCode[d[:n]]; ok {
+			return build(d[:n], d[n:])
+		}
+	}
+	if len(d) < 4 {
+		return nil, invalid("Phone number too short")
+	}
+	return nil, invalid("Unknown country code +%s", d[:3])
+}
+
+// build checks a national number against its calling code's rules
+func build(code, national string) (*Number, error) {
+	main := byCode[code][0]
+	if !main.keepZero && strings.HasPrefix(national, "0") {
+		national = national[1:] // trunk prefix typed after the country code
+	}
+	region := regionFor(code, national)
+	c, ok := byISO[region]
+	if !ok {
+		c = main // NANP regions share one length rule
+	}
+	switch {
+	case len(national) < c.min:
+		return nil, invalid("Phone number too short for %s (+%s)", c.iso, code)
+	case len(national) > c.max || len(code)+len(national) > 15:
+		return nil, invalid("Phone number too long for %s (+%s)", c.iso, code)
+	case code == "1" && national[0] < '2':
+		return nil, invalid("Not a valid North American area code")
+	}
+	return &Number{E164: "+" + code + national, Country: region, CallingCode: code, National: national}, nil
+}
+
+// regionFor tells regions sharing a calling code apart where the numbering plan allows it. A
+// +1 number whose area code isn't in nanpAreas (a new overlay, toll-free) gets "": it could
+// be in Canada or the Caribbean as well as the US.
+func regionFor(code, national string) string {
+	switch code {
+	case "1":
+		if len(national) >= 3 {
+			return nanpAreas[national[:3]]
+		}
+		return ""
+	case "7":
+		if strings.HasPrefix(national, "6") || strings.HasPrefix(national, "7") {
+			return "KZ"
+		}
+	}
+	return byCode[code][0].iso
+}
+
+// Policy is how the server accepts numbers: the country assumed when none is given, and which
+// countries are allowed. An empty Allow allows every country not in Deny.
+type Policy struct {
+	DefaultCountry string
+	Allow, Deny    map[string]bool
+}
+
+// NewPolicy builds a Policy from comma-separated ISO country lists, rejecting unknown codes
+func NewPolicy(defaultCountry, allow, deny string) (*Policy, error) {
+	p := &Policy{DefaultCountry: strings.ToUpper(strings.TrimSpace(defaultCountry))}
+	if p.DefaultCountry != "" && !KnownCountry(p.DefaultCountry) {
+		return nil, fmt.Errorf("phone: unknown default country %q", defaultCountry)
+	}
+	var err error
+	if p.Allow, err = countrySet(allow); err != nil {
+		return nil, err
+	}
+	if p.Deny, err = countrySet(deny); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func countrySet(list string) (map[string]bool, error) {
+	set := map[string]bool{}
+	for _, iso := range strings.Split(list, ",") {
+		iso = strings.ToUpper(strings.TrimSpace(iso))
+		if iso == "" {
+			continue
+		}
+		c, ok := byISO[iso]
+		if !ok {
+			return nil, fmt.Errorf("phone: unknown country %q", iso)
+		}
+		if main := byCode[c.code][0]; main.iso != iso && c.code != "1" && c.code != "7" {
+			return nil, fmt.Errorf("phone: %s shares +%s with %s and can't be told apart; list %s", iso, c.code, main.iso, main.iso)
+		}
+		set[iso] = true
+	}
+	return set, nil
+}
+
+// Parse parses s with the policy's default country and checks its country is allowed. A nil
+// Policy requires the country code and allows everything.
+func (p *Policy) Parse(s string) (*Number, error) {
+	if p == nil {
+		return Parse(s, "")
+	}
+	n, err := Parse(s, p.DefaultCountry)
+	if err != nil {
+		return nil, err
+	}
+	if !p.Allowed(n.Country) {
+		if n.Country == "" {
+			return nil, invalid("Numbers with area code %s (+%s) can't be used to sign in", n.National[:3], n.CallingCode)
+		}
+		return nil, invalid("Numbers from %s (+%s) can't be used to sign in", n.Country, n.CallingCode)
+	}
+	return n, nil
+}
+
+// Allowed reports whether numbers from a country are accepted. An unknown region ("") is only
+// accepted without allow and deny lists, since it can't be checked against them.
+func (p *Policy) Allowed(iso string) bool {
+	if p == nil {
+		return true
+	}
+	if iso == "" {
+		return len(p.Allow) == 0 && len(p.Deny) == 0
+	}
+	return !p.Deny[iso] && (len(p.Allow) == 0 || p.Allow[iso])
+}
+
+// String describes the policy for the startup log
+func (p *Policy) String() string {
+	list := func(set map[string]bool) string {
+		out := make([]string, 0, len(set))
+		for iso := range set {
+			out = append(out, iso)
+		}
+		sort.Strings(out)
+		return strings.Join(out, ",")
+	}
+	s := "all countries"
+	if len(p.Allow) > 0 {
+		s = "only " + list(p.Allow)
+	}
+	if len(p.Deny) > 0 {
+		s += " except " + list(p.Deny)
+	}
+	if p.DefaultCountry != "" {
+		s += ", national numbers as " + p.DefaultCountry
+	}
+	return s
+}
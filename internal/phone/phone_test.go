@@ -0,0 +1,126 @@
+package phone
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in, country string
+		e164, iso   string // empty e164 means the number is rejected
+	}{
+		{"+44 7911 123456", "", "+447911123456", "GB"},
+		{"+44 (0)7911 123456", "", "+447911123456", "GB"},
+		{"0044 7911 123456", "US", "+447911123456", "GB"},
+		{"011 44 7911 123456", "US", "+447911123456", "GB"},
+		{"011 44 7911 123456", "CA", "+447911123456", "GB"},
+		{"(212) 555-0123", "US", "+12125550123", "US"},
+		{"1 212 555 0123", "US", "+12125550123", "US"},
+		{"416.555.0123", "US", "+14165550123", "CA"},
+		{"+1 942 555 0123", "", "+19425550123", "CA"},
+		{"+1 876 555 0123", "", "+18765550123", "JM"},
+		{"+1 999 555 0123", "", "+19995550123", ""}, // not assigned: region unknown
+		{"0113 496 0000", "GB", "+441134960000", "GB"},
+		{"07911 123456", "GB", "+447911123456", "GB"},
+		{"+7 701 123 4567", "", "+77011234567", "KZ"},
+		{"+7 912 123 4567", "", "+79121234567", "RU"},
+		{"+39 06 1234 5678", "", "+390612345678", "IT"},
+
+		{"", "US", "", ""},
+		{"212 555 0123", "", "", ""},         // national without a default country
+		{"212-555-0123 ext 4", "US", "", ""}, // letters
+		{"+1 112 555 0123", "", "", ""},      // area codes start at 2
+		{"+1 212 555 012", "", "", ""},       // too short
+		{"+44 7911 123456 7890", "", "", ""}, // too long
+		{"+999 1234 5678", "", "", ""},       // unknown calling code
+		{"+1", "", "", ""},
+		{"011 44 7911 123456", "", "", ""}, // 011 needs a NANP default
+		{"0113 496 0000", "US", "", ""},    // and elsewhere is a national number
+	}
+	for _, tt := range tests {
+		n, err := Parse(tt.in, tt.country)
+		if tt.e164 == "" {
+			var perr *Error
+			if err == nil {
+				t.Errorf("Parse(%q, %q) = %s, want an error", tt.in, tt.country, n.E164)
+			} else if !errors.As(err, &perr) {
+				t.Errorf("Parse(%q, %q): %v is not a *phone.Error", tt.in, tt.country, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q, %q): %v", tt.in, tt.country, err)
+			continue
+		}
+		if n.E164 != tt.e164 || n.Country != tt.iso {
+			t.Errorf("Parse(%q, %q) = %s %q, want %s %q", tt.in, tt.country, n.E164, n.Country, tt.e164, tt.iso)
+		}
+	}
+}
+
+func TestRegionFor(t *testing.T) {
+	tests := []struct {
+		code, national, want string
+	}{
+		{"1", "2125550123", "US"},
+		{"1", "9075550123", "US"},
+		{"1", "6045550123", "CA"},
+		{"1", "9425550123", "CA"},
+		{"1", "7875550123", "PR"},
+		{"1", "8095550123", "DO"},
+		{"1", "8005550123", ""}, // toll-free spans the whole NANP
+		{"1", "9995550123", ""},
+		{"1", "21", ""},
+		{"7", "7011234567", "KZ"},
+		{"7", "6011234567", "KZ"},
+		{"7", "9121234567", "RU"},
+		{"44", "1534123456", "GB"}, // Jersey shares +44 and isn't told apart
+	}
+	for _, tt := range tests {
+		if got := regionFor(tt.code, tt.national); got != tt.want {
+			t.Errorf("regionFor(%s, %s) = %q, want %q", tt.code, tt.national, got, tt.want)
+		}
+	}
+}
+
+// Every NANP region in the table is a known country under +1, so country lists can name it
+func TestNANPAreas(t *testing.T) {
+	for area, iso := range nanpAreas {
+		if c, ok := byISO[iso]; !ok || c.code != "1" {
+			t.Errorf("area code %s maps to %s, which isn't a +1 region", area, iso)
+		}
+		if len(area) != 3 || area[0] < '2' {
+			t.Errorf("%q is not an area code", area)
+		}
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	tests := []struct {
+		allow, deny string
+		number      string
+		ok          bool
+	}{
+		{"", "", "+19995550123", true},
+		{"US", "", "+12125550123", true},
+		{"US", "", "+14165550123", false},
+		{"US", "", "+19425550123", false},
+		{"US", "", "+19995550123", false},
+		{"", "CA", "+19425550123", false},
+		{"", "CA", "+19995550123", false},
+		{"", "CA", "+12125550123", true},
+		{"GB", "", "+447911123456", true},
+		{"", "RU", "+77011234567", true},
+	}
+	for _, tt := range tests {
+		p, err := NewPolicy("US", tt.allow, tt.deny)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = p.Parse(tt.number)
+		if ok := err == nil; ok != tt.ok {
+			t.Errorf("allow %q deny %q: Parse(%s) ok = %v (%v), want %v", tt.allow, tt.deny, tt.number, ok, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+// Package refcheck validates the reference tables (cities, routes, distances) for consistency.
+package refcheck
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+
+	"triangle_travel/internal/db"
+	"triangle_travel/internal/geo"
+)
+
+// Severity of an issue; errors fail validation, warnings only with strict mode
+const (
+	Error   = "error"
+	Warning = "warning"
+)
+
+// Issue is one finding
+type Issue struct {
+	Severity string `json:"severity"`
+	Check    string `json:"check"`
+	Message  string `json:"message"`
+}
+
+// Report holds all findings of a validation run
+type Report struct {
+	Issues []Issue `json:"issues"`
+}
+
+// Errors returns the number of error-severity issues
+func (r *Report) Errors() int {
+	n := 0
+	for _, i := range r.Issues {
+		if i.Severity == Error {
+			n++
+		}
+	}
+	return n
+}
+
+// Warnings returns the number of warning-severity issues
+func (r *Report) Warnings() int {
+	return len(r.Issues) - r.Errors()
+}
+
+// Write prints the issues grouped by check, followed by a summary line
+func (r *Report) Write(w io.Writer) {
+	for _, i := range r.Issues {
+		fmt.Fprintf(w, "%-7s %-20s %s\n", i.Severity, i.Check, i.Message)
+	}
+	fmt.Fprintf(w, "%d errors, %d warnings\n", r.Errors(), r.Warnings())
+}
+
+func (r *Report) add(severity, check, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, Issue{Severity: severity, Check: check, Message: fmt.Sprintf(format, args...)})
+}
+
+var codePattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// data is the reference data loaded for checking
+type data struct {
+	cityAirports map[string][]string // city -> airports
+	airportCity  map[string][]string // airport -> cities (more than one is an error)
+	airports     map[string]db.Airport
+	distances    []db.Distance
+	routes       []db.Route
+}
+
+func load(ctx context.Context, database db.ReferenceStore) (*data, error) {
+	d := &data{
+		cityAirports: make(map[string][]string),
+		airportCity:  make(map[string][]string),
+		airports:     make(map[string]db.Airport),
+	}
+	cities, err := database.ListCityAirports(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range cities {
+		d.cityAirports[c.CityCode] = append(d.cityAirports[c.CityCode], c.AirportCode)
+		d.airportCity[c.AirportCode] = append(d.airportCity[c.AirportCode], c.CityCode)
+	}
+	airports, err := database.ListAirports(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range airports {
+		d.airports[a.IATA] = a
+	}
+	if d.distances, err = database.ListDistances(ctx); err != nil {
+		return nil, err
+	}
+	if d.routes, err = database.ListRoutes(ctx); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// known reports whether code is a city or airport in iata_cities or airports
+func (d *data) known(code string) bool {
+	if _, ok := d.cityAirports[code]; ok {
+		return true
+	}
+	if _, ok := d.airportCity[code]; ok {
+		return true
+	}
+	_, ok := d.airports[code]
+	return ok
+}
+
+// city maps an airport to its city (the code itself when it's a city or unmapped)
+func (d *data) city(code string) string {
+	if cities := d.airportCity[code]; len(cities) > 0 {
+		return cities[0]
+	}
+	if a, ok := d.airports[code]; ok && a.CityCode != "" {
+		return a.CityCode
+	}
+	return code
+}
+
+// coords returns coordinates for an airport, or for a city via its first located airport
+func (d *data) coords(code string) (db.Airport, bool) {
+	if a, ok := d.airports[code]; ok {
+		return a, true
+	}
+	for _, apt := range d.cityAirports[code] {
+		if a, ok := d.airports[apt]; ok {
+			return a, true
+		}
+	}
+	return db.Airport{}, false
+}
+
+// Run loads the reference tables and runs every check. Route and distance codes missing from
+// iata_cities and airports are only warnings: the seed data routes to many airports it has no
+// rows for, and searches cope with them (no coordinates, no award prices). Admin edits are
+// stricter, see CheckRoute and CheckDistance.
+func Run(ctx context.Context, database db.ReferenceStore) (*Report, error) {
+	d, err := load(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+	r := &Report{Issues: []Issue{}}
+	checkCodes(d, r)
+	checkCities(d, r)
+	checkDistances(d, r)
+	checkRoutes(d, r)
+	checkOrphans(d, r)
+	return r, nil
+}
+
+func checkCodes(d *data, r *Report) {
+	seen := make(map[string]bool)
+	check := func(code, where string) {
+		if seen[code] {
+			return
+		}
+		seen[code] = true
+		if !codePattern.MatchString(code) {
+			r.add(Error, "code-format", "%q in %s is not a three-letter IATA code", code, where)
+		}
+	}
+	for city, airports := range d.cityAirports {
+		check(city, "iata_cities.city_code")
+		for _, a := range airports {
+			check(a, "iata_cities.airport_code")
+		}
+	}
+	for _, x := range d.distances {
+		check(x.From, "distances.from_iata")
+		check(x.To, "distances.to_iata")
+	}
+	for _, x := range d.routes {
+		check(x.From, "city_routes.city_iata")
+		check(x.To, "city_routes.route_to")
+	}
+	sortIssues(r, "code-format")
+}
+
+func checkCities(d *data, r *Report) {
+	for airport, cities := range d.airportCity {
+		if len(cities) > 1 {
+			r.add(Error, "airport-in-two-cities", "%s belongs to %v", airport, cities)
+		}
+	}
+	sortIssues(r, "airport-in-two-cities")
+}
+
+func checkDistances(d *data, r *Report) {
+	byPair := make(map[[2]string]float64, len(d.distances))
+	for _, x := range d.distances {
+		byPair[[2]string{x.From, x.To}] = x.Miles
+	}
+	for _, x := range d.distances {
+		for _, code := range []string{x.From, x.To} {
+			if !d.known(code) {
+				r.add(Warning, "unknown-code", "distances %s-%s: %s is not in iata_cities or airports", x.From, x.To, code)
+			}
+		}
+		if x.From == x.To || d.city(x.From) == d.city(x.To) && x.Miles == 0 {
+			r.add(Error, "self-distance", "distances %s-%s: same place", x.From, x.To)
+			continue
+		}
+		if x.Miles <= 0 {
+			r.add(Error, "impossible-distance", "distances %s-%s: %.1f miles", x.From, x.To, x.Miles)
+			continue
+		}
+		if reverse, ok := byPair[[2]string{x.To, x.From}]; !ok {
+			r.add(Warning, "asymmetric-distance", "%s-%s (%.1f mi) has no %s-%s row", x.From, x.To, x.Miles, x.To, x.From)
+		} else if x.From < x.To && math.Abs(reverse-x.Miles) > 1 {
+			r.add(Error, "asymmetric-distance", "%s-%s is %.1f mi but %s-%s is %.1f mi", x.From, x.To, x.Miles, x.To, x.From, reverse)
+		}
+		// A city's coordinates are those of one of its airports, so skip pairs within one city
+		if d.city(x.From) == d.city(x.To) {
+			continue
+		}
+		a, okA := d.coords(x.From)
+		b, okB := d.coords(x.To)
+		if !okA || !okB {
+			continue
+		}
+		if msg := implausibleDistance(x, a, b); msg != "" {
+			r.add(Error, "impossible-distance", "%s", msg)
+		}
+	}
+}
+
+func checkRoutes(d *data, r *Report) {
+	// Same (origin city, alliance, destination city) listed under several codes
+	type key struct{ city, alliance, dest string }
+	first := make(map[key]db.Route)
+	for _, x := range d.routes {
+		for _, code := range []string{x.From, x.To} {
+			if !d.known(code) {
+				r.add(Warning, "unknown-code", "city_routes %s-%s (%s): %s is not in iata_cities or airports", x.From, x.To, x.Alliance, code)
+			}
+		}
+		fromCity, toCity := d.city(x.From), d.city(x.To)
+		if fromCity == toCity {
+			r.add(Error, "self-route", "city_routes %s-%s (%s): both in %s", x.From, x.To, x.Alliance, fromCity)
+			continue
+		}
+		k := key{fromCity, x.Alliance, toCity}
+		if prev, ok := first[k]; ok && prev.To == x.To {
+			r.add(Warning, "duplicate-route", "city_routes %s-%s and %s-%s (%s) are the same city pair", prev.From, prev.To, x.From, x.To, x.Alliance)
+		} else if !ok {
+			first[k] = x
+		}
+	}
+}
+
+func checkOrphans(d *data, r *Report) {
+	used := make(map[string]bool)
+	for _, x := range d.distances {
+		used[d.city(x.From)] = true
+		used[d.city(x.To)] = true
+	}
+	for _, x := range d.routes {
+		used[d.city(x.From)] = true
+		used[d.city(x.To)] = true
+	}
+	var orphans []string
+	for city := range d.cityAirports {
+		if !used[city] {
+			orphans = append(orphans, city)
+		}
+	}
+	sort.Strings(orphans)
+	for _, city := range orphans {
+		r.add(Warning, "orphan-city", "%s (%v) has no routes or distances", city, d.cityAirports[city])
+	}
+}
+
+// implausibleDistance compares a distance with the great circle between the airports at either end
+func implausibleDistance(x db.Distance, a, b db.Airport) string {
+	gc := geo.DistanceMiles(a.Latitude, a.Longitude, b.Latitude, b.Longitude)
+	// Ground distance can't beat the great circle, and shouldn't be wildly longer
+	if x.Miles < gc*0.9-5 {
+		return fmt.Sprintf("%s-%s is %.1f mi but the great-circle distance is %.1f mi", x.From, x.To, x.Miles, gc)
+	}
+	if x.Miles > gc*2.5+25 {
+		return fmt.Sprintf("%s-%s is %.1f mi but the great-circle distance is only %.1f mi", x.From, x.To, x.Miles, gc)
+	}
+	return ""
+}
+
+// sortIssues orders the trailing issues of one check by message (map iteration is random)
+func sortIssues(r *Report, check string) {
+	start := len(r.Issues)
+	for start > 0 && r.Issues[start-1].Check == check {
+		start--
+	}
+	tail := r.Issues[start:]
+	sort.Slice(tail, func(i, j int) bool { return tail[i].Message < tail[j].Message })
+}
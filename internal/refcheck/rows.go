@@ -0,0 +1,226 @@
+package refcheck
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"triangle_travel/internal/db"
+)
+
+// Invalid is a reference data change rejected by row validation
+type Invalid struct {
+	Problems []string
+}
+
+func (e *Invalid) Error() string {
+	return strings.Join(e.Problems, "; ")
+}
+
+// problems collects validation failures for one row
+type problems []string
+
+func (p *problems) add(format string, args ...interface{}) {
+	*p = append(*p, fmt.Sprintf(format, args...))
+}
+
+func (p problems) err() error {
+	if len(p) == 0 {
+		return nil
+	}
+	return &Invalid{Problems: p}
+}
+
+// Lookup is what row validation reads from the database
+type Lookup interface {
+	CodeUsage(ctx context.Context, code string) (*db.CodeUsage, error)
+	GetAirport(ctx context.Context, code string) (*db.Airport, error)
+}
+
+var (
+	// RouteAlliances are the alliance values of city_routes ("ALL" is any alliance member)
+	RouteAlliances = []string{"None", "ALL", "ONE_WORLD", "SKY_TEAM", "STAR_ALLIANCE"}
+	// AirlineAlliances are the alliance values of airlines
+	AirlineAlliances = []string{"None", "ONE_WORLD", "SKY_TEAM", "STAR_ALLIANCE"}
+	// Continents are the continent codes of airports
+	Continents = []string{"AF", "AN", "AS", "EU", "NA", "OC", "SA"}
+
+	countryPattern     = regexp.MustCompile(`^[A-Z]{2}$`)
+	airlinePattern     = regexp.MustCompile(`^[A-Z0-9]{2}$`)
+	airlineICAOPattern = regexp.MustCompile(`^([A-Z]{3})?$`)
+)
+
+func oneOf(value string, allowed []string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckAirport validates an airports row on its own
+func CheckAirport(a db.Airport) error {
+	var p problems
+	if !codePattern.MatchString(a.IATA) {
+		p.add("iata %q is not a three-letter IATA code", a.IATA)
+	}
+	if !codePattern.MatchString(a.CityCode) {
+		p.add("cityCode %q is not a three-letter IATA code", a.CityCode)
+	}
+	if strings.TrimSpace(a.Name) == "" {
+		p.add("name is required")
+	}
+	if !countryPattern.MatchString(a.Country) {
+		p.add("country %q is not an ISO 3166-1 alpha-2 code", a.Country)
+	}
+	if !oneOf(a.Continent, Continents) {
+		p.add("continent %q is not one of %s", a.Continent, strings.Join(Continents, ", "))
+	}
+	if math.IsNaN(a.Latitude) || a.Latitude < -90 || a.Latitude > 90 {
+		p.add("latitude %v is out of range", a.Latitude)
+	}
+	if math.IsNaN(a.Longitude) || a.Longitude < -180 || a.Longitude > 180 {
+		p.add("longitude %v is out of range", a.Longitude)
+	}
+	if a.Latitude == 0 && a.Longitude == 0 {
+		p.add("coordinates 0,0 are missing coordinates")
+	}
+	return p.err()
+}
+
+// CheckAirline validates an airlines row on its own
+func CheckAirline(a db.Airline) error {
+	var p problems
+	if !airlinePattern.MatchString(a.IATA) {
+		p.add("iata %q is not a two-character IATA airline code", a.IATA)
+	}
+	if !airlineICAOPattern.MatchString(a.ICAO) {
+		p.add("icao %q is not a three-letter ICAO airline code", a.ICAO)
+	}
+	if strings.TrimSpace(a.Name) == "" {
+		p.add("name is required")
+	}
+	if a.Country != "" && !countryPattern.MatchString(a.Country) {
+		p.add("country %q is not an ISO 3166-1 alpha-2 code", a.Country)
+	}
+	if !oneOf(a.Alliance, AirlineAlliances) {
+		p.add("alliance %q is not one of %s", a.Alliance, strings.Join(AirlineAlliances, ", "))
+	}
+	return p.err()
+}
+
+// CheckCityAirport validates mapping an airport to a city against the airport master data
+func CheckCityAirport(ctx context.Context, l Lookup, c db.CityAirport) error {
+	var p problems
+	if !codePattern.MatchString(c.CityCode) {
+		p.add("city %q is not a three-letter IATA code", c.CityCode)
+	}
+	if !codePattern.MatchString(c.AirportCode) {
+		p.add("airport %q is not a three-letter IATA code", c.AirportCode)
+	}
+	if len(p) > 0 {
+		return p.err()
+	}
+	u, err := l.CodeUsage(ctx, c.AirportCode)
+	if err != nil {
+		return err
+	}
+	switch {
+	case u.Airport == nil:
+		p.add("%s is not in the airports table; add the airport first", c.AirportCode)
+	case u.Airport.CityCode != c.CityCode && u.Airport.CityCode != c.AirportCode:
+		p.add("%s belongs to city %s in the airports table", c.AirportCode, u.Airport.CityCode)
+	}
+	for _, city := range u.Cities {
+		if city != c.CityCode {
+			p.add("%s is already mapped to city %s", c.AirportCode, city)
+		}
+	}
+	return p.err()
+}
+
+// CheckRoute validates a city_routes row: known codes in different cities and a valid alliance
+func CheckRoute(ctx context.Context, l Lookup, r db.Route) error {
+	var p problems
+	if !oneOf(r.Alliance, RouteAlliances) {
+		p.add("alliance %q is not one of %s", r.Alliance, strings.Join(RouteAlliances, ", "))
+	}
+	from, to, err := endpoints(ctx, l, r.From, r.To, &p)
+	if err != nil || from == nil || to == nil {
+		return orProblems(err, p)
+	}
+	if city := from.City(r.From); city == to.City(r.To) {
+		p.add("%s and %s are both in %s", r.From, r.To, city)
+	}
+	return p.err()
+}
+
+// CheckDistance validates a distances row: known codes in different cities and, where both
+// have coordinates, a distance consistent with the great circle between them
+func CheckDistance(ctx context.Context, l Lookup, x db.Distance) error {
+	var p problems
+	if math.IsNaN(x.Miles) || x.Miles <= 0 {
+		p.add("miles must be positive")
+	}
+	from, to, err := endpoints(ctx, l, x.From, x.To, &p)
+	if err != nil || from == nil || to == nil {
+		return orProblems(err, p)
+	}
+	if city := from.City(x.From); city == to.City(x.To) {
+		p.add("%s and %s are both in %s", x.From, x.To, city)
+		return p.err()
+	}
+	if len(p) > 0 {
+		return p.err()
+	}
+	a, err := l.GetAirport(ctx, x.From)
+	if err != nil {
+		return err
+	}
+	b, err := l.GetAirport(ctx, x.To)
+	if err != nil {
+		return err
+	}
+	if a != nil && b != nil {
+		if msg := implausibleDistance(x, *a, *b); msg != "" {
+			p.add("%s", msg)
+		}
+	}
+	return p.err()
+}
+
+// endpoints checks both codes' format and looks them up; nil usages mean a problem was recorded
+func endpoints(ctx context.Context, l Lookup, fromCode, toCode string, p *problems) (from, to *db.CodeUsage, err error) {
+	lookup := func(code string) (*db.CodeUsage, error) {
+		if !codePattern.MatchString(code) {
+			p.add("%q is not a three-letter IATA code", code)
+			return nil, nil
+		}
+		u, err := l.CodeUsage(ctx, code)
+		if err != nil {
+			return nil, err
+		}
+		if !u.Known() {
+			p.add("%s is not in iata_cities or airports", code)
+			return nil, nil
+		}
+		return u, nil
+	}
+	if from, err = lookup(fromCode); err != nil {
+		return nil, nil, err
+	}
+	if to, err = lookup(toCode); err != nil {
+		return nil, nil, err
+	}
+	return from, to, nil
+}
+
+func orProblems(err error, p problems) error {
+	if err != nil {
+		return err
+	}
+	return p.err()
+}
@@ -0,0 +1,385 @@
+// Package routegraph keeps iata_cities, city_routes, distances and airport coordinates in an
+// immutable in-memory graph so searches don't issue one query per airport. A Store swaps in a
+// freshly loaded graph when the reference data changes.
+package routegraph
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"triangle_travel/internal/db"
+	"triangle_travel/internal/geo"
+)
+
+// spatialCellDeg is the grid cell size of the airport spatial index
+const spatialCellDeg = 2
+
+// Graph is a read-only snapshot of the route data; safe for concurrent use
+type Graph struct {
+	Version  int64
+	Snapshot int64 // dataset snapshot the graph was loaded from; 0 for the live tables
+	LoadedAt time.Time
+
+	cityOf    map[string]string              // airport -> city
+	airports  map[string][]string            // city -> airports (sorted)
+	routes    map[string]map[string][]string // alliance -> origin -> destinations (sorted)
+	distances map[string]map[string]float64  // from -> to -> miles, both directions
+	located   map[string]db.Airport          // airports with coordinates
+	spatial   *geo.Index
+}
+
+// NearbyAirport is an airport found by a spatial query
+type NearbyAirport struct {
+	db.Airport
+	Miles float64 `json:"miles"`
+}
+
+// Load reads the reference tables, or a snapshot of them, into a new Graph
+func Load(ctx context.Context, database db.RouteData) (*Graph, error) {
+	version, err := database.ReferenceVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	g := &Graph{
+		Version:   version,
+		LoadedAt:  time.Now(),
+		cityOf:    make(map[string]string),
+		airports:  make(map[string][]string),
+		routes:    make(map[string]map[string][]string),
+		distances: make(map[string]map[string]float64),
+		located:   make(map[string]db.Airport),
+	}
+
+	cities, err := database.ListCityAirports(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range cities {
+		if _, ok := g.cityOf[c.AirportCode]; !ok {
+			g.cityOf[c.AirportCode] = c.CityCode
+		}
+		g.airports[c.CityCode] = append(g.airports[c.CityCode], c.AirportCode)
+	}
+
+	routes, err := database.ListRoutes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range routes {
+		byOrigin := g.routes[r.Alliance]
+		if byOrigin == nil {
+			byOrigin = make(map[string][]string)
+			g.routes[r.Alliance] = byOrigin
+		}
+		byOrigin[r.From] = append(byOrigin[r.From], r.To)
+	}
+
+	distances, err := database.ListDistances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Distances are symmetric; a pair may be seeded in either direction. When both rows exist
+	// the forward one wins for that direction.
+	forward := make(map[[2]string]bool, len(distances))
+	for _, x := range distances {
+		g.setDistance(x.From, x.To, x.Miles)
+		forward[[2]string{x.From, x.To}] = true
+		if !forward[[2]string{x.To, x.From}] {
+			g.setDistance(x.To, x.From, x.Miles)
+		}
+	}
+
+	airports, err := database.ListAirports(ctx)
+	if err != nil {
+		return nil, err
+	}
+	points := make([]geo.Point, 0, len(airports))
+	for _, a := range airports {
+		g.located[a.IATA] = a
+		points = append(points, geo.Point{ID: a.IATA, Lat: a.Latitude, Lon: a.Longitude})
+	}
+	g.spatial = geo.NewIndex(points, spatialCellDeg)
+	return g, nil
+}
+
+func (g *Graph) setDistance(from, to string, miles float64) {
+	if g.distances[from] == nil {
+		g.distances[from] = make(map[string]float64)
+	}
+	g.distances[from][to] = miles
+}
+
+// CityForAirport returns the city code for an airport, or the code itself if unknown
+func (g *Graph) CityForAirport(airport string) string {
+	if city, ok := g.cityOf[airport]; ok {
+		return city
+	}
+	return airport
+}
+
+// SameCity returns true if both airport codes belong to the same city
+func (g *Graph) SameCity(a, b string) bool {
+	return a == b || g.CityForAirport(a) == g.CityForAirport(b)
+}
+
+// AirportsForCity returns the airports of a city, or the code itself if it has none
+func (g *Graph) AirportsForCity(city string) []string {
+	if airports, ok := g.airports[city]; ok {
+		return airports
+	}
+	return []string{city}
+}
+
+// DistancesFrom returns other code -> miles for an airport, whichever direction the pair was
+// stored in. The map is shared; don't modify it.
+func (g *Graph) DistancesFrom(from string) map[string]float64 {
+	return g.distances[from]
+}
+
+// RoutesFrom returns destinations from a city or airport code for an alliance
+func (g *Graph) RoutesFrom(origin, alliance string) []string {
+	return g.routes[alliance][origin]
+}
+
+// RoutesFromWithFallback checks the code itself first, then each airport in its city
+func (g *Graph) RoutesFromWithFallback(origin, alliance string) []string {
+	if routes := g.RoutesFrom(origin, alliance); len(routes) > 0 {
+		return routes
+	}
+	seen := make(map[string]bool)
+	var routes []string
+	for _, apt := range g.AirportsForCity(origin) {
+		for _, dest := range g.RoutesFrom(apt, alliance) {
+			if !seen[dest] {
+				seen[dest] = true
+				routes = append(routes, dest)
+			}
+		}
+	}
+	sort.Strings(routes)
+	return routes
+}
+
+// HasDirectRoute reports, for each of toIatas, whether origin or an airport in its city flies there
+func (g *Graph) HasDirectRoute(origin, alliance string, toIatas map[string]bool) map[string]bool {
+	result := make(map[string]bool, len(toIatas))
+	for k := range toIatas {
+		result[k] = false
+	}
+	mark := func(code string) {
+		for _, dest := range g.RoutesFrom(code, alliance) {
+			if toIatas[dest] {
+				result[dest] = true
+			}
+		}
+	}
+	mark(origin)
+	for _, apt := range g.AirportsForCity(origin) {
+		if apt != origin {
+			mark(apt)
+		}
+	}
+	return result
+}
+
+// Flies reports whether there is a route from one city or airport to another for an alliance.
+// An airport stands for its whole city on both ends: routes stored for the city or any of its
+// airports count, and so does landing at any airport of the destination city.
+func (g *Graph) Flies(from, to, alliance string) bool {
+	toCity := g.CityForAirport(to)
+	fromCity := g.CityForAirport(from)
+	for _, origin := range append([]string{from, fromCity}, g.AirportsForCity(fromCity)...) {
+		for _, dest := range g.RoutesFrom(origin, alliance) {
+			if dest == to || g.CityForAirport(dest) == toCity {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Connects reports whether two cities or airports are joined by a route in either direction
+// for an alliance (see Flies)
+func (g *Graph) Connects(a, b, alliance string) bool {
+	return g.Flies(a, b, alliance) || g.Flies(b, a, alliance)
+}
+
+// Location returns the coordinates for an airport, or for a city as the centroid of its
+// located airports
+func (g *Graph) Location(code string) (lat, lon float64, ok bool) {
+	if a, found := g.located[code]; found {
+		return a.Latitude, a.Longitude, true
+	}
+	n := 0
+	for _, apt := range g.airports[code] {
+		if a, found := g.located[apt]; found {
+			lat += a.Latitude
+			lon += a.Longitude
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, 0, false
+	}
+	// Metro airports are close together, so averaging longitudes is safe away from the antimeridian
+	return lat / float64(n), lon / float64(n), true
+}
+
+// NearestAirports returns up to limit airports closest to lat/lon; maxMiles > 0 caps the distance
+func (g *Graph) NearestAirports(lat, lon float64, limit int, maxMiles float64) []NearbyAirport {
+	return g.nearby(g.spatial.Nearest(lat, lon, limit, maxMiles))
+}
+
+// AirportsWithin returns every airport within miles of a city or airport code, nearest first.
+// ok is false when the code has no coordinates.
+func (g *Graph) AirportsWithin(code string, miles float64) (airports []NearbyAirport, ok bool) {
+	lat, lon, ok := g.Location(code)
+	if !ok {
+		return nil, false
+	}
+	return g.nearby(g.spatial.Within(lat, lon, miles)), true
+}
+
+func (g *Graph) nearby(hits []geo.Hit) []NearbyAirport {
+	out := make([]NearbyAirport, 0, len(hits))
+	for _, h := range hits {
+		out = append(out, NearbyAirport{Airport: g.located[h.ID], Miles: math.Round(h.Miles*10) / 10})
+	}
+	return out
+}
+
+// Store holds the current Graph and replaces it atomically on reload
+type Store struct {
+	database db.ReferenceStore
+	current  atomic.Pointer[Graph]
+}
+
+// NewStore loads the initial graph
+func NewStore(ctx context.Context, database db.ReferenceStore) (*Store, error) {
+	s := &Store{database: database}
+	if err := s.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Graph returns the current snapshot; callers keep using it even if a reload happens meanwhile
+func (s *Store) Graph() *Graph {
+	return s.current.Load()
+}
+
+// Reload loads a fresh graph and swaps it in
+func (s *Store) Reload(ctx context.Context) error {
+	g, err := Load(ctx, s.database)
+	if err != nil {
+		return err
+	}
+	s.current.Store(g)
+	return nil
+}
+
+// Watch polls the reference data version every interval and reloads when it changed.
+// It returns when ctx is done.
+func (s *Store) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			version, err := s.database.ReferenceVersion(ctx)
+			if err != nil {
+				log.Printf("Route graph: version check: %v", err)
+				continue
+			}
+			if version == s.Graph().Version {
+				continue
+			}
+			if err := s.Reload(ctx); err != nil {
+				log.Printf("Route graph: reload: %v", err)
+				continue
+			}
+			log.Printf("Route graph reloaded (version %d)", version)
+		}
+	}
+}
+
+// SnapshotCache keeps graphs loaded from dataset snapshots for pinned searches. Snapshots never
+// change, so a cached graph stays valid; the least recently used one is dropped when full.
+type SnapshotCache struct {
+	database db.SnapshotStore
+	size     int
+
+	mu     sync.Mutex
+	graphs map[int64]*Graph
+	used   []int64 // least recently used first
+}
+
+// NewSnapshotCache holds up to size graphs (at least one)
+func NewSnapshotCache(database db.SnapshotStore, size int) *SnapshotCache {
+	return &SnapshotCache{database: database, size: max(size, 1), graphs: make(map[int64]*Graph)}
+}
+
+// Graph returns the graph of a snapshot, loading it on first use. It fails with
+// db.ErrSnapshotNotFound for unknown or deleted snapshots.
+func (c *SnapshotCache) Graph(ctx context.Context, id int64) (*Graph, error) {
+	c.mu.Lock()
+	g := c.graphs[id]
+	if g != nil {
+		c.touch(id)
+	}
+	c.mu.Unlock()
+	if g != nil {
+		return g, nil
+	}
+	// Loading happens outside the lock; concurrent first requests may load the same snapshot twice
+	g, err := Load(ctx, c.database.SnapshotData(id))
+	if err != nil {
+		return nil, err
+	}
+	g.Snapshot = id
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached := c.graphs[id]; cached != nil {
+		return cached, nil
+	}
+	for len(c.used) >= c.size {
+		delete(c.graphs, c.used[0])
+		c.used = c.used[1:]
+	}
+	c.graphs[id] = g
+	c.used = append(c.used, id)
+	return g, nil
+}
+
+// Forget drops a snapshot's graph, e.g. after the snapshot was deleted
+func (c *SnapshotCache) Forget(id int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.graphs[id]; !ok {
+		return
+	}
+	delete(c.graphs, id)
+	for i, used := range c.used {
+		if used == id {
+			c.used = append(c.used[:i], c.used[i+1:]...)
+			break
+		}
+	}
+}
+
+// touch marks id most recently used; c.mu must be held
+func (c *SnapshotCache) touch(id int64) {
+	for i, used := range c.used {
+		if used == id {
+			c.used = append(append(c.used[:i], c.used[i+1:]...), id)
+			return
+		}
+	}
+}
@@ -5,28 +5,86 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io/fs"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gin-contrib/static"
 	"github.com/gin-gonic/gin"
+	dbfiles "triangle_travel/db"
 	"triangle_travel/internal/api"
 	"triangle_travel/internal/auth"
+	"triangle_travel/internal/backup"
 	"triangle_travel/internal/db"
+	"triangle_travel/internal/mail"
+	"triangle_travel/internal/migrate"
+	"triangle_travel/internal/oidc"
+	"triangle_travel/internal/phone"
+	"triangle_travel/internal/routegraph"
+	"triangle_travel/internal/sms"
 )
 
-// Run starts the HTTP server
-func Run() {
+// Run starts the HTTP server. ui is the embedded frontend build (nil if not compiled in);
+// a build/ directory under -data takes precedence.
+func Run(ui fs.FS) {
 	host := flag.String("host", "localhost", "Server host")
 	port := flag.Int("port", 8080, "Server port")
-	dataDir := flag.String("data", ".", "Project root (contains db/data.sqlite3)")
+	dataDir := flag.String("data", ".", "Project root (contains db/data.sqlite3; created on first run)")
+	dsn := flag.String("database", os.Getenv("DATABASE_URL"), "PostgreSQL URL; empty uses SQLite under -data")
+	autoMigrate := flag.Bool("migrate", false, "Apply pending migrations at startup instead of refusing to start")
+	reloadInterval := flag.Duration("route-reload", 30*time.Second, "How often to check route data for changes (0 disables)")
+	dbTimeout := flag.Duration("db-timeout", 5*time.Second, "Deadline for a request's database work; exceeded requests get 504 (0 disables)")
+	adminToken := flag.String("admin-token", os.Getenv("ADMIN_TOKEN"), "Bearer tokens for /api/admin as name:token,... (a bare token is named admin; empty disables it)")
+	backupDir := flag.String("backup-dir", "", "Directory for admin backups (default <data>/db/backups)")
+	backupKeep := flag.Int("backup-keep", 7, "Newest backups to keep (0 keeps all)")
+	snapshotCache := flag.Int("snapshot-cache", 4, "Route graphs of dataset snapshots kept in memory for pinned searches")
+	smsCfg := sms.Config{Kind: sms.KindConsole}
+	if !auth.IsDev() {
+		smsCfg.Kind = sms.KindTwilio
+	}
+	flag.StringVar(&smsCfg.Kind, "sms", smsCfg.Kind, "How login codes are texted: console (log), file or twilio")
+	flag.StringVar(&smsCfg.File, "sms-file", "", "File the file sender appends messages to (JSON lines)")
+	flag.StringVar(&smsCfg.TwilioURL, "twilio-url", sms.DefaultTwilioURL, "Twilio API base URL (point it at cmd/smsgateway for a local stand-in)")
+	flag.StringVar(&smsCfg.TwilioAccountSID, "twilio-sid", os.Getenv("TWILIO_ACCOUNT_SID"), "Twilio account SID")
+	flag.StringVar(&smsCfg.TwilioAuthToken, "twilio-token", os.Getenv("TWILIO_AUTH_TOKEN"), "Twilio auth token")
+	flag.StringVar(&smsCfg.From, "sms-from", os.Getenv("SMS_FROM"), "Number login codes are sent from")
+	mailCfg := mail.Config{Kind: mail.KindConsole}
+	if !auth.IsDev() {
+		mailCfg.Kind = mail.KindSMTP
+	}
+	flag.StringVar(&mailCfg.Kind, "mail", mailCfg.Kind, "How sign-in links are emailed: console (log), file or smtp")
+	flag.StringVar(&mailCfg.File, "mail-file", "", "File the file sender appends emails to (JSON lines)")
+	flag.StringVar(&mailCfg.SMTPAddr, "smtp-addr", os.Getenv("SMTP_ADDR"), "SMTP server host:port (cmd/smtpstub is a local stand-in)")
+	flag.StringVar(&mailCfg.SMTPUser, "smtp-user", os.Getenv("SMTP_USER"), "SMTP username (empty skips AUTH)")
+	flag.StringVar(&mailCfg.SMTPPassword, "smtp-password", os.Getenv("SMTP_PASSWORD"), "SMTP password")
+	flag.StringVar(&mailCfg.From, "mail-from", os.Getenv("MAIL_FROM"), "Address sign-in links are sent from")
+	linkKey := flag.String("email-link-key", os.Getenv("EMAIL_LINK_KEY"), "Secret (32+ bytes) signing emailed sign-in links; empty uses a random one, so links die on restart")
+	publicURL := flag.String("public-url", os.Getenv("PUBLIC_URL"), "Site address links in emails point to (default http://<host>:<port> in development)")
+	passkeyOrigins := flag.String("passkey-origins", os.Getenv("PASSKEY_ORIGINS"), "Origins besides -public-url passkeys may be used from, comma-separated (e.g. the Vite dev server)")
+	oidcCfg := oidc.Config{}
+	flag.StringVar(&oidcCfg.Issuer, "oidc-issuer", os.Getenv("OIDC_ISSUER"), "OpenID Connect provider for single sign-on, e.g. https://login.example.com (empty disables; cmd/oidcmock is a local stand-in)")
+	flag.StringVar(&oidcCfg.ClientID, "oidc-client-id", os.Getenv("OIDC_CLIENT_ID"), "Client ID registered with the OIDC provider")
+	flag.StringVar(&oidcCfg.ClientSecret, "oidc-client-secret", os.Getenv("OIDC_CLIENT_SECRET"), "Client secret from the OIDC provider (empty for a public client)")
+	flag.StringVar(&oidcCfg.Name, "oidc-name", os.Getenv("OIDC_NAME"), "Name of the OIDC provider on the sign-in button (default SSO)")
+	phoneCountry := flag.String("phone-country", "US", "Country of login numbers typed without a country code (ISO code; empty requires one)")
+	phoneAllow := flag.String("phone-allow", os.Getenv("PHONE_ALLOW_COUNTRIES"), "Countries whose numbers may sign in, as ISO codes (e.g. US,CA,GB; empty allows all)")
+	phoneDeny := flag.String("phone-deny", os.Getenv("PHONE_DENY_COUNTRIES"), "Countries whose numbers may not sign in, as ISO codes")
+	dbOpts := db.DefaultOptions()
+	flag.StringVar(&dbOpts.JournalMode, "sqlite-journal", dbOpts.JournalMode, "SQLite journal mode (WAL, DELETE, ...; empty keeps the file's)")
+	flag.StringVar(&dbOpts.Synchronous, "sqlite-synchronous", dbOpts.Synchronous, "SQLite synchronous level (OFF, NORMAL, FULL, EXTRA)")
+	flag.DurationVar(&dbOpts.BusyTimeout, "sqlite-busy-timeout", dbOpts.BusyTimeout, "How long SQLite writers wait for a lock")
+	flag.BoolVar(&dbOpts.ForeignKeys, "sqlite-foreign-keys", dbOpts.ForeignKeys, "Enforce foreign keys in SQLite")
+	flag.IntVar(&dbOpts.MaxOpenConns, "db-max-conns", dbOpts.MaxOpenConns, "Maximum open database connections (0 is unlimited)")
 	flag.Parse()
+	dbOpts.MaxIdleConns = dbOpts.MaxOpenConns
 
 	// Render.com and other PaaS set PORT
 	if p := os.Getenv("PORT"); p != "" {
@@ -35,33 +93,223 @@ func Run() {
 		}
 	}
 
-	database, err := db.New(*dataDir)
+	dbPath := filepath.Join(*dataDir, "db", "data.sqlite3")
+	firstRun := false
+	if *dsn == "" {
+		_, statErr := os.Stat(dbPath)
+		firstRun = os.IsNotExist(statErr)
+		if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+			log.Fatalf("Database: %v", err)
+		}
+	}
+
+	database, err := db.Open(*dataDir, *dsn, dbOpts)
 	if err != nil {
 		log.Fatalf("Database: %v", err)
 	}
 	defer database.Close()
+	if *dsn != "" {
+		// A PostgreSQL database is new when it has never been migrated
+		hasMigrations, err := database.TableExists("schema_migrations")
+		if err != nil {
+			log.Fatalf("Database: %v", err)
+		}
+		firstRun = !hasMigrations
+		dbPath = "PostgreSQL"
+	}
+
+	migrations, err := migrate.Load(dbfiles.Migrations(*dataDir, string(database.Dialect())))
+	if err != nil {
+		log.Fatalf("Migrations: %v", err)
+	}
+	if firstRun || *autoMigrate {
+		ran, err := migrate.Up(database, migrations, 0)
+		if err != nil {
+			log.Fatalf("Migrations: %v", err)
+		}
+		if len(ran) > 0 {
+			log.Printf("Applied %d migrations", len(ran))
+		}
+	}
+	if firstRun {
+		seed, err := dbfiles.SeedData(*dataDir)
+		if err != nil {
+			log.Fatalf("Seed data: %v", err)
+		}
+		if err := migrate.RefreshReference(database, string(seed)); err != nil {
+			log.Fatalf("Seed data: %v", err)
+		}
+		log.Printf("Initialized new database at %s", dbPath)
+	}
+	// Refuse to serve an unmigrated database
+	if err := migrate.Check(database, migrations); err != nil {
+		log.Fatalf("Database: %v (run: go run ./cmd/migrate up, or start with -migrate)", err)
+	}
+	if err := database.PrepareStatements(context.Background()); err != nil {
+		log.Fatalf("Database: %v", err)
+	}
 
 	// Seed dev user when in sandbox/development
 	if auth.IsDev() {
-		_, _ = database.Exec("INSERT OR IGNORE INTO users (phone) VALUES (?)", auth.DevPhone)
+		if _, err := database.EnsureUser(context.Background(), auth.DevPhone); err != nil {
+			log.Fatalf("Dev user: %v", err)
+		}
 		log.Printf("Dev mode: seeded user %s (OTP: %s)", auth.DevPhone, auth.DevOTP)
 	}
 
+	routes, err := routegraph.NewStore(context.Background(), database)
+	if err != nil {
+		log.Fatalf("Route graph: %v", err)
+	}
+	// baseCtx parents the route watcher and every request; canceling it aborts their queries
+	baseCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	if *reloadInterval > 0 {
+		go routes.Watch(baseCtx, *reloadInterval)
+	}
+
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.Default()
 
-	handlers := &api.Handlers{DB: database}
+	adminTokens, err := api.ParseAdminTokens(*adminToken)
+	if err != nil {
+		log.Fatalf("Admin: %v", err)
+	}
+	if !auth.IsDev() && smsCfg.Kind != sms.KindTwilio {
+		log.Fatalf("SMS: -sms %s would write login codes where operators can read them; use twilio in production", smsCfg.Kind)
+	}
+	smsSender, err := sms.New(smsCfg)
+	if err != nil {
+		log.Fatalf("SMS: %v", err)
+	}
+	phones, err := phone.NewPolicy(*phoneCountry, *phoneAllow, *phoneDeny)
+	if err != nil {
+		log.Fatalf("Phones: %v", err)
+	}
+	log.Printf("Login numbers: %s", phones)
+	if !auth.IsDev() {
+		switch {
+		case mailCfg.Kind != mail.KindSMTP:
+			log.Fatalf("Mail: -mail %s would write sign-in links where operators can read them; use smtp in production", mailCfg.Kind)
+		case *linkKey == "":
+			log.Fatalf("Mail: set -email-link-key (or EMAIL_LINK_KEY) so sign-in links survive restarts and work on every instance")
+		case *publicURL == "":
+			log.Fatalf("Mail: set -public-url (or PUBLIC_URL) for links in sign-in emails")
+		}
+	}
+	mailSender, err := mail.New(mailCfg)
+	if err != nil {
+		log.Fatalf("Mail: %v", err)
+	}
+	links, err := auth.NewLinkSigner(*linkKey)
+	if err != nil {
+		log.Fatalf("Mail: %v", err)
+	}
+	if *publicURL == "" {
+		*publicURL = fmt.Sprintf("http://%s:%d", *host, *port)
+	}
+	relyingParty, err := auth.NewRelyingParty("Triangle Travel", *publicURL, strings.Split(*passkeyOrigins, ","))
+	if err != nil {
+		log.Fatalf("Passkeys: %v", err)
+	}
+	var oidcProvider *oidc.Provider
+	if oidcCfg.Issuer != "" {
+		// The provider sends the browser back to the page that started the sign-in
+		oidcCfg.RedirectURL = strings.TrimRight(*publicURL, "/") + "/my-flights"
+		if oidcProvider, err = oidc.New(oidcCfg); err != nil {
+			log.Fatalf("OIDC: %v", err)
+		}
+		log.Printf("Single sign-on: %s (redirect URL %s)", oidcCfg.Issuer, oidcCfg.RedirectURL)
+	}
+	handlers := &api.Handlers{
+		DB:             database,
+		Routes:         routes,
+		DBTimeout:      *dbTimeout,
+		AdminTokens:    adminTokens,
+		SnapshotGraphs: routegraph.NewSnapshotCache(database, *snapshotCache),
+		SMS:            smsSender,
+		Phones:         phones,
+		Mail:           mailSender,
+		Links:          links,
+		PublicURL:      strings.TrimRight(*publicURL, "/"),
+		Passkeys:       relyingParty,
+		OIDC:           oidcProvider,
+	}
+	if database.Dialect() == db.SQLite {
+		if *backupDir == "" {
+			*backupDir = filepath.Join(*dataDir, "db", "backups")
+		}
+		handlers.Backups = &backup.Set{Source: database, Dir: *backupDir, Keep: *backupKeep}
+	}
 	apiGroup := router.Group("/api")
-	apiGroup.POST("/search", handlers.Search)
+	apiGroup.POST("/search", handlers.OptionalAuthMiddleware, handlers.Search)
 	apiGroup.GET("/cities", handlers.Cities)
+	apiGroup.GET("/award-programs", handlers.AwardPrograms)
+	apiGroup.GET("/snapshots", handlers.ListSnapshots)
+	apiGroup.GET("/airports/nearest", handlers.NearestAirports)
+	apiGroup.GET("/airports/near/:code", handlers.AirportsNear)
 	apiGroup.POST("/chat", handlers.Chat)
+	apiGroup.GET("/rtw/rule-sets", handlers.RTWRuleSets)
+	apiGroup.POST("/rtw/validate", handlers.ValidateRTW)
+	apiGroup.POST("/rtw/suggest", handlers.SuggestRTW)
 	apiGroup.POST("/auth/send-otp", handlers.SendOTP)
 	apiGroup.POST("/auth/verify-otp", handlers.VerifyOTP)
+	apiGroup.POST("/auth/refresh", handlers.Refresh)
+	apiGroup.POST("/auth/email/send", handlers.SendEmailLink)
+	apiGroup.POST("/auth/email/verify", handlers.VerifyEmailLink)
+	apiGroup.POST("/auth/passkeys/login/begin", handlers.BeginPasskeyLogin)
+	apiGroup.POST("/auth/passkeys/login/finish", handlers.FinishPasskeyLogin)
+	apiGroup.GET("/auth/oidc", handlers.OIDCConfig)
+	apiGroup.POST("/auth/oidc/start", handlers.StartOIDCLogin)
+	apiGroup.POST("/auth/oidc/callback", handlers.OIDCCallback)
+	sessionsGroup := apiGroup.Group("/auth")
+	sessionsGroup.Use(handlers.AuthMiddleware)
+	sessionsGroup.POST("/logout", handlers.Logout)
+	sessionsGroup.POST("/email/link", handlers.LinkEmail)
+	sessionsGroup.POST("/phone/link", handlers.LinkPhone)
+	sessionsGroup.POST("/oidc/link", handlers.LinkOIDC)
+	sessionsGroup.POST("/passkeys/register/begin", handlers.BeginPasskeyRegistration)
+	sessionsGroup.POST("/passkeys/register/finish", handlers.FinishPasskeyRegistration)
+	sessionsGroup.GET("/passkeys", handlers.ListPasskeys)
+	sessionsGroup.DELETE("/passkeys/:id", handlers.DeletePasskey)
+	sessionsGroup.GET("/sessions", handlers.ListSessions)
+	sessionsGroup.DELETE("/sessions/:id", handlers.DeleteSession)
+	meGroup := apiGroup.Group("/me")
+	meGroup.Use(handlers.AuthMiddleware)
+	meGroup.GET("", handlers.Me)
+	meGroup.PUT("", handlers.UpdateMe)
+	meGroup.DELETE("", handlers.DeleteMe)
+	meGroup.GET("/export", handlers.ExportMe)
 	flightsGroup := apiGroup.Group("/flights")
 	flightsGroup.Use(handlers.AuthMiddleware)
 	flightsGroup.GET("", handlers.ListFlights)
 	flightsGroup.POST("", handlers.AddFlight)
 	flightsGroup.DELETE("/:id", handlers.DeleteFlight)
+	adminGroup := apiGroup.Group("/admin")
+	adminGroup.Use(handlers.AdminMiddleware)
+	adminGroup.POST("/backups", handlers.CreateBackup)
+	adminGroup.GET("/backups", handlers.ListBackups)
+	adminGroup.GET("/backups/:name", handlers.DownloadBackup)
+	adminGroup.GET("/airports", handlers.AdminAirports)
+	adminGroup.GET("/airports/:code", handlers.AdminCodeUsage)
+	adminGroup.PUT("/airports/:code", handlers.PutAirport)
+	adminGroup.DELETE("/airports/:code", handlers.DeleteAirport)
+	adminGroup.GET("/cities", handlers.AdminCityAirports)
+	adminGroup.POST("/cities", handlers.AddCityAirport)
+	adminGroup.DELETE("/cities/:city/:airport", handlers.DeleteCityAirport)
+	adminGroup.GET("/routes", handlers.AdminRoutes)
+	adminGroup.POST("/routes", handlers.AddRoute)
+	adminGroup.DELETE("/routes/:from/:alliance/:to", handlers.DeleteRoute)
+	adminGroup.GET("/distances", handlers.AdminDistances)
+	adminGroup.PUT("/distances/:from/:to", handlers.PutDistance)
+	adminGroup.DELETE("/distances/:from/:to", handlers.DeleteDistance)
+	adminGroup.GET("/airlines", handlers.AdminAirlines)
+	adminGroup.PUT("/airlines/:code", handlers.PutAirline)
+	adminGroup.DELETE("/airlines/:code", handlers.DeleteAirline)
+	adminGroup.GET("/audit", handlers.AuditLog)
+	adminGroup.POST("/snapshots", handlers.CreateSnapshot)
+	adminGroup.GET("/snapshots/diff", handlers.DiffSnapshots)
+	adminGroup.DELETE("/snapshots/:id", handlers.DeleteSnapshot)
 
 	buildPath := filepath.Join(*dataDir, "build")
 	if _, err := os.Stat(buildPath); err == nil {
@@ -69,6 +317,15 @@ func Run() {
 		router.NoRoute(func(c *gin.Context) {
 			c.File(filepath.Join(buildPath, "index.html"))
 		})
+	} else if ui != nil {
+		index, err := fs.ReadFile(ui, "index.html")
+		if err != nil {
+			log.Fatalf("Embedded UI: %v", err)
+		}
+		router.Use(static.Serve("/", embeddedFS{http.FS(ui)}))
+		router.NoRoute(func(c *gin.Context) {
+			c.Data(http.StatusOK, "text/html; charset=utf-8", index)
+		})
 	}
 
 	serverPath := fmt.Sprintf("%s:%d", *host, *port)
@@ -77,6 +334,7 @@ func Run() {
 		Handler:      router,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
+		BaseContext:  func(net.Listener) context.Context { return baseCtx },
 	}
 
 	go func() {
@@ -86,6 +344,19 @@ func Run() {
 		}
 	}()
 
+	// SIGHUP reloads the route graph immediately
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := routes.Reload(baseCtx); err != nil {
+				log.Printf("Route graph: reload: %v", err)
+				continue
+			}
+			log.Println("Route graph reloaded")
+		}
+	}()
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
@@ -94,7 +365,23 @@ func Run() {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalln(err)
+		// Abort the queries of requests still running so they answer 503 instead of hanging
+		stop()
+		log.Printf("Shutdown: %v", err)
 	}
 	log.Println("Server exiting")
 }
+
+// embeddedFS adapts the embedded UI to static.ServeFileSystem
+type embeddedFS struct {
+	http.FileSystem
+}
+
+func (e embeddedFS) Exists(prefix string, path string) bool {
+	f, err := e.Open(strings.TrimPrefix(path, prefix))
+	if err != nil {
+		return false
+	}
+	f.Close()
+	return true
+}
@@ -0,0 +1,166 @@
+package sms
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Gateway is a local stand-in for the Twilio Messages API: it accepts messages like the real
+// one and keeps them in memory instead of delivering them. Twilio's magic test numbers fail the
+// same way they do there:
+//
+//	+15005550001  invalid number (21211)
+//	+15005550004  blocked (21610)
+//	+15005550009  can't receive SMS (21614)
+//
+// A From that isn't a number, short code, alphanumeric sender ID or messaging service SID
+// fails with 21212, as a misconfigured sender does there.
+//
+// GET /messages?to= lists what was sent (newest last) and DELETE /messages clears it; these
+// aren't part of the real API.
+type Gateway struct {
+	AccountSID string // empty accepts any credentials
+	AuthToken  string
+	OnMessage  func(Message) // called for each accepted message, if set
+
+	mu       sync.Mutex
+	messages []Message
+}
+
+var gatewayFailures = map[string]struct {
+	code    int
+	message string
+}{
+	"+15005550001": {21211, "The 'To' number is not a valid phone number."},
+	"+15005550004": {21610, "Attempt to send to unsubscribed recipient"},
+	"+15005550009": {21614, "'To' number is not a valid mobile number"},
+}
+
+// Handler returns the gateway's HTTP routes
+func (g *Gateway) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /2010-04-01/Accounts/{sid}/Messages.json", g.create)
+	mux.HandleFunc("GET /messages", g.list)
+	mux.HandleFunc("DELETE /messages", g.clear)
+	return mux
+}
+
+// Messages returns the messages received so far, optionally only those to one number
+func (g *Gateway) Messages(to string) []Message {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	out := []Message{}
+	for _, m := range g.messages {
+		if to == "" || m.To == to {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+func (g *Gateway) create(w http.ResponseWriter, r *http.Request) {
+	sid, token, ok := r.BasicAuth()
+	if g.AccountSID != "" && (!ok || sid != r.PathValue("sid") ||
+		subtle.ConstantTimeCompare([]byte(sid), []byte(g.AccountSID)) != 1 ||
+		subtle.ConstantTimeCompare([]byte(token), []byte(g.AuthToken)) != 1) {
+		gatewayError(w, http.StatusUnauthorized, 20003, "Authenticate")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		gatewayError(w, http.StatusBadRequest, 21602, "Invalid request body")
+		return
+	}
+	to, from, body := r.PostForm.Get("To"), r.PostForm.Get("From"), r.PostForm.Get("Body")
+	switch {
+	case to == "":
+		gatewayError(w, http.StatusBadRequest, 21604, "A 'To' phone number is required.")
+		return
+	case from == "":
+		gatewayError(w, http.StatusBadRequest, 21603, "A 'From' phone number is required.")
+		return
+	case !validFrom(from):
+		gatewayError(w, http.StatusBadRequest, 21212, "The 'From' number "+from+" is not a valid phone number, shortcode, or alphanumeric sender ID.")
+		return
+	case strings.TrimSpace(body) == "":
+		gatewayError(w, http.StatusBadRequest, 21602, "Message body is required.")
+		return
+	}
+	if f, ok := gatewayFailures[to]; ok {
+		gatewayError(w, http.StatusBadRequest, f.code, f.message)
+		return
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	m := Message{SID: "SM" + hex.EncodeToString(b), To: to, From: from, Body: body, Created: time.Now().UTC()}
+	g.mu.Lock()
+	g.messages = append(g.messages, m)
+	g.mu.Unlock()
+	if g.OnMessage != nil {
+		g.OnMessage(m)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"sid": m.SID, "to": m.To, "from": m.From, "body": m.Body, "status": "queued",
+		"account_sid": r.PathValue("sid"), "date_created": m.Created.Format(time.RFC1123Z),
+	})
+}
+
+// validFrom reports whether from is a sender Twilio accepts: an E.164 number, a short code,
+// an alphanumeric sender ID or a messaging service SID
+func validFrom(from string) bool {
+	digits := func(s string) bool {
+		for _, c := range s {
+			if c < '0' || c > '9' {
+				return false
+			}
+		}
+		return true
+	}
+	switch {
+	case strings.HasPrefix(from, "+"):
+		return len(from) >= 3 && len(from) <= 16 && digits(from[1:])
+	case strings.HasPrefix(from, "MG"):
+		return len(from) == 34
+	case digits(from):
+		return len(from) == 5 || len(from) == 6
+	}
+	if len(from) > 11 {
+		return false
+	}
+	for _, c := range from {
+		if !(c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == ' ') {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *Gateway) list(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(g.Messages(r.URL.Query().Get("to")))
+}
+
+func (g *Gateway) clear(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	g.messages = nil
+	g.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// gatewayError writes an error in Twilio's format
+func gatewayError(w http.ResponseWriter, status, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code": code, "message": message, "status": status,
+		"more_info": "https://www.twilio.com/docs/errors/" + strconv.Itoa(code),
+	})
+}
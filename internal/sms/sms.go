@@ -0,0 +1,102 @@
+// Package sms delivers text messages (login codes). Sender has implementations for development
+// (Console, File), a Twilio-compatible gateway (Twilio), and Gateway, a local stand-in for that
+// gateway's API so the real adapter can be exercised without an account.
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Sender delivers one text message to an E.164 number
+type Sender interface {
+	Send(ctx context.Context, to, body string) error
+}
+
+// Kinds of sender selectable by configuration
+const (
+	KindConsole = "console"
+	KindFile    = "file"
+	KindTwilio  = "twilio"
+)
+
+// Config selects and configures a Sender
+type Config struct {
+	Kind string // console, file or twilio
+	File string // file: path messages are appended to
+
+	// twilio: the gateway's base URL (empty for the real Twilio API), credentials and sender number
+	TwilioURL        string
+	TwilioAccountSID string
+	TwilioAuthToken  string
+	From             string
+}
+
+// New returns the Sender cfg selects
+func New(cfg Config) (Sender, error) {
+	switch cfg.Kind {
+	case KindConsole:
+		return Console{}, nil
+	case KindFile:
+		if cfg.File == "" {
+			return nil, fmt.Errorf("sms: file sender needs a path")
+		}
+		return &File{Path: cfg.File}, nil
+	case KindTwilio:
+		if cfg.TwilioAccountSID == "" || cfg.TwilioAuthToken == "" || cfg.From == "" {
+			return nil, fmt.Errorf("sms: twilio sender needs an account SID, auth token and from number")
+		}
+		return &Twilio{BaseURL: cfg.TwilioURL, AccountSID: cfg.TwilioAccountSID, AuthToken: cfg.TwilioAuthToken, From: cfg.From}, nil
+	default:
+		return nil, fmt.Errorf("sms: unknown sender %q (want %s, %s or %s)", cfg.Kind, KindConsole, KindFile, KindTwilio)
+	}
+}
+
+// Console logs messages instead of sending them (development only: codes end up in the log)
+type Console struct{}
+
+// Send logs the message
+func (Console) Send(ctx context.Context, to, body string) error {
+	log.Printf("SMS to %s: %s", to, body)
+	return nil
+}
+
+// File appends messages to a file as JSON lines, for development and scripted logins
+type File struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+// Message is a text message as File writes it and Gateway records it
+type Message struct {
+	SID     string    `json:"sid,omitempty"`
+	To      string    `json:"to"`
+	From    string    `json:"from,omitempty"`
+	Body    string    `json:"body"`
+	Created time.Time `json:"created"`
+}
+
+// Send appends the message
+func (f *File) Send(ctx context.Context, to, body string) error {
+	line, err := json.Marshal(Message{To: to, Body: body, Created: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out, err := os.OpenFile(f.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := out.Write(append(line, '\n')); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,96 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultTwilioURL is the real Twilio REST API
+const DefaultTwilioURL = "https://api.twilio.com"
+
+// Error is a message the gateway refused or failed to accept
+type Error struct {
+	Status  int    // HTTP status from the gateway
+	Code    int    // gateway error code, e.g. 21211 for an invalid number
+	Message string // gateway error message
+}
+
+func (e *Error) Error() string {
+	if e.Code != 0 {
+		return fmt.Sprintf("sms gateway: %s (status %d, code %d)", e.Message, e.Status, e.Code)
+	}
+	return fmt.Sprintf("sms gateway: %s (status %d)", e.Message, e.Status)
+}
+
+// recipientCodes are the Twilio error codes that refuse the recipient rather than the request
+// (https://www.twilio.com/docs/api/errors). Other 400s, such as 21212 for an invalid From
+// number, are our configuration's.
+var recipientCodes = map[int]bool{
+	21211: true, // invalid To number
+	21214: true, // To number can't be reached
+	21217: true, // To number isn't a valid phone number
+	21610: true, // recipient unsubscribed (replied STOP)
+	21612: true, // no route to the To number
+	21614: true, // To number isn't a mobile number
+}
+
+// Rejected reports whether the gateway refused the recipient (invalid, blocked or landline
+// number), so retrying won't help; other errors are the gateway's or our configuration's
+func (e *Error) Rejected() bool {
+	return e.Status == http.StatusBadRequest && recipientCodes[e.Code]
+}
+
+// Twilio sends messages through Twilio's Messages API, or anything speaking it (see Gateway)
+type Twilio struct {
+	BaseURL    string // empty for DefaultTwilioURL
+	AccountSID string
+	AuthToken  string
+	From       string
+	Client     *http.Client // nil uses a client with a 10s timeout
+}
+
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
+
+// Send posts the message; a refusal comes back as *Error
+func (t *Twilio) Send(ctx context.Context, to, body string) error {
+	base := t.BaseURL
+	if base == "" {
+		base = DefaultTwilioURL
+	}
+	endpoint := strings.TrimRight(base, "/") + "/2010-04-01/Accounts/" + url.PathEscape(t.AccountSID) + "/Messages.json"
+	form := url.Values{"To": {to}, "From": {t.From}, "Body": {body}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(t.AccountSID, t.AuthToken)
+	client := t.Client
+	if client == nil {
+		client = defaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sms gateway: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	gwErr := &Error{Status: resp.StatusCode, Message: resp.Status}
+	var payload struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&payload) == nil && payload.Message != "" {
+		gwErr.Code, gwErr.Message = payload.Code, payload.Message
+	}
+	return gwErr
+}
@@ -0,0 +1,66 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTwilioSend(t *testing.T) {
+	gw := &Gateway{AccountSID: "ACtest", AuthToken: "token"}
+	srv := httptest.NewServer(gw.Handler())
+	defer srv.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "upstream down", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	tests := []struct {
+		name     string
+		url      string
+		token    string
+		from     string
+		to       string
+		status   int  // of the *Error returned; 0 for success, -1 for an error that isn't one
+		code     int  // gateway error code
+		rejected bool // the recipient is refused
+	}{
+		{"sent", srv.URL, "token", "+15005550006", "+447911123456", 0, 0, false},
+		{"alphanumeric sender", srv.URL, "token", "Triangle", "+447911123456", 0, 0, false},
+		{"invalid number", srv.URL, "token", "+15005550006", "+15005550001", 400, 21211, true},
+		{"blocked number", srv.URL, "token", "+15005550006", "+15005550004", 400, 21610, true},
+		{"not a mobile", srv.URL, "token", "+15005550006", "+15005550009", 400, 21614, true},
+		{"invalid from", srv.URL, "token", "+1 555 0006", "+447911123456", 400, 21212, false},
+		{"bad credentials", srv.URL, "wrong", "+15005550006", "+447911123456", 401, 20003, false},
+		{"gateway failing", failing.URL, "token", "+15005550006", "+447911123456", 503, 0, false},
+		{"gateway unreachable", down.URL, "token", "+15005550006", "+447911123456", -1, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tw := &Twilio{BaseURL: tt.url, AccountSID: "ACtest", AuthToken: tt.token, From: tt.from}
+			err := tw.Send(context.Background(), tt.to, "Your code is 123456")
+			var gwErr *Error
+			switch {
+			case tt.status == 0:
+				if err != nil {
+					t.Fatal(err)
+				}
+				if sent := gw.Messages(tt.to); len(sent) == 0 || sent[len(sent)-1].From != tt.from {
+					t.Errorf("gateway received %+v", sent)
+				}
+			case tt.status < 0:
+				if err == nil || errors.As(err, &gwErr) {
+					t.Errorf("%v, want a transport error", err)
+				}
+			case !errors.As(err, &gwErr):
+				t.Errorf("%v, want *sms.Error", err)
+			case gwErr.Status != tt.status || gwErr.Code != tt.code || gwErr.Rejected() != tt.rejected:
+				t.Errorf("status %d code %d rejected %v, want %d %d %v", gwErr.Status, gwErr.Code, gwErr.Rejected(), tt.status, tt.code, tt.rejected)
+			}
+		})
+	}
+}
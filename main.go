@@ -1,7 +1,15 @@
 package main
 
-import "triangle_travel/internal/server"
+import (
+	"io/fs"
+
+	"triangle_travel/internal/server"
+)
 
 func main() {
-	server.Run()
+	var ui fs.FS
+	if uiEmbedded {
+		ui, _ = fs.Sub(uiFiles, "build")
+	}
+	server.Run(ui)
 }
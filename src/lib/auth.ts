@@ -1,5 +1,6 @@
 const TOKEN_KEY = 'travel_app_token';
 const PHONE_KEY = 'travel_app_phone';
+const REFRESH_KEY = 'travel_app_refresh';
 
 // Dev/sandbox: use sessionStorage (clears on tab close). Prod: use localStorage
 // Dev = Vite dev server (import.meta.env.DEV) or localhost (built app served locally)
@@ -31,10 +32,60 @@ export function clearToken(): void {
   const s = getStorage();
   if (s) {
     s.removeItem(TOKEN_KEY);
+    s.removeItem(REFRESH_KEY);
     s.removeItem(PHONE_KEY);
   }
 }
 
+// Access tokens last 15 minutes; the refresh token trades for a new pair once
+export function setTokens(data: { token: string; refreshToken?: string }): void {
+  setToken(data.token);
+  const s = getStorage();
+  if (s && data.refreshToken) s.setItem(REFRESH_KEY, data.refreshToken);
+}
+
+// One refresh at a time: a refresh token works once, and presenting it twice signs the session out
+let refreshing: Promise<boolean> | null = null;
+
+function refreshSession(): Promise<boolean> {
+  if (!refreshing) {
+    refreshing = (async () => {
+      const s = getStorage();
+      const refreshToken = s?.getItem(REFRESH_KEY);
+      if (!refreshToken) return false;
+      const res = await fetch('/api/auth/refresh', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ refreshToken })
+      });
+      if (!res.ok) return false;
+      setTokens(await res.json());
+      return true;
+    })()
+      .catch(() => false)
+      .finally(() => {
+        refreshing = null;
+      });
+  }
+  return refreshing;
+}
+
+// fetch with the access token, refreshing it once if it has expired. A 401 left after that
+// means the session is over (the stored tokens are cleared).
+export async function authFetch(url: string, init: RequestInit = {}): Promise<Response> {
+  const send = () =>
+    fetch(url, {
+      ...init,
+      headers: { 'Content-Type': 'application/json', Authorization: `Bearer ${getToken() ?? ''}`, ...init.headers }
+    });
+  let res = await send();
+  if (res.status !== 401) return res;
+  const data = await res.clone().json().catch(() => ({}));
+  if (data.code === 'token_expired' && (await refreshSession())) res = await send();
+  if (res.status === 401) clearToken();
+  return res;
+}
+
 export function setPhone(phone: string): void {
   const s = getStorage();
   if (s) s.setItem(PHONE_KEY, phone);
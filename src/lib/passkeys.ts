@@ -0,0 +1,86 @@
+// Passkeys (WebAuthn): the server sends options with binary fields as base64url strings and
+// expects the credential back the same way; these helpers convert to and from ArrayBuffers
+// around navigator.credentials.
+
+function fromBase64url(s: string): ArrayBuffer {
+  const b64 = s.replace(/-/g, '+').replace(/_/g, '/') + '==='.slice((s.length + 3) % 4);
+  const bin = atob(b64);
+  const out = new Uint8Array(bin.length);
+  for (let i = 0; i < bin.length; i++) out[i] = bin.charCodeAt(i);
+  return out.buffer;
+}
+
+function toBase64url(buf: ArrayBuffer | null): string | undefined {
+  if (!buf) return undefined;
+  let bin = '';
+  for (const b of new Uint8Array(buf)) bin += String.fromCharCode(b);
+  return btoa(bin).replace(/\+/g, '-').replace(/\//g, '_').replace(/=+$/, '');
+}
+
+// The publicKey object the server returns, as JSON
+type PasskeyOptions = Record<string, any>;
+
+interface CredentialDescriptorJSON {
+  type: 'public-key';
+  id: string;
+  transports?: AuthenticatorTransport[];
+}
+
+function descriptors(list: CredentialDescriptorJSON[] = []): PublicKeyCredentialDescriptor[] {
+  return list.map((d) => ({ ...d, id: fromBase64url(d.id) }));
+}
+
+export function passkeysSupported(): boolean {
+  return typeof window !== 'undefined' && !!window.PublicKeyCredential && !!navigator.credentials;
+}
+
+// A dismissed or timed-out browser prompt; not worth showing as an error
+export function isPasskeyCancel(e: unknown): boolean {
+  return e instanceof DOMException && (e.name === 'NotAllowedError' || e.name === 'AbortError');
+}
+
+// Runs navigator.credentials.create() with options from /api/auth/passkeys/register/begin
+// and returns the credential for /register/finish
+export async function createPasskey(options: PasskeyOptions) {
+  const publicKey: PublicKeyCredentialCreationOptions = {
+    ...options,
+    challenge: fromBase64url(options.challenge),
+    user: { ...options.user, id: fromBase64url(options.user.id) },
+    excludeCredentials: descriptors(options.excludeCredentials)
+  };
+  const cred = (await navigator.credentials.create({ publicKey })) as PublicKeyCredential | null;
+  if (!cred) throw new Error('No passkey was created');
+  const response = cred.response as AuthenticatorAttestationResponse;
+  return {
+    id: cred.id,
+    type: cred.type,
+    response: {
+      clientDataJSON: toBase64url(response.clientDataJSON),
+      attestationObject: toBase64url(response.attestationObject),
+      transports: response.getTransports?.() ?? []
+    }
+  };
+}
+
+// Runs navigator.credentials.get() with options from /api/auth/passkeys/login/begin and
+// returns the assertion for /login/finish
+export async function getPasskey(options: PasskeyOptions) {
+  const publicKey: PublicKeyCredentialRequestOptions = {
+    ...options,
+    challenge: fromBase64url(options.challenge),
+    allowCredentials: descriptors(options.allowCredentials)
+  };
+  const cred = (await navigator.credentials.get({ publicKey })) as PublicKeyCredential | null;
+  if (!cred) throw new Error('No passkey was chosen');
+  const response = cred.response as AuthenticatorAssertionResponse;
+  return {
+    id: cred.id,
+    type: cred.type,
+    response: {
+      clientDataJSON: toBase64url(response.clientDataJSON),
+      authenticatorData: toBase64url(response.authenticatorData),
+      signature: toBase64url(response.signature),
+      userHandle: toBase64url(response.userHandle)
+    }
+  };
+}
@@ -0,0 +1,12 @@
+//go:build embedui
+
+package main
+
+import "embed"
+
+// Built SvelteKit app (npm run build), compiled in with: go build -tags embedui
+//
+//go:embed all:build
+var uiFiles embed.FS
+
+const uiEmbedded = true
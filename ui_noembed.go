@@ -0,0 +1,10 @@
+//go:build !embedui
+
+package main
+
+import "embed"
+
+// Without the embedui tag the UI is only served from <data>/build on disk
+var uiFiles embed.FS
+
+const uiEmbedded = false